@@ -0,0 +1,475 @@
+package filter
+
+import (
+	"strings"
+
+	"dissemblir/sapModelsGenerator/gpt5mini"
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+// Model is the structural types of parsed metadata, which expressions are
+// checked against. Properties are found by their metadata names and by
+// the aliases Service Layer sends for the "...Property" ones; see
+// sapgen.PropertyAlias.
+type Model struct {
+	types   map[string]map[string]string // entity and complex types: property -> type
+	aliases map[string]map[string]string // type: alias -> metadata name, and the reverse
+	enums   map[string]*gpt5mini.EnumType
+	sets    map[string]string // entity set -> entity type
+}
+
+// NewModel collects the types of schemas, with the properties of their
+// base types and the navigation properties of the entity types.
+func NewModel(schemas []*gpt5mini.Schema) *Model {
+	m := &Model{types: map[string]map[string]string{}, aliases: map[string]map[string]string{}, enums: map[string]*gpt5mini.EnumType{}, sets: map[string]string{}}
+	entities := map[string]*gpt5mini.EntityType{}
+	complexes := map[string]*gpt5mini.ComplexType{}
+	ends := map[string]gpt5mini.AssocEnd{} // Association/Role -> end
+	for _, s := range schemas {
+		for _, e := range s.EntityTypes {
+			entities[s.Namespace+"."+e.Name] = e
+		}
+		for _, c := range s.ComplexTypes {
+			complexes[s.Namespace+"."+c.Name] = c
+		}
+		for _, e := range s.EnumTypes {
+			m.enums[s.Namespace+"."+e.Name] = e
+		}
+		for _, a := range s.Associations {
+			for _, end := range a.Ends {
+				ends[s.Namespace+"."+a.Name+"/"+end.Role] = end
+			}
+		}
+		for _, es := range s.EntitySets {
+			m.sets[es.Name] = es.EntityType
+		}
+	}
+	for qn, e := range entities {
+		props := map[string]string{}
+		var names []string
+		for cur, depth := e, 0; cur != nil && depth < 32; cur, depth = entities[cur.BaseType], depth+1 {
+			names = addProperties(props, names, cur.Properties)
+			for _, np := range cur.NavPropsV4 {
+				props[np.Name] = np.Type
+			}
+			for _, np := range cur.NavPropsV3 {
+				if end, ok := ends[np.Relationship+"/"+np.ToRole]; ok {
+					props[np.Name] = end.Type
+					if end.Multiplicity == "*" {
+						props[np.Name] = "Collection(" + end.Type + ")"
+					}
+				}
+			}
+		}
+		m.types[qn] = props
+		m.addAliases(qn, names)
+	}
+	for qn, c := range complexes {
+		props := map[string]string{}
+		var names []string
+		for cur, depth := c, 0; cur != nil && depth < 32; cur, depth = complexes[cur.BaseType], depth+1 {
+			names = addProperties(props, names, cur.Properties)
+		}
+		m.types[qn] = props
+		m.addAliases(qn, names)
+	}
+	return m
+}
+
+// addProperties adds list to props and their names to names.
+func addProperties(props map[string]string, names []string, list []*gpt5mini.Property) []string {
+	for _, p := range list {
+		if _, ok := props[p.Name]; !ok { // derived types win
+			props[p.Name] = p.Type
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// addAliases records the aliases of the properties names of type qn.
+func (m *Model) addAliases(qn string, names []string) {
+	for name, alias := range sapgen.PropertyAlias(sapgen.AliasWire).Aliases(names) {
+		if m.aliases[qn] == nil {
+			m.aliases[qn] = map[string]string{}
+		}
+		m.aliases[qn][alias] = name
+		m.aliases[qn][name] = alias
+	}
+}
+
+// Parse reads s and checks it against the properties of name, an entity
+// set or a qualified entity or complex type.
+func (m *Model) Parse(name, s string) (Expr, error) {
+	e, err := parse(s)
+	if err == nil {
+		err = m.check(name, e)
+	}
+	if err != nil {
+		return nil, withInput(err, s)
+	}
+	return e, nil
+}
+
+// Check checks e, an expression of Parse, against the properties of
+// name, setting the types of its nodes.
+func (m *Model) Check(name string, e Expr) error {
+	return m.check(name, e)
+}
+
+func (m *Model) check(name string, e Expr) error {
+	root := name
+	if t, ok := m.sets[name]; ok {
+		root = t
+	}
+	if _, ok := m.types[root]; !ok {
+		return errorAt(0, "no entity set or type %s", name)
+	}
+	c := &checker{m: m, root: root, vars: map[string]string{}}
+	if err := c.expr(e); err != nil {
+		return err
+	}
+	if t := e.Type(); t != "" && t != "Edm.Boolean" {
+		return errorAt(e.Pos(), "the filter is of type %s, not a condition", t)
+	}
+	return nil
+}
+
+type checker struct {
+	m    *Model
+	root string
+	vars map[string]string // lambda variable -> element type
+}
+
+// kinds of types, for what an operator or function accepts
+const (
+	kindUnknown = ""
+	kindString  = "string"
+	kindNumber  = "number"
+	kindBool    = "boolean"
+	kindTime    = "date or time"
+	kindGUID    = "guid"
+	kindEnum    = "enum"
+	kindStruct  = "structure"
+	kindColl    = "collection"
+	kindOther   = "other"
+)
+
+func (m *Model) kind(t string) string {
+	switch t {
+	case "":
+		return kindUnknown
+	case "Edm.String":
+		return kindString
+	case "Edm.Boolean":
+		return kindBool
+	case "Edm.Guid":
+		return kindGUID
+	case "Edm.Byte", "Edm.SByte", "Edm.Int16", "Edm.Int32", "Edm.Int64", "Edm.Decimal", "Edm.Double", "Edm.Single":
+		return kindNumber
+	case "Edm.Date", "Edm.DateTime", "Edm.DateTimeOffset", "Edm.TimeOfDay", "Edm.Time":
+		return kindTime
+	}
+	switch {
+	case strings.HasPrefix(t, "Collection("):
+		return kindColl
+	case strings.HasPrefix(t, "Edm."):
+		return kindOther
+	case m.types[t] != nil:
+		return kindStruct
+	}
+	return kindEnum // enum literals of types the model lacks too
+}
+
+func (c *checker) expr(e Expr) error {
+	switch e := e.(type) {
+	case *Literal:
+		if c.m.kind(e.typ) == kindEnum {
+			return c.member(e, e.typ)
+		}
+		return nil
+	case *Path:
+		t, err := c.path(e)
+		e.typ = t
+		if err == nil && c.m.kind(t) == kindColl {
+			return errorAt(e.at, "%s is a collection: use any or all", strings.Join(e.Segments, "/"))
+		}
+		return err
+	case *Unary:
+		if err := c.expr(e.X); err != nil {
+			return err
+		}
+		if e.Op == "not" {
+			e.typ = "Edm.Boolean"
+			return c.want(e.X, kindBool, "not")
+		}
+		e.typ = e.X.Type()
+		return c.want(e.X, kindNumber, "-")
+	case *Binary:
+		return c.binary(e)
+	case *Call:
+		return c.call(e)
+	case *Lambda:
+		return c.lambda(e)
+	}
+	return errorAt(e.Pos(), "unexpected %T", e)
+}
+
+// path resolves the segments of p from the root type or a lambda
+// variable, by metadata name or alias; a collection is allowed as the last
+// segment only.
+func (c *checker) path(p *Path) (string, error) {
+	p.alt = make([]string, len(p.Segments))
+	t, segs := c.root, p.Segments
+	if v, ok := c.vars[segs[0]]; ok {
+		t, segs = v, segs[1:]
+	} else if segs[0] == "$it" {
+		segs = segs[1:]
+	}
+	for i, seg := range segs {
+		at := p.segAt[len(p.Segments)-len(segs)+i]
+		props, ok := c.m.types[t]
+		if !ok {
+			return "", errorAt(at, "%s has no properties: it is a %s", t, c.m.kind(t))
+		}
+		other := c.m.aliases[t][seg]
+		next, ok := props[seg]
+		if !ok {
+			next, ok = props[other]
+		}
+		if !ok {
+			return "", errorAt(at, "%s has no property %s", t, seg)
+		}
+		p.alt[len(p.Segments)-len(segs)+i] = other
+		if c.m.kind(next) == kindColl && i < len(segs)-1 {
+			return "", errorAt(at, "%s is a collection: use any or all", seg)
+		}
+		t = next
+	}
+	return t, nil
+}
+
+func (c *checker) lambda(l *Lambda) error {
+	t, err := c.path(l.Collection)
+	if err != nil {
+		return err
+	}
+	l.Collection.typ = t
+	if c.m.kind(t) != kindColl {
+		return errorAt(l.at, "%s needs a collection, %s is a %s", l.Op, strings.Join(l.Collection.Segments, "/"), c.m.kind(t))
+	}
+	l.typ = "Edm.Boolean"
+	if l.Body == nil {
+		return nil
+	}
+	if l.Var == "$it" {
+		return errorAt(l.at, "$it cannot be a lambda variable")
+	}
+	outer, shadowed := c.vars[l.Var]
+	c.vars[l.Var] = strings.TrimSuffix(strings.TrimPrefix(t, "Collection("), ")")
+	defer func() {
+		if shadowed {
+			c.vars[l.Var] = outer
+		} else {
+			delete(c.vars, l.Var)
+		}
+	}()
+	if err := c.expr(l.Body); err != nil {
+		return err
+	}
+	return c.want(l.Body, kindBool, l.Op)
+}
+
+func (c *checker) binary(b *Binary) error {
+	if err := c.expr(b.Left); err != nil {
+		return err
+	}
+	if list, ok := b.Right.(*List); ok {
+		for _, item := range list.Items {
+			if err := c.expr(item); err != nil {
+				return err
+			}
+			if err := c.comparable(b.at, b.Left, item); err != nil {
+				return err
+			}
+		}
+		b.typ = "Edm.Boolean"
+		return nil
+	}
+	if err := c.expr(b.Right); err != nil {
+		return err
+	}
+	switch b.Op {
+	case "and", "or":
+		b.typ = "Edm.Boolean"
+		if err := c.want(b.Left, kindBool, b.Op); err != nil {
+			return err
+		}
+		return c.want(b.Right, kindBool, b.Op)
+	case "eq", "ne", "gt", "ge", "lt", "le":
+		b.typ = "Edm.Boolean"
+		return c.comparable(b.at, b.Left, b.Right)
+	case "has":
+		b.typ = "Edm.Boolean"
+		if err := c.want(b.Left, kindEnum, "has"); err != nil {
+			return err
+		}
+		return c.comparable(b.at, b.Left, b.Right)
+	}
+	if err := c.want(b.Left, kindNumber, b.Op); err != nil {
+		return err
+	}
+	if err := c.want(b.Right, kindNumber, b.Op); err != nil {
+		return err
+	}
+	b.typ = numericResult(b.Left.Type(), b.Right.Type())
+	return nil
+}
+
+// numericResult is the type of arithmetic on numbers of types l and r.
+func numericResult(l, r string) string {
+	switch {
+	case l == r:
+		return l
+	case l == "" || r == "":
+		return ""
+	case l == "Edm.Double" || r == "Edm.Double" || l == "Edm.Single" || r == "Edm.Single":
+		return "Edm.Double"
+	}
+	return "Edm.Decimal"
+}
+
+// want checks that e is of kind k (or of an unknown type) for op.
+func (c *checker) want(e Expr, k, op string) error {
+	got := c.m.kind(e.Type())
+	if got == kindUnknown || got == k {
+		return nil
+	}
+	return errorAt(e.Pos(), "%s needs a %s, not %s", op, k, describe(e))
+}
+
+func describe(e Expr) string {
+	if l, ok := e.(*Literal); ok && l.Value == nil {
+		return "null"
+	}
+	return e.Type()
+}
+
+// comparable checks that l and r can be compared by the operator at at.
+// Strings compare with dates, GUIDs and enum members (the forms of v2/v3
+// and of Service Layer), numbers with enums by value; null with anything.
+func (c *checker) comparable(at int, l, r Expr) error {
+	if lit, ok := r.(*Literal); ok && lit.Value == nil {
+		return nil
+	}
+	if lit, ok := l.(*Literal); ok && lit.Value == nil {
+		return nil
+	}
+	lk, rk := c.m.kind(l.Type()), c.m.kind(r.Type())
+	if lk == kindEnum || rk == kindEnum {
+		return c.enumOperands(at, l, r)
+	}
+	switch {
+	case lk == kindUnknown || rk == kindUnknown, lk == rk && lk != kindStruct && lk != kindColl:
+		return nil
+	case lk == kindString && (rk == kindTime || rk == kindGUID), rk == kindString && (lk == kindTime || lk == kindGUID):
+		return nil
+	}
+	return errorAt(at, "cannot compare %s with %s", describe(l), describe(r))
+}
+
+// enumOperands checks a comparison with an enum: the other side is the
+// same enum, a number, or a string naming a member.
+func (c *checker) enumOperands(at int, l, r Expr) error {
+	enum, other := l, r
+	if c.m.kind(l.Type()) != kindEnum {
+		enum, other = r, l
+	}
+	switch k := c.m.kind(other.Type()); {
+	case k == kindUnknown || k == kindNumber:
+		return nil
+	case k == kindEnum && other.Type() == enum.Type():
+		return nil
+	case k == kindString:
+		if lit, ok := other.(*Literal); ok {
+			return c.member(lit, enum.Type())
+		}
+	}
+	return errorAt(at, "cannot compare %s with %s", describe(l), describe(r))
+}
+
+// member checks that the value of lit names members of enum; flags
+// enums take several, separated by commas.
+func (c *checker) member(lit *Literal, enum string) error {
+	e, ok := c.m.enums[enum]
+	if !ok {
+		return errorAt(lit.at, "unknown enum type %s", enum)
+	}
+	s, _ := lit.Value.(string)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, m := range e.Members {
+			if m.Name == name || m.Value == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errorAt(lit.at, "%s is not a member of %s", name, enum)
+		}
+	}
+	return nil
+}
+
+// signature is what a function takes and returns; ret "" is the type of
+// the first argument.
+type signature struct {
+	args []string // kinds
+	min  int
+	ret  string
+}
+
+var functions = map[string]signature{
+	"contains":    {[]string{kindString, kindString}, 2, "Edm.Boolean"},
+	"startswith":  {[]string{kindString, kindString}, 2, "Edm.Boolean"},
+	"endswith":    {[]string{kindString, kindString}, 2, "Edm.Boolean"},
+	"substringof": {[]string{kindString, kindString}, 2, "Edm.Boolean"},
+	"indexof":     {[]string{kindString, kindString}, 2, "Edm.Int32"},
+	"concat":      {[]string{kindString, kindString}, 2, "Edm.String"},
+	"substring":   {[]string{kindString, kindNumber, kindNumber}, 2, "Edm.String"},
+	"length":      {[]string{kindString}, 1, "Edm.Int32"},
+	"tolower":     {[]string{kindString}, 1, "Edm.String"},
+	"toupper":     {[]string{kindString}, 1, "Edm.String"},
+	"trim":        {[]string{kindString}, 1, "Edm.String"},
+	"year":        {[]string{kindTime}, 1, "Edm.Int32"},
+	"month":       {[]string{kindTime}, 1, "Edm.Int32"},
+	"day":         {[]string{kindTime}, 1, "Edm.Int32"},
+	"hour":        {[]string{kindTime}, 1, "Edm.Int32"},
+	"minute":      {[]string{kindTime}, 1, "Edm.Int32"},
+	"second":      {[]string{kindTime}, 1, "Edm.Int32"},
+	"round":       {[]string{kindNumber}, 1, ""},
+	"floor":       {[]string{kindNumber}, 1, ""},
+	"ceiling":     {[]string{kindNumber}, 1, ""},
+}
+
+func (c *checker) call(call *Call) error {
+	sig := functions[call.Func]
+	for i, arg := range call.Args {
+		if err := c.expr(arg); err != nil {
+			return err
+		}
+		want := sig.args[i]
+		if want == kindTime && c.m.kind(arg.Type()) == kindString {
+			continue // a date in a string
+		}
+		if err := c.want(arg, want, call.Func); err != nil {
+			return err
+		}
+	}
+	call.typ = sig.ret
+	if sig.ret == "" {
+		call.typ = call.Args[0].Type()
+	}
+	return nil
+}
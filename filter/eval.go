@@ -0,0 +1,501 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Match reports whether e holds for entity: a map[string]any of JSON
+// values, or a value whose JSON form is an object, such as a generated
+// model. Comparisons with null and a missing property are false, except
+// eq null.
+func Match(e Expr, entity any) (bool, error) {
+	v, err := Eval(e, entity)
+	if err != nil {
+		return false, err
+	}
+	b, _ := v.(bool)
+	return b, nil
+}
+
+// Matching returns the items for which e holds, e.g. to filter a cache
+// of generated models.
+func Matching[T any](items []T, e Expr) ([]T, error) {
+	var out []T
+	for _, item := range items {
+		ok, err := Match(e, item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// Eval returns the value of e for entity (see Match): a bool, float64,
+// string, json.Number, a *big.Rat for arithmetic, a map or slice of the
+// entity, or nil.
+func Eval(e Expr, entity any) (any, error) {
+	it, err := object(entity)
+	if err != nil {
+		return nil, err
+	}
+	return eval(e, scope{it: it})
+}
+
+// object returns the JSON object of v; numbers stay json.Number.
+func object(v any) (map[string]any, error) {
+	if m, ok := v.(map[string]any); ok {
+		return m, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("filter: %T is not an object: %w", v, err)
+	}
+	return m, nil
+}
+
+// scope is the entity an expression is evaluated on, with the lambda
+// variables.
+type scope struct {
+	it   map[string]any
+	vars map[string]any
+}
+
+func (s scope) with(name string, v any) scope {
+	vars := map[string]any{name: v}
+	for k, x := range s.vars {
+		if k != name {
+			vars[k] = x
+		}
+	}
+	return scope{s.it, vars}
+}
+
+// resolve returns the value at p from the entity, or from a lambda
+// variable when p starts with one. A property missing under the name
+// written is looked up under its other name, the alias or metadata name
+// the checker found.
+func (s scope) resolve(p *Path) any {
+	var v any = s.it
+	i := 0
+	if x, ok := s.vars[p.Segments[0]]; ok {
+		v, i = x, 1
+	} else if p.Segments[0] == "$it" {
+		i = 1
+	}
+	for ; i < len(p.Segments); i++ {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		x, found := m[p.Segments[i]]
+		if !found && i < len(p.alt) && p.alt[i] != "" {
+			x = m[p.alt[i]]
+		}
+		v = x
+	}
+	return v
+}
+
+// Value returns the value at path in v, a JSON object; nil when a
+// segment is missing.
+func Value(v any, path ...string) any {
+	for _, seg := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[seg]
+	}
+	return v
+}
+
+func eval(e Expr, s scope) (any, error) {
+	switch e := e.(type) {
+	case *Literal:
+		if _, ok := e.Value.(float64); ok {
+			return exactNumber(e), nil
+		}
+		return e.Value, nil
+	case *Path:
+		return s.resolve(e), nil
+	case *Unary:
+		x, err := eval(e.X, s)
+		if err != nil {
+			return nil, err
+		}
+		if e.Op == "not" {
+			b, _ := x.(bool)
+			return !b, nil
+		}
+		if q, ok := x.(*big.Rat); ok {
+			return new(big.Rat).Neg(q), nil
+		}
+		if n, ok := x.(json.Number); ok {
+			if abs, neg := strings.CutPrefix(string(n), "-"); neg {
+				return json.Number(abs), nil
+			}
+			return "-" + n, nil
+		}
+		if f, ok := number(x); ok {
+			return -f, nil
+		}
+		return nil, nil
+	case *Binary:
+		return evalBinary(e, s)
+	case *Call:
+		args := make([]any, len(e.Args))
+		for i, a := range e.Args {
+			v, err := eval(a, s)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		return call(e.Func, args), nil
+	case *Lambda:
+		items, _ := s.resolve(e.Collection).([]any)
+		if e.Body == nil {
+			return len(items) > 0, nil
+		}
+		for _, item := range items {
+			v, err := eval(e.Body, s.with(e.Var, item))
+			if err != nil {
+				return nil, err
+			}
+			b, _ := v.(bool)
+			if e.Op == "any" && b {
+				return true, nil
+			}
+			if e.Op == "all" && !b {
+				return false, nil
+			}
+		}
+		return e.Op == "all", nil
+	}
+	return nil, fmt.Errorf("filter: cannot evaluate %T", e)
+}
+
+func evalBinary(e *Binary, s scope) (any, error) {
+	l, err := eval(e.Left, s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case "and", "or":
+		lb, _ := l.(bool)
+		if e.Op == "and" && !lb || e.Op == "or" && lb {
+			return lb, nil
+		}
+		r, err := eval(e.Right, s)
+		if err != nil {
+			return nil, err
+		}
+		rb, _ := r.(bool)
+		return rb, nil
+	case "in":
+		list, _ := e.Right.(*List)
+		if list == nil {
+			return nil, fmt.Errorf("filter: in needs a list")
+		}
+		for _, item := range list.Items {
+			r, err := eval(item, s)
+			if err != nil {
+				return nil, err
+			}
+			if c, ok := Compare(l, r); ok && c == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	r, err := eval(e.Right, s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case "eq", "ne":
+		eq := l == nil && r == nil
+		if c, ok := Compare(l, r); ok {
+			eq = c == 0
+		}
+		return eq == (e.Op == "eq"), nil
+	case "gt", "ge", "lt", "le":
+		c, ok := Compare(l, r)
+		if !ok {
+			return false, nil
+		}
+		switch e.Op {
+		case "gt":
+			return c > 0, nil
+		case "ge":
+			return c >= 0, nil
+		case "lt":
+			return c < 0, nil
+		}
+		return c <= 0, nil
+	case "has":
+		ls, _ := l.(string)
+		rs, _ := r.(string)
+		flags := strings.Split(ls, ",")
+		for _, want := range strings.Split(rs, ",") {
+			found := false
+			for _, f := range flags {
+				found = found || strings.TrimSpace(f) == strings.TrimSpace(want)
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return arithmetic(e, l, r), nil
+}
+
+// arithmetic computes l op r exactly. div truncates when both operands are
+// of integer types, as in OData; mod has the sign of l. Division by zero
+// gives null.
+func arithmetic(e *Binary, l, r any) any {
+	_, lnum := number(l)
+	_, rnum := number(r)
+	a, ok1 := decimal(l)
+	b, ok2 := decimal(r)
+	if !lnum || !rnum || !ok1 || !ok2 {
+		return nil
+	}
+	switch e.Op {
+	case "add":
+		return new(big.Rat).Add(a, b)
+	case "sub":
+		return new(big.Rat).Sub(a, b)
+	case "mul":
+		return new(big.Rat).Mul(a, b)
+	}
+	if b.Sign() == 0 {
+		return nil
+	}
+	q := new(big.Rat).Quo(a, b)
+	if e.Op == "div" {
+		if isInteger(e.Left.Type()) && isInteger(e.Right.Type()) {
+			return truncate(q)
+		}
+		return q
+	}
+	return new(big.Rat).Sub(a, new(big.Rat).Mul(b, truncate(q)))
+}
+
+// truncate returns the integer part of q.
+func truncate(q *big.Rat) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+}
+
+func isInteger(t string) bool {
+	switch t {
+	case "Edm.Byte", "Edm.SByte", "Edm.Int16", "Edm.Int32", "Edm.Int64":
+		return true
+	}
+	return false
+}
+
+// number returns v as a float64 when it is a number: a JSON one, one of
+// the Go numbers of a map built in code, or the result of arithmetic.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
+		f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// exactNumber returns the value of a number literal as written, without
+// its v3 suffix, so that a Decimal keeps all its digits.
+func exactNumber(l *Literal) any {
+	digits, _ := numberType(l.Text)
+	if _, err := strconv.ParseFloat(digits, 64); err != nil {
+		return l.Value
+	}
+	return json.Number(digits)
+}
+
+// decimal returns v as an exact rational when it is a number: a JSON one,
+// one of the Go numbers of a map built in code, or a numeric string. A
+// float64 stands for its shortest decimal form, so 0.1 is 1/10.
+func decimal(v any) (*big.Rat, bool) {
+	var s string
+	switch v := v.(type) {
+	case *big.Rat:
+		return v, true
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s = fmt.Sprint(v)
+	default:
+		return nil, false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil, false // also rejects the fractions big.Rat reads
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// Compare orders two JSON values: numbers (also against numeric strings,
+// the Int64 and Decimal values of IEEE754Compatible payloads), booleans,
+// dates and times, or strings. Numbers compare exactly, digit by digit,
+// as Edm.Decimal needs. ok is false when they cannot be compared, as with
+// null.
+func Compare(a, b any) (c int, ok bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if _, isNum := number(a); isNum {
+		x, _ := decimal(a)
+		y, isNum := decimal(b)
+		if x == nil || !isNum {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	}
+	if x, isStr := a.(string); isStr {
+		if _, isNum := number(b); isNum {
+			c, ok := Compare(b, a)
+			return -c, ok
+		}
+		y, isStr := b.(string)
+		if !isStr {
+			return 0, false
+		}
+		if tx, ok1 := parseTime(x); ok1 {
+			if ty, ok2 := parseTime(y); ok2 {
+				return cmp3(tx.Before(ty), tx.After(ty)), true
+			}
+		}
+		return strings.Compare(x, y), true
+	}
+	if x, isBool := a.(bool); isBool {
+		y, isBool := b.(bool)
+		if !isBool {
+			return 0, false
+		}
+		return cmp3(!x && y, x && !y), true
+	}
+	return 0, false
+}
+
+func cmp3(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02T15:04", "2006-01-02", "15:04:05.999999999", "15:04"}
+
+// parseTime reads the date and time forms of OData payloads and literals;
+// values without a zone are UTC.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// call evaluates the built-in function fn; a null argument gives null.
+func call(fn string, args []any) any {
+	for _, a := range args {
+		if a == nil {
+			return nil
+		}
+	}
+	str := func(i int) string {
+		if s, ok := args[i].(string); ok {
+			return s
+		}
+		return fmt.Sprint(args[i])
+	}
+	switch fn {
+	case "contains":
+		return strings.Contains(str(0), str(1))
+	case "substringof": // v3: substringof(needle, haystack)
+		return strings.Contains(str(1), str(0))
+	case "startswith":
+		return strings.HasPrefix(str(0), str(1))
+	case "endswith":
+		return strings.HasSuffix(str(0), str(1))
+	case "indexof":
+		return float64(strings.Index(str(0), str(1)))
+	case "concat":
+		return str(0) + str(1)
+	case "substring":
+		s := []rune(str(0))
+		from, _ := number(args[1])
+		start := min(max(int(from), 0), len(s))
+		end := len(s)
+		if len(args) == 3 {
+			n, _ := number(args[2])
+			end = min(start+max(int(n), 0), len(s))
+		}
+		return string(s[start:end])
+	case "length":
+		return float64(len([]rune(str(0))))
+	case "tolower":
+		return strings.ToLower(str(0))
+	case "toupper":
+		return strings.ToUpper(str(0))
+	case "trim":
+		return strings.TrimSpace(str(0))
+	case "round", "floor", "ceiling":
+		f, ok := number(args[0])
+		if !ok {
+			return nil
+		}
+		switch fn {
+		case "round":
+			return math.Round(f)
+		case "floor":
+			return math.Floor(f)
+		}
+		return math.Ceil(f)
+	}
+	t, ok := parseTime(str(0))
+	if !ok {
+		return nil
+	}
+	parts := map[string]int{"year": t.Year(), "month": int(t.Month()), "day": t.Day(), "hour": t.Hour(), "minute": t.Minute(), "second": t.Second()}
+	return float64(parts[fn])
+}
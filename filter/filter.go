@@ -0,0 +1,124 @@
+// Package filter parses OData $filter expressions into a syntax tree,
+// checks them against the entity types of parsed metadata and evaluates
+// them over entities: maps of JSON values, or generated Go models, which
+// are compared in their JSON form. It reads the v4 syntax and the v2/v3
+// one of Service Layer (substringof, datetime'...', 12L), as written by
+// the query builders of the odata package and of query.ts.
+//
+//	m := filter.NewModel(schemas) // gpt5mini.ParseMetadata
+//	e, err := m.Parse("Orders", "CardCode eq 'C001' and DocTotal gt 100")
+//	ok, err := filter.Match(e, order)
+//
+// Errors are *Error, with the byte offset of the offending token.
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr is a node of a parsed $filter.
+type Expr interface {
+	// Pos is the byte offset of the node in the expression; that of the
+	// operator for a Binary.
+	Pos() int
+	// Type is the type of the node's value: an Edm type (Edm.Boolean for a
+	// comparison), a qualified enum, complex or entity type, Collection(T),
+	// or "" when unknown, as for the properties of an unchecked expression.
+	Type() string
+}
+
+type node struct {
+	at  int
+	typ string
+}
+
+func (n *node) Pos() int     { return n.at }
+func (n *node) Type() string { return n.typ }
+
+// Binary is a logical (and, or), comparison (eq, ne, gt, ge, lt, le, has,
+// in) or arithmetic (add, sub, mul, div, mod) operation.
+type Binary struct {
+	node
+	Op          string
+	Left, Right Expr // Right is a *List for in
+}
+
+// Unary is not, or - for a negative number.
+type Unary struct {
+	node
+	Op string
+	X  Expr
+}
+
+// Literal is a constant. Value is a string (also for dates, GUIDs and enum
+// members), a float64, a bool, or nil for null; Text is the literal as
+// written. Its Type is known without a model: Edm.String, Edm.Int32,
+// Edm.DateTimeOffset, the enum type of NS.Type'Member', "" for null.
+type Literal struct {
+	node
+	Value any
+	Text  string
+}
+
+// Path is a property path, A/B/C. Its first segment may be the variable of
+// an enclosing lambda, or $it. A segment may name a property by its alias,
+// Activity for ActivityProperty, or the reverse; a checked path is
+// evaluated against either name.
+type Path struct {
+	node
+	Segments []string
+	segAt    []int
+	alt      []string // per segment: the other name of the property, set by the checker
+}
+
+// Call is a call of a built-in function such as contains or year.
+type Call struct {
+	node
+	Func string // lower case
+	Args []Expr
+}
+
+// Lambda is Collection/any(Var: Body) or Collection/all(Var: Body); Body
+// is nil for any().
+type Lambda struct {
+	node
+	Collection *Path
+	Op         string
+	Var        string
+	Body       Expr
+}
+
+// List is the right operand of in: (a, b, c).
+type List struct {
+	node
+	Items []Expr
+}
+
+// Error is a syntax or type error at a byte offset of an expression.
+type Error struct {
+	Input string
+	Pos   int
+	Msg   string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("at %d: %s", e.Pos, e.Msg) }
+
+// Caret returns the expression with a caret under the error position, for
+// messages spanning two lines.
+func (e *Error) Caret() string {
+	pos := min(max(e.Pos, 0), len(e.Input))
+	return e.Input + "\n" + strings.Repeat(" ", len([]rune(e.Input[:pos]))) + "^"
+}
+
+func errorAt(at int, format string, args ...any) *Error {
+	return &Error{Pos: at, Msg: fmt.Sprintf(format, args...)}
+}
+
+// withInput sets the input of err when it is an *Error.
+func withInput(err error, input string) error {
+	if e, ok := err.(*Error); ok {
+		e.Input = input
+	}
+	return err
+}
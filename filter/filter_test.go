@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/gpt5mini"
+)
+
+const testMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+      <EntityType Name="Activity">
+        <Key><PropertyRef Name="ActivityCode"/></Key>
+        <Property Name="ActivityCode" Type="Edm.Int32" Nullable="false"/>
+        <Property Name="ActivityProperty" Type="Edm.String"/>
+        <Property Name="U_TypeProperty" Type="Edm.String"/>
+        <Property Name="DocTotal" Type="Edm.Decimal" Scale="6"/>
+        <Property Name="DocNum" Type="Edm.Int32"/>
+      </EntityType>
+      <EntityContainer Name="ServiceLayer">
+        <EntitySet Name="Activities" EntityType="SAPB1.Activity"/>
+      </EntityContainer>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+func testModel(t *testing.T) *Model {
+	t.Helper()
+	schemas, err := gpt5mini.ParseMetadata(strings.NewReader(testMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewModel(schemas)
+}
+
+// activity is a generated model with the alias as its JSON name.
+type activity struct {
+	ActivityCode int    `json:"ActivityCode"`
+	Activity     string `json:"Activity,omitempty"`
+}
+
+func TestAliasProperty(t *testing.T) {
+	m := testModel(t)
+	entities := map[string]any{
+		"alias":    map[string]any{"Activity": "cn_Call"},
+		"metadata": map[string]any{"ActivityProperty": "cn_Call"},
+		"model":    activity{ActivityCode: 1, Activity: "cn_Call"},
+	}
+	for _, s := range []string{"ActivityProperty eq 'cn_Call'", "Activity eq 'cn_Call'", "$it/Activity eq 'cn_Call'"} {
+		e, err := m.Parse("Activities", s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		for name, entity := range entities {
+			if ok, err := Match(e, entity); err != nil || !ok {
+				t.Errorf("%s on the %s name = %v, %v; want true", s, name, ok, err)
+			}
+		}
+	}
+	for _, s := range []string{"Activitys eq 'x'", "U_Type eq 'x'"} {
+		if _, err := m.Parse("Activities", s); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestCompareDecimal(t *testing.T) {
+	m := testModel(t)
+	tests := []struct {
+		filter string
+		total  any
+		want   bool
+	}{
+		{"DocTotal eq 100.5000000000000000001", json.Number("100.50"), false},
+		{"DocTotal eq 100.5000000000000000001", 100.5, false},
+		{"DocTotal gt 100.5000000000000000001", json.Number("100.50"), false},
+		{"DocTotal lt 100.5000000000000000001", json.Number("100.50"), true},
+		{"DocTotal eq 100.5", json.Number("100.50"), true},
+		{"DocTotal eq 100.5", "100.500000", true},
+		{"DocTotal eq 0.1", 0.1, true},
+		{"DocTotal eq 0.1M", json.Number("0.1"), true},
+		{"DocTotal eq -1.5", json.Number("-1.50"), true},
+		{"DocTotal in (1, 2.25)", json.Number("2.250"), true},
+		{"DocTotal ge 12345678901234567890.01", json.Number("12345678901234567890.001"), false},
+	}
+	for _, tt := range tests {
+		e, err := m.Parse("Activities", tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		if got, err := Match(e, map[string]any{"DocTotal": tt.total}); err != nil || got != tt.want {
+			t.Errorf("%s with %v = %v, %v; want %v", tt.filter, tt.total, got, err, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	m := testModel(t)
+	entity := map[string]any{"DocTotal": 0.1, "DocNum": json.Number("5")}
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"DocTotal add 0.2 eq 0.3", true},
+		{"DocTotal mul 3 eq 0.3", true},
+		{"DocTotal sub 0.3 eq -0.2", true},
+		{"DocTotal div 2 eq 0.05", true},
+		{"DocNum div 2 eq 2", true},
+		{"DocNum div 2 eq 2.5", false},
+		{"DocNum div 2.0 eq 2.5", true},
+		{"-DocNum div 2 eq -2", true},
+		{"DocNum mod 3 eq 2", true},
+		{"-DocNum mod 3 eq -2", true},
+		{"DocTotal mod 0.03 eq 0.01", true},
+		{"DocNum div 0 eq null", true},
+		{"round(DocNum div 2.0) eq 3", true},
+	}
+	for _, tt := range tests {
+		e, err := m.Parse("Activities", tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		if got, err := Match(e, entity); err != nil || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v", tt.filter, got, err, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want int
+		ok   bool
+	}{
+		{json.Number("1"), 1.0, 0, true},
+		{1, json.Number("1.0"), 0, true},
+		{"2", json.Number("10"), -1, true},
+		{json.Number("1"), "1/2", 0, false},
+		{"2024-01-02", "2024-01-02T00:00:00Z", 0, true},
+		{true, false, 1, true},
+		{nil, 1.0, 0, false},
+	}
+	for _, tt := range tests {
+		if c, ok := Compare(tt.a, tt.b); c != tt.want || ok != tt.ok {
+			t.Errorf("Compare(%#v, %#v) = %d, %v; want %d, %v", tt.a, tt.b, c, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokLiteral // strings, numbers and typed literals: datetime'...', NS.Enum'...'
+	tokPunct   // ( ) , / : -
+)
+
+type token struct {
+	kind tokKind
+	text string // as written
+	at   int
+	lit  *Literal // of a tokLiteral
+}
+
+var (
+	reGUID      = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`)
+	reDate      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:\d{2})?)?`)
+	reTimeOfDay = regexp.MustCompile(`^\d{2}:\d{2}(:\d{2}(\.\d+)?)?`)
+	reNumber    = regexp.MustCompile(`^\d+(\.\d+)?([eE][+-]?\d+)?[LlMmDdFf]?`)
+)
+
+// literalPrefixes are the types of the prefixed literals of v2/v3.
+var literalPrefixes = map[string]string{
+	"datetime":       "Edm.DateTime",
+	"datetimeoffset": "Edm.DateTimeOffset",
+	"guid":           "Edm.Guid",
+	"time":           "Edm.Time",
+	"duration":       "Edm.Duration",
+	"binary":         "Edm.Binary",
+	"x":              "Edm.Binary",
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	literal := func(at int, text, typ string, v any) {
+		toks = append(toks, token{tokLiteral, text, at, &Literal{node{at, typ}, v, text}})
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("(),/:-", c) >= 0:
+			toks = append(toks, token{tokPunct, string(c), i, nil})
+			i++
+		case c == '\'':
+			v, n, err := quoted(s, i)
+			if err != nil {
+				return nil, err
+			}
+			literal(i, s[i:n], "Edm.String", v)
+			i = n
+		case reGUID.MatchString(rest): // bare v4 GUID, before numbers and names
+			m := reGUID.FindString(rest)
+			literal(i, m, "Edm.Guid", m)
+			i += len(m)
+		case reDate.MatchString(rest): // bare v4 date and date-time
+			m := reDate.FindString(rest)
+			typ := "Edm.Date"
+			if strings.Contains(m, "T") {
+				typ = "Edm.DateTimeOffset"
+			}
+			literal(i, m, typ, m)
+			i += len(m)
+		case reTimeOfDay.MatchString(rest):
+			m := reTimeOfDay.FindString(rest)
+			literal(i, m, "Edm.TimeOfDay", m)
+			i += len(m)
+		case c >= '0' && c <= '9':
+			m := reNumber.FindString(rest)
+			digits, typ := numberType(m)
+			f, err := strconv.ParseFloat(digits, 64)
+			if err != nil {
+				return nil, errorAt(i, "bad number %s", m)
+			}
+			literal(i, m, typ, f)
+			i += len(m)
+		case c == '_' || c == '$' || c == '@' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] == '.' || s[i] == '$' || s[i] == '@' ||
+				unicode.IsLetter(rune(s[i])) || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+			name := s[start:i]
+			if i == len(s) || s[i] != '\'' {
+				toks = append(toks, token{tokIdent, name, start, nil})
+				continue
+			}
+			// datetime'...', guid'...' or NS.Enum'Member'
+			v, n, err := quoted(s, i)
+			if err != nil {
+				return nil, err
+			}
+			typ, ok := literalPrefixes[strings.ToLower(name)]
+			if !ok && strings.Contains(name, ".") {
+				typ, ok = name, true
+			}
+			if !ok {
+				return nil, errorAt(start, "unknown literal prefix %s", name)
+			}
+			literal(start, s[start:n], typ, v)
+			i = n
+		default:
+			return nil, errorAt(i, "unexpected %q", c)
+		}
+	}
+	return append(toks, token{tokEOF, "", len(s), nil}), nil
+}
+
+// numberType returns the digits of a number literal and its type: that of
+// its v3 suffix, Edm.Int32 or Edm.Int64 for integers, else Edm.Decimal.
+func numberType(m string) (string, string) {
+	switch m[len(m)-1] {
+	case 'L', 'l':
+		return m[:len(m)-1], "Edm.Int64"
+	case 'M', 'm':
+		return m[:len(m)-1], "Edm.Decimal"
+	case 'D', 'd':
+		return m[:len(m)-1], "Edm.Double"
+	case 'F', 'f':
+		return m[:len(m)-1], "Edm.Single"
+	}
+	if strings.ContainsAny(m, ".eE") {
+		return m, "Edm.Decimal"
+	}
+	if _, err := strconv.ParseInt(m, 10, 32); err != nil {
+		return m, "Edm.Int64"
+	}
+	return m, "Edm.Int32"
+}
+
+// quoted reads the literal quoted at s[i], in which a quote is doubled,
+// and returns the index after it.
+func quoted(s string, i int) (string, int, error) {
+	var b strings.Builder
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != '\'' {
+			b.WriteByte(s[j])
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == '\'' {
+			b.WriteByte('\'')
+			j++
+			continue
+		}
+		return b.String(), j + 1, nil
+	}
+	return "", 0, errorAt(i, "unterminated string")
+}
+
+// Parse reads a $filter expression without checking it against a model:
+// the Type of its paths is "".
+func Parse(s string) (Expr, error) {
+	e, err := parse(s)
+	return e, withInput(err, s)
+}
+
+// ParseLiteral reads a single literal, such as a value of a key
+// predicate: 42, -1.5, 'C001', 42L, guid'...' or true.
+func ParseLiteral(s string) (*Literal, error) {
+	e, err := parse(s)
+	if err != nil {
+		return nil, withInput(err, s)
+	}
+	if u, ok := e.(*Unary); ok && u.Op == "-" {
+		if l, ok := u.X.(*Literal); ok {
+			if f, ok := l.Value.(float64); ok {
+				return &Literal{node{u.at, l.typ}, -f, s[u.at:]}, nil
+			}
+		}
+	}
+	if l, ok := e.(*Literal); ok {
+		return l, nil
+	}
+	return nil, withInput(errorAt(e.Pos(), "not a literal"), s)
+}
+
+func parse(s string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t.at, "unexpected %s", t.text)
+	}
+	return e, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == text
+}
+
+// keyword consumes the first of words that comes next.
+func (p *parser) keyword(words ...string) (token, bool) {
+	t := p.peek()
+	if t.kind == tokIdent {
+		for _, w := range words {
+			if t.text == w {
+				p.i++
+				return t, true
+			}
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(punct string) error {
+	t := p.next()
+	if t.kind != tokPunct || t.text != punct {
+		if t.kind == tokEOF {
+			return errorAt(t.at, "expected %s at the end", punct)
+		}
+		return errorAt(t.at, "expected %s, found %s", punct, t.text)
+	}
+	return nil
+}
+
+// binary parses operands of next joined by the operators ops, left to right.
+func (p *parser) binary(next func() (Expr, error), ops ...string) (Expr, error) {
+	l, err := next()
+	for err == nil {
+		op, ok := p.keyword(ops...)
+		if !ok {
+			return l, nil
+		}
+		var r Expr
+		if r, err = next(); err == nil {
+			l = &Binary{node: node{at: op.at}, Op: op.text, Left: l, Right: r}
+		}
+	}
+	return nil, err
+}
+
+func (p *parser) parseOr() (Expr, error)  { return p.binary(p.parseAnd, "or") }
+func (p *parser) parseAnd() (Expr, error) { return p.binary(p.parseNot, "and") }
+
+func (p *parser) parseNot() (Expr, error) {
+	if t, ok := p.keyword("not"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{node: node{at: t.at}, Op: "not", X: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.keyword("eq", "ne", "gt", "ge", "lt", "le", "has"); ok {
+		r, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{node: node{at: op.at}, Op: op.text, Left: l, Right: r}, nil
+	}
+	op, ok := p.keyword("in")
+	if !ok {
+		return l, nil
+	}
+	list := &List{node: node{at: p.peek().at}}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for {
+		item, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &Binary{node: node{at: op.at}, Op: "in", Left: l, Right: list}, nil
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	return p.binary(p.parseMultiplicative, "add", "sub")
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	return p.binary(p.parseUnary, "mul", "div", "mod")
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.isPunct("-") {
+		t := p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{node: node{at: t.at}, Op: "-", X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLiteral:
+		return t.lit, nil
+	case tokPunct:
+		if t.text == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		}
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return &Literal{node{t.at, "Edm.Boolean"}, t.text == "true", t.text}, nil
+		case "null":
+			return &Literal{node{t.at, ""}, nil, t.text}, nil
+		}
+		if p.isPunct("(") {
+			return p.parseCall(t)
+		}
+		return p.parsePath(t)
+	case tokEOF:
+		return nil, errorAt(t.at, "expected an operand at the end")
+	}
+	return nil, errorAt(t.at, "expected an operand, found %s", t.text)
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	p.next() // (
+	call := &Call{node: node{at: name.at}, Func: strings.ToLower(name.text)}
+	if _, ok := functions[call.Func]; !ok {
+		return nil, errorAt(name.at, "unknown function %s", name.text)
+	}
+	if p.isPunct(")") {
+		p.next()
+		return call, checkArity(call)
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if p.isPunct(")") {
+			p.next()
+			return call, checkArity(call)
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func checkArity(c *Call) error {
+	f := functions[c.Func]
+	if len(c.Args) < f.min || len(c.Args) > len(f.args) {
+		if f.min == len(f.args) {
+			return errorAt(c.at, "%s takes %d arguments, not %d", c.Func, f.min, len(c.Args))
+		}
+		return errorAt(c.at, "%s takes %d to %d arguments, not %d", c.Func, f.min, len(f.args), len(c.Args))
+	}
+	return nil
+}
+
+// parsePath reads A/B/C, which may end in a lambda: A/any(x:x/B eq 1).
+func (p *parser) parsePath(first token) (Expr, error) {
+	path := &Path{node: node{at: first.at}, Segments: []string{first.text}, segAt: []int{first.at}}
+	for p.isPunct("/") {
+		p.next()
+		seg := p.next()
+		if seg.kind != tokIdent {
+			return nil, errorAt(seg.at, "expected a property name after /")
+		}
+		if (seg.text == "any" || seg.text == "all") && p.isPunct("(") {
+			return p.parseLambda(path, seg)
+		}
+		path.Segments = append(path.Segments, seg.text)
+		path.segAt = append(path.segAt, seg.at)
+	}
+	return path, nil
+}
+
+func (p *parser) parseLambda(coll *Path, op token) (Expr, error) {
+	p.next() // (
+	l := &Lambda{node: node{at: op.at}, Collection: coll, Op: op.text}
+	if p.isPunct(")") {
+		if op.text == "all" {
+			return nil, errorAt(p.peek().at, "all needs a predicate")
+		}
+		p.next()
+		return l, nil
+	}
+	v := p.next()
+	if v.kind != tokIdent {
+		return nil, errorAt(v.at, "expected a lambda variable")
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	l.Var, l.Body = v.text, body
+	return l, p.expect(")")
+}
@@ -0,0 +1,435 @@
+// Package fixtures generates sample entities from parsed metadata, for
+// test data, the mock server and example payloads in docs. The values
+// follow the facets of each property: strings within MaxLength, members
+// of the enum, decimals within Precision and Scale, dates in a range, with
+// complex types and collections (document lines) filled in. Properties
+// are named as Service Layer sends them, Activity for ActivityProperty,
+// unless the propertyAlias option says otherwise. A seed makes
+// the output the same on every run; an Options value read from a JSON
+// profile narrows it.
+//
+//	sets, err := fixtures.Generate(metadata, fixtures.Options{Seed: 1, Count: 3})
+//	for _, s := range sets {
+//		srv.Seed(s.Name, s.Entities...) // mock.Server
+//	}
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"dissemblir/sapModelsGenerator/gpt5mini"
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+// Options shape the generated entities; it is also the JSON form of a
+// profile. Zero fields take the defaults.
+type Options struct {
+	Seed  uint64   `json:"seed"`
+	Count int      `json:"count"` // entities per set, 3 by default
+	Lines int      `json:"lines"` // items of a collection of complex types, 2 by default
+	Sets  []string `json:"sets"`  // entity sets to generate, all by default
+
+	// From and To bound the dates, as 2006-01-02; 2024 by default, so the
+	// output does not change with the clock.
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Values lists the values to pick from for a property, by name or by
+	// Type.Name (the unqualified type). Integer and string keys with values
+	// take them in turn.
+	Values map[string][]any `json:"values"`
+
+	// Nulls is the share of nullable properties left null, 0 to 1.
+	Nulls float64 `json:"nulls"`
+
+	// IEEE754 writes Int64 and Decimal values as strings, as services with
+	// IEEE754Compatible=true send them.
+	IEEE754 bool `json:"ieee754"`
+
+	// PropertyAlias names the "...Property" properties: by their alias, as
+	// Service Layer sends them (the default), or by the metadata name with
+	// "metadata" or "off". Values keys are metadata names in any case.
+	PropertyAlias sapgen.PropertyAlias `json:"propertyAlias"`
+}
+
+// LoadProfile reads Options from a JSON file.
+func LoadProfile(path string) (Options, error) {
+	var opts Options
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return opts, fmt.Errorf("fixtures: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		return opts, fmt.Errorf("fixtures: profile %s: %w", path, err)
+	}
+	return opts, nil
+}
+
+// Set is the generated entities of an entity set.
+type Set struct {
+	Name     string
+	Type     string // qualified
+	Entities []any  // Entity values
+}
+
+// Entity is a JSON object that keeps the order of the properties of its
+// type.
+type Entity []Field
+
+// Field is a property of an Entity.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Get returns the value of the property name, or nil.
+func (e Entity) Get(name string) any {
+	for _, f := range e {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+func (e Entity) set(name string, v any) {
+	for i := range e {
+		if e[i].Name == name {
+			e[i].Value = v
+		}
+	}
+}
+
+// put sets the property name, added at the end when e has none; a derived
+// type may declare a property of its base type again.
+func (e Entity) put(name string, v any) Entity {
+	if slices.ContainsFunc(e, func(f Field) bool { return f.Name == name }) {
+		e.set(name, v)
+		return e
+	}
+	return append(e, Field{name, v})
+}
+
+func (e Entity) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range e {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.Name)
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Generate returns opts.Count entities for each entity set of metadata, in
+// the order of the metadata; for each entity type when it has no entity
+// container. Integer keys count from 1. A single-valued navigation
+// property with referential constraints gets the key of an entity of the
+// target set, when that set is generated too, so $expand finds it.
+func Generate(metadata []byte, opts Options) ([]*Set, error) {
+	schemas, err := gpt5mini.ParseMetadata(bytes.NewReader(metadata))
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	g, err := newGenerator(schemas, opts)
+	if err != nil {
+		return nil, err
+	}
+	var sets []*Set
+	for _, s := range g.sets {
+		if len(opts.Sets) > 0 && !slices.Contains(opts.Sets, s.Name) {
+			continue
+		}
+		t := g.types[s.EntityType]
+		if t == nil {
+			return nil, fmt.Errorf("fixtures: entity set %s: unknown type %s", s.Name, s.EntityType)
+		}
+		rng := g.rand(s.Name)
+		set := &Set{Name: s.Name, Type: s.EntityType}
+		for i := range g.opts.Count {
+			set.Entities = append(set.Entities, g.entity(rng, t, i))
+		}
+		sets = append(sets, set)
+	}
+	for _, name := range opts.Sets {
+		if !slices.ContainsFunc(sets, func(s *Set) bool { return s.Name == name }) {
+			return nil, fmt.Errorf("fixtures: no entity set %s", name)
+		}
+	}
+	g.link(sets)
+	return sets, nil
+}
+
+// generator holds the types of the metadata by qualified name.
+type generator struct {
+	opts      Options
+	from, to  time.Time
+	types     map[string]*gpt5mini.EntityType
+	complexes map[string]*gpt5mini.ComplexType
+	enums     map[string]*gpt5mini.EnumType
+	assocs    map[string]*gpt5mini.Association
+	sets      []*gpt5mini.EntitySet
+}
+
+func newGenerator(schemas []*gpt5mini.Schema, opts Options) (*generator, error) {
+	if opts.Count <= 0 {
+		opts.Count = 3
+	}
+	if opts.Lines <= 0 {
+		opts.Lines = 2
+	}
+	if err := opts.PropertyAlias.Validate(); err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	g := &generator{
+		opts:      opts,
+		types:     map[string]*gpt5mini.EntityType{},
+		complexes: map[string]*gpt5mini.ComplexType{},
+		enums:     map[string]*gpt5mini.EnumType{},
+		assocs:    map[string]*gpt5mini.Association{},
+	}
+	g.from = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.to = time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	for _, bound := range []struct {
+		s string
+		t *time.Time
+	}{{opts.From, &g.from}, {opts.To, &g.to}} {
+		if bound.s == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, bound.s)
+		if err != nil {
+			return nil, fmt.Errorf("fixtures: date %q is not 2006-01-02", bound.s)
+		}
+		*bound.t = t
+	}
+	if g.to.Before(g.from) {
+		return nil, fmt.Errorf("fixtures: dates from %s to %s", opts.From, opts.To)
+	}
+	var types []*gpt5mini.EntitySet
+	for _, s := range schemas {
+		for _, e := range s.EntityTypes {
+			g.types[s.Namespace+"."+e.Name] = e
+		}
+		for _, c := range s.ComplexTypes {
+			g.complexes[s.Namespace+"."+c.Name] = c
+		}
+		for _, e := range s.EnumTypes {
+			g.enums[s.Namespace+"."+e.Name] = e
+		}
+		for _, a := range s.Associations {
+			g.assocs[s.Namespace+"."+a.Name] = a
+		}
+		g.sets = append(g.sets, s.EntitySets...)
+		for _, name := range sortedNames(s.EntityTypes) {
+			types = append(types, &gpt5mini.EntitySet{Name: name, EntityType: s.Namespace + "." + name})
+		}
+	}
+	if len(g.sets) == 0 {
+		g.sets = types
+	}
+	return g, nil
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// rand returns the random source of a set: the entities of a set do not
+// change when other sets are added or left out.
+func (g *generator) rand(name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewPCG(g.opts.Seed, h.Sum64()))
+}
+
+// entity returns the i-th entity of type t.
+func (g *generator) entity(rng *rand.Rand, t *gpt5mini.EntityType, i int) Entity {
+	var chain []*gpt5mini.EntityType
+	for cur, depth := t, 0; cur != nil && depth < 32; cur, depth = g.types[cur.BaseType], depth+1 {
+		chain = append(chain, cur)
+	}
+	keys := g.keys(t)
+	aliases := g.opts.PropertyAlias.Aliases(g.propertyNames(t))
+	var e Entity
+	for _, cur := range slices.Backward(chain) {
+		for _, p := range cur.Properties {
+			var v any
+			if slices.Contains(keys, p.Name) {
+				v = g.key(rng, t.Name, p, i)
+			} else {
+				v = g.property(rng, t.Name, p, 0)
+			}
+			e = e.put(g.opts.PropertyAlias.WireName(p.Name, aliases), v)
+		}
+	}
+	return e
+}
+
+// propertyNames returns the metadata names of the properties of t and its
+// base types.
+func (g *generator) propertyNames(t *gpt5mini.EntityType) []string {
+	var names []string
+	for cur, depth := t, 0; cur != nil && depth < 32; cur, depth = g.types[cur.BaseType], depth+1 {
+		for _, p := range cur.Properties {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// wireName returns the name the property name of the entity type qn is
+// written under.
+func (g *generator) wireName(qn, name string) string {
+	pa := g.opts.PropertyAlias
+	return pa.WireName(name, pa.Aliases(g.propertyNames(g.types[qn])))
+}
+
+// keys returns the key of t, declared by t or a base type.
+func (g *generator) keys(t *gpt5mini.EntityType) []string {
+	for cur, depth := t, 0; cur != nil && depth < 32; cur, depth = g.types[cur.BaseType], depth+1 {
+		if len(cur.Keys) > 0 {
+			return cur.Keys
+		}
+	}
+	return nil
+}
+
+// link makes the entities of sets refer to each other along the
+// navigation properties with referential constraints: the dependent
+// entity, such as an order of a business partner or a line of an order,
+// gets the key of a random principal entity.
+func (g *generator) link(sets []*Set) {
+	byName := map[string]*Set{}
+	for _, s := range sets {
+		byName[s.Name] = s
+	}
+	for _, es := range g.sets {
+		s := byName[es.Name]
+		if s == nil {
+			continue
+		}
+		rng := g.rand(s.Name + "/links")
+		for _, nav := range g.navigations(g.types[s.Type]) {
+			target := byName[es.Bindings[nav.name]]
+			if target == nil {
+				target = g.firstSet(byName, nav.target)
+			}
+			if target == nil || len(target.Entities) == 0 {
+				continue
+			}
+			dependent, principal, pairs := s, target, nav.pairs
+			if nav.coll {
+				dependent, principal, pairs = target, s, nil
+				for _, p := range nav.pairs {
+					pairs = append(pairs, [2]string{p[1], p[0]})
+				}
+			}
+			if g.wholeKey(dependent.Type, pairs) {
+				continue
+			}
+			for _, e := range dependent.Entities {
+				to := principal.Entities[rng.IntN(len(principal.Entities))].(Entity)
+				for _, p := range pairs {
+					e.(Entity).set(g.wireName(dependent.Type, p[0]), to.Get(g.wireName(principal.Type, p[1])))
+				}
+			}
+		}
+	}
+}
+
+// wholeKey reports whether the dependent properties of pairs are the key of
+// the entity type qn, which the link would make the same for several
+// entities.
+func (g *generator) wholeKey(qn string, pairs [][2]string) bool {
+	keys := g.keys(g.types[qn])
+	for _, k := range keys {
+		if !slices.ContainsFunc(pairs, func(p [2]string) bool { return p[0] == k }) {
+			return false
+		}
+	}
+	return len(keys) > 0
+}
+
+// firstSet returns the first generated set of the entity type qn.
+func (g *generator) firstSet(byName map[string]*Set, qn string) *Set {
+	for _, es := range g.sets {
+		if s := byName[es.Name]; s != nil && s.Type == qn {
+			return s
+		}
+	}
+	return nil
+}
+
+// navigation is a navigation property with referential constraints: pairs
+// of a property of the entity and the matching one of the target.
+type navigation struct {
+	name   string
+	target string // qualified entity type
+	coll   bool
+	pairs  [][2]string
+}
+
+// navigations returns the navigation properties of t and its base types
+// that have referential constraints.
+func (g *generator) navigations(t *gpt5mini.EntityType) []navigation {
+	var navs []navigation
+	for cur, depth := t, 0; cur != nil && depth < 32; cur, depth = g.types[cur.BaseType], depth+1 {
+		for _, np := range cur.NavPropsV4 {
+			nav := navigation{name: np.Name, target: strings.TrimSuffix(strings.TrimPrefix(np.Type, "Collection("), ")")}
+			nav.coll = nav.target != np.Type
+			for _, c := range np.Constraints {
+				nav.pairs = append(nav.pairs, [2]string{c.Property, c.ReferencedProperty})
+			}
+			navs = append(navs, nav)
+		}
+		for _, np := range cur.NavPropsV3 {
+			a := g.assocs[np.Relationship]
+			if a == nil || len(a.Dependent.Properties) != len(a.Principal.Properties) {
+				continue
+			}
+			nav := navigation{name: np.Name}
+			for _, end := range a.Ends {
+				if end.Role == np.ToRole {
+					nav.target, nav.coll = end.Type, end.Multiplicity == "*"
+				}
+			}
+			from, to := a.Principal, a.Dependent
+			if np.FromRole == a.Dependent.Role {
+				from, to = to, from
+			}
+			if from.Role != np.FromRole {
+				continue
+			}
+			for i := range from.Properties {
+				nav.pairs = append(nav.pairs, [2]string{from.Properties[i], to.Properties[i]})
+			}
+			navs = append(navs, nav)
+		}
+	}
+	return slices.DeleteFunc(navs, func(nav navigation) bool { return nav.target == "" || len(nav.pairs) == 0 })
+}
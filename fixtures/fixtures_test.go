@@ -0,0 +1,166 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+const testMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+      <EnumType Name="BoStatus">
+        <Member Name="bost_Open" Value="0"/>
+        <Member Name="bost_Close" Value="1"/>
+      </EnumType>
+      <ComplexType Name="DocumentLine">
+        <Property Name="LineNum" Type="Edm.Int32" Nullable="false"/>
+        <Property Name="ItemCode" Type="Edm.String" MaxLength="4"/>
+        <Property Name="Price" Type="Edm.Decimal" Precision="5" Scale="3"/>
+      </ComplexType>
+      <EntityType Name="Document">
+        <Key><PropertyRef Name="DocEntry"/></Key>
+        <Property Name="DocEntry" Type="Edm.Int32" Nullable="false"/>
+        <Property Name="CardCode" Type="Edm.String" MaxLength="3"/>
+        <Property Name="Comments" Type="Edm.String" MaxLength="5"/>
+        <Property Name="DocumentStatus" Type="SAPB1.BoStatus"/>
+        <Property Name="DocTotal" Type="Edm.Decimal" Precision="4" Scale="2"/>
+        <Property Name="Rounding" Type="Edm.Decimal" Precision="3" Scale="0"/>
+        <Property Name="DocumentLines" Type="Collection(SAPB1.DocumentLine)"/>
+      </EntityType>
+      <EntityType Name="Activity">
+        <Key><PropertyRef Name="ActivityCode"/></Key>
+        <Property Name="ActivityCode" Type="Edm.Int32" Nullable="false"/>
+        <Property Name="ActivityProperty" Type="Edm.String"/>
+        <Property Name="U_KindProperty" Type="Edm.String"/>
+      </EntityType>
+      <EntityContainer Name="ServiceLayer">
+        <EntitySet Name="Orders" EntityType="SAPB1.Document"/>
+        <EntitySet Name="Activities" EntityType="SAPB1.Activity"/>
+      </EntityContainer>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+func generate(t *testing.T, opts Options) map[string]*Set {
+	t.Helper()
+	sets, err := Generate([]byte(testMetadata), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]*Set{}
+	for _, s := range sets {
+		byName[s.Name] = s
+	}
+	return byName
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSeed(t *testing.T) {
+	a := generate(t, Options{Seed: 7, Count: 5})
+	b := generate(t, Options{Seed: 7, Count: 5})
+	if marshal(t, a["Orders"].Entities) != marshal(t, b["Orders"].Entities) {
+		t.Error("the same seed gave different orders")
+	}
+	if c := generate(t, Options{Seed: 8, Count: 5}); marshal(t, a["Orders"].Entities) == marshal(t, c["Orders"].Entities) {
+		t.Error("seeds 7 and 8 gave the same orders")
+	}
+	// a set does not change when others are left out
+	only := generate(t, Options{Seed: 7, Count: 5, Sets: []string{"Orders"}})
+	if len(only) != 1 || marshal(t, only["Orders"].Entities) != marshal(t, a["Orders"].Entities) {
+		t.Error("Sets changed the entities of Orders")
+	}
+}
+
+// decimalDigits returns the integer and fraction digits of a decimal.
+func decimalDigits(t *testing.T, v any) (int, int) {
+	t.Helper()
+	s := fmt.Sprint(v)
+	if _, ok := new(big.Rat).SetString(s); !ok {
+		t.Fatalf("%q is not a decimal", s)
+	}
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	return len(strings.TrimLeft(whole, "0")), len(frac)
+}
+
+func TestFacets(t *testing.T) {
+	for _, ieee754 := range []bool{false, true} {
+		orders := generate(t, Options{Seed: 1, Count: 50, IEEE754: ieee754})["Orders"]
+		for _, v := range orders.Entities {
+			e := v.(Entity)
+			for name, max := range map[string]int{"CardCode": 3, "Comments": 5} {
+				if s, _ := e.Get(name).(string); utf8.RuneCountInString(s) > max {
+					t.Errorf("%s %q is longer than MaxLength %d", name, s, max)
+				}
+			}
+			if s := e.Get("DocumentStatus"); !slices.Contains([]any{"bost_Open", "bost_Close"}, s) {
+				t.Errorf("DocumentStatus %v is not a member of BoStatus", s)
+			}
+			for name, facets := range map[string][2]int{"DocTotal": {4, 2}, "Rounding": {3, 0}} {
+				whole, frac := decimalDigits(t, e.Get(name))
+				if whole > facets[0]-facets[1] || frac > facets[1] {
+					t.Errorf("%s %v exceeds Precision %d, Scale %d", name, e.Get(name), facets[0], facets[1])
+				}
+			}
+			lines, _ := e.Get("DocumentLines").([]any)
+			if len(lines) != 2 {
+				t.Fatalf("%d DocumentLines, want 2", len(lines))
+			}
+			for i, l := range lines {
+				line := l.(Entity)
+				if n := line.Get("LineNum"); n != i {
+					t.Errorf("LineNum %v, want %d", n, i)
+				}
+				if s, _ := line.Get("ItemCode").(string); utf8.RuneCountInString(s) > 4 {
+					t.Errorf("ItemCode %q is longer than MaxLength 4", s)
+				}
+				if whole, frac := decimalDigits(t, line.Get("Price")); whole > 2 || frac > 3 {
+					t.Errorf("Price %v exceeds Precision 5, Scale 3", line.Get("Price"))
+				}
+			}
+		}
+	}
+}
+
+func TestPropertyAlias(t *testing.T) {
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{"", []string{"ActivityCode", "Activity", "U_KindProperty"}},
+		{"alias", []string{"ActivityCode", "Activity", "U_KindProperty"}},
+		{"metadata", []string{"ActivityCode", "ActivityProperty", "U_KindProperty"}},
+		{"off", []string{"ActivityCode", "ActivityProperty", "U_KindProperty"}},
+	}
+	for _, tt := range tests {
+		sets, err := Generate([]byte(testMetadata), Options{Count: 1, Sets: []string{"Activities"}, PropertyAlias: sapgen.PropertyAlias(tt.mode)})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.mode, err)
+		}
+		var names []string
+		for _, f := range sets[0].Entities[0].(Entity) {
+			names = append(names, f.Name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("propertyAlias %q: properties %v, want %v", tt.mode, names, tt.want)
+		}
+	}
+	if _, err := Generate([]byte(testMetadata), Options{PropertyAlias: "both"}); err == nil {
+		t.Error("propertyAlias both: no error")
+	}
+}
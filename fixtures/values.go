@@ -0,0 +1,295 @@
+package fixtures
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"dissemblir/sapModelsGenerator/gpt5mini"
+)
+
+// maxDepth stops complex types that contain themselves.
+const maxDepth = 4
+
+var (
+	companies = []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Vandelay", "Stark", "Wayne", "Soylent", "Tyrell"}
+	suffixes  = []string{"Ltd", "GmbH", "Inc", "AG", "SA", "LLC"}
+	cities    = []string{"Berlin", "Hamburg", "Munich", "London", "Paris", "Madrid", "Vienna", "Zurich", "New York", "Toronto"}
+	streets   = []string{"Main Street", "Market Street", "Park Avenue", "Station Road", "High Street", "Mill Lane"}
+	countries = []string{"DE", "GB", "FR", "ES", "AT", "CH", "US", "CA"}
+	words     = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod", "tempor"}
+)
+
+// values returns the values of the profile for the property name of the
+// type typeName.
+func (g *generator) values(typeName, name string) []any {
+	if v, ok := g.opts.Values[typeName+"."+name]; ok {
+		return v
+	}
+	return g.opts.Values[name]
+}
+
+// key returns the key property p of the i-th entity: unique per set.
+func (g *generator) key(rng *rand.Rand, typeName string, p *gpt5mini.Property, i int) any {
+	if vs := g.values(typeName, p.Name); len(vs) > 0 {
+		return vs[i%len(vs)]
+	}
+	switch p.Type {
+	case "Edm.String":
+		code := initials(p.Name) + fmt.Sprintf("%04d", i+1)
+		if p.MaxLength > 0 && len(code) > p.MaxLength {
+			code = strconv.Itoa(i + 1)
+		}
+		return code
+	case "Edm.Int64":
+		return g.int64(int64(i + 1))
+	case "Edm.Byte", "Edm.SByte", "Edm.Int16", "Edm.Int32":
+		return i + 1
+	}
+	return g.property(rng, typeName, p, 0)
+}
+
+// property returns a value of the property p of the type typeName.
+func (g *generator) property(rng *rand.Rand, typeName string, p *gpt5mini.Property, depth int) any {
+	if vs := g.values(typeName, p.Name); len(vs) > 0 {
+		return vs[rng.IntN(len(vs))]
+	}
+	nullable := p.Nullable == nil || *p.Nullable
+	if nullable && g.opts.Nulls > 0 && rng.Float64() < g.opts.Nulls {
+		return nil
+	}
+	if elem, ok := strings.CutPrefix(p.Type, "Collection("); ok {
+		elem = strings.TrimSuffix(elem, ")")
+		n := g.opts.Lines
+		if g.complexes[elem] == nil {
+			n = rng.IntN(3)
+		}
+		if depth >= maxDepth {
+			n = 0
+		}
+		items := make([]any, n)
+		notNull := false
+		for i := range items {
+			item := *p
+			item.Type, item.Nullable = elem, &notNull
+			items[i] = g.property(rng, typeName, &item, depth)
+			if line, ok := items[i].(Entity); ok {
+				numberLine(line, i)
+			}
+		}
+		return items
+	}
+	if c := g.complexes[p.Type]; c != nil {
+		if depth >= maxDepth {
+			return nil
+		}
+		return g.complex(rng, c, depth+1)
+	}
+	if e := g.enums[p.Type]; e != nil {
+		if len(e.Members) == 0 {
+			return nil
+		}
+		return e.Members[rng.IntN(len(e.Members))].Name
+	}
+	return g.primitive(rng, p)
+}
+
+// complex returns a value of the complex type c, with the properties of
+// its base types first.
+func (g *generator) complex(rng *rand.Rand, c *gpt5mini.ComplexType, depth int) Entity {
+	var chain []*gpt5mini.ComplexType
+	for cur, n := c, 0; cur != nil && n < 32; cur, n = g.complexes[cur.BaseType], n+1 {
+		chain = append(chain, cur)
+	}
+	var names []string
+	for _, cur := range chain {
+		for _, p := range cur.Properties {
+			names = append(names, p.Name)
+		}
+	}
+	aliases := g.opts.PropertyAlias.Aliases(names)
+	var e Entity
+	for _, cur := range slices.Backward(chain) {
+		for _, p := range cur.Properties {
+			e = e.put(g.opts.PropertyAlias.WireName(p.Name, aliases), g.property(rng, c.Name, p, depth))
+		}
+	}
+	return e
+}
+
+// numberLine numbers the i-th line of a collection from 0, as Service Layer
+// does with LineNum.
+func numberLine(line Entity, i int) {
+	for _, name := range []string{"LineNum", "LineNumber", "LineId"} {
+		switch line.Get(name).(type) {
+		case int:
+			line.set(name, i)
+		case json.Number:
+			line.set(name, json.Number(strconv.Itoa(i)))
+		}
+	}
+}
+
+// primitive returns a value of the Edm type of p within its facets.
+func (g *generator) primitive(rng *rand.Rand, p *gpt5mini.Property) any {
+	switch p.Type {
+	case "Edm.String":
+		return truncate(text(rng, p.Name), p.MaxLength)
+	case "Edm.Boolean":
+		return rng.IntN(2) == 1
+	case "Edm.Byte":
+		return rng.IntN(256)
+	case "Edm.SByte":
+		return rng.IntN(128)
+	case "Edm.Int16", "Edm.Int32":
+		return 1 + rng.IntN(100)
+	case "Edm.Int64":
+		return g.int64(1 + rng.Int64N(10000))
+	case "Edm.Decimal":
+		return g.decimal(rng, p)
+	case "Edm.Double", "Edm.Single":
+		return json.Number(strconv.FormatFloat(float64(rng.IntN(100000))/100, 'f', 2, 64))
+	case "Edm.Date":
+		return g.date(rng).Format(time.DateOnly)
+	case "Edm.DateTimeOffset":
+		return g.date(rng).Add(clock(rng)).Format(time.RFC3339)
+	case "Edm.DateTime": // v2/v3
+		return g.date(rng).Add(clock(rng)).Format("2006-01-02T15:04:05")
+	case "Edm.TimeOfDay":
+		return time.Time{}.Add(clock(rng)).Format(time.TimeOnly)
+	case "Edm.Time": // v2/v3
+		d := clock(rng)
+		return fmt.Sprintf("PT%dH%dM", int(d.Hours()), int(d.Minutes())%60)
+	case "Edm.Duration":
+		return fmt.Sprintf("PT%dH%dM", rng.IntN(8), rng.IntN(60))
+	case "Edm.Guid":
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(rng.IntN(256))
+		}
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "Edm.Binary":
+		b := make([]byte, 8)
+		for i := range b {
+			b[i] = byte(rng.IntN(256))
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return nil // Edm.Stream, geography and unknown types
+}
+
+// int64 writes an Int64 as a number, or a string with IEEE754.
+func (g *generator) int64(n int64) any {
+	if g.opts.IEEE754 {
+		return strconv.FormatInt(n, 10)
+	}
+	return json.Number(strconv.FormatInt(n, 10))
+}
+
+// decimal returns a decimal within the Precision and Scale of p, with at
+// most four integer and two fraction digits to read like an amount.
+func (g *generator) decimal(rng *rand.Rand, p *gpt5mini.Property) any {
+	scale := p.Scale
+	if scale < 0 {
+		scale = 2
+	}
+	digits := 4
+	if p.Precision > 0 {
+		scale = min(scale, p.Precision)
+		digits = min(digits, p.Precision-scale)
+	}
+	scale = min(scale, 2)
+	n := 0
+	if digits > 0 {
+		n = rng.IntN(pow10(digits))
+	}
+	s := strconv.Itoa(n)
+	if scale > 0 {
+		s += fmt.Sprintf(".%0*d", scale, rng.IntN(pow10(scale)))
+	}
+	if g.opts.IEEE754 {
+		return s
+	}
+	return json.Number(s)
+}
+
+func pow10(n int) int {
+	p := 1
+	for range n {
+		p *= 10
+	}
+	return p
+}
+
+// date returns a day between From and To.
+func (g *generator) date(rng *rand.Rand) time.Time {
+	days := int(g.to.Sub(g.from).Hours()/24) + 1
+	return g.from.AddDate(0, 0, rng.IntN(days))
+}
+
+// clock returns a time of day in office hours.
+func clock(rng *rand.Rand) time.Duration {
+	return time.Duration(8+rng.IntN(10))*time.Hour + time.Duration(rng.IntN(60))*time.Minute
+}
+
+// text returns a string that suits the property name: a code, a company
+// name, an e-mail address, a city, and words otherwise.
+func text(rng *rand.Rand, name string) string {
+	lower := strings.ToLower(name)
+	n := 1 + rng.IntN(999)
+	switch {
+	case strings.Contains(lower, "mail"):
+		return fmt.Sprintf("%s%d@example.com", strings.ToLower(pick(rng, companies)), n)
+	case strings.Contains(lower, "phone"), strings.Contains(lower, "fax"), strings.Contains(lower, "cellular"):
+		return fmt.Sprintf("555-%04d", rng.IntN(10000))
+	case strings.Contains(lower, "country"):
+		return pick(rng, countries)
+	case strings.Contains(lower, "city"):
+		return pick(rng, cities)
+	case strings.Contains(lower, "street"), strings.Contains(lower, "address"):
+		return fmt.Sprintf("%d %s", n, pick(rng, streets))
+	case strings.Contains(lower, "zip"), strings.Contains(lower, "postal"):
+		return fmt.Sprintf("%05d", rng.IntN(100000))
+	case strings.HasSuffix(lower, "code"), strings.HasSuffix(lower, "id"), strings.HasSuffix(lower, "num"):
+		return initials(name) + fmt.Sprintf("%03d", n)
+	case strings.Contains(lower, "name"):
+		return pick(rng, companies) + " " + pick(rng, suffixes)
+	}
+	w := make([]string, 2+rng.IntN(4))
+	for i := range w {
+		w[i] = pick(rng, words)
+	}
+	return strings.ToUpper(w[0][:1]) + strings.Join(w, " ")[1:]
+}
+
+func pick(rng *rand.Rand, list []string) string { return list[rng.IntN(len(list))] }
+
+// initials returns the capitals of a property name, CardCode: CC, or its
+// first letter.
+func initials(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 && name != "" {
+		return strings.ToUpper(name[:1])
+	}
+	return b.String()
+}
+
+// truncate cuts s to max runes; max 0 is unbounded.
+func truncate(s string, max int) string {
+	if r := []rune(s); max > 0 && len(r) > max {
+		return strings.TrimSpace(string(r[:max]))
+	}
+	return s
+}
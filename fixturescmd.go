@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dissemblir/sapModelsGenerator/fixtures"
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+// ========================= fixtures =========================
+
+// runFixtures implements `fixtures --metadata metadata.xml`: sample
+// entities per entity set, written as the <Set>.json files of --out (the
+// fixtures of mock-server) or as one JSON object of arrays by set name.
+func runFixtures(args []string) error {
+	fs := flag.NewFlagSet("fixtures", flag.ExitOnError)
+	metadataPath := fs.String("metadata", "metadata.xml", "Path to the EDMX metadata")
+	out := fs.String("out", "", "Directory to write <Set>.json files to (default: one JSON object on stdout)")
+	profile := fs.String("profile", "", "JSON file of options: seed, count, lines, sets, from, to, values, nulls, ieee754, propertyAlias")
+	seed := fs.Uint64("seed", 0, "Seed of the random values")
+	count := fs.Int("count", 0, "Entities per set (default 3)")
+	lines := fs.Int("lines", 0, "Items of collections of complex types, such as document lines (default 2)")
+	sets := fs.String("sets", "", "Comma-separated entity sets to generate (default: all)")
+	ieee754 := fs.Bool("ieee754", false, "Write Int64 and Decimal values as strings")
+	propertyAlias := fs.String("property-alias", "", `Names of the "...Property" properties: alias (default, as Service Layer sends them) | metadata | off`)
+	fs.Parse(args)
+
+	var opts fixtures.Options
+	if *profile != "" {
+		var err error
+		if opts, err = fixtures.LoadProfile(*profile); err != nil {
+			return err
+		}
+	}
+	// flags given override the profile
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seed":
+			opts.Seed = *seed
+		case "count":
+			opts.Count = *count
+		case "lines":
+			opts.Lines = *lines
+		case "sets":
+			opts.Sets = strings.Split(*sets, ",")
+		case "ieee754":
+			opts.IEEE754 = *ieee754
+		case "property-alias":
+			opts.PropertyAlias = sapgen.PropertyAlias(*propertyAlias)
+		}
+	})
+
+	metadata, err := os.ReadFile(*metadataPath)
+	if err != nil {
+		return err
+	}
+	generated, err := fixtures.Generate(metadata, opts)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		all := fixtures.Entity{}
+		for _, s := range generated {
+			all = append(all, fixtures.Field{Name: s.Name, Value: s.Entities})
+		}
+		b, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("%s\n", b)
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for _, s := range generated {
+		b, err := json.MarshalIndent(s.Entities, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(*out, s.Name+".json"), append(b, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Wrote %d entity sets to %s\n", len(generated), *out)
+	return nil
+}
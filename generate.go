@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"dissemblir/sapModelsGenerator/gpt5mini"
+	"dissemblir/sapModelsGenerator/main2"
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+// ========================= generate --config =========================
+
+// runGenerate implements `generate --config sapgen.json [-target a,b]`.
+// Every metadata source is read once, and decoded at most once per
+// generator family, however many targets use it.
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	configPath := fs.String("config", "sapgen.json", "Path to the generator config file")
+	targets := fs.String("target", "", "Comma-separated target names (default: all)")
+	listQuirks := fs.Bool("list-quirks", false, "Print the SAP quirk rules a target can turn on or off and exit")
+	check := fs.Bool("check", false, "Render into memory and diff against the files on disk; exit 1 when anything differs")
+	fs.Parse(args)
+	if *listQuirks {
+		sapgen.WriteQuirks(os.Stdout)
+		return nil
+	}
+
+	cfg, err := sapgen.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	selected, err := cfg.Select(sapgen.SplitList(*targets))
+	if err != nil {
+		return err
+	}
+
+	srcs := &sourceCache{cfg: cfg}
+	var w sapgen.Writer = sapgen.DiskWriter{}
+	mem := sapgen.NewMemoryWriter()
+	if *check {
+		w = mem
+	}
+	for _, t := range selected {
+		log.Printf("Target %s (%s)", t.Name, t.Kind)
+		if err := generateTarget(srcs, t, w); err != nil {
+			return fmt.Errorf("target %s: %w", t.Name, err)
+		}
+	}
+	if !*check {
+		return nil
+	}
+
+	// perType directories are searched for generated files no target wrote
+	var dirs []string
+	for _, t := range selected {
+		if t.SplitMode() == "perType" {
+			dirs = append(dirs, t.OutDir)
+		}
+	}
+	differs, err := sapgen.Check(os.Stdout, mem.Files, dirs)
+	if err != nil {
+		return err
+	}
+	if differs {
+		return errors.New("generated files are out of date; run generate without --check")
+	}
+	log.Printf("%d generated files are up to date", len(mem.Files))
+	return nil
+}
+
+// sourceHash returns the hash of the metadata t is generated from for the
+// file headers, or "" unless t sets sourceHash.
+func (c *sourceCache) sourceHash(t sapgen.Target) (string, error) {
+	if !t.SourceHash {
+		return "", nil
+	}
+	data, err := c.data(t.Source)
+	if err != nil {
+		return "", err
+	}
+	sources := [][]byte{data}
+	if t.UDF.Source != "" {
+		udf, err := c.data(t.UDF.Source)
+		if err != nil {
+			return "", err
+		}
+		sources = append(sources, udf)
+	}
+	return sapgen.SourceHash(sources...), nil
+}
+
+// sourceCache holds the raw and decoded metadata of each source.
+type sourceCache struct {
+	cfg     *sapgen.Config
+	raw     map[string][]byte
+	arkEdmx map[string]*EDMX
+	zodEdmx map[string]*main2.EDMX
+	goSch   map[string][]*gpt5mini.Schema
+}
+
+func (c *sourceCache) data(name string) ([]byte, error) {
+	if b, ok := c.raw[name]; ok {
+		return b, nil
+	}
+	b, err := os.ReadFile(c.cfg.Sources[name].Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading source %s: %w", name, err)
+	}
+	if c.raw == nil {
+		c.raw = map[string][]byte{}
+	}
+	c.raw[name] = b
+	return b, nil
+}
+
+func (c *sourceCache) arkType(name string) (*EDMX, error) {
+	if e, ok := c.arkEdmx[name]; ok {
+		return e, nil
+	}
+	b, err := c.data(name)
+	if err != nil {
+		return nil, err
+	}
+	var edmx EDMX
+	if err := xml.Unmarshal(b, &edmx); err != nil {
+		return nil, fmt.Errorf("unmarshaling source %s: %w", name, err)
+	}
+	if c.arkEdmx == nil {
+		c.arkEdmx = map[string]*EDMX{}
+	}
+	c.arkEdmx[name] = &edmx
+	return &edmx, nil
+}
+
+func (c *sourceCache) zod(name string) (*main2.EDMX, error) {
+	if e, ok := c.zodEdmx[name]; ok {
+		return e, nil
+	}
+	b, err := c.data(name)
+	if err != nil {
+		return nil, err
+	}
+	edmx, err := main2.ParseEDMX(b)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling source %s: %w", name, err)
+	}
+	if c.zodEdmx == nil {
+		c.zodEdmx = map[string]*main2.EDMX{}
+	}
+	c.zodEdmx[name] = edmx
+	return edmx, nil
+}
+
+func (c *sourceCache) goSchemas(name string) ([]*gpt5mini.Schema, error) {
+	if s, ok := c.goSch[name]; ok {
+		return s, nil
+	}
+	b, err := c.data(name)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := gpt5mini.ParseMetadata(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", name, err)
+	}
+	if c.goSch == nil {
+		c.goSch = map[string][]*gpt5mini.Schema{}
+	}
+	c.goSch[name] = schemas
+	return schemas, nil
+}
+
+func generateTarget(srcs *sourceCache, t sapgen.Target, w sapgen.Writer) error {
+	mode, err := t.DecimalMode()
+	if err != nil {
+		return err
+	}
+	hash, err := srcs.sourceHash(t)
+	if err != nil {
+		return err
+	}
+	var types sapgen.TypeSet
+	if !t.Selection.IsZero() || t.Side() != sapgen.ObjectsAll {
+		// the dependency closure and user objects are computed on the Zod
+		// decode of the source
+		edmx, err := srcs.zod(t.Source)
+		if err != nil {
+			return err
+		}
+		if !t.Selection.IsZero() {
+			if types, err = main2.SelectTypes(edmx, t.Selection); err != nil {
+				return err
+			}
+		}
+		if types, err = main2.ObjectTypes(edmx, t.UserObjects, types); err != nil {
+			return err
+		}
+		log.Printf("Selected %d types", len(types))
+	}
+	switch t.Kind {
+	case sapgen.KindArkType:
+		edmx, err := srcs.arkType(t.Source)
+		if err != nil {
+			return err
+		}
+		decimalMode = sapgen.DecimalNumber
+		if mode != "" {
+			decimalMode = mode
+		}
+		ieee754 = t.IEEE754
+		scalars = t.Scalars
+		quirks = t.Quirks
+		propertyAlias = quirks.PropertyAlias(t.PropertyAlias)
+		output, sourceHash = w, hash
+		if t.Side() == sapgen.ObjectsUser {
+			types = withEnums(edmx, types)
+		}
+		return generateArkType(filterEDMX(edmx, types), t.Naming, t.SplitMode(), t.Out, t.OutDir)
+	case sapgen.KindZod:
+		edmx, err := srcs.zod(t.Source)
+		if err != nil {
+			return err
+		}
+		var udfEdmx *main2.EDMX
+		if t.UDF.Source != "" {
+			if udfEdmx, err = srcs.zod(t.UDF.Source); err != nil {
+				return err
+			}
+		}
+		return main2.Generate(edmx, main2.Options{
+			Split:   t.SplitMode(),
+			Output:  t.Out,
+			OutDir:  t.OutDir,
+			Decimal: mode,
+			IEEE754: t.IEEE754,
+			Types:   types,
+			Naming:  t.Naming,
+			Scalars: t.Scalars,
+			UDF:     t.UDF,
+			UDFEdmx: udfEdmx,
+
+			StandardModule: t.StandardModule,
+			PropertyAlias:  t.PropertyAlias,
+			Quirks:         t.Quirks,
+			Writer:         w,
+			SourceHash:     hash,
+			Query:          t.Query,
+			Batch:          t.Batch,
+			ETag:           t.ETag,
+		})
+	case sapgen.KindGo:
+		schemas, err := srcs.goSchemas(t.Source)
+		if err != nil {
+			return err
+		}
+		var udfSchemas []*gpt5mini.Schema
+		if t.UDF.Source != "" {
+			if udfSchemas, err = srcs.goSchemas(t.UDF.Source); err != nil {
+				return err
+			}
+		}
+		return gpt5mini.Generate(schemas, gpt5mini.Options{
+			PkgName:         t.Package,
+			DecimalMode:     mode,
+			IEEE754:         t.IEEE754,
+			RuntimeImport:   t.Runtime,
+			NsPrefixMode:    strings.ToLower(t.NsPrefix),
+			Nullable:        strings.ToLower(t.Nullable),
+			Patch:           t.Patch,
+			Validate:        t.Validate,
+			Client:          t.Client,
+			Query:           t.Query,
+			ETag:            t.ETag,
+			OutPath:         t.Out,
+			Split:           t.SplitMode(),
+			OutDir:          t.OutDir,
+			PkgPerNamespace: t.PkgPerNamespace,
+			ImportPath:      t.ImportPath,
+			Types:           types,
+			Naming:          t.Naming,
+			Scalars:         t.Scalars,
+			UDF:             t.UDF,
+			UDFSchemas:      udfSchemas,
+			Objects:         t.UserObjects,
+			PropertyAlias:   t.PropertyAlias,
+			Quirks:          t.Quirks,
+			Writer:          w,
+			SourceHash:      hash,
+		})
+	}
+	return fmt.Errorf("unknown kind %q", t.Kind)
+}
@@ -0,0 +1,101 @@
+package gpt5mini
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+// aliasMain decodes Activity from both names and encodes it again.
+const aliasMain = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	for _, in := range []string{
+		` + "`" + `{"ActivityCode":1,"ActivityProperty":"cn_Call"}` + "`" + `,
+		` + "`" + `{"ActivityCode":2,"Activity":"cn_Meeting"}` + "`" + `,
+	} {
+		var a Activity
+		if err := json.Unmarshal([]byte(in), &a); err != nil {
+			panic(err)
+		}
+		out, _ := json.Marshal(a)
+		fmt.Printf("%s %s\n", *a.ActivityProperty, out)
+	}
+}
+`
+
+// runGenerated compiles the Go output for schemas with aliasMain and
+// returns what it printed. The files exist only in an overlay, so the
+// package resolves the odata runtime of this module.
+func runGenerated(t *testing.T, schemas []*Schema, opts Options) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	opts.PkgName = "main"
+	src, err := generate(schemas, withDefaults(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	pkg := filepath.Join(cwd, "aliasrun")
+	replace := map[string]string{}
+	for name, content := range map[string]string{"models.go": src, "main.go": aliasMain} {
+		real := filepath.Join(tmp, name)
+		if err := os.WriteFile(real, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		replace[filepath.Join(pkg, name)] = real
+	}
+	overlay, _ := json.Marshal(map[string]any{"Replace": replace})
+	overlayPath := filepath.Join(tmp, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(gobin, "run", "-overlay", overlayPath, "./aliasrun").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s\n%s", err, out, src)
+	}
+	return string(out)
+}
+
+func TestPropertyAliasUnmarshal(t *testing.T) {
+	schemas, err := ParseMetadata(strings.NewReader(quirksMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mode sapgen.PropertyAlias
+		want string
+	}{
+		{sapgen.AliasWire, `cn_Call {"ActivityCode":1,"Activity":"cn_Call"}
+cn_Meeting {"ActivityCode":2,"Activity":"cn_Meeting"}
+`},
+		{sapgen.AliasMetadata, `cn_Call {"ActivityCode":1,"ActivityProperty":"cn_Call"}
+cn_Meeting {"ActivityCode":2,"ActivityProperty":"cn_Meeting"}
+`},
+	}
+	for _, tt := range tests {
+		if got := runGenerated(t, schemas, Options{PropertyAlias: tt.mode}); got != tt.want {
+			t.Errorf("propertyAlias %s printed\n%s\nwant\n%s", tt.mode, got, tt.want)
+		}
+	}
+}
@@ -2,28 +2,80 @@ package gpt5mini
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"flag"
 	"fmt"
+	"go/token"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"unicode"
+
+	"dissemblir/sapModelsGenerator/main2"
+	"dissemblir/sapModelsGenerator/sapgen"
 )
 
 // TODO: FIX DUPLICATE ENUM VALUES THAT CAUSE ERROR (duplicate key 1 in map literal)
 // usage go run gpt5mini-attempt.go -in sap-metadata.xml -out models_gen.go -pkg models
+// per type: -split perType -outDir ./models [-pkg-per-ns -import-path example.com/app/models]
+// subset:   -include set:Orders,set:BusinessPartners -exclude ns:Company.EXT [-stub-nav]
+// UDFs:     -udf extension [-udf-in company-metadata.xml]
+// objects:  -objects standard | -objects user -standard-module example.com/app/models
+// checks:   -validate (Validate() error on every struct)
+// client:   -client (Client with a service per entity set, see odata.Client)
+// queries:  -query (<Type>Fields descriptors for odata.NewQuery)
+// etags:    -etag (ETag field on entities; If-Match on Update/Replace)
 
 type Options struct {
-	PkgName      string
-	DecimalMode  string // "shopspring" or "string"
-	NsPrefixMode string // "auto", "always", "none"
-	InPath       string
-	OutPath      string
+	PkgName       string
+	DecimalMode   sapgen.DecimalMode // see sapgen.GoDecimalModes
+	IEEE754       bool               // service sends Int64/Decimal as JSON strings
+	RuntimeImport string             // import path of the odata runtime package
+	NsPrefixMode  string             // "auto", "always", "none"
+	Nullable      string             // "pointer" (*T) or "opt" (odata.Opt[T])
+	Patch         bool               // emit PATCH delta rules and helpers
+	Validate      bool               // emit Validate methods checking the metadata facets
+	Client        bool               // emit Client with a service per entity set
+	Query         bool               // emit <Type>Fields query descriptors
+	ETag          bool               // emit the ETag field of entities, sent as If-Match by the client
+	InPath        string
+	OutPath       string
+
+	Split           string // "single" (OutPath/stdout) or "perType" (files under OutDir)
+	OutDir          string
+	PkgPerNamespace bool   // perType: one package (sub-directory) per namespace
+	ImportPath      string // import path of OutDir, for cross-package references
+
+	Selection sapgen.Selection // -include/-exclude, resolved into Types by run
+	Types     sapgen.TypeSet   // types to generate; nil means all
+	Naming    sapgen.Naming    // -case, -rename, -initialisms
+	Scalars   sapgen.Scalars   // user types for Edm types and Type.Property paths
+
+	UDF        sapgen.UDF // -udf, -udf-in (UDF.Source is a file for the flags)
+	UDFSchemas []*Schema  // metadata of the UDF extensions; nil uses the input
+
+	// Objects (-objects, -user-objects) is resolved into Types by run. With
+	// Objects.StandardModule set, types outside Types are referenced from
+	// that package instead of being dropped.
+	Objects sapgen.UserObjects
+
+	// PropertyAlias (-property-alias) handles the B1 "...Property" fields:
+	// they are tagged with the name written, and an UnmarshalJSON accepts
+	// the other one as well.
+	PropertyAlias sapgen.PropertyAlias
+
+	Quirks sapgen.QuirkSet // -quirks: SAP B1 rules turned on or off
+
+	Writer     sapgen.Writer // where the files go; nil writes them to disk
+	SourceHash string        // metadata hash for the file headers; "" leaves it out
+	HashSource bool          // -source-hash: run sets SourceHash from the input
 }
 
 func gpt5mini() {
@@ -36,25 +88,114 @@ func gpt5mini() {
 
 func strconvQuote(s string) string { return strconv.Quote(s) }
 
+const defaultRuntimeImport = "dissemblir/sapModelsGenerator/odata"
+
 func parseFlags() Options {
 	var opts Options
 	flag.StringVar(&opts.InPath, "in", "", "input metadata XML file (default: stdin)")
 	flag.StringVar(&opts.OutPath, "out", "",
 		"output Go file (default: stdout)")
 	flag.StringVar(&opts.PkgName, "pkg", "models", "package name for generated code")
-	flag.StringVar(&opts.DecimalMode, "decimal", "shopspring",
-		"decimal mode: shopspring | string")
+	flag.StringVar(&opts.Split, "split", "single",
+		"output mode: single (-out or stdout) | perType (one file per type under -outDir)")
+	flag.StringVar(&opts.OutDir, "outDir", "models", "output directory for -split=perType")
+	flag.BoolVar(&opts.PkgPerNamespace, "pkg-per-ns", false,
+		"-split=perType: one package per schema namespace")
+	flag.StringVar(&opts.ImportPath, "import-path", "",
+		"import path of -outDir (required with -pkg-per-ns)")
+	decimalMode := flag.String("decimal", "shopspring",
+		"decimal mode: shopspring | bigrat | string | float64")
+	flag.BoolVar(&opts.IEEE754, "ieee754", false,
+		"service uses IEEE754Compatible=true (Int64/Decimal sent as strings)")
+	flag.StringVar(&opts.RuntimeImport, "runtime", defaultRuntimeImport,
+		"import path of the odata runtime package")
 	flag.StringVar(&opts.NsPrefixMode, "ns-prefix", "auto",
 		"namespace prefix mode: auto | always | none")
+	flag.StringVar(&opts.Nullable, "nullable", "pointer",
+		"nullable properties: pointer (*T) | opt (odata.Opt[T]: absent/null/value)")
+	flag.BoolVar(&opts.Patch, "patch", false,
+		"emit PatchDelta/PatchSnapshot helpers producing minimal PATCH bodies")
+	flag.BoolVar(&opts.HashSource, "source-hash", false,
+		"record the SHA-256 of the metadata in the file headers")
+	flag.BoolVar(&opts.Validate, "validate", false,
+		"emit Validate() error checking required fields, MaxLength, decimal precision/scale and enums")
+	flag.BoolVar(&opts.Client, "client", false,
+		"emit Client with a typed service (Get, List, Create, Update, Replace, Delete) per entity set")
+	flag.BoolVar(&opts.Query, "query", false,
+		"emit <Type>Fields descriptors for building $filter/$select/$expand/$orderby with odata.Query")
+	flag.BoolVar(&opts.ETag, "etag", false,
+		"emit an ETag field on entities holding @odata.etag, sent as If-Match by the client")
+	include := flag.String("include", "",
+		"comma-separated type patterns to generate, e.g. set:Orders,type:Item* (default: all)")
+	exclude := flag.String("exclude", "",
+		"comma-separated type patterns to leave out")
+	flag.BoolVar(&opts.Selection.StubNavigation, "stub-nav", false,
+		"do not follow navigation properties when selecting types")
+	flag.StringVar(&opts.Naming.Case, "case", "preserve",
+		"Go identifiers: preserve (U_MyField, Id) | go (UMyField, ID)")
+	renames := flag.String("rename", "",
+		"comma-separated overrides: Type=GoName,Type.Property=FieldName")
+	initialisms := flag.String("initialisms", "",
+		"comma-separated extra initialisms for -case=go, e.g. BPL,UDF")
+	scalars := flag.String("scalars", "",
+		"JSON file mapping Edm types and Type.Property paths to user types")
+	flag.StringVar(&opts.UDF.Mode, "udf", "inline",
+		"user-defined (U_) fields: inline | extension (embedded <Type>UDF structs)")
+	flag.StringVar(&opts.UDF.Source, "udf-in", "",
+		"-udf=extension: metadata XML file the UDF structs are generated from (default: -in)")
+	flag.StringVar(&opts.Objects.Objects, "objects", "all",
+		"objects to generate: all | standard | user (UDTs and UDOs)")
+	userObjects := flag.String("user-objects", "",
+		"comma-separated patterns of more types to treat as user objects")
+	flag.StringVar(&opts.Objects.StandardModule, "standard-module", "",
+		"-objects=user: import path of the package generated with -objects=standard")
+	propertyAlias := flag.String("property-alias", "alias",
+		`"...Property" fields (ActivityProperty): alias (read both, write Activity) | metadata | off`)
+	quirks := flag.String("quirks", "",
+		"comma-separated SAP quirk rules to turn on or off, e.g. yesNoBool,enumCasing=false")
+	listQuirks := flag.Bool("list-quirks", false, "print the SAP quirk rules and exit")
 	flag.Parse()
-	opts.DecimalMode = strings.ToLower(opts.DecimalMode)
-	switch opts.DecimalMode {
-	case "shopspring", "string":
-	default:
+	if *listQuirks {
+		sapgen.WriteQuirks(os.Stdout)
+		os.Exit(0)
+	}
+	opts.PropertyAlias = sapgen.PropertyAlias(*propertyAlias)
+	opts.Objects.UserPatterns = sapgen.SplitList(*userObjects)
+	opts.Selection.Include = sapgen.SplitList(*include)
+	opts.Selection.Exclude = sapgen.SplitList(*exclude)
+	mode, err := sapgen.ParseDecimalMode(*decimalMode, sapgen.GoDecimalModes)
+	if err != nil {
 		fmt.Fprintf(os.Stderr,
-			"warning: -decimal=%q invalid; falling back to shopspring\n",
-			opts.DecimalMode)
-		opts.DecimalMode = "shopspring"
+			"warning: %v; falling back to shopspring\n", err)
+		mode = sapgen.DecimalShopspring
+	}
+	opts.DecimalMode = mode
+	opts.Naming.Initialisms = sapgen.SplitList(*initialisms)
+	if opts.Naming.Renames, err = sapgen.ParseRenames(*renames); err == nil {
+		err = opts.Naming.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; ignoring -case/-rename\n", err)
+		opts.Naming = sapgen.Naming{}
+	}
+	if opts.Scalars, err = sapgen.LoadScalars(*scalars); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; ignoring -scalars\n", err)
+	}
+	if err := opts.UDF.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; keeping UDFs inline\n", err)
+		opts.UDF = sapgen.UDF{}
+	}
+	if err := opts.Objects.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; generating all objects\n", err)
+		opts.Objects = sapgen.UserObjects{}
+	}
+	if err := opts.PropertyAlias.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; falling back to alias\n", err)
+		opts.PropertyAlias = sapgen.AliasWire
+	}
+	if opts.Quirks, err = sapgen.ParseQuirks(*quirks); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; using the default quirks\n", err)
+		opts.Quirks = nil
 	}
 	opts.NsPrefixMode = strings.ToLower(opts.NsPrefixMode)
 	switch opts.NsPrefixMode {
@@ -65,6 +206,23 @@ func parseFlags() Options {
 			opts.NsPrefixMode)
 		opts.NsPrefixMode = "auto"
 	}
+	opts.Nullable = strings.ToLower(opts.Nullable)
+	switch opts.Nullable {
+	case "pointer", "opt":
+	default:
+		fmt.Fprintf(os.Stderr,
+			"warning: -nullable=%q invalid; falling back to pointer\n",
+			opts.Nullable)
+		opts.Nullable = "pointer"
+	}
+	switch opts.Split {
+	case "single", "perType":
+	default:
+		fmt.Fprintf(os.Stderr,
+			"warning: -split=%q invalid; falling back to single\n",
+			opts.Split)
+		opts.Split = "single"
+	}
 	return opts
 }
 
@@ -78,13 +236,73 @@ func run(opts Options) error {
 		}
 		defer in.Close()
 	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+
+	schemas, err := ParseMetadata(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if opts.Objects.Side() == sapgen.ObjectsUser && opts.Objects.StandardModule == "" {
+		return errors.New("-objects=user needs -standard-module")
+	}
+	if opts.Types == nil && (!opts.Selection.IsZero() || opts.Objects.Side() != sapgen.ObjectsAll) {
+		// the dependency closure and object detection are shared with the Zod generator
+		edmx, err := main2.ParseEDMX(data)
+		if err != nil {
+			return fmt.Errorf("parse metadata: %w", err)
+		}
+		if !opts.Selection.IsZero() {
+			if opts.Types, err = main2.SelectTypes(edmx, opts.Selection); err != nil {
+				return fmt.Errorf("select: %w", err)
+			}
+		}
+		if opts.Types, err = main2.ObjectTypes(edmx, opts.Objects, opts.Types); err != nil {
+			return fmt.Errorf("objects: %w", err)
+		}
+	}
+	sources := [][]byte{data}
+	if opts.UDF.Extension() && opts.UDF.Source != "" && opts.UDFSchemas == nil {
+		udfData, err := os.ReadFile(opts.UDF.Source)
+		if err != nil {
+			return fmt.Errorf("read udf input: %w", err)
+		}
+		if opts.UDFSchemas, err = ParseMetadata(bytes.NewReader(udfData)); err != nil {
+			return fmt.Errorf("udf input: %w", err)
+		}
+		sources = append(sources, udfData)
+	}
+	if opts.HashSource {
+		opts.SourceHash = sapgen.SourceHash(sources...)
+	}
+	return Generate(schemas, opts)
+}
 
-	schemas, err := parseEdmx(in)
+// ParseMetadata reads a $metadata document. The schemas are not modified by
+// Generate, so one parse can serve several targets.
+func ParseMetadata(r io.Reader) ([]*Schema, error) {
+	schemas, err := parseEdmx(r)
 	if err != nil {
-		return fmt.Errorf("parse metadata: %w", err)
+		return nil, fmt.Errorf("parse metadata: %w", err)
 	}
 	if len(schemas) == 0 {
-		return errors.New("no <Schema> found in metadata")
+		return nil, errors.New("no <Schema> found in metadata")
+	}
+	return schemas, nil
+}
+
+// Generate writes the Go models for schemas as configured by opts. Empty
+// options take the same defaults as the command-line flags.
+func Generate(schemas []*Schema, opts Options) error {
+	opts = withDefaults(opts)
+	if opts.Split == "perType" {
+		files, err := generateFiles(schemas, opts)
+		if err != nil {
+			return fmt.Errorf("generate: %w", err)
+		}
+		return writeFiles(sapgen.OrDisk(opts.Writer), opts.OutDir, files)
 	}
 
 	gen, err := generate(schemas, opts)
@@ -92,21 +310,156 @@ func run(opts Options) error {
 		return fmt.Errorf("generate: %w", err)
 	}
 
-	out := os.Stdout
 	if opts.OutPath != "" {
-		out, err = os.Create(opts.OutPath)
-		if err != nil {
-			return fmt.Errorf("create output: %w", err)
+		if err := sapgen.OrDisk(opts.Writer).WriteFile(opts.OutPath, []byte(gen)); err != nil {
+			return fmt.Errorf("write: %w", err)
 		}
-		defer out.Close()
+		return nil
 	}
-	w := bufio.NewWriter(out)
+	w := bufio.NewWriter(os.Stdout)
 	if _, err := w.WriteString(gen); err != nil {
 		return fmt.Errorf("write: %w", err)
 	}
 	return w.Flush()
 }
 
+// filterSchemas keeps the selected types. Navigation properties whose target
+// was dropped resolve to interface{} (see resolveTypeRef), unless the target
+// is in the standard package of -objects=user.
+func filterSchemas(schemas []*Schema, types sapgen.TypeSet) []*Schema {
+	if types == nil {
+		return schemas
+	}
+	out := make([]*Schema, 0, len(schemas))
+	for _, s := range schemas {
+		f := &Schema{
+			Namespace:    s.Namespace,
+			EntityTypes:  map[string]*EntityType{},
+			ComplexTypes: map[string]*ComplexType{},
+			EnumTypes:    map[string]*EnumType{},
+			Associations: s.Associations,
+			Container:    s.Container,
+			V3:           s.V3,
+		}
+		for _, es := range s.EntitySets {
+			if ns, name := splitQualified(es.EntityType); types.Has(ns, name) {
+				f.EntitySets = append(f.EntitySets, es)
+			}
+		}
+		for name, t := range s.EntityTypes {
+			if types.Has(s.Namespace, name) {
+				f.EntityTypes[name] = t
+			}
+		}
+		for name, t := range s.ComplexTypes {
+			if types.Has(s.Namespace, name) {
+				f.ComplexTypes[name] = t
+			}
+		}
+		for name, t := range s.EnumTypes {
+			if types.Has(s.Namespace, name) {
+				f.EnumTypes[name] = t
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// splitUDFs removes the U_ properties from the entity and complex types of
+// core and returns, for each of those types ("NS.Name"), the U_ properties of
+// the same type in ext. Types missing from ext get no fields, so every core
+// type has an extension and the core compiles against any company's UDFs.
+func splitUDFs(core, ext []*Schema) ([]*Schema, map[string][]*Property) {
+	udfs := map[string][]*Property{}
+	extProps := func(ns, name string) []*Property {
+		var props []*Property
+		if e := findEntity(ext, ns, name); e != nil {
+			props = e.Properties
+		} else if c := findComplex(ext, ns, name); c != nil {
+			props = c.Properties
+		}
+		var out []*Property
+		for _, p := range props {
+			if sapgen.IsUDF(p.Name) {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	out := make([]*Schema, 0, len(core))
+	for _, s := range core {
+		f := *s
+		f.EntityTypes = map[string]*EntityType{}
+		f.ComplexTypes = map[string]*ComplexType{}
+		for name, t := range s.EntityTypes {
+			c := *t
+			c.Properties = withoutUDFs(t.Properties)
+			f.EntityTypes[name] = &c
+			udfs[s.Namespace+"."+name] = extProps(s.Namespace, name)
+		}
+		for name, t := range s.ComplexTypes {
+			c := *t
+			c.Properties = withoutUDFs(t.Properties)
+			f.ComplexTypes[name] = &c
+			udfs[s.Namespace+"."+name] = extProps(s.Namespace, name)
+		}
+		out = append(out, &f)
+	}
+	return out, udfs
+}
+
+func withoutUDFs(props []*Property) []*Property {
+	out := make([]*Property, 0, len(props))
+	for _, p := range props {
+		if !sapgen.IsUDF(p.Name) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func withDefaults(opts Options) Options {
+	if opts.PkgName == "" {
+		opts.PkgName = "models"
+	}
+	if opts.DecimalMode == "" {
+		opts.DecimalMode = sapgen.DecimalShopspring
+	}
+	if opts.RuntimeImport == "" {
+		opts.RuntimeImport = defaultRuntimeImport
+	}
+	if opts.NsPrefixMode == "" {
+		opts.NsPrefixMode = "auto"
+	}
+	if opts.Nullable == "" {
+		opts.Nullable = "pointer"
+	}
+	if opts.Split == "" {
+		opts.Split = "single"
+	}
+	if opts.OutDir == "" {
+		opts.OutDir = "models"
+	}
+	return opts
+}
+
+// writeFiles writes the perType files and outDir's manifest, removing the
+// files of the previous run that are no longer generated.
+func writeFiles(w sapgen.Writer, outDir string, files []genFile) error {
+	manifest := sapgen.NewManifestWriter(w, outDir)
+	for _, f := range files {
+		path := filepath.Join(outDir, filepath.FromSlash(f.Path))
+		if err := manifest.WriteFile(path, []byte(f.Content)); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+	}
+	if err := manifest.Finish(); err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+	return nil
+}
+
 /* ===========================
    Metadata model structures
    =========================== */
@@ -117,6 +470,15 @@ type Schema struct {
 	ComplexTypes map[string]*ComplexType
 	EnumTypes    map[string]*EnumType
 	Associations map[string]*Association // v3 only
+	Container    string                  // EntityContainer name, "" without one
+	EntitySets   []*EntitySet
+	V3           bool // CSDL of OData v2/v3 (the Microsoft edm namespace)
+}
+
+type EntitySet struct {
+	Name       string
+	EntityType string            // qualified
+	Bindings   map[string]string // v4 NavigationPropertyBinding: path -> target set
 }
 
 type EntityType struct {
@@ -150,15 +512,27 @@ type EnumMember struct {
 }
 
 type Property struct {
-	Name     string
-	Type     string // Edm.* or Qualified.Type or Collection(...)
-	Nullable *bool
+	Name      string
+	Type      string // Edm.* or Qualified.Type or Collection(...)
+	Nullable  *bool
+	MaxLength int // 0: unbounded
+	Precision int // 0: unspecified
+	Scale     int // -1: unspecified or variable
 }
 
 type NavPropertyV4 struct {
-	Name     string
-	Type     string // Collection(NS.Type) or NS.Type
-	Nullable *bool
+	Name        string
+	Type        string // Collection(NS.Type) or NS.Type
+	Nullable    *bool
+	Partner     string          // the navigation property of the target leading back
+	Constraints []RefConstraint // ReferentialConstraint
+}
+
+// RefConstraint pairs a property of the entity with the property of the
+// related entity that holds the same value.
+type RefConstraint struct {
+	Property           string
+	ReferencedProperty string
 }
 
 type NavPropertyV3 struct {
@@ -172,6 +546,14 @@ type Association struct {
 	Namespace string
 	Name      string
 	Ends      []AssocEnd
+	Principal AssocRef // the ReferentialConstraint; empty Role without one
+	Dependent AssocRef
+}
+
+// AssocRef is one side of the ReferentialConstraint of an Association.
+type AssocRef struct {
+	Role       string
+	Properties []string
 }
 
 type AssocEnd struct {
@@ -217,6 +599,7 @@ func parseSchema(dec *xml.Decoder, start xml.StartElement) (*Schema, error) {
 		ComplexTypes: map[string]*ComplexType{},
 		EnumTypes:    map[string]*EnumType{},
 		Associations: map[string]*Association{},
+		V3:           start.Name.Space != "http://docs.oasis-open.org/odata/ns/edm",
 	}
 	for {
 		tok, err := dec.Token()
@@ -250,6 +633,13 @@ func parseSchema(dec *xml.Decoder, start xml.StartElement) (*Schema, error) {
 					return nil, err
 				}
 				s.Associations[a.Name] = a
+			case "EntityContainer":
+				s.Container = attr(tt, "Name")
+				sets, err := parseEntityContainer(dec, tt)
+				if err != nil {
+					return nil, err
+				}
+				s.EntitySets = append(s.EntitySets, sets...)
 			default:
 				if err := skip(dec, tt.Name.Local); err != nil {
 					return nil, err
@@ -300,10 +690,12 @@ func parseEntityType(
 				// v4 has Type attribute; v3 has Relationship attribute
 				if hasAttr(tt, "Type") {
 					np := parseNavPropV4(tt)
-					e.NavPropsV4 = append(e.NavPropsV4, &np)
-					if err := skip(dec, "NavigationProperty"); err != nil {
+					cs, err := parseConstraints(dec, tt)
+					if err != nil {
 						return nil, err
 					}
+					np.Constraints = cs
+					e.NavPropsV4 = append(e.NavPropsV4, &np)
 				} else {
 					np := parseNavPropV3(tt)
 					e.NavPropsV3 = append(e.NavPropsV3, &np)
@@ -428,6 +820,20 @@ func parseAssociation(
 				if err := skip(dec, "End"); err != nil {
 					return nil, err
 				}
+			} else if tt.Name.Local == "Principal" || tt.Name.Local == "Dependent" {
+				ref := AssocRef{Role: attr(tt, "Role")}
+				keys, err := parseKey(dec, tt) // the PropertyRefs, as in a Key
+				if err != nil {
+					return nil, err
+				}
+				ref.Properties = keys
+				if tt.Name.Local == "Principal" {
+					a.Principal = ref
+				} else {
+					a.Dependent = ref
+				}
+			} else if tt.Name.Local == "ReferentialConstraint" {
+				continue // its Principal and Dependent are read above
 			} else {
 				if err := skip(dec, tt.Name.Local); err != nil {
 					return nil, err
@@ -441,6 +847,94 @@ func parseAssociation(
 	}
 }
 
+func parseEntityContainer(dec *xml.Decoder, start xml.StartElement) ([]*EntitySet, error) {
+	var sets []*EntitySet
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tt := tok.(type) {
+		case xml.StartElement:
+			if tt.Name.Local == "EntitySet" {
+				es := &EntitySet{
+					Name:       attr(tt, "Name"),
+					EntityType: attr(tt, "EntityType"),
+				}
+				bindings, err := parseBindings(dec, tt)
+				if err != nil {
+					return nil, err
+				}
+				es.Bindings = bindings
+				sets = append(sets, es)
+				continue
+			}
+			if err := skip(dec, tt.Name.Local); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if tt.Name.Local == start.Name.Local {
+				return sets, nil
+			}
+		}
+	}
+}
+
+// parseBindings reads the NavigationPropertyBindings of an EntitySet.
+func parseBindings(dec *xml.Decoder, start xml.StartElement) (map[string]string, error) {
+	var bindings map[string]string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tt := tok.(type) {
+		case xml.StartElement:
+			if tt.Name.Local == "NavigationPropertyBinding" {
+				if bindings == nil {
+					bindings = map[string]string{}
+				}
+				bindings[attr(tt, "Path")] = attr(tt, "Target")
+			}
+			if err := skip(dec, tt.Name.Local); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if tt.Name.Local == start.Name.Local {
+				return bindings, nil
+			}
+		}
+	}
+}
+
+// parseConstraints reads the ReferentialConstraints of a v4
+// NavigationProperty.
+func parseConstraints(dec *xml.Decoder, start xml.StartElement) ([]RefConstraint, error) {
+	var cs []RefConstraint
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tt := tok.(type) {
+		case xml.StartElement:
+			if tt.Name.Local == "ReferentialConstraint" {
+				cs = append(cs, RefConstraint{
+					Property:           attr(tt, "Property"),
+					ReferencedProperty: attr(tt, "ReferencedProperty"),
+				})
+			}
+			if err := skip(dec, tt.Name.Local); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if tt.Name.Local == start.Name.Local {
+				return cs, nil
+			}
+		}
+	}
+}
+
 func parseKey(dec *xml.Decoder, start xml.StartElement) ([]string, error) {
 	var keys []string
 	for {
@@ -476,6 +970,12 @@ func parseProperty(start xml.StartElement) Property {
 		Name:     attr(start, "Name"),
 		Type:     attr(start, "Type"),
 		Nullable: parseNullablePtr(attr(start, "Nullable")),
+		Scale:    -1,
+	}
+	p.MaxLength, _ = strconv.Atoi(attr(start, "MaxLength"))
+	p.Precision, _ = strconv.Atoi(attr(start, "Precision"))
+	if scale, err := strconv.Atoi(attr(start, "Scale")); err == nil {
+		p.Scale = scale
 	}
 	return p
 }
@@ -485,6 +985,7 @@ func parseNavPropV4(start xml.StartElement) NavPropertyV4 {
 		Name:     attr(start, "Name"),
 		Type:     attr(start, "Type"),
 		Nullable: parseNullablePtr(attr(start, "Nullable")),
+		Partner:  attr(start, "Partner"),
 	}
 }
 
@@ -555,15 +1056,26 @@ type genState struct {
 	useTime       bool
 	useDecimal    bool
 	decimalImport string                  // "github.com/shopspring/decimal"
+	useRuntime    bool                    // generated code references odata.*
 	knownTypes    map[string]bool         // qualified "NS.Name"
 	typeNameMap   map[string]string       // qualified -> GoTypeName
 	assocByQName  map[string]*Association // "NS.Assoc"
 	useJSON       bool
 	useFmt        bool
 	useStrings    bool
+	curNS         string            // namespace of the block being emitted
+	curType       string            // metadata name of the type being emitted
+	curAliases    map[string]string // "...Property" aliases of the type being emitted
+	pkgImports    map[string]bool   // cross-namespace packages used by the block
+	names         *sapgen.Namer
+	udfs          map[string][]*Property // -udf=extension: U_ fields per type; nil keeps them inline
+	external      map[string]string      // qualified -> GoTypeName in Objects.StandardModule
+	externalEnums map[string]bool        // the enums among external
 }
 
-func generate(schemas []*Schema, opts Options) (string, error) {
+func newGenState(all []*Schema, opts Options) (*genState, error) {
+	schemas := filterSchemas(all, opts.Types)
+	opts.PropertyAlias = opts.Quirks.PropertyAlias(opts.PropertyAlias)
 	st := &genState{
 		opts:          opts,
 		schemas:       schemas,
@@ -572,19 +1084,28 @@ func generate(schemas []*Schema, opts Options) (string, error) {
 		typeNameMap:   map[string]string{},
 		assocByQName:  map[string]*Association{},
 		decimalImport: "github.com/shopspring/decimal",
+		pkgImports:    map[string]bool{},
+		names:         sapgen.NewNamer(opts.Naming),
+	}
+	if opts.UDF.Extension() {
+		ext := opts.UDFSchemas
+		if ext == nil {
+			ext = schemas
+		}
+		st.schemas, st.udfs = splitUDFs(schemas, ext)
 	}
 
 	// Build list of namespaces and decide aliasing
-	nsList := distinctNamespaces(schemas)
+	nsList := distinctNamespaces(st.schemas)
 	needPrefix := opts.NsPrefixMode == "always" ||
-		(opts.NsPrefixMode == "auto" && len(nsList) > 1)
+		(opts.NsPrefixMode == "auto" && len(nsList) > 1 && !opts.PkgPerNamespace)
 	for _, ns := range nsList {
 		alias := namespaceAlias(ns)
 		st.nsAliases[ns] = alias
 	}
 
 	// Register known types and associations
-	for _, s := range schemas {
+	for _, s := range st.schemas {
 		for name := range s.EntityTypes {
 			qn := s.Namespace + "." + name
 			st.knownTypes[qn] = true
@@ -604,6 +1125,7 @@ func generate(schemas []*Schema, opts Options) (string, error) {
 
 	// Compute Go type names for qualified types
 	// If needPrefix, always prepend ns alias; else only if conflicts
+	// (one package per namespace keeps same-named types apart already)
 	conflictNames := map[string]int{}
 	for qn := range st.knownTypes {
 		base := baseNameFromQualified(qn)
@@ -611,25 +1133,161 @@ func generate(schemas []*Schema, opts Options) (string, error) {
 	}
 	for qn := range st.knownTypes {
 		ns, base := splitQualified(qn)
-		goName := goExported(base)
-		if needPrefix || conflictNames[base] > 1 {
+		if to, ok := st.names.TypeName(ns, base); ok {
+			st.typeNameMap[qn] = to
+			continue
+		}
+		goName := st.ident(base)
+		if needPrefix || (conflictNames[base] > 1 && !opts.PkgPerNamespace) {
 			goName = st.nsAliases[ns] + goName
 		}
 		st.typeNameMap[qn] = goName
 	}
+	if err := st.checkNames(); err != nil {
+		return nil, err
+	}
+	if opts.Objects.StandardModule != "" && opts.Types != nil {
+		// name the standard types the way the -objects=standard run does
+		stdOpts := opts
+		stdOpts.Types, stdOpts.UDF, stdOpts.Objects = nil, sapgen.UDF{}, sapgen.UserObjects{}
+		std, err := newGenState(all, stdOpts)
+		if err != nil {
+			return nil, fmt.Errorf("standard types: %w", err)
+		}
+		st.external = map[string]string{}
+		st.externalEnums = map[string]bool{}
+		for qn, goName := range std.typeNameMap {
+			if !st.knownTypes[qn] {
+				st.external[qn] = goName
+				ns, name := splitQualified(qn)
+				st.externalEnums[qn] = findEnum(std.schemas, ns, name) != nil
+			}
+		}
+	}
+	return st, nil
+}
 
-	// Start generating
-	var b strings.Builder
-	b.WriteString("// Code generated by odata2go. DO NOT EDIT.\n")
-	b.WriteString("// Source: OData metadata (Edmx)\n\n")
-	b.WriteString("package " + opts.PkgName + "\n\n")
+// ident turns a metadata name into an exported Go identifier per -case.
+func (st *genState) ident(name string) string {
+	if st.names.GoCase() {
+		return st.names.Pascal(name)
+	}
+	return goExported(name)
+}
+
+// fieldName is the Go field for property prop of the type ns.typeName.
+func (st *genState) fieldName(ns, typeName, prop string) string {
+	if to, ok := st.names.FieldName(ns, typeName, prop); ok {
+		return to
+	}
+	return safeFieldName(st.ident(prop))
+}
+
+// checkNames fails when two metadata names end up as the same Go
+// identifier: types within a package, fields within a struct and the
+// constants of an enum.
+func (st *genState) checkNames() error {
+	scopes := map[string]*sapgen.NameScope{}
+	qns := make([]string, 0, len(st.typeNameMap))
+	for qn := range st.typeNameMap {
+		qns = append(qns, qn)
+	}
+	sort.Strings(qns)
+	for _, qn := range qns {
+		ns, _ := splitQualified(qn)
+		pkg, _ := st.packageFor(ns)
+		if scopes[pkg] == nil {
+			scopes[pkg] = sapgen.NewNameScope("type")
+		}
+		if err := scopes[pkg].Claim(st.typeNameMap[qn], qn); err != nil {
+			return err
+		}
+		if _, ok := st.udfs[qn]; ok {
+			if err := scopes[pkg].Claim(st.typeNameMap[qn]+"UDF", qn+" UDF"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, s := range st.schemas {
+		for _, name := range sortedKeys(s.EntityTypes) {
+			e := s.EntityTypes[name]
+			fields := sapgen.NewNameScope("property of " + s.Namespace + "." + name)
+			var err error
+			for _, p := range e.Properties {
+				err = errors.Join(err, fields.Claim(st.fieldName(s.Namespace, name, p.Name), p.Name))
+			}
+			for _, np := range e.NavPropsV4 {
+				err = errors.Join(err, fields.Claim(st.fieldName(s.Namespace, name, np.Name), np.Name))
+			}
+			for _, np := range e.NavPropsV3 {
+				err = errors.Join(err, fields.Claim(st.fieldName(s.Namespace, name, np.Name), np.Name))
+			}
+			for _, p := range st.udfs[s.Namespace+"."+name] {
+				err = errors.Join(err, fields.Claim(st.fieldName(s.Namespace, name, p.Name), p.Name))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for _, name := range sortedKeys(s.ComplexTypes) {
+			c := s.ComplexTypes[name]
+			fields := sapgen.NewNameScope("property of " + s.Namespace + "." + name)
+			for _, p := range slices.Concat(c.Properties, st.udfs[s.Namespace+"."+name]) {
+				if err := fields.Claim(st.fieldName(s.Namespace, name, p.Name), p.Name); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range sortedKeys(s.EnumTypes) {
+			consts := sapgen.NewNameScope("member of " + s.Namespace + "." + name)
+			for _, m := range s.EnumTypes[name].Members {
+				if err := consts.Claim(st.ident(m.Name), m.Name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
 
-	// Imports determined later; collect types first
-	var typeBlocks []string
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// typeBlock is the code of one enum, complex or entity type together with
+// the imports it needs, so blocks can be grouped into files freely.
+type typeBlock struct {
+	ns      string
+	kind    string // "enum", "complex", "entity" or "udf"
+	goName  string
+	code    string
+	imports []string
+}
+
+// emitBlocks renders every type in a stable order: enums, complex types
+// (often used in entities), entity types, then UDF extensions.
+func (st *genState) emitBlocks() []typeBlock {
+	var blocks []typeBlock
+	add := func(ns, kind, qn string, emit func() string) {
+		st.beginBlock(ns)
+		code := emit()
+		blocks = append(blocks, typeBlock{
+			ns:      ns,
+			kind:    kind,
+			goName:  st.typeNameMap[qn],
+			code:    code,
+			imports: st.collectImports(),
+		})
+	}
 
 	// Enums
 	enumKeys := []string{}
-	for _, s := range schemas {
+	for _, s := range st.schemas {
 		for name := range s.EnumTypes {
 			enumKeys = append(enumKeys, s.Namespace+"."+name)
 		}
@@ -637,17 +1295,16 @@ func generate(schemas []*Schema, opts Options) (string, error) {
 	sort.Strings(enumKeys)
 	for _, qn := range enumKeys {
 		ns, name := splitQualified(qn)
-		e := findEnum(schemas, ns, name)
+		e := findEnum(st.schemas, ns, name)
 		if e == nil {
 			continue
 		}
-		typeDecl := st.emitEnum(e)
-		typeBlocks = append(typeBlocks, typeDecl)
+		add(ns, "enum", qn, func() string { return st.emitEnum(e) })
 	}
 
-	// Complex types first (often used in entities)
+	// Complex types
 	compKeys := []string{}
-	for _, s := range schemas {
+	for _, s := range st.schemas {
 		for name := range s.ComplexTypes {
 			compKeys = append(compKeys, s.Namespace+"."+name)
 		}
@@ -655,17 +1312,16 @@ func generate(schemas []*Schema, opts Options) (string, error) {
 	sort.Strings(compKeys)
 	for _, qn := range compKeys {
 		ns, name := splitQualified(qn)
-		c := findComplex(schemas, ns, name)
+		c := findComplex(st.schemas, ns, name)
 		if c == nil {
 			continue
 		}
-		typeDecl := st.emitComplex(c)
-		typeBlocks = append(typeBlocks, typeDecl)
+		add(ns, "complex", qn, func() string { return st.emitComplex(c) })
 	}
 
 	// Entity types
 	entKeys := []string{}
-	for _, s := range schemas {
+	for _, s := range st.schemas {
 		for name := range s.EntityTypes {
 			entKeys = append(entKeys, s.Namespace+"."+name)
 		}
@@ -673,33 +1329,205 @@ func generate(schemas []*Schema, opts Options) (string, error) {
 	sort.Strings(entKeys)
 	for _, qn := range entKeys {
 		ns, name := splitQualified(qn)
-		e := findEntity(schemas, ns, name)
+		e := findEntity(st.schemas, ns, name)
 		if e == nil {
 			continue
 		}
-		typeDecl := st.emitEntity(e)
-		typeBlocks = append(typeBlocks, typeDecl)
+		add(ns, "entity", qn, func() string { return st.emitEntity(e) })
+	}
+
+	// UDF extensions
+	for _, qn := range sortedKeys(st.udfs) {
+		ns, _ := splitQualified(qn)
+		add(ns, "udf", qn, func() string { return st.emitUDF(qn) })
+	}
+
+	// Client of the entity container
+	if ns, ok := st.clientBlock(); ok && st.opts.Client {
+		st.beginBlock(ns)
+		code := st.emitClient()
+		blocks = append(blocks, typeBlock{ns: ns, kind: "client", goName: "client", code: code, imports: st.collectImports()})
 	}
+	return blocks
+}
+
+// beginBlock resets the per-block import tracking.
+func (st *genState) beginBlock(ns string) {
+	st.curNS = ns
+	st.useTime = false
+	st.useDecimal = false
+	st.useRuntime = false
+	st.useJSON = false
+	st.useFmt = false
+	st.useStrings = false
+	st.pkgImports = map[string]bool{}
+}
+
+func generate(schemas []*Schema, opts Options) (string, error) {
+	st, err := newGenState(schemas, opts)
+	if err != nil {
+		return "", err
+	}
+	blocks := st.emitBlocks()
+	var imports []string
+	for _, blk := range blocks {
+		imports = append(imports, blk.imports...)
+	}
+	return renderGoFile(opts.PkgName, opts.SourceHash, imports, blocks), nil
+}
+
+// genFile is one generated file, relative to Options.OutDir.
+type genFile struct {
+	Path    string
+	Content string
+}
+
+// generateFiles implements -split=perType: one file per entity and complex
+// type plus enums_gen.go (and udf_gen.go with -udf=extension), optionally
+// in one package per namespace.
+func generateFiles(schemas []*Schema, opts Options) ([]genFile, error) {
+	if opts.PkgPerNamespace && opts.ImportPath == "" {
+		return nil, errors.New("-pkg-per-ns needs -import-path for cross-package imports")
+	}
+	st, err := newGenState(schemas, opts)
+	if err != nil {
+		return nil, err
+	}
+	blocks := st.emitBlocks()
+
+	// enums and UDF extensions are grouped into one file per package; the
+	// latter is the only file that differs between company databases
+	grouped := map[string]string{"enum": "enums_gen.go", "udf": "udf_gen.go"}
+	var files []genFile
+	groups := map[string][]typeBlock{}
+	var groupOrder []string
+	for _, blk := range blocks {
+		if name, ok := grouped[blk.kind]; ok {
+			dir, _ := st.packageFor(blk.ns)
+			path := filepathJoin(dir, name)
+			if _, ok := groups[path]; !ok {
+				groupOrder = append(groupOrder, path)
+			}
+			groups[path] = append(groups[path], blk)
+			continue
+		}
+		dir, pkg := st.packageFor(blk.ns)
+		files = append(files, genFile{
+			Path:    filepathJoin(dir, goFileName(blk.goName)),
+			Content: renderGoFile(pkg, opts.SourceHash, blk.imports, []typeBlock{blk}),
+		})
+	}
+	for _, path := range groupOrder {
+		var imports []string
+		for _, blk := range groups[path] {
+			imports = append(imports, blk.imports...)
+		}
+		_, pkg := st.packageFor(groups[path][0].ns)
+		files = append(files, genFile{
+			Path:    path,
+			Content: renderGoFile(pkg, opts.SourceHash, imports, groups[path]),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// renderGoFile writes the header, package clause, sorted imports and blocks.
+func renderGoFile(pkg, hash string, imports []string, blocks []typeBlock) string {
+	var b strings.Builder
+	b.WriteString("// Code generated by odata2go. DO NOT EDIT.\n")
+	b.WriteString("// Source: OData metadata (Edmx)\n")
+	b.WriteString(sapgen.HashHeader(hash) + "\n")
+	b.WriteString("package " + pkg + "\n\n")
 
-	// Imports
-	imports := st.collectImports()
-	if len(imports) > 0 {
+	set := map[string]bool{}
+	for _, imp := range imports {
+		set[imp] = true
+	}
+	keys := []string{}
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
 		b.WriteString("import (\n")
-		for _, imp := range imports {
+		for _, imp := range keys {
 			b.WriteString("  \"" + imp + "\"\n")
 		}
 		b.WriteString(")\n\n")
 	}
 
 	// Write types
-	for _, block := range typeBlocks {
-		b.WriteString(block)
-		if !strings.HasSuffix(block, "\n") {
+	for _, blk := range blocks {
+		b.WriteString(blk.code)
+		if !strings.HasSuffix(blk.code, "\n") {
 			b.WriteString("\n")
 		}
 	}
+	return b.String()
+}
 
-	return b.String(), nil
+// packageFor returns the directory (relative to OutDir) and package name
+// that hold the types of a namespace.
+func (st *genState) packageFor(ns string) (dir, pkg string) {
+	if !st.opts.PkgPerNamespace {
+		return "", st.opts.PkgName
+	}
+	pkg = strings.ToLower(st.nsAliases[ns])
+	return pkg, pkg
+}
+
+// typeRef returns the Go expression naming a generated type from the block
+// being emitted, qualifying it (and recording the import) when it lives in
+// another namespace package.
+func (st *genState) typeRef(qn string) string {
+	goName := st.typeNameMap[qn]
+	if goName == "" {
+		return st.externalRef(qn)
+	}
+	if !st.opts.PkgPerNamespace {
+		return goName
+	}
+	ns, _ := splitQualified(qn)
+	if ns == st.curNS {
+		return goName
+	}
+	dir, pkg := st.packageFor(ns)
+	st.pkgImports[strings.TrimSuffix(st.opts.ImportPath, "/")+"/"+dir] = true
+	return pkg + "." + goName
+}
+
+// externalRef qualifies a type of the standard package (-objects=user) and
+// records its import; it returns "" for types that are not generated at all.
+func (st *genState) externalRef(qn string) string {
+	goName, ok := st.external[qn]
+	if !ok {
+		return ""
+	}
+	imp := strings.TrimSuffix(st.opts.Objects.StandardModule, "/")
+	ns, _ := splitQualified(qn)
+	if dir, _ := st.packageFor(ns); dir != "" {
+		imp += "/" + dir
+	}
+	st.pkgImports[imp] = true
+	return imp[strings.LastIndex(imp, "/")+1:] + "." + goName
+}
+
+// goFileName maps a type name to a file name that the go tool will not
+// treat specially (leading "_", "_test" or GOOS/GOARCH suffixes).
+func goFileName(goName string) string {
+	name := strings.TrimLeft(strings.ToLower(goName), "_")
+	if name == "" {
+		name = "x"
+	}
+	return name + "_gen.go"
+}
+
+func filepathJoin(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
 }
 
 func (st *genState) collectImports() []string {
@@ -707,9 +1535,12 @@ func (st *genState) collectImports() []string {
 	if st.useTime {
 		set["time"] = true
 	}
-	if st.useDecimal && st.opts.DecimalMode == "shopspring" {
+	if st.useDecimal && st.opts.DecimalMode == sapgen.DecimalShopspring {
 		set[st.decimalImport] = true
 	}
+	if st.useRuntime {
+		set[st.runtimeImport()] = true
+	}
 	if st.useJSON {
 		set["encoding/json"] = true
 	}
@@ -719,6 +1550,9 @@ func (st *genState) collectImports() []string {
 	if st.useStrings {
 		set["strings"] = true
 	}
+	for imp := range st.pkgImports {
+		set[imp] = true
+	}
 	keys := []string{}
 	for k := range set {
 		keys = append(keys, k)
@@ -750,7 +1584,7 @@ func (st *genState) emitEnum(e *EnumType) string {
 	if len(e.Members) > 0 {
 		b.WriteString("const (\n")
 		for idx, m := range e.Members {
-			constName := goName + goExported(m.Name)
+			constName := goName + st.ident(m.Name)
 			if m.Value != "" {
 				b.WriteString("  " + constName + " " + goName + " = " +
 					castEnumValue(goUnder, m.Value) + "\n")
@@ -768,18 +1602,25 @@ func (st *genState) emitEnum(e *EnumType) string {
 	// name <-> value maps
 	b.WriteString("var _" + goName + "_nameToValue = map[string]" + goName + "{\n")
 	for _, m := range e.Members {
-		constName := goName + goExported(m.Name)
+		constName := goName + st.ident(m.Name)
 		b.WriteString("  " + strconvQuote(m.Name) + ": " + constName + ",\n")
 	}
 	b.WriteString("}\n\n")
 
 	b.WriteString("var _" + goName + "_valueToName = map[" + goName + "]string{\n")
 	for _, m := range e.Members {
-		constName := goName + goExported(m.Name)
+		constName := goName + st.ident(m.Name)
 		b.WriteString("  " + constName + ": " + strconvQuote(m.Name) + ",\n")
 	}
 	b.WriteString("}\n\n")
 
+	// enumCasing: the service sends other values than the member names
+	valueToWire := "_" + goName + "_valueToName"
+	if wire := st.enumWireValues(e); wire != "" {
+		valueToWire = "_" + goName + "_valueToWire"
+		b.WriteString(wire)
+	}
+
 	// UnmarshalJSON supports both string names and numeric values.
 	b.WriteString("func (t *" + goName + ") UnmarshalJSON(b []byte) error {\n")
 	b.WriteString("  if string(b) == \"null\" {\n")
@@ -817,7 +1658,7 @@ func (st *genState) emitEnum(e *EnumType) string {
 
 	// MarshalJSON emits the string name when known; numeric otherwise.
 	b.WriteString("func (t " + goName + ") MarshalJSON() ([]byte, error) {\n")
-	b.WriteString("  if s, ok := _" + goName + "_valueToName[t]; ok {\n")
+	b.WriteString("  if s, ok := " + valueToWire + "[t]; ok {\n")
 	b.WriteString("    return json.Marshal(s)\n")
 	b.WriteString("  }\n")
 	b.WriteString("  n := " + goUnder + "(t)\n")
@@ -829,6 +1670,71 @@ func (st *genState) emitEnum(e *EnumType) string {
 	b.WriteString("  if s, ok := _" + goName + "_valueToName[t]; ok { return s }\n")
 	b.WriteString("  return fmt.Sprintf(\"%d\", " + goUnder + "(t))\n")
 	b.WriteString("}\n\n")
+	if st.opts.Validate {
+		b.WriteString(st.emitEnumIsValid(e))
+	}
+	return b.String()
+}
+
+// emitEnumIsValid emits IsValid, used by the Validate methods: t is a
+// member, or for a flags enum a combination of members.
+func (st *genState) emitEnumIsValid(e *EnumType) string {
+	goName := st.typeNameMap[e.Namespace+"."+e.Name]
+	var b strings.Builder
+	b.WriteString("// IsValid reports whether t is a member of " + goName + ".\n")
+	b.WriteString("func (t " + goName + ") IsValid() bool {\n")
+	if e.IsFlags {
+		consts := make([]string, len(e.Members))
+		for i, m := range e.Members {
+			consts[i] = goName + st.ident(m.Name)
+		}
+		if len(consts) == 0 {
+			consts = []string{"0"}
+		}
+		b.WriteString("  return t&^(" + strings.Join(consts, " | ") + ") == 0\n")
+	} else {
+		b.WriteString("  _, ok := _" + goName + "_valueToName[t]\n")
+		b.WriteString("  return ok\n")
+	}
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+// enumWireValues emits the wire values of e's members under the enumCasing
+// quirk, when any differs from the member name: _<Enum>_valueToWire, and an
+// init adding them to _<Enum>_nameToValue so either form is read.
+func (st *genState) enumWireValues(e *EnumType) string {
+	goName := st.typeNameMap[e.Namespace+"."+e.Name]
+	names := map[string]bool{}
+	for _, m := range e.Members {
+		names[m.Name] = true
+	}
+	differs := false
+	for _, m := range e.Members {
+		differs = differs || st.opts.Quirks.EnumValue(m.Name) != m.Name
+	}
+	if !differs {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("var _" + goName + "_valueToWire = map[" + goName + "]string{\n")
+	seen := map[string]bool{}
+	var extra []string
+	for _, m := range e.Members {
+		constName := goName + st.ident(m.Name)
+		wire := st.opts.Quirks.EnumValue(m.Name)
+		b.WriteString("  " + constName + ": " + strconvQuote(wire) + ",\n")
+		if !names[wire] && !seen[wire] {
+			seen[wire] = true
+			extra = append(extra, "  _"+goName+"_nameToValue["+strconvQuote(wire)+"] = "+constName+"\n")
+		}
+	}
+	b.WriteString("}\n\n")
+	if len(extra) > 0 {
+		b.WriteString("func init() {\n")
+		b.WriteString(strings.Join(extra, ""))
+		b.WriteString("}\n\n")
+	}
 	return b.String()
 }
 
@@ -846,34 +1752,57 @@ func castEnumValue(goUnder string, value string) string {
 func (st *genState) emitComplex(c *ComplexType) string {
 	qn := c.Namespace + "." + c.Name
 	goName := st.typeNameMap[qn]
+	st.curType = c.Name
+	chain := st.propertyChain(c.Namespace, c.Name, c.BaseType, c.Properties)
+	st.curAliases = st.opts.PropertyAlias.Aliases(chainNames(chain))
+	defer func() { st.curAliases = nil }()
 	var b strings.Builder
 	b.WriteString("// " + goName + " is a complex type.\n")
 	b.WriteString("type " + goName + " struct {\n")
 	// Embed base type if present
-	if c.BaseType != "" && st.knownTypes[c.BaseType] {
-		bName := st.typeNameMap[c.BaseType]
+	if bName := st.typeRef(c.BaseType); c.BaseType != "" && bName != "" {
 		b.WriteString("  " + bName + "\n")
 	}
+	if _, ok := st.udfs[qn]; ok {
+		b.WriteString("  " + goName + "UDF\n")
+	}
 	// Properties
 	for _, p := range c.Properties {
 		field := st.fieldForProperty(p, c.Namespace)
 		b.WriteString("  " + field + "\n")
 	}
 	b.WriteString("}\n\n")
+	b.WriteString(st.emitAliasUnmarshal(goName, chain))
+	if st.opts.Validate {
+		fields := st.validateFields(c.Namespace, c.Properties)
+		b.WriteString(st.emitValidate(goName, st.embeddedNames(qn, c.BaseType), fields))
+	}
+	if st.opts.Patch {
+		b.WriteString(st.emitPatchRules(goName, c.Namespace, nil, c.Properties, nil))
+	}
+	if st.opts.Query {
+		b.WriteString(st.emitFields(goName, chain, nil))
+	}
 	return b.String()
 }
 
 func (st *genState) emitEntity(e *EntityType) string {
 	qn := e.Namespace + "." + e.Name
 	goName := st.typeNameMap[qn]
+	st.curType = e.Name
+	chain := st.propertyChain(e.Namespace, e.Name, e.BaseType, e.Properties)
+	st.curAliases = st.opts.PropertyAlias.Aliases(chainNames(chain))
+	defer func() { st.curAliases = nil }()
 	var b strings.Builder
 	b.WriteString("// " + goName + " is an entity type.\n")
 	b.WriteString("type " + goName + " struct {\n")
 	// Embed base type if present
-	if e.BaseType != "" && st.knownTypes[e.BaseType] {
-		bName := st.typeNameMap[e.BaseType]
+	if bName := st.typeRef(e.BaseType); e.BaseType != "" && bName != "" {
 		b.WriteString("  " + bName + "\n")
 	}
+	if _, ok := st.udfs[qn]; ok {
+		b.WriteString("  " + goName + "UDF\n")
+	}
 	// Properties
 	keySet := map[string]bool{}
 	for _, k := range e.Keys {
@@ -892,10 +1821,917 @@ func (st *genState) emitEntity(e *EntityType) string {
 		field := st.fieldForNavV3(np, e.Namespace)
 		b.WriteString("  " + field + "\n")
 	}
+	etag := st.etagField(e, chain)
+	if etag != "" {
+		b.WriteString("  " + etag + " string `json:\"" + st.etagAnnotation(e.Namespace) + ",omitempty\"` // the entity's version, sent as If-Match\n")
+	}
+	b.WriteString("}\n\n")
+	if etag != "" {
+		b.WriteString("// EntityTag returns the ETag " + goName + " was read with (odata.ETagger).\n")
+		b.WriteString("func (m *" + goName + ") EntityTag() string { return m." + etag + " }\n\n")
+	}
+	b.WriteString(st.emitAliasUnmarshal(goName, chain))
+	if st.opts.Validate {
+		fields := st.validateFields(e.Namespace, e.Properties)
+		for _, np := range e.NavPropsV4 {
+			fields = append(fields, validateField{
+				field:  st.fieldName(e.Namespace, e.Name, np.Name),
+				path:   np.Name,
+				goType: st.resolveTypeRef(np.Type, np.Nullable, e.Namespace),
+			})
+		}
+		for _, np := range e.NavPropsV3 {
+			fields = append(fields, validateField{
+				field:  st.fieldName(e.Namespace, e.Name, np.Name),
+				path:   np.Name,
+				goType: st.navV3GoType(np, e.Namespace),
+			})
+		}
+		b.WriteString(st.emitValidate(goName, st.embeddedNames(qn, e.BaseType), fields))
+	}
+	if st.opts.Patch {
+		var navs []string
+		for _, np := range e.NavPropsV4 {
+			navs = append(navs, np.Name)
+		}
+		for _, np := range e.NavPropsV3 {
+			navs = append(navs, np.Name)
+		}
+		b.WriteString(st.emitPatchRules(goName, e.Namespace, e.Keys, e.Properties, navs))
+		b.WriteString(st.emitPatchMethods(goName))
+	}
+	if st.opts.Query {
+		var navs []fieldsNav
+		for _, np := range e.NavPropsV4 {
+			target, coll := np.Type, false
+			if m := reCollection.FindStringSubmatch(np.Type); len(m) == 2 {
+				target, coll = m[1], true
+			}
+			navs = append(navs, fieldsNav{name: np.Name, qn: target, coll: coll})
+		}
+		for _, np := range e.NavPropsV3 {
+			if target, coll := st.navV3Target(np); target != "" {
+				navs = append(navs, fieldsNav{name: np.Name, qn: target, coll: coll})
+			}
+		}
+		st.curType = e.Name
+		b.WriteString(st.emitFields(goName, chain, navs))
+	}
+	return b.String()
+}
+
+// etagField returns the name of the field holding the ETag of e, "" when
+// none is emitted: without -etag, or when e inherits it from its base. It
+// is ETag unless a property took that name.
+func (st *genState) etagField(e *EntityType, chain []chainProperty) string {
+	if !st.opts.ETag || e.BaseType != "" && st.typeRef(e.BaseType) != "" {
+		return ""
+	}
+	taken := map[string]bool{}
+	for _, cp := range chain {
+		taken[st.fieldName(cp.ns, cp.typeName, cp.p.Name)] = true
+	}
+	for _, np := range e.NavPropsV4 {
+		taken[st.fieldName(e.Namespace, e.Name, np.Name)] = true
+	}
+	for _, np := range e.NavPropsV3 {
+		taken[st.fieldName(e.Namespace, e.Name, np.Name)] = true
+	}
+	name := "ETag"
+	for taken[name] {
+		name = "OData" + name
+	}
+	return name
+}
+
+// etagAnnotation is the JSON name of the ETag of the entities of ns:
+// @odata.etag, or odata.etag in the JSON of OData v3.
+func (st *genState) etagAnnotation(ns string) string {
+	for _, s := range st.schemas {
+		if s.Namespace == ns && s.V3 {
+			return "odata.etag"
+		}
+	}
+	return "@odata.etag"
+}
+
+// emitUDF emits the extension struct holding the user-defined fields of the
+// type qn. It is embedded in the core struct, so the fields are promoted
+// (m.U_Region) and encoding/json reads and writes them inline.
+func (st *genState) emitUDF(qn string) string {
+	ns, name := splitQualified(qn)
+	goName := st.typeNameMap[qn]
+	st.curType = name
+	var b strings.Builder
+	b.WriteString("// " + goName + "UDF holds the user-defined fields of " + goName + ".\n")
+	b.WriteString("type " + goName + "UDF struct {\n")
+	for _, p := range st.udfs[qn] {
+		b.WriteString("  " + st.fieldForProperty(p, ns) + "\n")
+	}
+	b.WriteString("}\n\n")
+	if st.opts.Validate {
+		b.WriteString(st.emitValidate(goName+"UDF", nil, st.validateFields(ns, st.udfs[qn])))
+	}
+	if st.opts.Query {
+		// the query fields of the UDFs, on the core type's descriptor
+		var chain []chainProperty
+		for _, p := range st.udfs[qn] {
+			chain = append(chain, chainProperty{ns, name, p})
+		}
+		st.useRuntime = true
+		b.WriteString(st.fieldMethods(goName+"Fields", chain))
+		st.curType = name
+	}
+	return b.String()
+}
+
+// embeddedNames returns the field names of the structs embedded in the
+// struct of qn: the base type and the UDF struct.
+func (st *genState) embeddedNames(qn, baseType string) []string {
+	var names []string
+	if bName := st.typeRef(baseType); baseType != "" && bName != "" {
+		names = append(names, bName[strings.LastIndex(bName, ".")+1:])
+	}
+	if _, ok := st.udfs[qn]; ok {
+		names = append(names, st.typeNameMap[qn]+"UDF")
+	}
+	return names
+}
+
+/* ===========================
+   Validate
+   =========================== */
+
+// validateField is a struct field checked by the generated Validate.
+type validateField struct {
+	field  string    // Go field name
+	path   string    // JSON name, the start of the error paths
+	goType string    // field type
+	qn     string    // qualified element type, "" for Edm types
+	p      *Property // facets; nil for navigation properties
+}
+
+// emitValidate emits Validate for the struct goName: the checks of its
+// fields, then those of the embedded structs (base type, UDF struct), whose
+// errors keep their own paths.
+func (st *genState) emitValidate(goName string, embedded []string, fields []validateField) string {
+	st.useRuntime = true
+	var b strings.Builder
+	b.WriteString("// Validate checks m against the metadata: required fields, MaxLength,\n")
+	b.WriteString("// decimal precision and scale, enum values and nested values. The error\n")
+	b.WriteString("// is an odata.ValidationError.\n")
+	b.WriteString("func (m *" + goName + ") Validate() error {\n")
+	b.WriteString("  var v odata.Validator\n")
+	for _, f := range fields {
+		b.WriteString(st.validateChecks(f))
+	}
+	for _, e := range embedded {
+		b.WriteString("  v.Nested(\"\", m." + e + ".Validate())\n")
+	}
+	b.WriteString("  return v.Err()\n")
 	b.WriteString("}\n\n")
 	return b.String()
 }
 
+// validateFields returns the checked fields of props: those not mapped to a
+// user type through -scalars.
+func (st *genState) validateFields(ns string, props []*Property) []validateField {
+	var out []validateField
+	for _, p := range props {
+		if _, mapped := st.scalarGoType(p, ns); mapped {
+			continue
+		}
+		inner := p.Type
+		if m := reCollection.FindStringSubmatch(p.Type); len(m) == 2 {
+			inner = m[1]
+		}
+		qn := ""
+		if !strings.HasPrefix(inner, "Edm.") {
+			qn = inner
+			if !strings.Contains(qn, ".") {
+				qn = ns + "." + qn
+			}
+		}
+		out = append(out, validateField{
+			field:  st.fieldName(ns, st.curType, p.Name),
+			path:   st.opts.PropertyAlias.WireName(p.Name, st.curAliases),
+			goType: st.propertyGoType(p, ns),
+			qn:     qn,
+			p:      p,
+		})
+	}
+	return out
+}
+
+// validateChecks returns the statements checking field f of m.
+func (st *genState) validateChecks(f validateField) string {
+	m := "m." + f.field
+	path := strconvQuote(f.path)
+	var b strings.Builder
+	if f.p != nil && !boolOrDefault(f.p.Nullable, true) {
+		switch f.goType {
+		case "string":
+			b.WriteString("  v.Required(" + path + ", " + m + " != \"\")\n")
+		case "time.Time", "odata.Date":
+			b.WriteString("  v.Required(" + path + ", !" + m + ".IsZero())\n")
+		}
+	}
+	switch {
+	case strings.HasPrefix(f.goType, "[]") && f.goType != "[]byte":
+		check := st.valueCheck(f, m+"[i]", "odata.ElemPath("+path+", i)", strings.TrimPrefix(f.goType, "[]"))
+		if check != "" {
+			b.WriteString("  for i := range " + m + " {\n")
+			b.WriteString("    " + check + "\n")
+			b.WriteString("  }\n")
+		}
+	case strings.HasPrefix(f.goType, "odata.Opt["):
+		inner := strings.TrimSuffix(strings.TrimPrefix(f.goType, "odata.Opt["), "]")
+		if check := st.valueCheck(f, "x", path, inner); check != "" {
+			b.WriteString("  if x, ok := " + m + ".Get(); ok {\n")
+			b.WriteString("    " + check + "\n")
+			b.WriteString("  }\n")
+		}
+	case strings.HasPrefix(f.goType, "*"):
+		x := "*" + m
+		if st.validateKind(f) == "struct" {
+			x = m
+		}
+		if check := st.valueCheck(f, x, path, f.goType[1:]); check != "" {
+			b.WriteString("  if " + m + " != nil {\n")
+			b.WriteString("    " + check + "\n")
+			b.WriteString("  }\n")
+		}
+	default:
+		if st.validateKind(f) == "enum" {
+			// the zero value is left out of the payload (omitempty)
+			b.WriteString("  v.Enum(" + path + ", " + m + " == 0 || " + m + ".IsValid())\n")
+		} else if check := st.valueCheck(f, m, path, f.goType); check != "" {
+			b.WriteString("  " + check + "\n")
+		}
+	}
+	return b.String()
+}
+
+// validateKind classifies the element type of f: "string", "decimal",
+// "enum", "struct" or "" when nothing is checked.
+func (st *genState) validateKind(f validateField) string {
+	switch {
+	case f.p == nil: // navigation property
+		if strings.TrimLeft(f.goType, "[]*") == "interface{}" {
+			return ""
+		}
+		return "struct"
+	case f.qn == "":
+		switch strings.TrimSuffix(strings.TrimPrefix(f.p.Type, "Collection("), ")") {
+		case "Edm.String":
+			return "string"
+		case "Edm.Decimal":
+			return "decimal"
+		}
+		return ""
+	case st.opts.Quirks.YesNo(f.qn):
+		return ""
+	case st.isEnum(f.qn):
+		return "enum"
+	case st.typeRef(f.qn) != "":
+		return "struct"
+	}
+	return ""
+}
+
+// valueCheck returns the check of the value x (of type goType) at the path
+// expression path, or "" when f has none.
+func (st *genState) valueCheck(f validateField, x, path, goType string) string {
+	switch st.validateKind(f) {
+	case "string":
+		if f.p.MaxLength > 0 && goType == "string" {
+			return fmt.Sprintf("v.MaxLength(%s, %s, %d)", path, x, f.p.MaxLength)
+		}
+	case "decimal":
+		if f.p.Precision > 0 || f.p.Scale >= 0 {
+			return fmt.Sprintf("v.Decimal(%s, %s, %d, %d)", path, x, f.p.Precision, f.p.Scale)
+		}
+	case "enum":
+		if strings.HasPrefix(x, "*") {
+			x = "(" + x + ")"
+		}
+		return "v.Enum(" + path + ", " + x + ".IsValid())"
+	case "struct":
+		return "v.Nested(" + path + ", " + x + ".Validate())"
+	}
+	return ""
+}
+
+// isEnum reports whether qn is an enum type, generated here or in the
+// standard objects package.
+func (st *genState) isEnum(qn string) bool {
+	ns, name := splitQualified(qn)
+	if findEnum(st.schemas, ns, name) != nil {
+		return true
+	}
+	return st.externalEnums[qn]
+}
+
+/* ===========================
+   "...Property" aliases
+   =========================== */
+
+// chainProperty is a property of a type or of one of its base types.
+type chainProperty struct {
+	ns, typeName string
+	p            *Property
+}
+
+// propertyChain returns the properties of the type ns.name followed by those
+// of its base types, which are promoted into its struct.
+func (st *genState) propertyChain(ns, name, baseType string, props []*Property) []chainProperty {
+	var chain []chainProperty
+	seen := map[string]bool{}
+	for {
+		for _, p := range props {
+			chain = append(chain, chainProperty{ns, name, p})
+		}
+		seen[ns+"."+name] = true
+		if baseType == "" || seen[baseType] {
+			return chain
+		}
+		ns, name = splitQualified(baseType)
+		if e := findEntity(st.schemas, ns, name); e != nil {
+			baseType, props = e.BaseType, e.Properties
+		} else if c := findComplex(st.schemas, ns, name); c != nil {
+			baseType, props = c.BaseType, c.Properties
+		} else {
+			return chain // base outside the generated types
+		}
+	}
+}
+
+func chainNames(chain []chainProperty) []string {
+	names := make([]string, len(chain))
+	for i, cp := range chain {
+		names[i] = cp.p.Name
+	}
+	return names
+}
+
+// emitAliasUnmarshal emits an UnmarshalJSON that reads the "...Property"
+// fields of goName under both names; the tagged one wins when a payload has
+// both. Encoding needs nothing: the tag holds the name to write. Fields of a
+// base type keep the names the base gave them, and a type whose base has
+// such a method gets one too, as the promoted method would otherwise decode
+// only the base.
+func (st *genState) emitAliasUnmarshal(goName string, chain []chainProperty) string {
+	names := map[string]bool{}
+	for _, cp := range chain {
+		names[cp.p.Name] = true
+	}
+	var fields, apply strings.Builder
+	baseDecodes := false
+	var aliases map[string]string
+	i := 0
+	for j, cp := range chain {
+		if j == 0 || cp.typeName != chain[j-1].typeName || cp.ns != chain[j-1].ns {
+			// the aliases of a type are those of its own struct
+			aliases = st.opts.PropertyAlias.Aliases(chainNames(chain[j:]))
+			baseDecodes = baseDecodes || j > 0 && len(aliases) > 0
+		}
+		alias, ok := aliases[cp.p.Name]
+		if !ok {
+			continue
+		}
+		wire := st.opts.PropertyAlias.WireName(cp.p.Name, aliases)
+		other := cp.p.Name
+		if other == wire {
+			other = alias
+		}
+		// names taken by another field of the struct stay with it
+		var raws []string
+		if !names[other] || other == cp.p.Name {
+			fields.WriteString(fmt.Sprintf("    Other%d json.RawMessage `json:%q`\n", i, other))
+			raws = append(raws, fmt.Sprintf("aux.Other%d", i))
+		}
+		if !names[wire] || wire == cp.p.Name {
+			fields.WriteString(fmt.Sprintf("    Wire%d json.RawMessage `json:%q`\n", i, wire))
+			raws = append(raws, fmt.Sprintf("aux.Wire%d", i))
+		}
+		if len(raws) == 0 {
+			continue
+		}
+		apply.WriteString("  for _, raw := range []json.RawMessage{" + strings.Join(raws, ", ") + "} {\n")
+		apply.WriteString("    if raw != nil {\n")
+		apply.WriteString("      if err := json.Unmarshal(raw, &m." + st.fieldName(cp.ns, cp.typeName, cp.p.Name) + "); err != nil {\n")
+		apply.WriteString("        return err\n")
+		apply.WriteString("      }\n")
+		apply.WriteString("    }\n")
+		apply.WriteString("  }\n")
+		i++
+	}
+	if i == 0 && !baseDecodes {
+		return ""
+	}
+	st.useJSON = true
+	var b strings.Builder
+	b.WriteString("// UnmarshalJSON accepts the SAP B1 \"...Property\" fields of " + goName + " under both names.\n")
+	b.WriteString("func (m *" + goName + ") UnmarshalJSON(data []byte) error {\n")
+	b.WriteString("  type plain " + goName + "\n")
+	b.WriteString("  var aux struct {\n")
+	b.WriteString("    *plain\n")
+	if baseDecodes {
+		b.WriteString("    UnmarshalJSON struct{} `json:\"-\"` // hides the method promoted from the base\n")
+	}
+	b.WriteString(fields.String())
+	b.WriteString("  }\n")
+	b.WriteString("  aux.plain = (*plain)(m)\n")
+	b.WriteString("  if err := json.Unmarshal(data, &aux); err != nil {\n")
+	b.WriteString("    return err\n")
+	b.WriteString("  }\n")
+	b.WriteString(apply.String())
+	b.WriteString("  return nil\n")
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+/* ===========================
+   PATCH delta helpers
+   =========================== */
+
+// lineKeyNames identify elements of B1 document line style collections, so
+// a changed line is sent as {LineNum, changed fields} instead of in full.
+var lineKeyNames = []string{"LineNum", "LineNumber", "InternalCode"}
+
+// emitPatchRules emits the odata.PatchRules for a type: keys and navigation
+// properties are never sent, complex properties are diffed recursively and
+// collections of complex values are matched on their line key.
+func (st *genState) emitPatchRules(
+	goName, ctxNS string,
+	keys []string,
+	props []*Property,
+	navs []string,
+) string {
+	st.useRuntime = true
+	var complexes, colls []string
+	for _, p := range props {
+		raw := p.Type
+		m := reCollection.FindStringSubmatch(raw)
+		if len(m) == 2 {
+			raw = m[1]
+		}
+		qn := raw
+		if !strings.Contains(raw, ".") {
+			qn = ctxNS + "." + raw
+		}
+		ns, name := splitQualified(qn)
+		c := findComplex(st.schemas, ns, name)
+		if c == nil {
+			continue
+		}
+		rulesVar := "&" + st.typeRef(qn) + "PatchRules"
+		wire := st.opts.PropertyAlias.WireName(p.Name, st.curAliases)
+		if len(m) != 2 {
+			complexes = append(complexes, "    "+strconvQuote(wire)+": "+rulesVar+",\n")
+			continue
+		}
+		var lineKeys []string
+		for _, k := range lineKeyNames {
+			for _, cp := range c.Properties {
+				if cp.Name == k {
+					lineKeys = append(lineKeys, k)
+				}
+			}
+			if len(lineKeys) > 0 {
+				break
+			}
+		}
+		keysField := ""
+		if len(lineKeys) > 0 {
+			keysField = "Keys: " + goStringSlice(lineKeys) + ", "
+		}
+		colls = append(colls, "    "+strconvQuote(wire)+": {"+keysField+
+			"Rules: "+rulesVar+"},\n")
+	}
+
+	var b strings.Builder
+	b.WriteString("// " + goName + "PatchRules drives PATCH deltas for " + goName + ".\n")
+	b.WriteString("var " + goName + "PatchRules = odata.PatchRules{\n")
+	if len(keys) > 0 {
+		wireKeys := make([]string, len(keys))
+		for i, k := range keys {
+			wireKeys[i] = st.opts.PropertyAlias.WireName(k, st.curAliases)
+		}
+		b.WriteString("  Keys: " + goStringSlice(wireKeys) + ",\n")
+	}
+	if len(navs) > 0 {
+		b.WriteString("  Skip: " + goStringSlice(navs) + ",\n")
+	}
+	if len(complexes) > 0 {
+		b.WriteString("  Complex: map[string]*odata.PatchRules{\n")
+		b.WriteString(strings.Join(complexes, ""))
+		b.WriteString("  },\n")
+	}
+	if len(colls) > 0 {
+		b.WriteString("  Collections: map[string]*odata.CollectionRules{\n")
+		b.WriteString(strings.Join(colls, ""))
+		b.WriteString("  },\n")
+	}
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+func (st *genState) emitPatchMethods(goName string) string {
+	var b strings.Builder
+	b.WriteString("// PatchDelta returns the minimal PATCH body that turns before into m.\n")
+	b.WriteString("func (m *" + goName + ") PatchDelta(before *" + goName + ") (*odata.Patch, error) {\n")
+	b.WriteString("  return odata.Delta(before, m, &" + goName + "PatchRules)\n")
+	b.WriteString("}\n\n")
+	b.WriteString("// PatchSnapshot records m; call Delta on the result after changing m.\n")
+	b.WriteString("func (m *" + goName + ") PatchSnapshot() (*odata.Snapshot, error) {\n")
+	b.WriteString("  return odata.TakeSnapshot(m, &" + goName + "PatchRules)\n")
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+func goStringSlice(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconvQuote(v)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+/* ===========================
+   Query field descriptors
+   =========================== */
+
+// fieldsNav is a navigation property of an entity's field descriptor.
+type fieldsNav struct {
+	name string
+	qn   string // target entity type
+	coll bool
+}
+
+// emitFields emits <goName>Fields, the query descriptor of a type: a
+// method per property, base type properties included, returning an
+// odata.Field, odata.StringField, odata.CollectionField or the descriptor of
+// a complex or entity type. Its zero value addresses the type at the root of
+// a query; complex and navigation properties are methods, so cycles in the
+// metadata cost nothing.
+func (st *genState) emitFields(goName string, chain []chainProperty, navs []fieldsNav) string {
+	st.useRuntime = true
+	fields := goName + "Fields"
+	var b strings.Builder
+	b.WriteString("// " + fields + " are the query fields of " + goName + ".\n")
+	b.WriteString("type " + fields + " odata.Path\n\n")
+	b.WriteString("func (f " + fields + ") ODataPath() odata.Path { return odata.Path(f) }\n\n")
+	ns, typeName := st.curNS, st.curType
+	b.WriteString(st.fieldMethods(fields, chain))
+	for _, n := range navs {
+		elem := st.typeRef(n.qn)
+		if elem == "" {
+			continue
+		}
+		elem += "Fields"
+		name := st.fieldName(ns, typeName, n.name)
+		if n.coll {
+			b.WriteString(fieldsMethod(fields, name, n.name, "odata.CollectionField["+elem+"]",
+				"odata.NewCollectionField(p, func(p odata.Path) "+elem+" { return "+elem+"(p) })"))
+		} else {
+			b.WriteString(fieldsMethod(fields, name, n.name, elem, elem+"(p)"))
+		}
+	}
+	return b.String()
+}
+
+// fieldMethods emits the methods of the descriptor fields for the
+// structural properties in chain.
+func (st *genState) fieldMethods(fields string, chain []chainProperty) string {
+	var b strings.Builder
+	aliases := st.opts.PropertyAlias.Aliases(chainNames(chain))
+	for _, cp := range chain {
+		st.curType = cp.typeName
+		goType, expr, ok := st.fieldDescriptor(cp.p, cp.ns)
+		if !ok {
+			continue
+		}
+		b.WriteString(fieldsMethod(fields, st.fieldName(cp.ns, cp.typeName, cp.p.Name),
+			st.opts.PropertyAlias.WireName(cp.p.Name, aliases), goType, expr))
+	}
+	return b.String()
+}
+
+// fieldsMethod emits the method name of the descriptor fields returning
+// expr, which builds the descriptor from p, the path of property path.
+func fieldsMethod(fields, name, path, goType, expr string) string {
+	return "func (f " + fields + ") " + name + "() " + goType + " {\n" +
+		"  p := odata.Path(f).Child(" + strconvQuote(path) + ")\n" +
+		"  return " + expr + "\n" +
+		"}\n\n"
+}
+
+// fieldDescriptor returns the descriptor type of property p and the
+// expression building it from the path p; ok is false for properties that
+// cannot be queried (types left out of the output).
+func (st *genState) fieldDescriptor(p *Property, ns string) (goType, expr string, ok bool) {
+	inner := p.Type
+	coll := false
+	if m := reCollection.FindStringSubmatch(p.Type); len(m) == 2 {
+		inner, coll = m[1], true
+	}
+	if !strings.HasPrefix(inner, "Edm.") && !strings.Contains(inner, ".") {
+		inner = ns + "." + inner
+	}
+	notNull := false
+	elem := *p
+	elem.Type, elem.Nullable = inner, &notNull
+	switch {
+	case strings.HasPrefix(inner, "Edm.") || st.isEnum(inner) || st.opts.Quirks.YesNo(inner):
+		valueType, mapped := st.scalarGoType(&elem, ns)
+		if !mapped {
+			valueType = st.resolveTypeRef(inner, elem.Nullable, ns)
+		}
+		if valueType == "" || valueType == "interface{}" {
+			return "", "", false
+		}
+		goType = "odata.Field[" + valueType + "]"
+		expr = "odata.NewField[" + valueType + "](p, " + strconvQuote(inner) + ")"
+		if inner == "Edm.String" && valueType == "string" {
+			goType, expr = "odata.StringField", "odata.NewStringField(p)"
+		}
+	default:
+		ref := st.typeRef(inner)
+		if ref == "" {
+			return "", "", false
+		}
+		goType = ref + "Fields"
+		expr = goType + "(p)"
+	}
+	if coll {
+		expr = "odata.NewCollectionField(p, func(p odata.Path) " + goType + " { return " + expr + " })"
+		goType = "odata.CollectionField[" + goType + "]"
+	}
+	return goType, expr, true
+}
+
+/* ===========================
+   Client generation
+   =========================== */
+
+// clientKey is a key property as a parameter of the service methods.
+type clientKey struct {
+	param  string // Go parameter name
+	goType string
+	p      *Property
+}
+
+// clientBlock returns the namespace holding the entity container and
+// whether any of its sets are generated.
+func (st *genState) clientBlock() (string, bool) {
+	for _, s := range st.schemas {
+		if len(s.EntitySets) > 0 {
+			return s.Namespace, true
+		}
+	}
+	return "", false
+}
+
+// emitClient emits Client, with one method per entity set returning a
+// service with Get, List, Page, NextPage, All, Create, Update (PATCH),
+// Replace (PUT) and Delete.
+// The requests are sent by odata.Client.
+func (st *genState) emitClient() string {
+	st.useRuntime = true
+	st.pkgImports["context"] = true
+	st.pkgImports["iter"] = true
+	st.pkgImports["net/http"] = true
+	taken := map[string]bool{}
+	for _, goName := range st.typeNameMap {
+		taken[goName] = true
+	}
+	clientName := "Client"
+	for taken[clientName] {
+		clientName = "Service" + clientName
+	}
+	taken[clientName] = true
+
+	var container string
+	v3 := false
+	var sets []*EntitySet
+	for _, s := range st.schemas {
+		if len(s.EntitySets) > 0 && container == "" {
+			container, v3 = s.Container, s.V3
+		}
+		sets = append(sets, s.EntitySets...)
+	}
+
+	var b strings.Builder
+	b.WriteString("// " + clientName + " is the client of the " + container + " entity container.\n")
+	b.WriteString("type " + clientName + " struct {\n  *odata.Client\n}\n\n")
+	b.WriteString("// New" + clientName + " returns a client of the service at serviceRoot; a nil hc\n")
+	b.WriteString("// uses http.DefaultClient.\n")
+	b.WriteString("func New" + clientName + "(serviceRoot string, hc *http.Client) *" + clientName + " {\n")
+	b.WriteString("  c := odata.NewClient(serviceRoot, hc)\n")
+	if v3 {
+		b.WriteString("  c.V3 = true\n")
+	}
+	b.WriteString("  return &" + clientName + "{c}\n")
+	b.WriteString("}\n\n")
+
+	methods := map[string]bool{"Client": true}
+	for _, es := range sets {
+		ns, name := splitQualified(es.EntityType)
+		e := findEntity(st.schemas, ns, name)
+		entity := st.typeRef(es.EntityType)
+		if e == nil || entity == "" {
+			continue
+		}
+		method := st.ident(es.Name)
+		for methods[method] {
+			method += "Set"
+		}
+		methods[method] = true
+		service, batch := method+"Service", method+"Batch"
+		for taken[service] {
+			service = "_" + service
+		}
+		taken[service] = true
+		for taken[batch] {
+			batch = "_" + batch
+		}
+		taken[batch] = true
+
+		b.WriteString("// " + method + " is the " + es.Name + " entity set.\n")
+		b.WriteString("func (c *" + clientName + ") " + method + "() " + service + " {\n")
+		b.WriteString("  return " + service + "{odata.NewEntitySet[" + entity + "](c.Client, " + strconvQuote(es.Name) + ")}\n")
+		b.WriteString("}\n\n")
+		keys := st.clientKeys(e)
+		b.WriteString(st.emitService(service, es.Name, entity, keys))
+		b.WriteString(st.emitBatch(batch, service, es.Name, entity, keys))
+	}
+	return b.String()
+}
+
+// emitService emits the service of one entity set. Without key properties
+// only List and Create are emitted.
+func (st *genState) emitService(service, set, entity string, keys []clientKey) string {
+	var b strings.Builder
+	b.WriteString("// " + service + " reads and writes the " + entity + " entities of " + set + ".\n")
+	b.WriteString("type " + service + " struct {\n  set odata.EntitySet[" + entity + "]\n}\n\n")
+	var params, args, parts []string
+	for _, k := range keys {
+		params = append(params, k.param+" "+k.goType)
+		args = append(args, k.param)
+		parts = append(parts, "{Name: "+strconvQuote(k.p.Name)+", Type: "+strconvQuote(k.p.Type)+", Value: "+k.param+"}")
+	}
+	keyParams, key := strings.Join(params, ", "), "s.key("+strings.Join(args, ", ")+")"
+	method := func(doc, sig, body string) {
+		b.WriteString("// " + doc + "\n")
+		b.WriteString("func (s " + service + ") " + sig + " {\n  " + body + "\n}\n\n")
+	}
+	if len(keys) > 0 {
+		b.WriteString("func (s " + service + ") key(" + keyParams + ") odata.Key {\n")
+		b.WriteString("  return odata.Key{" + strings.Join(parts, ", ") + "}\n")
+		b.WriteString("}\n\n")
+		method("Get reads the entity with the given key.",
+			"Get(ctx context.Context, "+keyParams+", query ...odata.QueryString) (*"+entity+", error)",
+			"return s.set.Get(ctx, "+key+", query...)")
+	}
+	method("List reads the entities matching query (the first page).",
+		"List(ctx context.Context, query ...odata.QueryString) ([]"+entity+", error)",
+		"return s.set.List(ctx, query...)")
+	method("Page reads the first page of the entities matching query, with its next link.",
+		"Page(ctx context.Context, query ...odata.QueryString) (*odata.Collection["+entity+"], error)",
+		"return s.set.Page(ctx, query...)")
+	method("NextPage reads the page at link, the NextLink of the page before.",
+		"NextPage(ctx context.Context, link string) (*odata.Collection["+entity+"], error)",
+		"return s.set.NextPage(ctx, link)")
+	method("All iterates over the entities matching query, following the next links.",
+		"All(ctx context.Context, query ...odata.QueryString) iter.Seq2["+entity+", error]",
+		"return s.set.All(ctx, query...)")
+	method("Create posts entity and returns the created entity.",
+		"Create(ctx context.Context, entity *"+entity+") (*"+entity+", error)",
+		"return s.set.Create(ctx, entity)")
+	if len(keys) > 0 {
+		method("Update sends a PATCH with body, e.g. the odata.Patch of PatchDelta, with If-Match when it carries an ETag.",
+			"Update(ctx context.Context, "+keyParams+", body any) error",
+			"return s.set.Update(ctx, "+key+", body)")
+		method("Replace sends a PUT replacing the whole entity.",
+			"Replace(ctx context.Context, "+keyParams+", entity *"+entity+") error",
+			"return s.set.Replace(ctx, "+key+", entity)")
+		method("Delete deletes the entity with the given key.",
+			"Delete(ctx context.Context, "+keyParams+") error",
+			"return s.set.Delete(ctx, "+key+")")
+		method("DeleteIfMatch deletes the entity with the given key if its ETag is still etag.",
+			"DeleteIfMatch(ctx context.Context, "+keyParams+", etag string) error",
+			"return s.set.DeleteIfMatch(ctx, "+key+", etag)")
+	}
+	return b.String()
+}
+
+// emitBatch emits <Set>Batch, which adds the requests of the service
+// to an odata.Batch or one of its change sets, and the Batch method of the
+// service returning it.
+func (st *genState) emitBatch(batch, service, set, entity string, keys []clientKey) string {
+	var b strings.Builder
+	b.WriteString("// Batch adds the requests of the service to r, a batch or one of its change sets.\n")
+	b.WriteString("func (s " + service + ") Batch(r odata.Requests) " + batch + " {\n  return " + batch + "{s.set.Batch(r)}\n}\n\n")
+	b.WriteString("// " + batch + " adds requests for the " + entity + " entities of " + set + " to a batch.\n")
+	b.WriteString("type " + batch + " struct {\n  set odata.BatchSet[" + entity + "]\n}\n\n")
+	var params, args []string
+	for _, k := range keys {
+		params = append(params, k.param+" "+k.goType)
+		args = append(args, k.param)
+	}
+	keyParams, key := strings.Join(params, ", "), service+"{}.key("+strings.Join(args, ", ")+")"
+	method := func(doc, sig, body string) {
+		b.WriteString("// " + doc + "\n")
+		b.WriteString("func (b " + batch + ") " + sig + " {\n  " + body + "\n}\n\n")
+	}
+	result := "*odata.Result[" + entity + "]"
+	if len(keys) > 0 {
+		method("Get reads the entity with the given key.",
+			"Get("+keyParams+", query ...odata.QueryString) "+result,
+			"return b.set.Get("+key+", query...)")
+	}
+	method("List reads the first page of the entities matching query.",
+		"List(query ...odata.QueryString) *odata.Result[odata.Collection["+entity+"]]",
+		"return b.set.List(query...)")
+	method("Create posts entity; Ref() addresses it in later requests of the change set.",
+		"Create(entity *"+entity+") "+result,
+		"return b.set.Create(entity)")
+	if len(keys) > 0 {
+		method("Update sends a PATCH with body, e.g. the odata.Patch of PatchDelta.",
+			"Update("+keyParams+", body any) *odata.Operation",
+			"return b.set.Update("+key+", body)")
+		method("Replace sends a PUT replacing the whole entity.",
+			"Replace("+keyParams+", entity *"+entity+") *odata.Operation",
+			"return b.set.Replace("+key+", entity)")
+		method("Delete deletes the entity with the given key.",
+			"Delete("+keyParams+") *odata.Operation",
+			"return b.set.Delete("+key+")")
+		method("DeleteIfMatch deletes the entity with the given key if its ETag is still etag.",
+			"DeleteIfMatch("+keyParams+", etag string) *odata.Operation",
+			"return b.set.DeleteIfMatch("+key+", etag)")
+	}
+	return b.String()
+}
+
+// clientKeys returns the key properties of e, which may be declared on a
+// base type, as method parameters.
+func (st *genState) clientKeys(e *EntityType) []clientKey {
+	var names []string
+	for t, seen := e, map[*EntityType]bool{}; t != nil && !seen[t]; {
+		seen[t] = true
+		if len(t.Keys) > 0 {
+			names = t.Keys
+			break
+		}
+		ns, name := splitQualified(t.BaseType)
+		t = findEntity(st.schemas, ns, name)
+	}
+	chain := st.propertyChain(e.Namespace, e.Name, e.BaseType, e.Properties)
+	var keys []clientKey
+	used := map[string]bool{"ctx": true, "query": true, "body": true, "entity": true, "link": true, "etag": true, "r": true, "b": true, "s": true}
+	for _, name := range names {
+		for _, cp := range chain {
+			if cp.p.Name != name {
+				continue
+			}
+			notNull := false
+			p := *cp.p
+			p.Nullable = &notNull
+			st.curType = cp.typeName
+			goType, mapped := st.scalarGoType(&p, cp.ns)
+			if !mapped {
+				goType = st.resolveTypeRef(p.Type, &notNull, cp.ns)
+			}
+			param := lowerFirst(st.fieldName(cp.ns, cp.typeName, name))
+			for used[param] || token.IsKeyword(param) {
+				param += "Key"
+			}
+			used[param] = true
+			keys = append(keys, clientKey{param: param, goType: goType, p: cp.p})
+			break
+		}
+	}
+	return keys
+}
+
+// lowerFirst lowers the leading initialism or letter of an identifier:
+// DocEntry becomes docEntry, ID id and IDCode idCode.
+func lowerFirst(s string) string {
+	r := []rune(s)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n-- // the last capital starts the next word
+	}
+	for i := range n {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
 /* ===========================
    Field generation helpers
    =========================== */
@@ -909,10 +2745,21 @@ func (st *genState) fieldForPropertyWithKey(
 	ctxNS string,
 	keySet map[string]bool,
 ) string {
-	fieldName := safeFieldName(p.Name)
+	fieldName := st.fieldName(ctxNS, st.curType, p.Name)
 	// Resolve type
-	goType := st.resolveTypeRef(p.Type, p.Nullable, ctxNS)
-	tags := []string{`json:"` + p.Name + `,omitempty"`}
+	goType, mapped := st.scalarGoType(p, ctxNS)
+	if !mapped {
+		goType = st.propertyGoType(p, ctxNS)
+	}
+	jsonOpts := "," + omitOption(goType)
+	switch {
+	case mapped && !strings.HasPrefix(goType, "*") && !strings.HasPrefix(goType, "[]"):
+		jsonOpts = ",omitzero" // user types decide their own JSON form
+	case !mapped && !strings.HasPrefix(goType, "odata.Opt["):
+		jsonOpts += st.stringOpt(p.Type) // Opt decodes quoted numbers itself
+	}
+	wire := st.opts.PropertyAlias.WireName(p.Name, st.curAliases)
+	tags := []string{`json:"` + wire + jsonOpts + `"`}
 	if keySet != nil && keySet[p.Name] {
 		tags = append(tags, `key:"true"`)
 	}
@@ -924,7 +2771,7 @@ func (st *genState) fieldForNavV4(
 	np *NavPropertyV4,
 	ctxNS string,
 ) string {
-	fieldName := safeFieldName(np.Name)
+	fieldName := st.fieldName(ctxNS, st.curType, np.Name)
 	goType := st.resolveTypeRef(np.Type, np.Nullable, ctxNS)
 	tag := `json:"` + np.Name + `,omitempty"`
 	return fmt.Sprintf("%s %s `%s`", fieldName, goType, tag)
@@ -934,48 +2781,99 @@ func (st *genState) fieldForNavV3(
 	np *NavPropertyV3,
 	ctxNS string,
 ) string {
-	fieldName := safeFieldName(np.Name)
-	// Resolve via Association
-	// Relationship is NS.Association
-	assoc := st.assocByQName[np.Relationship]
+	fieldName := st.fieldName(ctxNS, st.curType, np.Name)
+	goType := st.navV3GoType(np, ctxNS)
+	tag := `json:"` + np.Name + `,omitempty"`
+	return fmt.Sprintf("%s %s `%s`", fieldName, goType, tag)
+}
+
+func (st *genState) navV3GoType(np *NavPropertyV3, ctxNS string) string {
+	target, isCollection := st.navV3Target(np)
 	elemType := "interface{}"
-	isCollection := false
-	if assoc != nil {
-		// Find the ToRole end
-		var target AssocEnd
-		for _, end := range assoc.Ends {
-			if end.Role == np.ToRole {
-				target = end
-				break
-			}
-		}
-		if target.Type != "" {
-			elemType = st.resolveTypeRef(target.Type, nil, ctxNS)
-			// target.Type is qualified NS.Entity, not a Collection
-			// Determine multiplicity for collection
-			if target.Multiplicity == "*" || target.Multiplicity == "0..*" {
-				isCollection = true
-			} else {
-				isCollection = false
-			}
-		}
+	if target != "" {
+		elemType = st.resolveTypeRef(target, nil, ctxNS)
 	}
-	var goType string
 	if isCollection {
 		// Slice of element type (strip pointer for collection)
 		elem := stripPointer(elemType)
-		goType = "[]" + elem
-	} else {
-		// Single; pointer to struct types
-		if !strings.HasPrefix(elemType, "*") &&
-			isStructNamedType(elemType) {
-			goType = "*" + elemType
-		} else {
-			goType = elemType
+		return "[]" + elem
+	}
+	// Single; pointer to struct types
+	if !strings.HasPrefix(elemType, "*") &&
+		isStructNamedType(elemType) {
+		return "*" + elemType
+	}
+	return elemType
+}
+
+// navV3Target resolves a v3 navigation property through its Association
+// to the qualified target type and whether the ToRole end is many.
+func (st *genState) navV3Target(np *NavPropertyV3) (qn string, coll bool) {
+	assoc := st.assocByQName[np.Relationship]
+	if assoc == nil {
+		return "", false
+	}
+	for _, end := range assoc.Ends {
+		if end.Role == np.ToRole {
+			return end.Type, end.Multiplicity == "*" || end.Multiplicity == "0..*"
 		}
 	}
-	tag := `json:"` + np.Name + `,omitempty"`
-	return fmt.Sprintf("%s %s `%s`", fieldName, goType, tag)
+	return "", false
+}
+
+// propertyGoType resolves a structural property. With -nullable=opt a
+// nullable single-valued property becomes odata.Opt[T] so that absent, null
+// and a value can be told apart; collections stay slices (nil means absent).
+func (st *genState) propertyGoType(p *Property, ctxNS string) string {
+	if st.opts.Nullable != "opt" || !boolOrDefault(p.Nullable, true) ||
+		reCollection.MatchString(p.Type) {
+		return st.resolveTypeRef(p.Type, p.Nullable, ctxNS)
+	}
+	notNull := false
+	base := st.resolveTypeRef(p.Type, &notNull, ctxNS)
+	st.useRuntime = true
+	return "odata.Opt[" + base + "]"
+}
+
+// scalarGoType applies a -scalars mapping to p. The user type is wrapped
+// like a primitive: slice for collections, *T or odata.Opt[T] when nullable.
+func (st *genState) scalarGoType(p *Property, ctxNS string) (string, bool) {
+	inner := p.Type
+	coll := false
+	if m := reCollection.FindStringSubmatch(p.Type); len(m) == 2 {
+		inner, coll = m[1], true
+	}
+	m, ok := st.opts.Scalars.Lookup(ctxNS, st.curType, p.Name, inner)
+	if !ok || m.Go == nil {
+		return "", false
+	}
+	if m.Go.Import != "" {
+		st.pkgImports[m.Go.Import] = true
+	}
+	switch {
+	case coll:
+		return "[]" + m.Go.Type, true
+	case !boolOrDefault(p.Nullable, true):
+		return m.Go.Type, true
+	case st.opts.Nullable == "opt":
+		st.useRuntime = true
+		return "odata.Opt[" + m.Go.Type + "]", true
+	}
+	return "*" + m.Go.Type, true
+}
+
+// omitOption picks the json omit option for a field type. omitempty never
+// drops struct values such as time.Time, so those use omitzero instead.
+func omitOption(goType string) string {
+	switch {
+	case strings.HasPrefix(goType, "*"), strings.HasPrefix(goType, "[]"),
+		strings.HasPrefix(goType, "map["):
+		return "omitempty"
+	case goType == "time.Time", goType == "decimal.Decimal",
+		goType == "odata.Rat", goType == "odata.Date", strings.HasPrefix(goType, "odata.Opt["):
+		return "omitzero"
+	}
+	return "omitempty"
 }
 
 func stripPointer(t string) string {
@@ -1039,7 +2937,11 @@ func (st *genState) resolveTypeRef(
 	if !strings.Contains(raw, ".") {
 		qn = ctxNS + "." + raw
 	}
-	goName := st.typeNameMap[qn]
+	goName := st.typeRef(qn)
+	if st.opts.Quirks.YesNo(qn) {
+		st.useRuntime = true
+		goName = "odata.YesNo"
+	}
 	if goName == "" {
 		// Unknown type; fallback
 		return "interface{}"
@@ -1107,18 +3009,17 @@ func (st *genState) mapEdmToGo(
 		}
 		return "float64", false, false
 	case "Edm.Decimal":
-		if st.opts.DecimalMode == "string" {
-			if nullable {
-				return "*string", false, false
-			}
-			return "string", false, false
-		}
-		// shopspring/decimal
+		t, needsDec := st.decimalGoType()
 		if nullable {
-			return "*decimal.Decimal", false, true
+			return "*" + t, false, needsDec
 		}
-		return "decimal.Decimal", false, true
+		return t, false, needsDec
 	case "Edm.Date", "Edm.DateTime", "Edm.DateTimeOffset":
+		if st.opts.Quirks.DateOnly(edm) {
+			// B1 calendar dates: "2024-01-31" or a timestamp without zone
+			st.useRuntime = true
+			return "odata.Date", false, false
+		}
 		// Use time.Time for all date/time
 		return "time.Time", true, false
 	case "Edm.TimeOfDay", "Edm.Time": // v4 time-only or v3 duration-like
@@ -1150,6 +3051,49 @@ func (st *genState) mapEdmToGo(
 	}
 }
 
+// decimalGoType maps Edm.Decimal according to -decimal. The string mode uses
+// json.Number unless the service quotes decimals (IEEE754Compatible), so
+// plain numbers in the payload still decode without losing precision.
+func (st *genState) decimalGoType() (goType string, needsDecimal bool) {
+	switch st.opts.DecimalMode {
+	case sapgen.DecimalString:
+		if st.opts.IEEE754 {
+			return "string", false
+		}
+		st.useJSON = true
+		return "json.Number", false
+	case sapgen.DecimalBigRat:
+		st.useRuntime = true
+		return "odata.Rat", false
+	case sapgen.DecimalFloat64:
+		return "float64", false
+	default:
+		return "decimal.Decimal", true
+	}
+}
+
+// stringOpt returns ",string" for numeric properties that IEEE754Compatible
+// services send as JSON strings and whose Go type cannot decode them itself.
+func (st *genState) stringOpt(edm string) string {
+	if !st.opts.IEEE754 {
+		return ""
+	}
+	switch {
+	case edm == "Edm.Int64":
+		return ",string"
+	case edm == "Edm.Decimal" && st.opts.DecimalMode == sapgen.DecimalFloat64:
+		return ",string"
+	}
+	return ""
+}
+
+func (st *genState) runtimeImport() string {
+	if st.opts.RuntimeImport != "" {
+		return st.opts.RuntimeImport
+	}
+	return defaultRuntimeImport
+}
+
 /* ===========================
    Lookups and utilities
    =========================== */
@@ -0,0 +1,92 @@
+package gpt5mini
+
+import (
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+const namingMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+ <edmx:DataServices>
+  <Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+   <EntityType Name="Item">
+    <Key><PropertyRef Name="ItemCode"/></Key>
+    <Property Name="ItemCode" Type="Edm.String" Nullable="false"/>
+    <Property Name="U_MyField" Type="Edm.String"/>
+    <Property Name="UMyField" Type="Edm.String"/>
+    <Property Name="ItemId" Type="Edm.Int32"/>
+   </EntityType>
+  </Schema>
+ </edmx:DataServices>
+</edmx:Edmx>`
+
+func TestNamingCollision(t *testing.T) {
+	schemas, err := ParseMetadata(strings.NewReader(namingMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		naming  sapgen.Naming
+		want    []string
+		wantErr string
+	}{
+		{
+			"preserve keeps the names apart",
+			sapgen.Naming{},
+			[]string{"U_MyField *string", "UMyField *string", "ItemId *int32"},
+			"",
+		},
+		{
+			"go case collides",
+			sapgen.Naming{Case: "go"},
+			nil,
+			`name collision: property of SAPB1.Item "UMyField" and "U_MyField" both become UMyField; add a rename`,
+		},
+		{
+			"a rename resolves it",
+			sapgen.Naming{Case: "go", Renames: map[string]string{"Item.U_MyField": "UserMyField"}},
+			[]string{"UserMyField *string `json:\"U_MyField,omitempty\"`", "UMyField *string `json:\"UMyField,omitempty\"`", "ItemID *int32"},
+			"",
+		},
+		{
+			"a qualified rename resolves it",
+			sapgen.Naming{Case: "go", Renames: map[string]string{"SAPB1.Item.UMyField": "PlainMyField"}},
+			[]string{"PlainMyField *string `json:\"UMyField,omitempty\"`", "UMyField *string `json:\"U_MyField,omitempty\"`"},
+			"",
+		},
+		{
+			"a rename that collides",
+			sapgen.Naming{Case: "go", Renames: map[string]string{"Item.U_MyField": "ItemCode", "Item.UMyField": "Other"}},
+			nil,
+			`"ItemCode" and "U_MyField" both become ItemCode`,
+		},
+		{
+			"type rename",
+			sapgen.Naming{Renames: map[string]string{"Item": "Product"}},
+			[]string{"type Product struct"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		out, err := generate(schemas, withDefaults(Options{Naming: tt.naming}))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		flat := strings.Join(strings.Fields(out), " ")
+		for _, s := range tt.want {
+			if !strings.Contains(flat, s) {
+				t.Errorf("%s: output lacks %s", tt.name, s)
+			}
+		}
+	}
+}
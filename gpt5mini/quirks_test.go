@@ -0,0 +1,65 @@
+package gpt5mini
+
+import (
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+const quirksMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+ <edmx:DataServices>
+  <Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+   <EnumType Name="BoYesNoEnum"><Member Name="tNO" Value="0"/><Member Name="tYES" Value="1"/></EnumType>
+   <EnumType Name="BoStatus"><Member Name="BoOpen" Value="0"/></EnumType>
+   <EntityType Name="Activity">
+    <Key><PropertyRef Name="ActivityCode"/></Key>
+    <Property Name="ActivityCode" Type="Edm.Int32" Nullable="false"/>
+    <Property Name="ActivityProperty" Type="Edm.String"/>
+    <Property Name="Closed" Type="SAPB1.BoYesNoEnum"/>
+    <Property Name="Status" Type="SAPB1.BoStatus"/>
+    <Property Name="StartDate" Type="Edm.Date"/>
+   </EntityType>
+  </Schema>
+ </edmx:DataServices>
+</edmx:Edmx>`
+
+// TestQuirkRules checks the Go output of each quirk rule, on and off.
+func TestQuirkRules(t *testing.T) {
+	schemas, err := ParseMetadata(strings.NewReader(quirksMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		quirks  string
+		want    []string
+		notWant []string
+	}{
+		{"", []string{`json:"Activity,omitempty"`, `"boOpen"`, "StartDate odata.Date"}, []string{"odata.YesNo"}},
+		{"propertyAlias=false", []string{`json:"ActivityProperty,omitempty"`}, []string{`json:"Activity,`}},
+		{"enumCasing=false", []string{`"BoOpen"`}, []string{`"boOpen"`}},
+		{"yesNoBool", []string{"odata.YesNo"}, nil},
+		{"b1Dates=false", nil, []string{"odata.Date"}},
+	}
+	for _, tt := range tests {
+		q, err := sapgen.ParseQuirks(tt.quirks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := generate(schemas, withDefaults(Options{Quirks: q, PropertyAlias: sapgen.AliasWire}))
+		if err != nil {
+			t.Fatalf("quirks %q: %v", tt.quirks, err)
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("quirks %q: output lacks %s", tt.quirks, s)
+			}
+		}
+		for _, s := range tt.notWant {
+			if strings.Contains(out, s) {
+				t.Errorf("quirks %q: output has %s", tt.quirks, s)
+			}
+		}
+	}
+}
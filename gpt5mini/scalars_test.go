@@ -0,0 +1,29 @@
+package gpt5mini
+
+import (
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+func TestScalarOverrides(t *testing.T) {
+	schemas, err := ParseMetadata(strings.NewReader(namingMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := generate(schemas, withDefaults(Options{Scalars: sapgen.Scalars{
+		"Edm.Int32":      {Go: &sapgen.GoScalar{Type: "int"}},
+		"Item.ItemCode":  {Go: &sapgen.GoScalar{Import: "example.com/app/item", Type: "item.Code"}},
+		"Item.U_MyField": {TS: &sapgen.TsScalar{Type: "Mine"}}, // the Go side stays built in
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat := strings.Join(strings.Fields(out), " ")
+	for _, s := range []string{`"example.com/app/item"`, "ItemCode item.Code `json", "ItemId *int `json", "U_MyField *string `json"} {
+		if !strings.Contains(flat, s) {
+			t.Errorf("output lacks %s:\n%s", s, out)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gpt5mini
+
+import (
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+const companyMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+ <edmx:DataServices>
+  <Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+   <EntityType Name="Item">
+    <Key><PropertyRef Name="ItemCode"/></Key>
+    <Property Name="ItemCode" Type="Edm.String" Nullable="false"/>
+    <Property Name="U_Colour" Type="Edm.String"/>
+    <Property Name="U_Weight" Type="Edm.Double"/>
+   </EntityType>
+  </Schema>
+ </edmx:DataServices>
+</edmx:Edmx>`
+
+func TestUDFExtension(t *testing.T) {
+	schemas, err := ParseMetadata(strings.NewReader(namingMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	company, err := ParseMetadata(strings.NewReader(companyMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		notWant []string
+	}{
+		{
+			"inline",
+			Options{},
+			[]string{"U_MyField *string `json:\"U_MyField,omitempty\"` UMyField"},
+			[]string{"ItemUDF"},
+		},
+		{
+			"extension",
+			Options{UDF: sapgen.UDF{Mode: "extension"}},
+			[]string{"type Item struct { ItemUDF ItemCode", "type ItemUDF struct { U_MyField *string"},
+			[]string{"U_MyField *string `json:\"U_MyField,omitempty\"` UMyField"},
+		},
+		{
+			"extension from the company metadata",
+			Options{UDF: sapgen.UDF{Mode: "extension"}, UDFSchemas: company},
+			[]string{"type ItemUDF struct { U_Colour *string `json:\"U_Colour,omitempty\"` U_Weight *float64"},
+			[]string{"U_MyField"},
+		},
+	}
+	for _, tt := range tests {
+		out, err := generate(schemas, withDefaults(tt.opts))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		flat := strings.Join(strings.Fields(out), " ")
+		for _, s := range tt.want {
+			if !strings.Contains(flat, s) {
+				t.Errorf("%s: output lacks %s:\n%s", tt.name, s, out)
+			}
+		}
+		for _, s := range tt.notWant {
+			if strings.Contains(flat, s) {
+				t.Errorf("%s: output has %s", tt.name, s)
+			}
+		}
+	}
+}
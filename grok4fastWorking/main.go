@@ -7,12 +7,19 @@ import (
 	"go/format"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
+
+	"dissemblir/sapModelsGenerator/sapgen"
 )
 
-//Usage go run main.go -input="metadata.xml" -output="types.go"
+//Usage go run main.go -input="metadata.xml" -output="types.go" [-pkg=odata] [-decimal=float64|shopspring|bigrat|string] [-ieee754] [-nullable=pointer|opt] [-scalars=scalars.json] [-property-alias=alias|metadata|off] [-quirks=yesNoBool,b1Dates=false] [-list-quirks] [-source-hash]
+//Per type: go run main.go -input="metadata.xml" -split="perType" -outDir="./models" -pkg="models"
+//         (one package for all namespaces: type names must be unique across them)
 
 // EDMX represents the root Edmx element.
 type EDMX struct {
@@ -79,7 +86,7 @@ type Property struct {
 	XMLName      xml.Name `xml:"http://docs.oasis-open.org/odata/ns/edm Property"`
 	Name         string   `xml:"Name,attr"`
 	Type         string   `xml:"Type,attr"`
-	Nullable     bool     `xml:"Nullable,attr,omitempty"`
+	Nullable     *bool    `xml:"Nullable,attr"` // absent: true
 	MaxLength    int      `xml:"MaxLength,attr,omitempty"`
 	Precision    int      `xml:"Precision,attr,omitempty"`
 	Scale        int      `xml:"Scale,attr,omitempty"`
@@ -87,6 +94,9 @@ type Property struct {
 	// SAP-specific? Add if present.
 }
 
+// IsNullable reports whether p may be null; Nullable defaults to true.
+func (p Property) IsNullable() bool { return p.Nullable == nil || *p.Nullable }
+
 // PropertyRef for keys.
 type PropertyRef struct {
 	XMLName xml.Name `xml:"http://docs.oasis-open.org/odata/ns/edm PropertyRef"`
@@ -131,7 +141,7 @@ var edmToGo = map[string]string{
 	"Byte":           "uint8",
 	"SByte":          "int8",
 	"Boolean":        "bool",
-	"Decimal":        "float64", // Changed to float64 for simplicity; use *big.Decimal if needed
+	"Decimal":        "float64", // Overridden by -decimal, see applyDecimalMode
 	"Double":         "float64",
 	"Single":         "float32",
 	"Guid":           "string",
@@ -143,6 +153,72 @@ var edmToGo = map[string]string{
 	"Duration":       "string", // Or custom type
 }
 
+// Import paths referenced by the Edm.Decimal representations.
+const (
+	shopspringImport = "github.com/shopspring/decimal"
+	runtimeImport    = "dissemblir/sapModelsGenerator/odata"
+)
+
+// ieee754 is set from -ieee754: Int64 and Decimal values arrive as JSON strings.
+var ieee754 bool
+
+// optNullable is set from -nullable=opt: nullable properties become
+// odata.Opt[T] (absent / null / value) instead of pointers.
+var optNullable bool
+
+// scalars is set from -scalars: user types replacing the built-in mapping
+// of an Edm type or of a single Type.Property.
+var scalars sapgen.Scalars
+
+// propertyAlias is set from -property-alias: how the B1 "...Property"
+// fields (ActivityProperty, sent as Activity) are named in JSON.
+var propertyAlias sapgen.PropertyAlias
+
+// quirks is set from -quirks: the SAP B1 rules turned on or off.
+var quirks sapgen.QuirkSet
+
+// sourceHash is set from -source-hash: the metadata SHA-256 recorded in the
+// file headers.
+var sourceHash string
+
+// scalarGoType returns the -scalars type of property p of ns.typeName,
+// wrapped for collections and nullability like the built-in types.
+func scalarGoType(ns, typeName string, p Property) (string, bool) {
+	isColl, innerEdm := isCollection(p.Type)
+	m, ok := scalars.Lookup(ns, typeName, p.Name, innerEdm)
+	if !ok || m.Go == nil {
+		return "", false
+	}
+	switch {
+	case isColl:
+		return "[]" + m.Go.Type, true
+	case !p.IsNullable():
+		return m.Go.Type, true
+	case optNullable:
+		return "odata.Opt[" + m.Go.Type + "]", true
+	}
+	return "*" + m.Go.Type, true
+}
+
+// applyDecimalMode points the Decimal entry of edmToGo at the selected
+// representation. float64 stays the default so existing output is unchanged.
+func applyDecimalMode(mode sapgen.DecimalMode) {
+	switch mode {
+	case sapgen.DecimalShopspring:
+		edmToGo["Decimal"] = "decimal.Decimal"
+	case sapgen.DecimalBigRat:
+		edmToGo["Decimal"] = "odata.Rat"
+	case sapgen.DecimalString:
+		if ieee754 {
+			edmToGo["Decimal"] = "string"
+		} else {
+			edmToGo["Decimal"] = "json.Number" // keeps the digits of unquoted numbers
+		}
+	default:
+		edmToGo["Decimal"] = "float64"
+	}
+}
+
 // Helper to extract the type name without namespace (e.g., "CAG_NS.BOE_IntDocNumber" -> "BOE_IntDocNumber", "Edm.Int32" -> "Int32").
 func extractEdmTypeName(edmType string) string {
 	// Handle Collection wrapper first.
@@ -169,8 +245,16 @@ func getGoType(edmType string, isNullable bool) string {
 	isColl, innerEdm := isCollection(edmType)
 	innerName := extractEdmTypeName(innerEdm)
 
+	if optNullable && isNullable && !isColl {
+		return "odata.Opt[" + getGoType(edmType, false) + "]"
+	}
+
 	var baseGoType string
-	if primitive, ok := edmToGo[innerName]; ok {
+	if quirks.DateOnly(innerEdm) {
+		baseGoType = "odata.Date" // B1 calendar date, see -list-quirks
+	} else if quirks.YesNo(innerEdm) {
+		baseGoType = "odata.YesNo"
+	} else if primitive, ok := edmToGo[innerName]; ok {
 		baseGoType = primitive
 	} else {
 		// Non-primitive: use the local name (e.g., "BOE_SalesOrder")
@@ -188,7 +272,7 @@ func getGoType(edmType string, isNullable bool) string {
 	// For non-collection primitives, apply nullability.
 	if isNullable {
 		switch baseGoType {
-		case "string", "[]byte", "time.Time": // These can be zero/empty
+		case "string", "[]byte", "time.Time", "odata.Date": // These can be zero/empty
 			// No pointer needed
 		default:
 			if !strings.HasPrefix(baseGoType, "*") {
@@ -217,17 +301,35 @@ func generateStruct(typ interface{}, isEntity bool, schemaNs string) string {
 		navs = t.NavigationProperties
 	}
 
+	names := make([]string, len(props))
+	for i, p := range props {
+		names[i] = p.Name
+	}
+	aliases := propertyAlias.Aliases(names)
+
 	var fields strings.Builder
 	fields.WriteString(fmt.Sprintf("type %s struct {\n", name))
 
 	// Fields from properties
 	for _, p := range props {
 		fieldName := strings.Title(p.Name) // CamelCase
-		goType := getGoType(p.Type, p.Nullable)
-		jsonTag := fmt.Sprintf("json:\"%s\"", p.Name)
-		if p.Nullable {
-			jsonTag += ",omitempty"
+		goType, mapped := scalarGoType(schemaNs, name, p)
+		if !mapped {
+			goType = getGoType(p.Type, p.IsNullable())
 		}
+		jsonOpts := ""
+		switch {
+		case strings.HasPrefix(goType, "odata.Opt["), p.IsNullable() && (goType == "time.Time" || goType == "odata.Date"):
+			jsonOpts += ",omitzero" // omitempty never drops struct values
+		case p.IsNullable():
+			jsonOpts += ",omitempty"
+		}
+		// IEEE754Compatible services quote Int64/Decimal; decode those via ",string"
+		if ieee754 && !mapped && (goType == "int64" || goType == "*int64" || goType == "float64" || goType == "*float64") &&
+			(p.Type == "Edm.Int64" || p.Type == "Edm.Decimal") {
+			jsonOpts += ",string"
+		}
+		jsonTag := fmt.Sprintf("json:\"%s%s\"", propertyAlias.WireName(p.Name, aliases), jsonOpts)
 		fields.WriteString(fmt.Sprintf("\t%s %s `%s`\n", fieldName, goType, jsonTag))
 	}
 
@@ -256,9 +358,49 @@ func generateStruct(typ interface{}, isEntity bool, schemaNs string) string {
 	}
 
 	fields.WriteString("}\n\n")
+	fields.WriteString(generateAliasUnmarshal(name, props, aliases))
 	return fields.String()
 }
 
+// generateAliasUnmarshal emits an UnmarshalJSON reading the "...Property"
+// fields of the struct under both names; the tagged one wins when a payload
+// has both. The tag already holds the name written on encoding.
+func generateAliasUnmarshal(name string, props []Property, aliases map[string]string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("// UnmarshalJSON accepts the SAP B1 \"...Property\" fields of %s under both names.\n", name))
+	b.WriteString(fmt.Sprintf("func (m *%s) UnmarshalJSON(data []byte) error {\n", name))
+	b.WriteString(fmt.Sprintf("\ttype plain %s\n", name))
+	b.WriteString("\tvar aux struct {\n\t\t*plain\n")
+	var apply strings.Builder
+	i := 0
+	for _, p := range props {
+		alias, ok := aliases[p.Name]
+		if !ok {
+			continue
+		}
+		wire, other := propertyAlias.WireName(p.Name, aliases), p.Name
+		if other == wire {
+			other = alias
+		}
+		b.WriteString(fmt.Sprintf("\t\tOther%d json.RawMessage `json:%q`\n", i, other))
+		b.WriteString(fmt.Sprintf("\t\tWire%d json.RawMessage `json:%q`\n", i, wire))
+		apply.WriteString(fmt.Sprintf("\tfor _, raw := range []json.RawMessage{aux.Other%d, aux.Wire%d} {\n", i, i))
+		apply.WriteString("\t\tif raw != nil {\n")
+		apply.WriteString(fmt.Sprintf("\t\t\tif err := json.Unmarshal(raw, &m.%s); err != nil {\n", strings.Title(p.Name)))
+		apply.WriteString("\t\t\t\treturn err\n\t\t\t}\n\t\t}\n\t}\n")
+		i++
+	}
+	b.WriteString("\t}\n")
+	b.WriteString("\taux.plain = (*plain)(m)\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &aux); err != nil {\n\t\treturn err\n\t}\n")
+	b.WriteString(apply.String())
+	b.WriteString("\treturn nil\n}\n\n")
+	return b.String()
+}
+
 // Handle enums as iota or const with values.
 func generateEnum(e EnumType) string {
 	var members strings.Builder
@@ -301,10 +443,51 @@ func dumpParsedXML(edmx *EDMX, filename string) {
 
 func main() {
 	inputFile := flag.String("input", "", "Path to the EDMX XML file")
-	outputFile := flag.String("output", "types.go", "Path to the output Go file")
+	outputFile := flag.String("output", "types.go", "Path to the output Go file for -split=single")
+	outDir := flag.String("outDir", "models", "Directory to write Go files for -split=perType")
+	splitMode := flag.String("split", "single", "Output mode: single | perType")
+	pkgName := flag.String("pkg", "odata", "Package name of the generated code")
 	dumpParsed := flag.Bool("dump", false, "Dump parsed XML structure to debug.xml")
+	decimalMode := flag.String("decimal", "float64", "Edm.Decimal representation: float64 | shopspring | bigrat | string")
+	flag.BoolVar(&ieee754, "ieee754", false, "Service uses IEEE754Compatible=true (Int64/Decimal sent as strings)")
+	nullable := flag.String("nullable", "pointer", "Nullable properties: pointer | opt (odata.Opt[T]: absent/null/value)")
+	scalarsFile := flag.String("scalars", "", "JSON file mapping Edm types and Type.Property paths to user types")
+	aliasMode := flag.String("property-alias", "alias", `"...Property" fields (ActivityProperty): alias (read both, write Activity) | metadata | off`)
+	quirkList := flag.String("quirks", "", "Comma-separated SAP quirk rules to turn on or off, e.g. yesNoBool,b1Dates=false")
+	listQuirks := flag.Bool("list-quirks", false, "Print the SAP quirk rules and exit")
+	hashHeader := flag.Bool("source-hash", false, "Record the SHA-256 of the metadata in the file headers")
 	flag.Parse()
 
+	if *listQuirks {
+		sapgen.WriteQuirks(os.Stdout)
+		return
+	}
+
+	switch *nullable {
+	case "pointer":
+	case "opt":
+		optNullable = true
+	default:
+		log.Fatalf("Unknown -nullable mode: %s (use 'pointer' or 'opt')", *nullable)
+	}
+
+	mode, err := sapgen.ParseDecimalMode(*decimalMode, sapgen.GoDecimalModes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	applyDecimalMode(mode)
+	if scalars, err = sapgen.LoadScalars(*scalarsFile); err != nil {
+		log.Fatal(err)
+	}
+	propertyAlias = sapgen.PropertyAlias(*aliasMode)
+	if err := propertyAlias.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if quirks, err = sapgen.ParseQuirks(*quirkList); err != nil {
+		log.Fatal(err)
+	}
+	propertyAlias = quirks.PropertyAlias(propertyAlias)
+
 	if *inputFile == "" {
 		log.Fatal("Please provide -input flag with the XML file path")
 	}
@@ -313,6 +496,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error reading XML file: %v", err)
 	}
+	if *hashHeader {
+		sourceHash = sapgen.SourceHash(data)
+	}
 
 	var edmx EDMX
 	if err := xml.Unmarshal(data, &edmx); err != nil {
@@ -326,62 +512,211 @@ func main() {
 		dumpParsedXML(&edmx, "debug.xml")
 	}
 
-	var output strings.Builder
-	output.WriteString("// Generated types from OData EDMX for SAP Business One Service Layer v2\n")
-	output.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-	output.WriteString(fmt.Sprintf("// Generated at %s\n\n", time.Now().Format(time.RFC3339)))
+	switch *splitMode {
+	case "single":
+		var output strings.Builder
+		// Generate for all schemas
+		generatedCount := 0
+		for i, schema := range edmx.DataServices.Schemas {
+			log.Printf("Processing schema %d: %s (Alias: %s)", i+1, schema.Namespace, schema.Alias)
+			log.Printf("  - %d EntityTypes", len(schema.EntityTypes))
+			log.Printf("  - %d ComplexTypes", len(schema.ComplexTypes))
+			log.Printf("  - %d EnumTypes", len(schema.EnumTypes))
+			log.Printf("  - %d EntityContainers", len(schema.EntityContainers))
+
+			for _, et := range schema.EntityTypes {
+				output.WriteString(generateStruct(et, true, schema.Namespace))
+				generatedCount++
+				log.Printf("  Generated EntityType: %s", et.Name)
+			}
+			for _, ct := range schema.ComplexTypes {
+				output.WriteString(generateStruct(ct, false, schema.Namespace))
+				generatedCount++
+				log.Printf("  Generated ComplexType: %s", ct.Name)
+			}
+			for _, en := range schema.EnumTypes {
+				output.WriteString(generateEnum(en))
+				generatedCount++
+				log.Printf("  Generated EnumType: %s", en.Name)
+			}
+		}
+
+		if generatedCount == 0 {
+			log.Println("Warning: No types generated. This could indicate namespace mismatches or unusual XML structure.")
+			log.Println("Tip: Run with -dump=true to generate 'debug.xml' and inspect the parsed structure.")
+			log.Println("Common issues: Custom SAP namespaces, version differences, or annotations wrapping content.")
+			output.WriteString("// No types found in metadata. Verify the EDMX file and consider -dump flag for debugging.\n")
+		} else {
+			log.Printf("Successfully generated %d types", generatedCount)
+		}
+
+		if err := writeGoFile(sapgen.DiskWriter{}, *outputFile, *pkgName, output.String()); err != nil {
+			log.Fatalf("Error writing output file: %v", err)
+		}
+		log.Printf("Generated file: %s", *outputFile)
+
+	case "perType":
+		if err := writePerTypeOutputs(&edmx, *outDir, *pkgName); err != nil {
+			log.Fatalf("Error generating per-type outputs: %v", err)
+		}
+		log.Printf("Generated per-type Go files in %s", *outDir)
+
+	default:
+		log.Fatalf("Unknown -split mode: %s (use 'single' or 'perType')", *splitMode)
+	}
+}
+
+// renderGoFile puts the header, package clause and imports in front of the
+// generated types, so the import list only contains what the types reference.
+func renderGoFile(pkgName string, body string) []byte {
+	var header strings.Builder
+	header.WriteString("// Generated types from OData EDMX for SAP Business One Service Layer v2\n")
+	header.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
+	header.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+	header.WriteString(fmt.Sprintf("package %s\n\n", pkgName))
+	var imports []string
+	if strings.Contains(body, "time.Time") {
+		imports = append(imports, "time")
+	}
+	if strings.Contains(body, "json.Number") || strings.Contains(body, "json.RawMessage") {
+		imports = append(imports, "encoding/json")
+	}
+	if strings.Contains(body, "decimal.Decimal") {
+		imports = append(imports, shopspringImport)
+	}
+	if strings.Contains(body, "odata.Rat") || strings.Contains(body, "odata.Opt[") ||
+		strings.Contains(body, "odata.Date") || strings.Contains(body, "odata.YesNo") {
+		imports = append(imports, runtimeImport)
+	}
+	for _, imp := range scalarImports(body) {
+		if !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+	if len(imports) > 0 {
+		header.WriteString("import (\n")
+		for _, imp := range imports {
+			header.WriteString(fmt.Sprintf("\t%q\n", imp))
+		}
+		header.WriteString(")\n\n")
+	}
+	src := header.String() + body
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		log.Printf("Warning: could not format output: %v", err)
+		return []byte(src)
+	}
+	return formatted
+}
 
-	// Package and imports
-	output.WriteString("package odata\n\n") // Customize package name as needed
-	output.WriteString("import (\n")
-	output.WriteString("\t\"time\"\n")
-	output.WriteString(")\n\n")
+// scalarImports returns the import paths of the -scalars types used in body.
+func scalarImports(body string) []string {
+	var imports []string
+	for _, m := range scalars {
+		if m.Go == nil || m.Go.Import == "" || !strings.Contains(body, m.Go.Type) {
+			continue
+		}
+		if !slices.Contains(imports, m.Go.Import) {
+			imports = append(imports, m.Go.Import)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+func writeGoFile(w sapgen.Writer, path string, pkgName string, body string) error {
+	return w.WriteFile(path, renderGoFile(pkgName, body))
+}
 
-	// Generate for all schemas
-	generatedCount := 0
-	for i, schema := range edmx.DataServices.Schemas {
-		log.Printf("Processing schema %d: %s (Alias: %s)", i+1, schema.Namespace, schema.Alias)
-		log.Printf("  - %d EntityTypes", len(schema.EntityTypes))
-		log.Printf("  - %d ComplexTypes", len(schema.ComplexTypes))
-		log.Printf("  - %d EnumTypes", len(schema.EnumTypes))
-		log.Printf("  - %d EntityContainers", len(schema.EntityContainers))
+// goFileName maps a type name to a file name that the go tool will not
+// treat specially (leading "_", "_test" or GOOS/GOARCH suffixes).
+func goFileName(typeName string) string {
+	name := strings.TrimLeft(strings.ToLower(typeName), "_")
+	if name == "" {
+		name = "x"
+	}
+	return name + "_gen.go"
+}
 
+// enumsFile is the file of all enum types in -split=perType.
+const enumsFile = "enums_gen.go"
+
+// checkPerTypeNames reports the types that would overwrite each other's
+// files or declare the same Go type: the package is one for all
+// namespaces and type names are not prefixed, so SAPB1.Item and
+// Other.Item collide, as does a type named Enums with the enums file.
+func checkPerTypeNames(edmx *EDMX) error {
+	files := map[string]string{enumsFile: "the enum types"}
+	idents := map[string]string{}
+	add := func(ns, name string, file bool) error {
+		qn := ns + "." + name
+		if prev, ok := idents[name]; ok {
+			return fmt.Errorf("%s and %s both generate the Go type %s; grok writes one package for all namespaces", prev, qn, name)
+		}
+		idents[name] = qn
+		if !file {
+			return nil
+		}
+		f := goFileName(name)
+		if prev, ok := files[f]; ok {
+			return fmt.Errorf("%s and %s are both written to %s", prev, qn, f)
+		}
+		files[f] = qn
+		return nil
+	}
+	for _, schema := range edmx.DataServices.Schemas {
 		for _, et := range schema.EntityTypes {
-			output.WriteString(generateStruct(et, true, schema.Namespace))
-			generatedCount++
-			log.Printf("  Generated EntityType: %s", et.Name)
+			if err := add(schema.Namespace, et.Name, true); err != nil {
+				return err
+			}
 		}
 		for _, ct := range schema.ComplexTypes {
-			output.WriteString(generateStruct(ct, false, schema.Namespace))
-			generatedCount++
-			log.Printf("  Generated ComplexType: %s", ct.Name)
+			if err := add(schema.Namespace, ct.Name, true); err != nil {
+				return err
+			}
 		}
 		for _, en := range schema.EnumTypes {
-			output.WriteString(generateEnum(en))
-			generatedCount++
-			log.Printf("  Generated EnumType: %s", en.Name)
+			if err := add(schema.Namespace, en.Name, false); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
 
-	if generatedCount == 0 {
-		log.Println("Warning: No types generated. This could indicate namespace mismatches or unusual XML structure.")
-		log.Println("Tip: Run with -dump=true to generate 'debug.xml' and inspect the parsed structure.")
-		log.Println("Common issues: Custom SAP namespaces, version differences, or annotations wrapping content.")
-		output.WriteString("// No types found in metadata. Verify the EDMX file and consider -dump flag for debugging.\n")
-	} else {
-		log.Printf("Successfully generated %d types", generatedCount)
-	}
-
-	formatted, err := format.Source([]byte(output.String()))
-	if err != nil {
-		log.Printf("Warning: could not format output: %v", err)
-		err = ioutil.WriteFile(*outputFile, []byte(output.String()), 0644)
-	} else {
-		err = ioutil.WriteFile(*outputFile, formatted, 0644)
+// writePerTypeOutputs writes one file per entity and complex type and a
+// single enums_gen.go, all in the same package. It fails before writing
+// anything when two types collide; see checkPerTypeNames.
+func writePerTypeOutputs(edmx *EDMX, outDir string, pkgName string) error {
+	if err := checkPerTypeNames(edmx); err != nil {
+		return err
 	}
-	if err != nil {
-		log.Fatalf("Error writing output file: %v", err)
+	// the files written are recorded in outDir's manifest, so that files of
+	// types gone from the metadata are removed next time
+	manifest := sapgen.NewManifestWriter(sapgen.DiskWriter{}, outDir)
+	var enums strings.Builder
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			target := filepath.Join(outDir, goFileName(et.Name))
+			if err := writeGoFile(manifest, target, pkgName, generateStruct(et, true, schema.Namespace)); err != nil {
+				return fmt.Errorf("writing entity file %s: %w", target, err)
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			target := filepath.Join(outDir, goFileName(ct.Name))
+			if err := writeGoFile(manifest, target, pkgName, generateStruct(ct, false, schema.Namespace)); err != nil {
+				return fmt.Errorf("writing complex file %s: %w", target, err)
+			}
+		}
+		for _, en := range schema.EnumTypes {
+			enums.WriteString(generateEnum(en))
+		}
 	}
 
-	log.Printf("Generated file: %s", *outputFile)
+	target := filepath.Join(outDir, enumsFile)
+	if err := writeGoFile(manifest, target, pkgName, enums.String()); err != nil {
+		return fmt.Errorf("writing enums file %s: %w", target, err)
+	}
+	return manifest.Finish()
 }
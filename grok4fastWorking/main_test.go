@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testEDMX parses a v4 document of the schemas, given as their contents
+// keyed by namespace.
+func testEDMX(t *testing.T, schemas ...[2]string) *EDMX {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(`<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx"><edmx:DataServices>`)
+	for _, s := range schemas {
+		b.WriteString(`<Schema Namespace="` + s[0] + `" xmlns="http://docs.oasis-open.org/odata/ns/edm">` + s[1] + `</Schema>`)
+	}
+	b.WriteString(`</edmx:DataServices></edmx:Edmx>`)
+	var edmx EDMX
+	if err := xml.Unmarshal([]byte(b.String()), &edmx); err != nil {
+		t.Fatal(err)
+	}
+	return &edmx
+}
+
+func TestCheckPerTypeNames(t *testing.T) {
+	const item = `<EntityType Name="Item"><Property Name="ItemCode" Type="Edm.String"/></EntityType>`
+	tests := []struct {
+		name    string
+		schemas [][2]string
+		wantErr string // "" for none
+	}{
+		{"distinct", [][2]string{{"SAPB1", item}, {"Other", `<ComplexType Name="Address"/>`}}, ""},
+		{"same name in two namespaces", [][2]string{{"SAPB1", item}, {"Other", item}}, "SAPB1.Item and Other.Item both generate the Go type Item"},
+		{"enum and entity", [][2]string{{"SAPB1", item}, {"Other", `<EnumType Name="Item"><Member Name="A"/></EnumType>`}}, "both generate the Go type Item"},
+		{"same file", [][2]string{{"SAPB1", item + `<ComplexType Name="ITEM"/>`}}, "SAPB1.Item and SAPB1.ITEM are both written to item_gen.go"},
+		{"enums file", [][2]string{{"SAPB1", `<ComplexType Name="Enums"/>`}}, "the enum types and SAPB1.Enums are both written to enums_gen.go"},
+	}
+	for _, tt := range tests {
+		err := checkPerTypeNames(testEDMX(t, tt.schemas...))
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWritePerTypeOutputsCollision(t *testing.T) {
+	dir := t.TempDir()
+	const item = `<EntityType Name="Item"/>`
+	if err := writePerTypeOutputs(testEDMX(t, [2]string{"SAPB1", item}, [2]string{"Other", item}), dir, "models"); err == nil {
+		t.Fatal("no error")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("wrote %d files before failing", len(entries))
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
+
+	"dissemblir/sapModelsGenerator/main2"
+	"dissemblir/sapModelsGenerator/sapgen"
 )
 
 /*
@@ -28,8 +30,33 @@ SPECIAL CASE (SAP B1 quirk):
 - If a property name ends with "Property" (e.g., "ActivityProperty") and there is
   no sibling property with the alias name (e.g., "Activity"), we emit the alias key
   instead, e.g. "Activity?" in the ArkType shape. This matches actual JSON payloads.
+  -property-alias=metadata keeps the metadata name, -property-alias=off
+  disables the rule; user-defined (U_) fields are never aliased.
+- Enum values get a lower-case first letter. -quirks turns these rules on or
+  off (e.g. -quirks=enumCasing=false); -list-quirks describes them.
+
+SELECTION:
+- -include/-exclude take comma-separated patterns ([set:|type:|ns:]glob or
+  /regexp/). Dependencies of the selected types are pulled in; -stub-nav
+  stops at navigation properties.
+- -objects=standard|user splits standard B1 objects from user-defined tables
+  (U_ sets) and UDOs, so each can be generated on its own; user output keeps
+  all enums since their values are inlined. -user-objects adds patterns.
+
+DECIMALS:
+- Edm.Decimal goes through a generated decimal(precision, scale) helper that
+  checks Precision/Scale and converts per -decimal (number | string |
+  decimal.js | big.js). Pass -ieee754 when the service sends them as strings.
 
 Usage:
+  From a config file (all targets, or a comma-separated -target list):
+    go run . generate --config sapgen.json [-target models,web]
+  Verify the files on disk are up to date (prints a diff, exits 1 if not):
+    go run . generate --config sapgen.json --check
+  Serve the entity sets of the metadata from memory, for tests:
+    go run . mock-server --metadata metadata.xml [--fixtures testdata/]
+  Sample entities per entity set, seeded (for tests, the mock and docs):
+    go run . fixtures --metadata metadata.xml [--out testdata/ --seed 1 --profile profile.json]
   Split per type (recommended):
     go run main.go -input="metadata.xml" -outDir="./types" -split="perType"
   Single file (legacy):
@@ -142,7 +169,7 @@ var edmToArkBase = map[string]string{
 	"Byte":           "number",
 	"SByte":          "number",
 	"Boolean":        "boolean",
-	"Decimal":        "number", // change to "string" if your API sends decimals as strings
+	"Decimal":        "number", // only used for shallow fallbacks; properties use decimal()
 	"Double":         "number",
 	"Single":         "number",
 	"Guid":           "string",
@@ -154,6 +181,31 @@ var edmToArkBase = map[string]string{
 	"Duration":       "string",
 }
 
+// Edm.Decimal handling, set from -decimal and -ieee754.
+var (
+	decimalMode = sapgen.DecimalNumber
+	ieee754     bool
+)
+
+// Type naming, set from -rename.
+var namer = sapgen.NewNamer(sapgen.Naming{})
+
+// User types from -scalars, keyed by Edm type or Type.Property.
+var scalars sapgen.Scalars
+
+// Naming of the "...Property" fields, set from -property-alias.
+var propertyAlias sapgen.PropertyAlias
+
+// SAP B1 rules turned on or off, set from -quirks.
+var quirks sapgen.QuirkSet
+
+// Where the files go (memory for generate --check), and the metadata hash
+// recorded in their headers with -source-hash.
+var (
+	output     sapgen.Writer = sapgen.DiskWriter{}
+	sourceHash string
+)
+
 // ========================= Helpers =========================
 
 func extractEdmTypeName(edmType string) string {
@@ -207,21 +259,109 @@ func arkPropTypeDSL(edmType string, isEnum bool, enumVals []string) string {
 	return "object|null"
 }
 
+// Report whether a property (or collection of) is Edm.Decimal.
+func isDecimalProp(p Property) bool {
+	_, inner := isCollection(p.Type)
+	return extractEdmTypeName(inner) == "Decimal"
+}
+
+// Report whether any property in a list is Edm.Decimal.
+func hasDecimalProp(props []Property) bool {
+	for _, p := range props {
+		if isDecimalProp(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report whether any entity or complex type has a decimal property.
+func usesDecimal(edmx *EDMX) bool {
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			if hasDecimalProp(et.Properties) {
+				return true
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			if hasDecimalProp(ct.Properties) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Build the ArkType expression (not DSL string) for a decimal property.
+// arkScalar returns the -scalars mapping of property p of the type typeName
+// when it has an ArkType validator; other mappings keep the built-in type.
+func arkScalar(typeName string, p Property) *sapgen.TsScalar {
+	_, inner := isCollection(p.Type)
+	if m, ok := scalars.Lookup("", typeName, p.Name, inner); ok && m.TS != nil && m.TS.Ark != "" {
+		return m.TS
+	}
+	return nil
+}
+
+// arkScalarImports renders the user module imports needed by props.
+func arkScalarImports(typeName string, props []Property) string {
+	imports := sapgen.TsImports{}
+	for _, p := range props {
+		imports.Add(arkScalar(typeName, p), "ark")
+	}
+	return imports.Render(`"`)
+}
+
+func arkDecimalExpr(p Property) string {
+	isColl, _ := isCollection(p.Type)
+	expr := fmt.Sprintf("decimal(%s)", sapgen.TsDecimalArgs(p.Precision, p.Scale))
+	if isColl {
+		expr += ".array()"
+	}
+	return expr + `.or("null")`
+}
+
 // ========================= ArkType emission =========================
 
-func generateArkEnum(e EnumType) string {
-	// Unique member names (as SAP usually returns them, e.g., "cn_Meeting")
+// Generate the decimal() helper: validates Precision/Scale on the textual
+// value, then converts it per -decimal.
+func generateArkDecimalHelper() string {
+	input := "number"
+	if sapgen.TsDecimalAcceptsString(decimalMode, ieee754) {
+		input = "number|string"
+	}
+	var b strings.Builder
+	b.WriteString(sapgen.TsFitsDecimalFunc)
+	b.WriteString("\n")
+	b.WriteString("export const decimal = (precision?: number, scale?: number) =>\n")
+	b.WriteString(fmt.Sprintf("  type(\"%s\")\n", input))
+	b.WriteString("    .narrow((v, ctx) =>\n")
+	b.WriteString("      fitsDecimal(String(v), precision, scale) ||\n")
+	b.WriteString("      ctx.mustBe(`an Edm.Decimal(${precision ?? '*'}, ${scale ?? '*'})`))\n")
+	b.WriteString(fmt.Sprintf("    .pipe((v) => %s);\n\n", sapgen.TsDecimalConvert(decimalMode)))
+	return b.String()
+}
+
+// enumValues returns the unique JSON values of e, as SAP returns them
+// (e.g., "cn_Meeting"; see the enumCasing quirk).
+func enumValues(e EnumType) []string {
 	seen := map[string]bool{}
 	vals := []string{}
 	for _, m := range e.Members {
-		if !seen[m.Name] {
-			seen[m.Name] = true
-			vals = append(vals, m.Name)
+		v := quirks.EnumValue(m.Name)
+		if !seen[v] {
+			seen[v] = true
+			vals = append(vals, v)
 		}
 	}
+	return vals
+}
+
+func generateArkEnum(e EnumType) string {
+	vals := enumValues(e)
 
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("export const %sType = type(\"", e.Name))
+	b.WriteString(fmt.Sprintf("export const %sType = type(\"", arkName(e.Name)))
 	for i, v := range vals {
 		if i > 0 {
 			b.WriteString("|")
@@ -232,7 +372,7 @@ func generateArkEnum(e EnumType) string {
 	return b.String()
 }
 
-// Generate ArkType object. Applies "Property" aliasing:
+// Generate ArkType object. Applies "Property" aliasing per -property-alias:
 // If a scalar property ends with "Property" and the alias (without suffix) does not
 // exist as a sibling, we emit the alias key instead (matches actual JSON).
 func generateArkObject(typ interface{}, enumsByName map[string][]string) string {
@@ -251,15 +391,16 @@ func generateArkObject(typ interface{}, enumsByName map[string][]string) string
 		navs = t.NavigationProperties
 	}
 
-	typeName := strings.Title(name)
+	typeName := arkName(name)
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("export const %sType = type({\n", typeName))
 
-	// Build set of existing property names to avoid alias collisions
-	propNames := make(map[string]struct{}, len(props))
-	for _, p := range props {
-		propNames[p.Name] = struct{}{}
+	// Aliases skip names that already exist as siblings
+	propNames := make([]string, len(props))
+	for i, p := range props {
+		propNames[i] = p.Name
 	}
+	aliases := propertyAlias.Aliases(propNames)
 
 	// Scalar props
 	for _, p := range props {
@@ -267,18 +408,21 @@ func generateArkObject(typ interface{}, enumsByName map[string][]string) string
 		enumVals, isEnum := enumsByName[innerName]
 		dsl := arkPropTypeDSL(p.Type, isEnum, enumVals)
 
-		// Alias rule: if ends with "Property" and alias key doesn't exist, use alias
-		keyName := p.Name
-		if strings.HasSuffix(keyName, "Property") {
-			alias := strings.TrimSuffix(keyName, "Property")
-			if alias != "" {
-				if _, exists := propNames[alias]; !exists {
-					keyName = alias
-				}
-			}
-		}
+		keyName := propertyAlias.WireName(p.Name, aliases)
 
 		// IMPORTANT: quoted key with ? for optional
+		if m := arkScalar(name, p); m != nil {
+			expr := m.Ark
+			if isColl, _ := isCollection(p.Type); isColl {
+				expr += ".array()"
+			}
+			b.WriteString(fmt.Sprintf("  \"%s?\": %s.or(\"null\"),\n", keyName, expr))
+			continue
+		}
+		if isDecimalProp(p) {
+			b.WriteString(fmt.Sprintf("  \"%s?\": %s,\n", keyName, arkDecimalExpr(p)))
+			continue
+		}
 		b.WriteString(fmt.Sprintf("  \"%s?\": \"%s\",\n", keyName, dsl))
 	}
 
@@ -294,35 +438,23 @@ func generateArkObject(typ interface{}, enumsByName map[string][]string) string
 
 // ========================= I/O helpers =========================
 
-func ensureDir(dir string) error {
-	return os.MkdirAll(dir, 0755)
-}
-
 func writeFile(path string, content string) error {
-	if err := ensureDir(filepath.Dir(path)); err != nil {
-		return err
-	}
-	return os.WriteFile(path, []byte(content), 0644)
+	return output.WriteFile(path, []byte(content))
 }
 
 // ========================= Writers =========================
 
 func writePerTypeOutputs(edmx *EDMX, outDir string) error {
-	generatedAt := time.Now().Format(time.RFC3339)
+	// the files written are recorded in outDir's manifest
+	manifest := sapgen.NewManifestWriter(output, outDir)
+	defer func(w sapgen.Writer) { output = w }(output)
+	output = manifest
 
 	// Map enum name -> values for quick lookup
 	enumsByName := map[string][]string{}
 	for _, schema := range edmx.DataServices.Schemas {
 		for _, en := range schema.EnumTypes {
-			seen := map[string]bool{}
-			vals := []string{}
-			for _, m := range en.Members {
-				if !seen[m.Name] {
-					seen[m.Name] = true
-					vals = append(vals, m.Name)
-				}
-			}
-			enumsByName[en.Name] = vals
+			enumsByName[en.Name] = enumValues(en)
 		}
 	}
 
@@ -331,7 +463,7 @@ func writePerTypeOutputs(edmx *EDMX, outDir string) error {
 		var b strings.Builder
 		b.WriteString("// Generated ArkType enums from OData EDMX for SAP Business One Service Layer v2\n")
 		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-		b.WriteString(fmt.Sprintf("// Generated at %s\n\n", generatedAt))
+		b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
 		b.WriteString(`import { type } from "arktype";` + "\n\n")
 
 		// stable order across schemas
@@ -350,77 +482,109 @@ func writePerTypeOutputs(edmx *EDMX, outDir string) error {
 		if err := writeFile(enumsPath, b.String()); err != nil {
 			return fmt.Errorf("writing enums.ts: %w", err)
 		}
-		log.Printf("Wrote %s", enumsPath)
+	}
+
+	// decimal.ts
+	if usesDecimal(edmx) {
+		var b strings.Builder
+		b.WriteString("// Generated ArkType Edm.Decimal helper for SAP Business One Service Layer v2\n")
+		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
+		b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+		b.WriteString(`import { type } from "arktype";` + "\n")
+		b.WriteString(sapgen.TsDecimalImport(decimalMode))
+		b.WriteString("\n")
+		b.WriteString(generateArkDecimalHelper())
+
+		decimalPath := filepath.Join(outDir, "decimal.ts")
+		if err := writeFile(decimalPath, b.String()); err != nil {
+			return fmt.Errorf("writing decimal.ts: %w", err)
+		}
 	}
 
 	// entities
 	entityDir := filepath.Join(outDir, "entities")
-	if err := ensureDir(entityDir); err != nil {
-		return err
-	}
 	for _, schema := range edmx.DataServices.Schemas {
 		for _, et := range schema.EntityTypes {
 			var b strings.Builder
 			b.WriteString("// Generated ArkType entity from OData EDMX for SAP Business One Service Layer v2\n")
 			b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-			b.WriteString(fmt.Sprintf("// Generated at %s\n\n", generatedAt))
-			b.WriteString(`import { type } from "arktype";` + "\n\n")
+			b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+			b.WriteString(`import { type } from "arktype";` + "\n")
+			if hasDecimalProp(et.Properties) {
+				b.WriteString(`import { decimal } from "../decimal";` + "\n")
+			}
+			b.WriteString(arkScalarImports(et.Name, et.Properties))
+			b.WriteString("\n")
 			b.WriteString(generateArkObject(et, enumsByName))
 
-			target := filepath.Join(entityDir, strings.Title(et.Name)+".ts")
+			target := filepath.Join(entityDir, arkName(et.Name)+".ts")
 			if err := writeFile(target, b.String()); err != nil {
 				return fmt.Errorf("writing entity file %s: %w", target, err)
 			}
-			log.Printf("Wrote %s", target)
 		}
 	}
 
 	// complex
 	complexDir := filepath.Join(outDir, "complex")
-	if err := ensureDir(complexDir); err != nil {
-		return err
-	}
 	for _, schema := range edmx.DataServices.Schemas {
 		for _, ct := range schema.ComplexTypes {
 			var b strings.Builder
 			b.WriteString("// Generated ArkType complex type from OData EDMX for SAP Business One Service Layer v2\n")
 			b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-			b.WriteString(fmt.Sprintf("// Generated at %s\n\n", generatedAt))
-			b.WriteString(`import { type } from "arktype";` + "\n\n")
+			b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+			b.WriteString(`import { type } from "arktype";` + "\n")
+			if hasDecimalProp(ct.Properties) {
+				b.WriteString(`import { decimal } from "../decimal";` + "\n")
+			}
+			b.WriteString(arkScalarImports(ct.Name, ct.Properties))
+			b.WriteString("\n")
 			b.WriteString(generateArkObject(ct, enumsByName))
 
-			target := filepath.Join(complexDir, strings.Title(ct.Name)+".ts")
+			target := filepath.Join(complexDir, arkName(ct.Name)+".ts")
 			if err := writeFile(target, b.String()); err != nil {
 				return fmt.Errorf("writing complex file %s: %w", target, err)
 			}
-			log.Printf("Wrote %s", target)
 		}
 	}
 
 	// No barrels to avoid loading everything at once
-	return nil
+	return manifest.Finish()
 }
 
 func writeSingleFile(edmx *EDMX, outputFile string) error {
 	var out strings.Builder
 	out.WriteString("// Generated ArkType types from OData EDMX for SAP Business One Service Layer v2\n")
 	out.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-	out.WriteString(fmt.Sprintf("// Generated at %s\n\n", time.Now().Format(time.RFC3339)))
-	out.WriteString(`import { type } from "arktype";` + "\n\n")
+	out.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+	out.WriteString(`import { type } from "arktype";` + "\n")
+	hasDecimal := usesDecimal(edmx)
+	if hasDecimal {
+		out.WriteString(sapgen.TsDecimalImport(decimalMode))
+	}
+	userImports := sapgen.TsImports{}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			for _, p := range et.Properties {
+				userImports.Add(arkScalar(et.Name, p), "ark")
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			for _, p := range ct.Properties {
+				userImports.Add(arkScalar(ct.Name, p), "ark")
+			}
+		}
+	}
+	out.WriteString(userImports.Render(`"`))
+	out.WriteString("\n")
+	if hasDecimal {
+		out.WriteString(generateArkDecimalHelper())
+	}
 
 	// enums map for props
 	enumsByName := map[string][]string{}
 	for _, schema := range edmx.DataServices.Schemas {
 		for _, en := range schema.EnumTypes {
-			seen := map[string]bool{}
-			vals := []string{}
-			for _, m := range en.Members {
-				if !seen[m.Name] {
-					seen[m.Name] = true
-					vals = append(vals, m.Name)
-				}
-			}
-			enumsByName[en.Name] = vals
+			enumsByName[en.Name] = enumValues(en)
 		}
 	}
 
@@ -441,18 +605,206 @@ func writeSingleFile(edmx *EDMX, outputFile string) error {
 		}
 	}
 
-	return ioutil.WriteFile(outputFile, []byte(out.String()), 0644)
+	return writeFile(outputFile, out.String())
+}
+
+// arkName is the exported name of a type: its -rename, or the title-cased name.
+func arkName(name string) string {
+	if to, ok := namer.TypeName("", name); ok {
+		return to
+	}
+	return strings.Title(name)
+}
+
+// applyNaming installs n for edmx. ArkType validates the payload as sent, so
+// keys keep their wire names; only types can be renamed.
+func applyNaming(edmx *EDMX, n sapgen.Naming) error {
+	if n.Keys == "camel" {
+		return fmt.Errorf("keys=camel is not supported by the ArkType generator (keys must match the wire names)")
+	}
+	namer = sapgen.NewNamer(n)
+	types := sapgen.NewNameScope("type")
+	checkFields := func(name string, props []Property, navs []NavigationProperty) error {
+		for _, p := range props {
+			if _, ok := namer.FieldName("", name, p.Name); ok {
+				return fmt.Errorf("rename of %s.%s: the ArkType generator cannot rename properties", name, p.Name)
+			}
+		}
+		for _, nav := range navs {
+			if _, ok := namer.FieldName("", name, nav.Name); ok {
+				return fmt.Errorf("rename of %s.%s: the ArkType generator cannot rename properties", name, nav.Name)
+			}
+		}
+		return nil
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, en := range schema.EnumTypes {
+			if err := types.Claim(arkName(en.Name), en.Name); err != nil {
+				return err
+			}
+		}
+		for _, et := range schema.EntityTypes {
+			if err := types.Claim(arkName(et.Name), et.Name); err != nil {
+				return err
+			}
+			if err := checkFields(et.Name, et.Properties, et.NavigationProperties); err != nil {
+				return err
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			if err := types.Claim(arkName(ct.Name), ct.Name); err != nil {
+				return err
+			}
+			if err := checkFields(ct.Name, ct.Properties, ct.NavigationProperties); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filterEDMX keeps only the selected types (see main2.SelectTypes).
+// Navigation properties are emitted shallow, so nothing needs stubbing.
+func filterEDMX(edmx *EDMX, types sapgen.TypeSet) *EDMX {
+	if types == nil {
+		return edmx
+	}
+	out := *edmx
+	out.DataServices.Schemas = nil
+	for _, schema := range edmx.DataServices.Schemas {
+		s := schema
+		s.EnumTypes, s.EntityTypes, s.ComplexTypes = nil, nil, nil
+		for _, e := range schema.EnumTypes {
+			if types.Has(schema.Namespace, e.Name) {
+				s.EnumTypes = append(s.EnumTypes, e)
+			}
+		}
+		for _, et := range schema.EntityTypes {
+			if types.Has(schema.Namespace, et.Name) {
+				s.EntityTypes = append(s.EntityTypes, et)
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			if types.Has(schema.Namespace, ct.Name) {
+				s.ComplexTypes = append(s.ComplexTypes, ct)
+			}
+		}
+		out.DataServices.Schemas = append(out.DataServices.Schemas, s)
+	}
+	return &out
+}
+
+// withEnums adds every enum type to types. ArkType inlines enum values, so
+// user objects (-objects=user) keep the standard enums they use.
+func withEnums(edmx *EDMX, types sapgen.TypeSet) sapgen.TypeSet {
+	out := sapgen.TypeSet{}
+	for qn := range types {
+		out[qn] = true
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, e := range schema.EnumTypes {
+			out[schema.Namespace+"."+e.Name] = true
+		}
+	}
+	return out
+}
+
+// generateArkType writes the ArkType output in the given split mode.
+func generateArkType(edmx *EDMX, naming sapgen.Naming, splitMode, outputFile, outDir string) error {
+	if err := applyNaming(edmx, naming); err != nil {
+		return err
+	}
+	switch splitMode {
+	case "single":
+		if err := writeSingleFile(edmx, outputFile); err != nil {
+			return fmt.Errorf("writing single output file: %w", err)
+		}
+		log.Printf("Generated ArkType types in %s", outputFile)
+	case "perType":
+		if err := writePerTypeOutputs(edmx, outDir); err != nil {
+			return fmt.Errorf("generating per-type outputs: %w", err)
+		}
+		log.Printf("Generated per-type ArkType TS files in %s", outDir)
+	default:
+		return fmt.Errorf("unknown -split mode: %s (use 'single' or 'perType')", splitMode)
+	}
+	return nil
 }
 
 // ========================= main =========================
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := runGenerate(os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mock-server" {
+		if err := runMockServer(os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fixtures" {
+		if err := runFixtures(os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	inputFile := flag.String("input", "", "Path to the EDMX XML file")
 	outputFile := flag.String("output", "types.ts", "Path to the output TS file for -split=single")
 	outDir := flag.String("outDir", "types", "Directory to write TS files for -split=perType")
 	splitMode := flag.String("split", "perType", "Output mode: single | perType")
+	decimal := flag.String("decimal", "number", "Edm.Decimal representation: number | string | decimal.js | big.js")
+	flag.BoolVar(&ieee754, "ieee754", false, "Service uses IEEE754Compatible=true (decimals sent as strings)")
+	include := flag.String("include", "", "Comma-separated patterns of types to generate, e.g. set:Orders,type:Item* (default: all)")
+	exclude := flag.String("exclude", "", "Comma-separated patterns of types to leave out")
+	stubNav := flag.Bool("stub-nav", false, "Do not follow navigation properties when selecting types")
+	renames := flag.String("rename", "", "Comma-separated type renames: Type=TsName")
+	scalarsFile := flag.String("scalars", "", "JSON file mapping Edm types and Type.Property paths to user types")
+	objects := flag.String("objects", "all", "Objects to generate: all | standard | user (UDTs and UDOs)")
+	userObjects := flag.String("user-objects", "", "Comma-separated patterns of more types to treat as user objects")
+	aliasMode := flag.String("property-alias", "alias", `"...Property" fields (ActivityProperty): alias (key Activity) | metadata | off`)
+	quirkList := flag.String("quirks", "", "Comma-separated SAP quirk rules to turn on or off, e.g. enumCasing=false")
+	listQuirks := flag.Bool("list-quirks", false, "Print the SAP quirk rules and exit")
+	hashHeader := flag.Bool("source-hash", false, "Record the SHA-256 of the metadata in the file headers")
 	flag.Parse()
 
+	if *listQuirks {
+		sapgen.WriteQuirks(os.Stdout)
+		return
+	}
+
+	mode, err := sapgen.ParseDecimalMode(*decimal, sapgen.TsDecimalModes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	decimalMode = mode
+	var naming sapgen.Naming
+	if naming.Renames, err = sapgen.ParseRenames(*renames); err == nil {
+		err = naming.Validate()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if scalars, err = sapgen.LoadScalars(*scalarsFile); err != nil {
+		log.Fatal(err)
+	}
+	uo := sapgen.UserObjects{Objects: *objects, UserPatterns: sapgen.SplitList(*userObjects)}
+	if err := uo.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	propertyAlias = sapgen.PropertyAlias(*aliasMode)
+	if err := propertyAlias.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if quirks, err = sapgen.ParseQuirks(*quirkList); err != nil {
+		log.Fatal(err)
+	}
+	propertyAlias = quirks.PropertyAlias(propertyAlias)
+
 	if *inputFile == "" {
 		log.Fatal("Please provide -input flag with the XML file path")
 	}
@@ -461,6 +813,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error reading XML file: %v", err)
 	}
+	if *hashHeader {
+		sourceHash = sapgen.SourceHash(data)
+	}
 
 	var edmx EDMX
 	if err := xml.Unmarshal(data, &edmx); err != nil {
@@ -470,18 +825,30 @@ func main() {
 	log.Printf("Parsed EDMX Version: %s", edmx.Version)
 	log.Printf("Parsed %d schemas", len(edmx.DataServices.Schemas))
 
-	switch *splitMode {
-	case "single":
-		if err := writeSingleFile(&edmx, *outputFile); err != nil {
-			log.Fatalf("Error writing single output file: %v", err)
+	sel := sapgen.Selection{Include: sapgen.SplitList(*include), Exclude: sapgen.SplitList(*exclude), StubNavigation: *stubNav}
+	if !sel.IsZero() || uo.Side() != sapgen.ObjectsAll {
+		// the dependency closure and user objects live with the Zod generator (main2)
+		zedmx, err := main2.ParseEDMX(data)
+		if err != nil {
+			log.Fatalf("Error unmarshaling XML: %v", err)
 		}
-		log.Printf("Generated ArkType types in %s", *outputFile)
-	case "perType":
-		if err := writePerTypeOutputs(&edmx, *outDir); err != nil {
-			log.Fatalf("Error generating per-type outputs: %v", err)
+		var types sapgen.TypeSet
+		if !sel.IsZero() {
+			if types, err = main2.SelectTypes(zedmx, sel); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
 		}
-		log.Printf("Generated per-type ArkType TS files in %s", *outDir)
-	default:
-		log.Fatalf("Unknown -split mode: %s (use 'single' or 'perType')", *splitMode)
+		if types, err = main2.ObjectTypes(zedmx, uo, types); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if uo.Side() == sapgen.ObjectsUser {
+			types = withEnums(&edmx, types)
+		}
+		log.Printf("Selected %d types", len(types))
+		edmx = *filterEDMX(&edmx, types)
+	}
+
+	if err := generateArkType(&edmx, naming, *splitMode, *outputFile, *outDir); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 }
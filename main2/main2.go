@@ -8,10 +8,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
+
+	"dissemblir/sapModelsGenerator/sapgen"
 )
 
 // Usage examples:
@@ -19,6 +21,17 @@ import (
 //     go run main.go -input="metadata.xml" -outDir="./types" -split="perType"
 //   Single file (legacy):
 //     go run main.go -input="metadata.xml" -output="types.ts" -split="single"
+//   Exact decimals (decimal.js / big.js / string), service in IEEE754Compatible mode:
+//     go run main.go -input="metadata.xml" -decimal="decimal.js" -ieee754
+//   UDFs in <Type>UDF extensions generated from one company's metadata:
+//     go run main.go -input="metadata.xml" -udf="extension" -udf-in="company.xml"
+//   Standard objects and one customer's UDTs/UDOs as separate modules:
+//     go run main.go -input="metadata.xml" -objects="standard" -outDir="./std"
+//     go run main.go -input="metadata.xml" -objects="user" -outDir="./acme" -standard-module="../std"
+//   Typed query builder (<Type>Fields descriptors and query.ts), $batch builder (batch.ts):
+//     go run main.go -input="metadata.xml" -query -batch
+//   Entity models keeping @odata.etag for optimistic concurrency:
+//     go run main.go -input="metadata.xml" -etag
 
 // (Same XML parsing structs as before - unchanged for SAP B1 compatibility)
 type EDMX struct {
@@ -48,7 +61,7 @@ type EntityType struct {
 	Key                  []PropertyRef        `xml:"http://docs.oasis-open.org/odata/ns/edm Key>PropertyRef"`
 	Properties           []Property           `xml:"http://docs.oasis-open.org/odata/ns/edm Property"`
 	NavigationProperties []NavigationProperty `xml:"http://docs.oasis-open.org/odata/ns/edm NavigationProperty"`
-	Base                 string               `xml:"Base,attr,omitempty"`
+	Base                 string               `xml:"BaseType,attr,omitempty"`
 }
 
 type ComplexType struct {
@@ -56,6 +69,7 @@ type ComplexType struct {
 	Name                 string               `xml:"Name,attr"`
 	Properties           []Property           `xml:"http://docs.oasis-open.org/odata/ns/edm Property"`
 	NavigationProperties []NavigationProperty `xml:"http://docs.oasis-open.org/odata/ns/edm NavigationProperty"`
+	Base                 string               `xml:"BaseType,attr,omitempty"`
 }
 
 type EnumType struct {
@@ -124,7 +138,7 @@ var edmToZod = map[string]string{
 	"Byte":           "z.number().int().nonnegative().max(255)",
 	"SByte":          "z.number().int().min(-128).max(127)",
 	"Boolean":        "z.boolean()",
-	"Decimal":        "z.number()", // Or "z.string()" for precision
+	"Decimal":        "decimal()", // generated helper, see -decimal and generateZodDecimalHelper
 	"Double":         "z.number()",
 	"Single":         "z.number()",
 	"Guid":           "z.string().uuid()",
@@ -146,7 +160,7 @@ var edmToTs = map[string]string{
 	"Byte":           "number",
 	"SByte":          "number",
 	"Boolean":        "boolean",
-	"Decimal":        "number", // or "string" if you prefer high precision
+	"Decimal":        "number", // set from -decimal by applyDecimalMode
 	"Double":         "number",
 	"Single":         "number",
 	"Guid":           "string",
@@ -158,6 +172,313 @@ var edmToTs = map[string]string{
 	"Duration":       "string",
 }
 
+// Edm.Decimal handling, set from -decimal and -ieee754.
+var (
+	decimalMode = sapgen.DecimalNumber
+	ieee754     bool
+)
+
+// Navigation targets that are not generated (left out by -include/-exclude);
+// their navigation properties are emitted as untyped objects.
+var navStubs map[string]struct{}
+
+// Naming of TS types and keys, set from -keys and -rename.
+var (
+	namer       = sapgen.NewNamer(sapgen.Naming{})
+	wireMapping bool                // some key differs from its wire name
+	structTypes map[string]struct{} // generated entity and complex type names
+)
+
+const (
+	tsNavStub  = "Record<string, unknown>"
+	zodNavStub = "z.record(z.string(), z.unknown())"
+)
+
+// applyDecimalMode aligns the TS model type with the output of the decimal() helper.
+func applyDecimalMode(mode sapgen.DecimalMode) {
+	decimalMode = mode
+	edmToTs["Decimal"] = sapgen.TsDecimalType(mode)
+}
+
+// User types from -scalars, keyed by Edm type or Type.Property.
+var scalars sapgen.Scalars
+
+// Naming of the "...Property" fields, set from -property-alias.
+var propertyAlias sapgen.PropertyAlias
+
+// SAP B1 rules turned on or off, set from -quirks.
+var quirks sapgen.QuirkSet
+
+// Where the files go (memory for generate --check), and the metadata hash
+// recorded in their headers with -source-hash.
+var (
+	output     sapgen.Writer = sapgen.DiskWriter{}
+	sourceHash string
+)
+
+// Zod types of the yesNoBool and b1Dates quirks. Timestamps without zone
+// are B1 dates, read as UTC rather than local time.
+const (
+	zodYesNo  = "z.preprocess((v) => v === 'tYES' ? true : v === 'tNO' ? false : v, z.boolean())"
+	zodB1Date = `z.preprocess((v) => typeof v === 'string' && /^\d{4}-\d{2}-\d{2}T[\d:.]+$/.test(v) ? v + 'Z' : v, z.coerce.date())`
+)
+
+// Types of the standard module imported by -objects=user output, by name
+// (true for enums), and where to import them from.
+var (
+	external       map[string]bool
+	standardModule string
+)
+
+// standardImport is standardModule as seen from a file dir levels below the
+// output root; package names are used as they are.
+func standardImport(dir int) string {
+	if !strings.HasPrefix(standardModule, ".") {
+		return standardModule
+	}
+	if dir == 0 {
+		return standardModule
+	}
+	return strings.TrimSuffix(strings.Repeat("../", dir)+strings.TrimPrefix(standardModule, "./"), "/")
+}
+
+// externalImports renders the imports of the external types among deps.
+func externalImports(deps []string, module string) string {
+	var typeNames, values []string
+	for _, dep := range deps {
+		isEnum, ok := external[dep]
+		if !ok {
+			continue
+		}
+		typeNames = append(typeNames, tsName(dep))
+		values = append(values, tsName(dep)+"Schema")
+		if wireMapping && !isEnum {
+			values = append(values, tsName(dep)+"ToWire")
+		}
+		if queryFields && !isEnum {
+			values = append(values, tsName(dep)+"Fields")
+		}
+	}
+	if len(typeNames) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("import type { %s } from '%s';\nimport { %s } from '%s';\n",
+		strings.Join(typeNames, ", "), module, strings.Join(values, ", "), module)
+}
+
+// User-defined (U_) fields per type name with -udf=extension; nil keeps
+// them inline.
+var udfs map[string][]Property
+
+// udfExtension is the <Name>UDF model and schema holding the user-defined
+// fields of the entity or complex type Name.
+type udfExtension struct {
+	Name       string
+	Properties []Property
+}
+
+// splitUDFs removes the U_ properties from the entity and complex types of
+// core and returns, per type name, the U_ properties of the same type in
+// ext. Every core type gets an entry, empty when ext has no UDFs for it.
+func splitUDFs(core, ext *EDMX) (*EDMX, map[string][]Property) {
+	extProps := map[string][]Property{}
+	for _, schema := range ext.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			extProps[et.Name] = et.Properties
+		}
+		for _, ct := range schema.ComplexTypes {
+			extProps[ct.Name] = ct.Properties
+		}
+	}
+	onlyUDFs := func(props []Property, want bool) []Property {
+		out := []Property{}
+		for _, p := range props {
+			if sapgen.IsUDF(p.Name) == want {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	split := map[string][]Property{}
+	out := *core
+	out.DataServices.Schemas = make([]Schema, len(core.DataServices.Schemas))
+	for i, schema := range core.DataServices.Schemas {
+		schema.EntityTypes = append([]EntityType(nil), schema.EntityTypes...)
+		for j, et := range schema.EntityTypes {
+			schema.EntityTypes[j].Properties = onlyUDFs(et.Properties, false)
+			split[et.Name] = onlyUDFs(extProps[et.Name], true)
+		}
+		schema.ComplexTypes = append([]ComplexType(nil), schema.ComplexTypes...)
+		for j, ct := range schema.ComplexTypes {
+			schema.ComplexTypes[j].Properties = onlyUDFs(ct.Properties, false)
+			split[ct.Name] = onlyUDFs(extProps[ct.Name], true)
+		}
+		out.DataServices.Schemas[i] = schema
+	}
+	return &out, split
+}
+
+// tsScalar returns the TS mapping of property p of the type typeName, if any.
+func tsScalar(typeName string, p Property) *sapgen.TsScalar {
+	_, inner := isCollection(p.Type)
+	if m, ok := scalars.Lookup("", typeName, p.Name, inner); ok {
+		return m.TS
+	}
+	return nil
+}
+
+// scalarImports collects the user modules needed by the properties of typ.
+func scalarImports(typ interface{}, into sapgen.TsImports) {
+	switch t := typ.(type) {
+	case EntityType:
+		for _, p := range t.Properties {
+			into.Add(tsScalar(t.Name, p), "zod")
+		}
+	case ComplexType:
+		for _, p := range t.Properties {
+			into.Add(tsScalar(t.Name, p), "zod")
+		}
+	case udfExtension:
+		for _, p := range t.Properties {
+			into.Add(tsScalar(t.Name, p), "zod")
+		}
+	}
+}
+
+// tsName is the TS identifier of a type: its rename, or the title-cased name.
+func tsName(name string) string {
+	if to, ok := namer.TypeName("", name); ok {
+		return to
+	}
+	return strings.Title(name)
+}
+
+// tsKey is the TS key of property prop of the type typeName: a
+// "Type.Property" rename, the camelCase name with -keys=camel, else the wire name.
+func tsKey(typeName, prop string) string {
+	if to, ok := namer.FieldName("", typeName, prop); ok {
+		return to
+	}
+	if namer.CamelKeys() {
+		return namer.Camel(prop)
+	}
+	return prop
+}
+
+// applyNaming sets up type and key naming for edmx and rejects names that
+// collide after renaming.
+func applyNaming(edmx *EDMX, n sapgen.Naming) error {
+	namer = sapgen.NewNamer(n)
+	wireMapping = false
+	structTypes = map[string]struct{}{}
+	types := sapgen.NewNameScope("type")
+	checkKeys := func(name string, props []Property, navs []NavigationProperty) error {
+		keys := sapgen.NewNameScope("property of " + name)
+		for _, p := range props {
+			wireMapping = wireMapping || tsKey(name, p.Name) != p.Name
+			if err := keys.Claim(tsKey(name, p.Name), p.Name); err != nil {
+				return err
+			}
+		}
+		for _, nav := range navs {
+			wireMapping = wireMapping || tsKey(name, nav.Name) != nav.Name
+			if err := keys.Claim(tsKey(name, nav.Name), nav.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, en := range schema.EnumTypes {
+			if err := types.Claim(tsName(en.Name), en.Name); err != nil {
+				return err
+			}
+		}
+		for _, et := range schema.EntityTypes {
+			structTypes[et.Name] = struct{}{}
+			if err := types.Claim(tsName(et.Name), et.Name); err != nil {
+				return err
+			}
+			if _, ok := udfs[et.Name]; ok {
+				if err := types.Claim(tsName(et.Name)+"UDF", et.Name+" UDF"); err != nil {
+					return err
+				}
+			}
+			if err := checkKeys(et.Name, slices.Concat(et.Properties, udfs[et.Name]), et.NavigationProperties); err != nil {
+				return err
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			structTypes[ct.Name] = struct{}{}
+			if err := types.Claim(tsName(ct.Name), ct.Name); err != nil {
+				return err
+			}
+			if _, ok := udfs[ct.Name]; ok {
+				if err := types.Claim(tsName(ct.Name)+"UDF", ct.Name+" UDF"); err != nil {
+					return err
+				}
+			}
+			if err := checkKeys(ct.Name, slices.Concat(ct.Properties, udfs[ct.Name]), ct.NavigationProperties); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Generate the decimal() Zod helper shared by every schema. It validates
+// Precision/Scale on the textual value and converts it per -decimal.
+func generateZodDecimalHelper() string {
+	input := "z.number()"
+	if sapgen.TsDecimalAcceptsString(decimalMode, ieee754) {
+		input = "z.union([z.number(), z.string()])"
+	}
+	var b strings.Builder
+	b.WriteString(sapgen.TsFitsDecimalFunc)
+	b.WriteString("\n")
+	b.WriteString("export const decimal = (precision?: number, scale?: number) =>\n")
+	b.WriteString(fmt.Sprintf("  %s\n", input))
+	b.WriteString("    .refine((v) => fitsDecimal(String(v), precision, scale), {\n")
+	b.WriteString("      message: `Expected Edm.Decimal(${precision ?? '*'}, ${scale ?? '*'})`,\n")
+	b.WriteString("    })\n")
+	b.WriteString(fmt.Sprintf("    .transform((v) => %s);\n\n", sapgen.TsDecimalConvert(decimalMode)))
+	return b.String()
+}
+
+// Report whether a property (or collection of) is Edm.Decimal.
+func isDecimalProp(p Property) bool {
+	_, inner := isCollection(p.Type)
+	return extractEdmTypeName(inner) == "Decimal"
+}
+
+// Report whether any entity or complex type has a decimal property.
+func usesDecimal(edmx *EDMX) bool {
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			for _, p := range et.Properties {
+				if isDecimalProp(p) {
+					return true
+				}
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			for _, p := range ct.Properties {
+				if isDecimalProp(p) {
+					return true
+				}
+			}
+		}
+	}
+	for _, props := range udfs {
+		for _, p := range props {
+			if isDecimalProp(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Helper to extract the type name without namespace.
 func extractEdmTypeName(edmType string) string {
 	fullType := strings.TrimPrefix(edmType, "Collection(")
@@ -183,11 +504,13 @@ func getTsType(edmType string) string {
 	innerName := extractEdmTypeName(innerEdm)
 
 	var baseTs string
-	if ts, ok := edmToTs[innerName]; ok {
+	if quirks.YesNo(innerEdm) {
+		baseTs = "boolean"
+	} else if ts, ok := edmToTs[innerName]; ok {
 		baseTs = ts
 	} else if innerName != "" {
 		// Non-primitive: reference the generated friendly type (enum or complex/entity)
-		baseTs = strings.Title(innerName)
+		baseTs = tsName(innerName)
 	} else {
 		baseTs = "unknown"
 		log.Printf("Warning: Unknown TS type for '%s', using unknown", edmType)
@@ -205,12 +528,16 @@ func getZodType(edmType string, isNullable bool, targetSchemaName string) string
 	innerName := extractEdmTypeName(innerEdm)
 
 	var baseZod string
-	if zod, ok := edmToZod[innerName]; ok {
+	if quirks.YesNo(innerEdm) {
+		baseZod = zodYesNo
+	} else if quirks.DateOnly(innerEdm) {
+		baseZod = zodB1Date
+	} else if zod, ok := edmToZod[innerName]; ok {
 		baseZod = zod
 	} else if innerName != "" {
 		// Non-primitive: reference the schema (enum or complex/entity).
 		if targetSchemaName == "" {
-			targetSchemaName = innerName + "Schema"
+			targetSchemaName = tsName(innerName) + "Schema"
 		}
 		baseZod = fmt.Sprintf("z.lazy(() => %s)", targetSchemaName)
 	} else {
@@ -228,45 +555,86 @@ func getZodType(edmType string, isNullable bool, targetSchemaName string) string
 	return baseZod
 }
 
+// Get Zod type string for a property. Edm.Decimal goes through the generated
+// decimal() helper so that Precision/Scale from the metadata are enforced.
+func getZodPropType(typeName string, p Property) string {
+	if m := tsScalar(typeName, p); m != nil {
+		base := m.Zod
+		if base == "" {
+			base = fmt.Sprintf("z.custom<%s>()", m.Type)
+		}
+		if isColl, _ := isCollection(p.Type); isColl {
+			return fmt.Sprintf("z.array(%s).nullish()", base)
+		}
+		return base + ".nullish()"
+	}
+	if !isDecimalProp(p) {
+		return getZodType(p.Type, p.Nullable, "")
+	}
+	base := fmt.Sprintf("decimal(%s)", sapgen.TsDecimalArgs(p.Precision, p.Scale))
+	if isColl, _ := isCollection(p.Type); isColl {
+		return fmt.Sprintf("z.array(%s).nullish()", base)
+	}
+	return base + ".nullish()"
+}
+
 // Generate a TypeScript model type alias (used to break TS inference cycles).
 // We generate NameModel instead of Name to preserve your existing export `type Name = z.infer<...>`
 func generateTsModelType(typ interface{}) string {
-	var name string
+	var name, suffix string
 	var props []Property
 	var navs []NavigationProperty
+	etag := false
 
 	switch t := typ.(type) {
 	case EntityType:
 		name = t.Name
 		props = t.Properties
 		navs = t.NavigationProperties
+		etag = etagKey
 	case ComplexType:
 		name = t.Name
 		props = t.Properties
 		navs = t.NavigationProperties
+	case udfExtension:
+		name = t.Name
+		props = t.Properties
+		suffix = "UDF"
 	}
 
-	tsTypeName := strings.Title(name) + "Model"
+	tsTypeName := tsName(name) + suffix + "Model"
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("export type %s = {\n", tsTypeName))
 
 	// Scalar properties
 	for _, p := range props {
 		tsType := getTsType(p.Type)
+		if m := tsScalar(name, p); m != nil {
+			tsType = m.Type
+			if isColl, _ := isCollection(p.Type); isColl {
+				tsType += "[]"
+			}
+		}
 		// Allow nulls commonly returned by the service: T | null, and property optional
-		b.WriteString(fmt.Sprintf("  %s?: %s | null;\n", p.Name, tsType))
+		b.WriteString(fmt.Sprintf("  %s?: %s | null;\n", tsKey(name, p.Name), tsType))
 	}
 
 	// Navigation properties
 	for _, n := range navs {
 		target := extractEdmTypeName(n.Type)
-		targetTs := strings.Title(target)
+		targetTs := tsName(target)
+		if _, stub := navStubs[target]; stub {
+			targetTs = tsNavStub
+		}
 		if isColl, _ := isCollection(n.Type); isColl {
-			b.WriteString(fmt.Sprintf("  %s?: %s[] | null;\n", n.Name, targetTs))
+			b.WriteString(fmt.Sprintf("  %s?: %s[] | null;\n", tsKey(name, n.Name), targetTs))
 		} else {
-			b.WriteString(fmt.Sprintf("  %s?: %s | null;\n", n.Name, targetTs))
+			b.WriteString(fmt.Sprintf("  %s?: %s | null;\n", tsKey(name, n.Name), targetTs))
 		}
 	}
+	if etag {
+		b.WriteString("  " + tsETagKey + "?: string;\n")
+	}
 
 	b.WriteString("};\n\n")
 	return b.String()
@@ -274,42 +642,38 @@ func generateTsModelType(typ interface{}) string {
 
 // Generate Zod schema for EntityType or ComplexType (as TS code string).
 func generateZodSchema(typ interface{}, isEntity bool, schemaNs string) string {
-	var name string
+	var name, suffix string
 	var props []Property
 	var navs []NavigationProperty
+	etag := false
 
 	switch t := typ.(type) {
 	case EntityType:
 		name = t.Name
 		props = t.Properties
 		navs = t.NavigationProperties
+		etag = etagKey
 	case ComplexType:
 		name = t.Name
 		props = t.Properties
 		navs = t.NavigationProperties
+	case udfExtension:
+		name = t.Name
+		props = t.Properties
+		suffix = "UDF"
 	}
 
-	schemaName := strings.Title(name) + "Schema" // e.g., "ActivitySchema"
-	tsModelName := strings.Title(name) + "Model" // e.g., "ActivityModel"
-	tsTypeName := strings.Title(name)            // e.g., "Activity"
-
-	// Build a set of all property names for conflict checks
-	propNames := make(map[string]struct{}, len(props))
-	for _, p := range props {
-		propNames[p.Name] = struct{}{}
-	}
+	tsTypeName := tsName(name) + suffix // e.g., "Activity"
+	schemaName := tsTypeName + "Schema" // e.g., "ActivitySchema"
+	tsModelName := tsTypeName + "Model" // e.g., "ActivityModel"
 
 	// Collect alias pairs: <alias -> canonical> like Activity -> ActivityProperty
 	type aliasPair struct{ alias, canonical string }
 	var aliases []aliasPair
+	aliasOf := propertyAlias.Aliases(propertyNames(props))
 	for _, p := range props {
-		if strings.HasSuffix(p.Name, "Property") {
-			alias := strings.TrimSuffix(p.Name, "Property")
-			if alias != "" {
-				if _, exists := propNames[alias]; !exists {
-					aliases = append(aliases, aliasPair{alias: alias, canonical: p.Name})
-				}
-			}
+		if alias, ok := aliasOf[p.Name]; ok {
+			aliases = append(aliases, aliasPair{alias: alias, canonical: p.Name})
 		}
 	}
 
@@ -317,27 +681,66 @@ func generateZodSchema(typ interface{}, isEntity bool, schemaNs string) string {
 	var shape strings.Builder
 	// Scalar props
 	for _, p := range props {
-		fieldKey := p.Name
-		zodType := getZodType(p.Type, p.Nullable, "")
+		fieldKey := tsKey(name, p.Name)
+		zodType := getZodPropType(name, p)
 		shape.WriteString(fmt.Sprintf("\t%s: %s,\n", fieldKey, zodType))
 	}
 	// Navigation props
 	for _, n := range navs {
-		fieldKey := n.Name
+		fieldKey := tsKey(name, n.Name)
 		targetName := extractEdmTypeName(n.Type)
-		targetSchema := strings.Title(targetName) + "Schema"
+		targetSchema := tsName(targetName) + "Schema"
+		target := fmt.Sprintf("z.lazy(() => %s)", targetSchema)
+		if _, stub := navStubs[targetName]; stub {
+			target = zodNavStub
+		}
 		isColl, _ := isCollection(n.Type)
 		var zodType string
 		if isColl {
-			zodType = fmt.Sprintf("z.array(z.lazy(() => %s))", targetSchema) + ".nullish()"
+			zodType = fmt.Sprintf("z.array(%s)", target) + ".nullish()"
 		} else {
-			zodType = fmt.Sprintf("z.lazy(() => %s)", targetSchema) + ".nullish()"
+			zodType = target + ".nullish()"
 		}
 		shape.WriteString(fmt.Sprintf("\t%s: %s,\n", fieldKey, zodType))
 	}
+	if etag {
+		shape.WriteString("\t" + tsETagKey + ": z.string().optional(),\n")
+	}
 
 	var out strings.Builder
-	if len(aliases) > 0 {
+	if wireMapping {
+		// Keys differ from the wire names: map them on parse, and back in <Name>ToWire
+		wireNames := tsTypeName + "WireNames"
+		out.WriteString(fmt.Sprintf("export const %s = {\n", wireNames))
+		for _, p := range props {
+			out.WriteString(fmt.Sprintf("\t%s: '%s',\n", tsKey(name, p.Name), propertyAlias.WireName(p.Name, aliasOf)))
+		}
+		for _, n := range navs {
+			out.WriteString(fmt.Sprintf("\t%s: '%s',\n", tsKey(name, n.Name), n.Name))
+		}
+		out.WriteString("} as const;\n\n")
+		out.WriteString(fmt.Sprintf("export const %s: ZodType<%s> = z.preprocess((raw) => {\n", schemaName, tsModelName))
+		out.WriteString("  if (raw && typeof raw === 'object') {\n")
+		out.WriteString("    const v: any = { ...(raw as any) };\n")
+		for _, a := range aliases {
+			// read the name that is not written, too
+			wire, other := propertyAlias.WireName(a.canonical, aliasOf), a.alias
+			if wire == a.alias {
+				other = a.canonical
+			}
+			out.WriteString(fmt.Sprintf("    if (v['%s'] == null && v['%s'] != null) v['%s'] = v['%s'];\n", wire, other, wire, other))
+		}
+		out.WriteString("    const out: any = {};\n")
+		out.WriteString(fmt.Sprintf("    for (const [k, w] of Object.entries(%s)) if (w in v) out[k] = v[w];\n", wireNames))
+		if etag {
+			out.WriteString("    if (" + tsETagKey + " in v) out[" + tsETagKey + "] = v[" + tsETagKey + "];\n")
+		}
+		out.WriteString("    return out;\n")
+		out.WriteString("  }\n")
+		out.WriteString("  return raw;\n")
+		out.WriteString(fmt.Sprintf("}, z.object({\n%s}));\n", shape.String()))
+		out.WriteString(generateToWire(name, tsTypeName, props, navs))
+	} else if len(aliases) > 0 {
 		// Wrap with a preprocessor that copies alias → canonical
 		out.WriteString(fmt.Sprintf("export const %s: ZodType<%s> = z.preprocess((raw) => {\n", schemaName, tsModelName))
 		out.WriteString("  if (raw && typeof raw === 'object') {\n")
@@ -361,23 +764,233 @@ func generateZodSchema(typ interface{}, isEntity bool, schemaNs string) string {
 	return out.String()
 }
 
-// Helper to convert enum member name to SAP B1 JSON string casing (lowercase first letter).
-func toSapJsonEnumValue(memberName string) string {
-	if len(memberName) == 0 {
-		return memberName
+// propertyNames returns the metadata names of props.
+func propertyNames(props []Property) []string {
+	names := make([]string, len(props))
+	for i, p := range props {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Generate <tsTypeName>ToWire, which turns a parsed model of the type name
+// back into a payload with the service's property names (per
+// -property-alias for "...Property" fields), recursing into complex and
+// entity values.
+func generateToWire(name, tsTypeName string, props []Property, navs []NavigationProperty) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\nexport function %sToWire(v: %sModel): Record<string, unknown> {\n", tsTypeName, tsTypeName))
+	b.WriteString("  const out: Record<string, unknown> = {};\n")
+	aliasOf := propertyAlias.Aliases(propertyNames(props))
+	field := func(prop, edmType string) {
+		key := tsKey(name, prop)
+		wire := propertyAlias.WireName(prop, aliasOf)
+		isColl, inner := isCollection(edmType)
+		target := extractEdmTypeName(inner)
+		expr := fmt.Sprintf("v['%s']", key)
+		_, isStruct := structTypes[target]
+		if isEnum, ok := external[target]; ok {
+			isStruct = !isEnum
+		}
+		// convert returns the wire form of the value x
+		var convert func(x string) string
+		if _, stub := navStubs[target]; isStruct && !stub {
+			if isColl {
+				expr = fmt.Sprintf("v['%s'] == null ? null : v['%s'].map(%sToWire)", key, key, tsName(target))
+			} else {
+				expr = fmt.Sprintf("v['%s'] == null ? null : %sToWire(v['%s'])", key, tsName(target), key)
+			}
+		} else if quirks.YesNo(inner) {
+			convert = func(x string) string { return fmt.Sprintf("(%s ? 'tYES' : 'tNO')", x) }
+		} else if quirks.DateOnly(inner) {
+			convert = func(x string) string { return x + ".toISOString().slice(0, 10)" }
+		}
+		if convert != nil {
+			if isColl {
+				expr = fmt.Sprintf("v['%s'] == null ? null : v['%s'].map((x) => %s)", key, key, convert("x"))
+			} else {
+				expr = fmt.Sprintf("v['%s'] == null ? null : %s", key, convert(fmt.Sprintf("v['%s']", key)))
+			}
+		}
+		b.WriteString(fmt.Sprintf("  if (v['%s'] !== undefined) out['%s'] = %s;\n", key, wire, expr))
+	}
+	for _, p := range props {
+		field(p.Name, p.Type)
+	}
+	for _, n := range navs {
+		field(n.Name, n.Type)
+	}
+	b.WriteString("  return out;\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Query field descriptors (<Name>Fields) are generated with -query; they
+// import the odata query runtime as a namespace, so its names never clash
+// with the generated types.
+var queryFields bool
+
+// The $batch builder (batch.ts, with errors.ts) is written with -batch.
+var batchModule bool
+
+// With -etag, entity models keep the @odata.etag of the payload they were
+// parsed from, for If-Match; <Type>ToWire leaves it out.
+var etagKey bool
+
+const tsETagKey = "'@odata.etag'"
+
+const tsQueryImport = "import * as odata from '%s';\n"
+
+// Generate <tsTypeName>Fields, the query descriptor of the type name: a
+// getter per property returning an odata.Field, odata.StringField,
+// odata.CollectionField or the descriptor of a complex or entity type, each
+// addressed by its wire name. The getters keep cycles in the metadata lazy;
+// the empty path addresses the type at the root of a query. extends, when
+// set, is the descriptor class the UDF getters are added to.
+func generateFieldsClass(name, tsTypeName, extends string, props []Property, navs []NavigationProperty) string {
+	var b strings.Builder
+	if extends != "" {
+		b.WriteString(fmt.Sprintf("export class %sFields extends %s {\n", tsTypeName, extends))
+	} else {
+		b.WriteString(fmt.Sprintf("export class %sFields implements odata.Pather {\n", tsTypeName))
+		b.WriteString("  constructor(readonly odataPath: string = '') {}\n")
+	}
+	sep := extends == "" // members are separated by a blank line
+	getter := func(key, wire, tsType string, expr func(path string) string) {
+		if sep {
+			b.WriteString("\n")
+		}
+		sep = true
+		b.WriteString(fmt.Sprintf("  get %s(): %s {\n", key, tsType))
+		b.WriteString(fmt.Sprintf("    return %s;\n", expr(fmt.Sprintf("odata.childPath(this.odataPath, '%s')", wire))))
+		b.WriteString("  }\n")
+	}
+	aliasOf := propertyAlias.Aliases(propertyNames(props))
+	for _, p := range props {
+		tsType, expr, ok := fieldDescriptor(name, p)
+		if !ok {
+			continue
+		}
+		getter(tsKey(name, p.Name), propertyAlias.WireName(p.Name, aliasOf), tsType, expr)
+	}
+	for _, n := range navs {
+		isColl, inner := isCollection(n.Type)
+		target := extractEdmTypeName(inner)
+		if _, stub := navStubs[target]; stub {
+			continue
+		}
+		tsType, expr := structFields(target, isColl)
+		getter(tsKey(name, n.Name), n.Name, tsType, expr)
+	}
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+// Generate the <Name>Fields query descriptor of an entity or complex type.
+func generateTypeFields(typ interface{}) string {
+	switch t := typ.(type) {
+	case EntityType:
+		return generateFieldsClass(t.Name, tsName(t.Name), "", t.Properties, t.NavigationProperties)
+	case ComplexType:
+		return generateFieldsClass(t.Name, tsName(t.Name), "", t.Properties, t.NavigationProperties)
+	}
+	return ""
+}
+
+// structFields returns the descriptor type of a complex or entity value
+// (a collection of them with isColl) and how it is built from its path.
+func structFields(target string, isColl bool) (string, func(path string) string) {
+	fields := tsName(target) + "Fields"
+	if isColl {
+		return fmt.Sprintf("odata.CollectionField<%s>", fields), func(path string) string {
+			return fmt.Sprintf("new odata.CollectionField(%s, (p) => new %s(p))", path, fields)
+		}
+	}
+	return fields, func(path string) string { return fmt.Sprintf("new %s(%s)", fields, path) }
+}
+
+// fieldDescriptor returns the descriptor type of property p of the type
+// typeName and how it is built from its path; ok is false for properties of
+// types that cannot be queried.
+func fieldDescriptor(typeName string, p Property) (tsType string, expr func(path string) string, ok bool) {
+	isColl, inner := isCollection(p.Type)
+	target := extractEdmTypeName(inner)
+	_, isStruct := structTypes[target]
+	if isEnum, ok := external[target]; ok {
+		isStruct = !isEnum
+	}
+	valueType := ""
+	if m := tsScalar(typeName, p); m != nil {
+		valueType = m.Type
+	} else if isStruct {
+		tsType, expr = structFields(target, isColl)
+		return tsType, expr, true
+	} else if _, known := edmToTs[target]; known || !strings.HasPrefix(inner, "Edm.") || quirks.YesNo(inner) {
+		valueType = getTsType(inner)
+	} else {
+		return "", nil, false
+	}
+	tsType = fmt.Sprintf("odata.Field<%s>", valueType)
+	expr = func(path string) string { return fmt.Sprintf("new odata.Field<%s>(%s, '%s')", valueType, path, inner) }
+	if inner == "Edm.String" && valueType == "string" {
+		tsType = "odata.StringField"
+		expr = func(path string) string { return fmt.Sprintf("new odata.StringField(%s)", path) }
+	}
+	if isColl {
+		elemType, elem := tsType, expr
+		tsType = fmt.Sprintf("odata.CollectionField<%s>", elemType)
+		expr = func(path string) string {
+			return fmt.Sprintf("new odata.CollectionField(%s, (p) => %s)", path, elem("p"))
+		}
+	}
+	return tsType, expr, true
+}
+
+// Generate the <Name>UDF model and schema of every type with -udf=extension,
+// plus <Name>WithUDF: the core schema and the extension intersected, which
+// parses both the stable core and the company's user-defined fields.
+func generateUDFExtensions() string {
+	var b strings.Builder
+	for _, name := range sortedKeys(udfs) {
+		ext := udfExtension{Name: name, Properties: udfs[name]}
+		base := tsName(name)
+		b.WriteString(generateTsModelType(ext))
+		b.WriteString(generateZodSchema(ext, false, ""))
+		b.WriteString(fmt.Sprintf("export type %sWithUDF = %sModel & %sUDFModel;\n", base, base, base))
+		b.WriteString(fmt.Sprintf("export const %sWithUDFSchema: ZodType<%sWithUDF> = z.intersection(%sSchema, %sUDFSchema);\n", base, base, base, base))
+		if wireMapping {
+			b.WriteString(fmt.Sprintf("\nexport function %sWithUDFToWire(v: %sWithUDF): Record<string, unknown> {\n", base, base))
+			b.WriteString(fmt.Sprintf("  return { ...%sToWire(v), ...%sUDFToWire(v) };\n", base, base))
+			b.WriteString("}\n")
+		}
+		b.WriteString("\n")
+		if queryFields {
+			b.WriteString(generateFieldsClass(name, base+"WithUDF", base+"Fields", ext.Properties, nil))
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	return strings.ToLower(memberName[0:1]) + memberName[1:]
+	sort.Strings(keys)
+	return keys
 }
 
-// Generate TS enum + Zod schema for EnumType, handling SAP B1 string casing in JSON.
+// Generate TS enum + Zod schema for EnumType, handling SAP B1 string casing
+// in JSON (the enumCasing quirk).
 func generateZodEnum(e EnumType) string {
+	name := tsName(e.Name)
 	var members strings.Builder
-	members.WriteString(fmt.Sprintf("export const %s = {\n", e.Name))
+	members.WriteString(fmt.Sprintf("export const %s = {\n", name))
 
 	currentValue := 0
 	for _, m := range e.Members {
 		var valStr string
-		jsonValue := toSapJsonEnumValue(m.Name)
+		jsonValue := quirks.EnumValue(m.Name)
 		if m.Value != "" {
 			val, err := strconv.Atoi(m.Value)
 			if err != nil {
@@ -396,12 +1009,12 @@ func generateZodEnum(e EnumType) string {
 	members.WriteString("} as const;\n\n")
 
 	// Type from const
-	members.WriteString(fmt.Sprintf("export type %s = typeof %s[keyof typeof %s];\n\n", e.Name, e.Name, e.Name))
+	members.WriteString(fmt.Sprintf("export type %s = typeof %s[keyof typeof %s];\n\n", name, name, name))
 
 	// Zod schema using z.enum with the JSON string values
-	schemaName := e.Name + "Schema"
-	members.WriteString(fmt.Sprintf("export const %s = z.enum(Object.values(%s));\n", schemaName, e.Name))
-	members.WriteString(fmt.Sprintf("export type %sType = z.infer<typeof %s>;\n\n", e.Name, schemaName))
+	schemaName := name + "Schema"
+	members.WriteString(fmt.Sprintf("export const %s = z.enum(Object.values(%s));\n", schemaName, name))
+	members.WriteString(fmt.Sprintf("export type %sType = z.infer<typeof %s>;\n\n", name, schemaName))
 	return members.String()
 }
 
@@ -418,15 +1031,8 @@ func dumpParsedXML(edmx *EDMX, filename string) {
 
 // ---------- Splitting helpers ----------
 
-func ensureDir(dir string) error {
-	return os.MkdirAll(dir, 0755)
-}
-
 func writeFile(path string, content string) error {
-	if err := ensureDir(filepath.Dir(path)); err != nil {
-		return err
-	}
-	return os.WriteFile(path, []byte(content), 0644)
+	return output.WriteFile(path, []byte(content))
 }
 
 func toSortedSlice(set map[string]struct{}) []string {
@@ -451,6 +1057,7 @@ func collectTypeAndEnumDeps(
 	entitySet map[string]struct{},
 	complexSet map[string]struct{},
 	enumSet map[string]struct{},
+	withNavs bool,
 ) (typeDeps map[string]struct{}, enumDeps map[string]struct{}) {
 	typeDeps = map[string]struct{}{}
 	enumDeps = map[string]struct{}{}
@@ -468,6 +1075,8 @@ func collectTypeAndEnumDeps(
 		selfName = t.Name
 		props = t.Properties
 		navs = t.NavigationProperties
+	case udfExtension:
+		props = t.Properties
 	}
 
 	addType := func(name string) {
@@ -497,101 +1106,577 @@ func collectTypeAndEnumDeps(
 	// scalar properties
 	for _, p := range props {
 		_, inner := isCollection(p.Type)
+		if quirks.YesNo(inner) {
+			continue // a boolean in the model
+		}
 		innerName := extractEdmTypeName(inner)
 		addType(innerName)
 	}
 
 	// navigation properties always point to entity or collection of entity
+	if !withNavs {
+		return
+	}
 	for _, n := range navs {
 		_, inner := isCollection(n.Type)
 		innerName := extractEdmTypeName(inner)
+		if _, stub := navStubs[innerName]; stub {
+			continue
+		}
 		addType(innerName)
 	}
 
 	return
 }
 
-func renderPerTypeFile(
-	typ interface{},
-	isEntity bool,
-	entitySet map[string]struct{},
-	complexSet map[string]struct{},
-	enumSet map[string]struct{},
-	generatedAt string,
-) (fileName string, content string) {
-	var name string
-	switch t := typ.(type) {
-	case EntityType:
-		name = t.Name
-	case ComplexType:
-		name = t.Name
-	}
-	titleName := strings.Title(name)
-	fileName = titleName + ".ts"
-
-	// Collect dependencies
-	typeDeps, enumDeps := collectTypeAndEnumDeps(typ, entitySet, complexSet, enumSet)
-	typeDepNames := toSortedSlice(typeDeps)
-	enumDepNames := toSortedSlice(enumDeps)
-
-	// Build imports
-	var b strings.Builder
+// ---------- Type selection (-include / -exclude) ----------
 
-	b.WriteString("// Generated from OData EDMX for SAP Business One Service Layer v2\n")
-	b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-	b.WriteString(fmt.Sprintf("// Generated at %s\n\n", generatedAt))
-	b.WriteString("import { z, ZodType } from 'zod';\n")
+type selNode struct {
+	ns, name string
+	typ      interface{} // EntityType, ComplexType or nil for enums
+	base     string
+	sets     []string
+}
 
-	// Enums: import type + schema in one module
-	if len(enumDepNames) > 0 {
-		// type imports
-		b.WriteString(fmt.Sprintf("import type { %s } from '../enums';\n",
-			strings.Join(enumDepNames, ", ")))
-		// value imports (schemas)
-		enumSchemas := make([]string, 0, len(enumDepNames))
-		for _, e := range enumDepNames {
-			enumSchemas = append(enumSchemas, e+"Schema")
-		}
-		b.WriteString(fmt.Sprintf("import { %s } from '../enums';\n", strings.Join(enumSchemas, ", ")))
+// SelectTypes computes the types to generate for sel: every type matching
+// the include patterns (all when there are none) and not excluded, plus
+// what those need through properties and base types, and through navigation
+// properties unless sel.StubNavigation is set. Excluded navigation targets
+// are stubbed; an excluded type required by a property or as a base type is
+// an error.
+func SelectTypes(edmx *EDMX, sel sapgen.Selection) (sapgen.TypeSet, error) {
+	m, err := sel.Compile()
+	if err != nil {
+		return nil, err
 	}
+	// stubs of an earlier Generate must not hide navigation targets here
+	defer func(stubs map[string]struct{}) { navStubs = stubs }(navStubs)
+	navStubs = nil
 
-	// Type deps: import type and schema from correct folders
+	enumSet := map[string]struct{}{}
+	entitySet := map[string]struct{}{}
+	complexSet := map[string]struct{}{}
+	aliases := map[string]string{}
+	byName := map[string][]string{} // local name -> qualified names
+	nodes := map[string]*selNode{}
+	var order []string
+
+	add := func(n *selNode) {
+		qn := n.ns + "." + n.name
+		nodes[qn] = n
+		byName[n.name] = append(byName[n.name], qn)
+		order = append(order, qn)
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		if schema.Alias != "" {
+			aliases[schema.Alias] = schema.Namespace
+		}
+		for _, e := range schema.EnumTypes {
+			enumSet[e.Name] = struct{}{}
+			add(&selNode{ns: schema.Namespace, name: e.Name})
+		}
+		for _, et := range schema.EntityTypes {
+			entitySet[et.Name] = struct{}{}
+			add(&selNode{ns: schema.Namespace, name: et.Name, typ: et, base: et.Base})
+		}
+		for _, ct := range schema.ComplexTypes {
+			complexSet[ct.Name] = struct{}{}
+			add(&selNode{ns: schema.Namespace, name: ct.Name, typ: ct, base: ct.Base})
+		}
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, c := range schema.EntityContainers {
+			for _, es := range c.EntitySets {
+				ns, name := splitQualified(es.EntityType)
+				if full, ok := aliases[ns]; ok {
+					ns = full
+				}
+				if n, ok := nodes[ns+"."+name]; ok {
+					n.sets = append(n.sets, es.Name)
+				}
+			}
+		}
+	}
+
+	excluded := func(qn string) bool {
+		n := nodes[qn]
+		return m.Excluded(n.ns, n.name, n.sets)
+	}
+
+	keep := sapgen.TypeSet{}
+	var queue []string
+	push := func(qn string) {
+		if !keep[qn] {
+			keep[qn] = true
+			queue = append(queue, qn)
+		}
+	}
+	for _, qn := range order {
+		n := nodes[qn]
+		if m.Included(n.ns, n.name, n.sets) && !excluded(qn) {
+			push(qn)
+		}
+	}
+
+	for len(queue) > 0 {
+		qn := queue[0]
+		queue = queue[1:]
+		n := nodes[qn]
+		if n.typ == nil {
+			continue
+		}
+		typeDeps, enumDeps := collectTypeAndEnumDeps(n.typ, entitySet, complexSet, enumSet, false)
+		required := toSortedSlice(typeDeps)
+		required = append(required, toSortedSlice(enumDeps)...)
+		if n.base != "" {
+			required = append(required, extractEdmTypeName(n.base))
+		}
+		for _, dep := range required {
+			for _, dq := range byName[dep] {
+				if excluded(dq) {
+					return nil, fmt.Errorf("%s is excluded but required by %s", dq, qn)
+				}
+				push(dq)
+			}
+		}
+		if sel.StubNavigation {
+			continue
+		}
+		navDeps, _ := collectTypeAndEnumDeps(n.typ, entitySet, complexSet, enumSet, true)
+		for _, dep := range toSortedSlice(navDeps) {
+			for _, dq := range byName[dep] {
+				if !excluded(dq) {
+					push(dq)
+				}
+			}
+		}
+	}
+
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("selection matches no types")
+	}
+	return keep, nil
+}
+
+// ---------- Standard and user objects (-objects) ----------
+
+// udoSystemFields are the properties the Service Layer adds to every UDO.
+var udoSystemFields = makeSet([]string{
+	"Code", "Name", "DocEntry", "DocNum", "Period", "Instance", "Series",
+	"Handwrtten", "Canceled", "Object", "LogInst", "UserSign", "Transfered",
+	"Status", "CreateDate", "CreateTime", "UpdateDate", "UpdateTime",
+	"DataSource", "Remark", "RequestStatus", "Creator",
+})
+
+// isUDO reports whether an entity type exposed by an entity set of the same
+// name (the UDO code) looks like a UDO: it has the Object and LogInst
+// system fields, or every other property is a user-defined field. Collection
+// properties are the UDO's child tables.
+func isUDO(et EntityType) bool {
+	hasObject, hasLogInst, udfs := false, false, 0
+	for _, p := range et.Properties {
+		switch {
+		case p.Name == "Object":
+			hasObject = true
+		case p.Name == "LogInst":
+			hasLogInst = true
+		case sapgen.IsUDF(p.Name):
+			udfs++
+			continue
+		case strings.HasPrefix(p.Type, "Collection("):
+			continue
+		}
+		if _, ok := udoSystemFields[p.Name]; !ok {
+			return false
+		}
+	}
+	return hasObject && hasLogInst || udfs > 0
+}
+
+// UserTypes returns the user-defined tables and objects of edmx: entity
+// types named U_* or exposed by a U_* entity set (UDTs), entity types that
+// isUDO recognises, types matching patterns, and the complex and enum types
+// only those use (UDO child tables). Navigation properties are not followed.
+func UserTypes(edmx *EDMX, patterns []string) (sapgen.TypeSet, error) {
+	m, err := sapgen.Selection{Include: patterns}.Compile()
+	if err != nil {
+		return nil, err
+	}
+	defer func(stubs map[string]struct{}) { navStubs = stubs }(navStubs)
+	navStubs = nil
+
+	enumSet := map[string]struct{}{}
+	entitySet := map[string]struct{}{}
+	complexSet := map[string]struct{}{}
+	byName := map[string][]string{}
+	nodes := map[string]*selNode{}
+	var order []string
+	aliases := map[string]string{}
+	entities := map[string]EntityType{}
+	for _, schema := range edmx.DataServices.Schemas {
+		if schema.Alias != "" {
+			aliases[schema.Alias] = schema.Namespace
+		}
+		add := func(n *selNode) {
+			qn := n.ns + "." + n.name
+			nodes[qn] = n
+			byName[n.name] = append(byName[n.name], qn)
+			order = append(order, qn)
+		}
+		for _, e := range schema.EnumTypes {
+			enumSet[e.Name] = struct{}{}
+			add(&selNode{ns: schema.Namespace, name: e.Name})
+		}
+		for _, et := range schema.EntityTypes {
+			entitySet[et.Name] = struct{}{}
+			entities[schema.Namespace+"."+et.Name] = et
+			add(&selNode{ns: schema.Namespace, name: et.Name, typ: et, base: et.Base})
+		}
+		for _, ct := range schema.ComplexTypes {
+			complexSet[ct.Name] = struct{}{}
+			add(&selNode{ns: schema.Namespace, name: ct.Name, typ: ct, base: ct.Base})
+		}
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, c := range schema.EntityContainers {
+			for _, es := range c.EntitySets {
+				ns, name := splitQualified(es.EntityType)
+				if full, ok := aliases[ns]; ok {
+					ns = full
+				}
+				if n, ok := nodes[ns+"."+name]; ok {
+					n.sets = append(n.sets, es.Name)
+				}
+			}
+		}
+	}
+
+	seed := func(n *selNode) bool {
+		if len(patterns) > 0 && m.Included(n.ns, n.name, n.sets) {
+			return true
+		}
+		if n.typ == nil {
+			return false
+		}
+		if _, ok := n.typ.(EntityType); !ok {
+			return false
+		}
+		if sapgen.IsUDF(n.name) {
+			return true
+		}
+		for _, set := range n.sets {
+			if sapgen.IsUDF(set) || set == n.name && isUDO(entities[n.ns+"."+n.name]) {
+				return true
+			}
+		}
+		return false
+	}
+	// closure walks properties and base types from the given types
+	closure := func(from []string) sapgen.TypeSet {
+		reached := sapgen.TypeSet{}
+		queue := append([]string(nil), from...)
+		for _, qn := range from {
+			reached[qn] = true
+		}
+		for len(queue) > 0 {
+			n := nodes[queue[0]]
+			queue = queue[1:]
+			if n.typ == nil {
+				continue
+			}
+			typeDeps, enumDeps := collectTypeAndEnumDeps(n.typ, entitySet, complexSet, enumSet, false)
+			deps := append(toSortedSlice(typeDeps), toSortedSlice(enumDeps)...)
+			if n.base != "" {
+				deps = append(deps, extractEdmTypeName(n.base))
+			}
+			for _, dep := range deps {
+				for _, dq := range byName[dep] {
+					if !reached[dq] {
+						reached[dq] = true
+						queue = append(queue, dq)
+					}
+				}
+			}
+		}
+		return reached
+	}
+
+	var seeds []string
+	for _, qn := range order {
+		if seed(nodes[qn]) {
+			seeds = append(seeds, qn)
+		}
+	}
+	// standard roots: the other entity types, and complex and enum types the
+	// user objects do not reach
+	userUse := closure(seeds)
+	var standard []string
+	for _, qn := range order {
+		n := nodes[qn]
+		if _, ok := n.typ.(EntityType); ok && !slices.Contains(seeds, qn) || !userUse[qn] {
+			standard = append(standard, qn)
+		}
+	}
+	standardUse := closure(standard)
+	user := sapgen.TypeSet{}
+	for qn := range userUse {
+		if !standardUse[qn] {
+			user[qn] = true
+		}
+	}
+	for _, qn := range seeds {
+		if !user[qn] {
+			return nil, fmt.Errorf("user object %s is required by a standard type", qn)
+		}
+	}
+	return user, nil
+}
+
+// ObjectTypes narrows types (nil: all types) to the side of the
+// standard/user split that uo selects.
+func ObjectTypes(edmx *EDMX, uo sapgen.UserObjects, types sapgen.TypeSet) (sapgen.TypeSet, error) {
+	if uo.Side() == sapgen.ObjectsAll {
+		return types, nil
+	}
+	user, err := UserTypes(edmx, uo.UserPatterns)
+	if err != nil {
+		return nil, err
+	}
+	out := sapgen.TypeSet{}
+	for _, schema := range edmx.DataServices.Schemas {
+		var names []string
+		for _, e := range schema.EnumTypes {
+			names = append(names, e.Name)
+		}
+		for _, et := range schema.EntityTypes {
+			names = append(names, et.Name)
+		}
+		for _, ct := range schema.ComplexTypes {
+			names = append(names, ct.Name)
+		}
+		for _, name := range names {
+			qn := schema.Namespace + "." + name
+			if types.Has(schema.Namespace, name) && user[qn] == (uo.Side() == sapgen.ObjectsUser) {
+				out[qn] = true
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no %s objects to generate", uo.Side())
+	}
+	return out, nil
+}
+
+func splitQualified(qn string) (ns, name string) {
+	if i := strings.LastIndex(qn, "."); i >= 0 {
+		return qn[:i], qn[i+1:]
+	}
+	return "", qn
+}
+
+// FilterEDMX returns a copy of edmx that only holds the given types; entity
+// sets of dropped entity types are removed as well. A nil set keeps all.
+func FilterEDMX(edmx *EDMX, types sapgen.TypeSet) *EDMX {
+	if types == nil {
+		return edmx
+	}
+	out := *edmx
+	out.DataServices.Schemas = nil
+	aliases := map[string]string{}
+	for _, schema := range edmx.DataServices.Schemas {
+		if schema.Alias != "" {
+			aliases[schema.Alias] = schema.Namespace
+		}
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		s := schema
+		s.EnumTypes, s.EntityTypes, s.ComplexTypes, s.EntityContainers = nil, nil, nil, nil
+		for _, e := range schema.EnumTypes {
+			if types.Has(schema.Namespace, e.Name) {
+				s.EnumTypes = append(s.EnumTypes, e)
+			}
+		}
+		for _, et := range schema.EntityTypes {
+			if types.Has(schema.Namespace, et.Name) {
+				s.EntityTypes = append(s.EntityTypes, et)
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			if types.Has(schema.Namespace, ct.Name) {
+				s.ComplexTypes = append(s.ComplexTypes, ct)
+			}
+		}
+		for _, c := range schema.EntityContainers {
+			sets := c.EntitySets
+			c.EntitySets = nil
+			for _, es := range sets {
+				ns, name := splitQualified(es.EntityType)
+				if full, ok := aliases[ns]; ok {
+					ns = full
+				}
+				if types.Has(ns, name) {
+					c.EntitySets = append(c.EntitySets, es)
+				}
+			}
+			s.EntityContainers = append(s.EntityContainers, c)
+		}
+		out.DataServices.Schemas = append(out.DataServices.Schemas, s)
+	}
+	return &out
+}
+
+// collectNavStubs returns the navigation targets that are not generated.
+func collectNavStubs(edmx *EDMX) map[string]struct{} {
+	known := map[string]struct{}{}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			known[et.Name] = struct{}{}
+		}
+		for _, ct := range schema.ComplexTypes {
+			known[ct.Name] = struct{}{}
+		}
+	}
+	stubs := map[string]struct{}{}
+	check := func(navs []NavigationProperty) {
+		for _, n := range navs {
+			target := extractEdmTypeName(n.Type)
+			_, ok := known[target]
+			if _, ext := external[target]; !ok && !ext {
+				stubs[target] = struct{}{}
+			}
+		}
+	}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			check(et.NavigationProperties)
+		}
+		for _, ct := range schema.ComplexTypes {
+			check(ct.NavigationProperties)
+		}
+	}
+	return stubs
+}
+
+func renderPerTypeFile(
+	typ interface{},
+	isEntity bool,
+	entitySet map[string]struct{},
+	complexSet map[string]struct{},
+	enumSet map[string]struct{},
+) (fileName string, content string) {
+	var name string
+	switch t := typ.(type) {
+	case EntityType:
+		name = t.Name
+	case ComplexType:
+		name = t.Name
+	}
+	titleName := tsName(name)
+	fileName = titleName + ".ts"
+
+	// Collect dependencies
+	typeDeps, enumDeps := collectTypeAndEnumDeps(typ, entitySet, complexSet, enumSet, true)
+	hasDecimal := false
+	switch t := typ.(type) {
+	case EntityType:
+		for _, p := range t.Properties {
+			hasDecimal = hasDecimal || (isDecimalProp(p) && tsScalar(name, p) == nil)
+		}
+	case ComplexType:
+		for _, p := range t.Properties {
+			hasDecimal = hasDecimal || (isDecimalProp(p) && tsScalar(name, p) == nil)
+		}
+	}
+	userImports := sapgen.TsImports{}
+	scalarImports(typ, userImports)
+	typeDepNames := toSortedSlice(typeDeps)
+	enumDepNames := toSortedSlice(enumDeps)
+	for i, e := range enumDepNames {
+		enumDepNames[i] = tsName(e)
+	}
+
+	// Build imports
+	var b strings.Builder
+
+	b.WriteString("// Generated from OData EDMX for SAP Business One Service Layer v2\n")
+	b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
+	b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+	b.WriteString("import { z, ZodType } from 'zod';\n")
+	if hasDecimal {
+		b.WriteString(sapgen.TsDecimalTypeImport(decimalMode))
+		b.WriteString("import { decimal } from '../decimal';\n")
+	}
+	b.WriteString(userImports.Render("'"))
+	if queryFields {
+		b.WriteString(fmt.Sprintf(tsQueryImport, "../query"))
+	}
+
+	// Enums: import type + schema in one module
+	if len(enumDepNames) > 0 {
+		// type imports
+		b.WriteString(fmt.Sprintf("import type { %s } from '../enums';\n",
+			strings.Join(enumDepNames, ", ")))
+		// value imports (schemas)
+		enumSchemas := make([]string, 0, len(enumDepNames))
+		for _, e := range enumDepNames {
+			enumSchemas = append(enumSchemas, e+"Schema")
+		}
+		b.WriteString(fmt.Sprintf("import { %s } from '../enums';\n", strings.Join(enumSchemas, ", ")))
+	}
+
+	// Type deps: import type and schema from correct folders
 	for _, dep := range typeDepNames {
+		if _, ok := external[dep]; ok {
+			continue
+		}
 		depPath := ""
 		// decide folder and relative path
 		if _, ok := entitySet[dep]; ok {
 			if isEntity {
-				depPath = "./" + strings.Title(dep)
+				depPath = "./" + tsName(dep)
 			} else {
-				depPath = "../entities/" + strings.Title(dep)
+				depPath = "../entities/" + tsName(dep)
 			}
 		} else if _, ok := complexSet[dep]; ok {
 			if isEntity {
-				depPath = "../complex/" + strings.Title(dep)
+				depPath = "../complex/" + tsName(dep)
 			} else {
-				depPath = "./" + strings.Title(dep)
+				depPath = "./" + tsName(dep)
 			}
 		} else {
 			// fallback assume entity in sibling (better than nothing)
 			if isEntity {
-				depPath = "./" + strings.Title(dep)
+				depPath = "./" + tsName(dep)
 			} else {
-				depPath = "./" + strings.Title(dep)
+				depPath = "./" + tsName(dep)
 			}
 		}
 		// type-only import for friendly type
-		b.WriteString(fmt.Sprintf("import type { %s } from '%s';\n", strings.Title(dep), depPath))
-		// schema import
-		b.WriteString(fmt.Sprintf("import { %sSchema } from '%s';\n", strings.Title(dep), depPath))
+		b.WriteString(fmt.Sprintf("import type { %s } from '%s';\n", tsName(dep), depPath))
+		// schema import (and the wire mapper when keys are renamed, the
+		// query descriptor with -query)
+		values := []string{tsName(dep) + "Schema"}
+		if wireMapping {
+			values = append(values, tsName(dep)+"ToWire")
+		}
+		if queryFields {
+			values = append(values, tsName(dep)+"Fields")
+		}
+		b.WriteString(fmt.Sprintf("import { %s } from '%s';\n", strings.Join(values, ", "), depPath))
 	}
 
-	if len(enumDepNames) > 0 || len(typeDepNames) > 0 {
+	b.WriteString(externalImports(typeDepNames, standardImport(1)))
+
+	if hasDecimal || len(userImports) > 0 || queryFields || len(enumDepNames) > 0 || len(typeDepNames) > 0 {
 		b.WriteString("\n")
 	}
 
 	// Model + Schema
 	b.WriteString(generateTsModelType(typ))
 	b.WriteString(generateZodSchema(typ, isEntity, ""))
+	if queryFields {
+		b.WriteString(generateTypeFields(typ))
+	}
 
 	content = b.String()
 	return
@@ -601,7 +1686,11 @@ func writePerTypeOutputs(
 	edmx *EDMX,
 	outDir string,
 ) error {
-	generatedAt := time.Now().Format(time.RFC3339)
+	// the files written are recorded in outDir's manifest, so that files of
+	// types gone from the metadata are removed next time
+	manifest := sapgen.NewManifestWriter(output, outDir)
+	defer func(w sapgen.Writer) { output = w }(output)
+	output = manifest
 
 	// Index sets for quick lookups
 	enumSet := map[string]struct{}{}
@@ -633,7 +1722,7 @@ func writePerTypeOutputs(
 		var b strings.Builder
 		b.WriteString("// Generated enums from OData EDMX for SAP Business One Service Layer v2\n")
 		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-		b.WriteString(fmt.Sprintf("// Generated at %s\n\n", generatedAt))
+		b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
 		b.WriteString("import { z } from 'zod';\n\n")
 
 		for _, en := range allEnums {
@@ -644,39 +1733,67 @@ func writePerTypeOutputs(
 		if err := writeFile(enumsPath, b.String()); err != nil {
 			return fmt.Errorf("writing enums.ts: %w", err)
 		}
-		log.Printf("Wrote %s", enumsPath)
+	}
+
+	// 1b) Write decimal.ts (decimal() helper used by entity/complex schemas)
+	if usesDecimal(edmx) {
+		var b strings.Builder
+		b.WriteString("// Generated Edm.Decimal helper for SAP Business One Service Layer v2\n")
+		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
+		b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+		b.WriteString("import { z } from 'zod';\n")
+		b.WriteString(sapgen.TsDecimalImport(decimalMode))
+		b.WriteString("\n")
+		b.WriteString(generateZodDecimalHelper())
+
+		decimalPath := filepath.Join(outDir, "decimal.ts")
+		if err := writeFile(decimalPath, b.String()); err != nil {
+			return fmt.Errorf("writing decimal.ts: %w", err)
+		}
+	}
+
+	// 1c) Write query.ts (the runtime of the <Type>Fields query descriptors)
+	// and batch.ts with errors.ts
+	if queryFields {
+		if err := writeQueryModule(outDir); err != nil {
+			return err
+		}
+	}
+	if batchModule {
+		if err := writeBatchModule(outDir); err != nil {
+			return err
+		}
 	}
 
 	// 2) Write per-entity files
 	entityDir := filepath.Join(outDir, "entities")
-	if err := ensureDir(entityDir); err != nil {
-		return err
-	}
 	entityNames := make([]string, 0, len(allEntities))
 	for _, et := range allEntities {
-		fileName, content := renderPerTypeFile(et, true, entitySet, complexSet, enumSet, generatedAt)
+		fileName, content := renderPerTypeFile(et, true, entitySet, complexSet, enumSet)
 		target := filepath.Join(entityDir, fileName)
 		if err := writeFile(target, content); err != nil {
 			return fmt.Errorf("writing entity file %s: %w", target, err)
 		}
 		entityNames = append(entityNames, strings.TrimSuffix(fileName, ".ts"))
-		log.Printf("Wrote %s", target)
 	}
 
 	// 3) Write per-complex files
 	complexDir := filepath.Join(outDir, "complex")
-	if err := ensureDir(complexDir); err != nil {
-		return err
-	}
 	complexNames := make([]string, 0, len(allComplexes))
 	for _, ct := range allComplexes {
-		fileName, content := renderPerTypeFile(ct, false, entitySet, complexSet, enumSet, generatedAt)
+		fileName, content := renderPerTypeFile(ct, false, entitySet, complexSet, enumSet)
 		target := filepath.Join(complexDir, fileName)
 		if err := writeFile(target, content); err != nil {
 			return fmt.Errorf("writing complex file %s: %w", target, err)
 		}
 		complexNames = append(complexNames, strings.TrimSuffix(fileName, ".ts"))
-		log.Printf("Wrote %s", target)
+	}
+
+	// 3b) Write udf.ts (UDF extensions; the only file that differs between companies)
+	if udfs != nil {
+		if err := writeUDFFile(outDir, entitySet, complexSet, enumSet); err != nil {
+			return err
+		}
 	}
 
 	// 4) Barrel files
@@ -685,6 +1802,7 @@ func writePerTypeOutputs(
 		sort.Strings(entityNames)
 		var b strings.Builder
 		b.WriteString("// Barrel file for entity schemas\n")
+		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
 		for _, n := range entityNames {
 			b.WriteString(fmt.Sprintf("export * from './%s';\n", n))
 		}
@@ -697,6 +1815,7 @@ func writePerTypeOutputs(
 		sort.Strings(complexNames)
 		var b strings.Builder
 		b.WriteString("// Barrel file for complex schemas\n")
+		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
 		for _, n := range complexNames {
 			b.WriteString(fmt.Sprintf("export * from './%s';\n", n))
 		}
@@ -708,124 +1827,544 @@ func writePerTypeOutputs(
 	{
 		var b strings.Builder
 		b.WriteString("// Root barrel file\n")
+		b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
 		b.WriteString("export * from './enums';\n")
+		if usesDecimal(edmx) {
+			b.WriteString("export * from './decimal';\n")
+		}
 		b.WriteString("export * from './entities';\n")
 		b.WriteString("export * from './complex';\n")
+		if udfs != nil {
+			b.WriteString("export * from './udf';\n")
+		}
+		if queryFields {
+			b.WriteString("export * as odata from './query';\n")
+		}
+		if batchModule {
+			b.WriteString("export * from './batch';\n")
+			b.WriteString("export * from './errors';\n")
+		}
 		if err := writeFile(filepath.Join(outDir, "index.ts"), b.String()); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return manifest.Finish()
 }
 
-// ---------- Main (supports single file or per-type split) ----------
-
-func main2() {
-	inputFile := flag.String("input", "", "Path to the EDMX XML file")
-	outputFile := flag.String("output", "types.ts", "Path to the output TS file for -split=single")
-	outDir := flag.String("outDir", "types", "Directory to write TS files for -split=perType")
-	splitMode := flag.String("split", "perType", "Output mode: single | perType")
-	dumpParsed := flag.Bool("dump", false, "Dump parsed XML structure to debug.xml")
-	flag.Parse()
-
-	if *inputFile == "" {
-		log.Fatal("Please provide -input flag with the XML file path")
+// Write udf.ts with the UDF extensions of -split=perType. It imports the
+// core models and schemas it extends from the entities and complex folders.
+func writeUDFFile(
+	outDir string,
+	entitySet, complexSet, enumSet map[string]struct{},
+) error {
+	typeDeps := map[string]struct{}{}
+	enumDeps := map[string]struct{}{}
+	userImports := sapgen.TsImports{}
+	hasDecimal := false
+	for name, props := range udfs {
+		ext := udfExtension{Name: name, Properties: props}
+		types, enums := collectTypeAndEnumDeps(ext, entitySet, complexSet, enumSet, false)
+		for t := range types {
+			typeDeps[t] = struct{}{}
+		}
+		for e := range enums {
+			enumDeps[e] = struct{}{}
+		}
+		scalarImports(ext, userImports)
+		for _, p := range props {
+			hasDecimal = hasDecimal || (isDecimalProp(p) && tsScalar(name, p) == nil)
+		}
 	}
 
-	data, err := ioutil.ReadFile(*inputFile)
-	if err != nil {
-		log.Fatalf("Error reading XML file: %v", err)
+	var b strings.Builder
+	b.WriteString("// Generated user-defined field extensions from OData EDMX for SAP Business One Service Layer v2\n")
+	b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
+	b.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+	b.WriteString("import { z, ZodType } from 'zod';\n")
+	if hasDecimal {
+		b.WriteString(sapgen.TsDecimalTypeImport(decimalMode))
+		b.WriteString("import { decimal } from './decimal';\n")
 	}
-
-	var edmx EDMX
-	if err := xml.Unmarshal(data, &edmx); err != nil {
-		log.Fatalf("Error unmarshaling XML: %v", err)
+	b.WriteString(userImports.Render("'"))
+	if queryFields {
+		b.WriteString(fmt.Sprintf(tsQueryImport, "./query"))
 	}
+	if len(enumDeps) > 0 {
+		enumNames := toSortedSlice(enumDeps)
+		enumSchemas := make([]string, len(enumNames))
+		for i, e := range enumNames {
+			enumNames[i] = tsName(e)
+			enumSchemas[i] = tsName(e) + "Schema"
+		}
+		b.WriteString(fmt.Sprintf("import type { %s } from './enums';\n", strings.Join(enumNames, ", ")))
+		b.WriteString(fmt.Sprintf("import { %s } from './enums';\n", strings.Join(enumSchemas, ", ")))
+	}
+	// the extended types need their model and schema, property types their
+	// type and schema; both need the wire mapper when keys are renamed
+	imported := map[string]struct{}{}
+	for name := range udfs {
+		imported[name] = struct{}{}
+	}
+	for name := range typeDeps {
+		imported[name] = struct{}{}
+	}
+	for _, name := range toSortedSlice(imported) {
+		if _, ok := external[name]; ok {
+			continue
+		}
+		folder := "complex"
+		if _, ok := entitySet[name]; ok {
+			folder = "entities"
+		}
+		var typeNames, values []string
+		if _, ok := typeDeps[name]; ok {
+			typeNames = append(typeNames, tsName(name))
+		}
+		if _, ok := udfs[name]; ok {
+			typeNames = append(typeNames, tsName(name)+"Model")
+		}
+		values = append(values, tsName(name)+"Schema")
+		if wireMapping {
+			values = append(values, tsName(name)+"ToWire")
+		}
+		if queryFields {
+			values = append(values, tsName(name)+"Fields")
+		}
+		path := fmt.Sprintf("./%s/%s", folder, tsName(name))
+		b.WriteString(fmt.Sprintf("import type { %s } from '%s';\n", strings.Join(typeNames, ", "), path))
+		b.WriteString(fmt.Sprintf("import { %s } from '%s';\n", strings.Join(values, ", "), path))
+	}
+	b.WriteString(externalImports(toSortedSlice(typeDeps), standardImport(0)))
+	b.WriteString("\n")
+	b.WriteString(generateUDFExtensions())
 
-	log.Printf("Parsed EDMX Version: %s", edmx.Version)
-	log.Printf("Parsed %d schemas", len(edmx.DataServices.Schemas))
+	udfPath := filepath.Join(outDir, "udf.ts")
+	if err := writeFile(udfPath, b.String()); err != nil {
+		return fmt.Errorf("writing udf.ts: %w", err)
+	}
+	return nil
+}
 
-	if *dumpParsed {
-		dumpParsedXML(&edmx, "debug.xml")
+// Write all enums, model types and schemas into a single TS file.
+func writeSingleFile(edmx *EDMX, outputFile string) error {
+	var output strings.Builder
+	output.WriteString("// Generated Zod schemas from OData EDMX for SAP Business One Service Layer v2\n")
+	output.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
+	output.WriteString(sapgen.HashHeader(sourceHash) + "\n")
+
+	// TS imports
+	output.WriteString("import { z, ZodType } from 'zod';\n")
+	hasDecimal := usesDecimal(edmx)
+	if hasDecimal {
+		output.WriteString(sapgen.TsDecimalImport(decimalMode))
+	}
+	userImports := sapgen.TsImports{}
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			scalarImports(et, userImports)
+		}
+		for _, ct := range schema.ComplexTypes {
+			scalarImports(ct, userImports)
+		}
+	}
+	for name, props := range udfs {
+		scalarImports(udfExtension{Name: name, Properties: props}, userImports)
+	}
+	output.WriteString(userImports.Render("'"))
+	if queryFields {
+		output.WriteString(fmt.Sprintf(tsQueryImport, "./query"))
+	}
+	if external != nil {
+		deps := map[string]struct{}{}
+		for _, schema := range edmx.DataServices.Schemas {
+			for _, et := range schema.EntityTypes {
+				typeDeps, _ := collectTypeAndEnumDeps(et, nil, nil, nil, true)
+				for d := range typeDeps {
+					deps[d] = struct{}{}
+				}
+			}
+			for _, ct := range schema.ComplexTypes {
+				typeDeps, _ := collectTypeAndEnumDeps(ct, nil, nil, nil, true)
+				for d := range typeDeps {
+					deps[d] = struct{}{}
+				}
+			}
+		}
+		output.WriteString(externalImports(toSortedSlice(deps), standardImport(0)))
+	}
+	output.WriteString("\n")
+	if hasDecimal {
+		output.WriteString(generateZodDecimalHelper())
 	}
 
-	switch *splitMode {
-	case "single":
-		var output strings.Builder
-		output.WriteString("// Generated Zod schemas from OData EDMX for SAP Business One Service Layer v2\n")
-		output.WriteString("// DO NOT EDIT - Regenerate from metadata.\n")
-		output.WriteString(fmt.Sprintf("// Generated at %s\n\n", time.Now().Format(time.RFC3339)))
+	// First, collect and generate ALL enums from ALL schemas to minimize forward refs
+	allEnums := make(map[string]string)
+	for i, schema := range edmx.DataServices.Schemas {
+		for _, en := range schema.EnumTypes {
+			enumCode := generateZodEnum(en)
+			allEnums[en.Name] = enumCode
+			log.Printf("  Pre-generated EnumType Schema: %s from schema %d", en.Name, i+1)
+		}
+	}
 
-		// TS imports
-		output.WriteString("import { z, ZodType } from 'zod';\n\n")
+	// Output all enums first, by name so that the output is stable
+	enumNames := make([]string, 0, len(allEnums))
+	for name := range allEnums {
+		enumNames = append(enumNames, name)
+	}
+	sort.Strings(enumNames)
+	for _, name := range enumNames {
+		output.WriteString(allEnums[name])
+	}
 
-		// First, collect and generate ALL enums from ALL schemas to minimize forward refs
-		allEnums := make(map[string]string)
-		for i, schema := range edmx.DataServices.Schemas {
-			for _, en := range schema.EnumTypes {
-				enumCode := generateZodEnum(en)
-				allEnums[en.Name] = enumCode
-				log.Printf("  Pre-generated EnumType Schema: %s from schema %d", en.Name, i+1)
-			}
+	// Generate TS model types (NameModel) for all entities/complex first
+	var allModelTypes strings.Builder
+	for _, schema := range edmx.DataServices.Schemas {
+		for _, et := range schema.EntityTypes {
+			allModelTypes.WriteString(generateTsModelType(et))
 		}
-
-		// Output all enums first
-		for _, enumCode := range allEnums {
-			output.WriteString(enumCode)
+		for _, ct := range schema.ComplexTypes {
+			allModelTypes.WriteString(generateTsModelType(ct))
+		}
+	}
+	output.WriteString(allModelTypes.String())
+
+	// Now generate Zod object schemas (entities/complex) for all schemas
+	generatedCount := len(allEnums)
+	for i, schema := range edmx.DataServices.Schemas {
+		log.Printf("Processing schema %d: %s (Alias: %s)", i+1, schema.Namespace, schema.Alias)
+		log.Printf("  - %d EntityTypes", len(schema.EntityTypes))
+		log.Printf("  - %d ComplexTypes", len(schema.ComplexTypes))
+
+		for _, et := range schema.EntityTypes {
+			output.WriteString(generateZodSchema(et, true, schema.Namespace))
+			generatedCount++
+			log.Printf("  Generated EntityType Schema: %s", et.Name)
 		}
+		for _, ct := range schema.ComplexTypes {
+			output.WriteString(generateZodSchema(ct, false, schema.Namespace))
+			generatedCount++
+			log.Printf("  Generated ComplexType Schema: %s", ct.Name)
+		}
+	}
 
-		// Generate TS model types (NameModel) for all entities/complex first
-		var allModelTypes strings.Builder
+	// Query descriptors, before the UDF extensions that extend them
+	if queryFields {
 		for _, schema := range edmx.DataServices.Schemas {
 			for _, et := range schema.EntityTypes {
-				allModelTypes.WriteString(generateTsModelType(et))
+				output.WriteString(generateTypeFields(et))
 			}
 			for _, ct := range schema.ComplexTypes {
-				allModelTypes.WriteString(generateTsModelType(ct))
+				output.WriteString(generateTypeFields(ct))
 			}
 		}
-		output.WriteString(allModelTypes.String())
+	}
 
-		// Now generate Zod object schemas (entities/complex) for all schemas
-		generatedCount := len(allEnums)
-		for i, schema := range edmx.DataServices.Schemas {
-			log.Printf("Processing schema %d: %s (Alias: %s)", i+1, schema.Namespace, schema.Alias)
-			log.Printf("  - %d EntityTypes", len(schema.EntityTypes))
-			log.Printf("  - %d ComplexTypes", len(schema.ComplexTypes))
+	if udfs != nil {
+		output.WriteString(generateUDFExtensions())
+	}
 
+	if generatedCount == 0 {
+		log.Println("Warning: No types generated.")
+		output.WriteString("// No schemas found in metadata.\n")
+	} else {
+		log.Printf("Successfully generated %d schemas (including %d enums)", generatedCount, len(allEnums))
+	}
+
+	if queryFields {
+		if err := writeQueryModule(filepath.Dir(outputFile)); err != nil {
+			return err
+		}
+	}
+	if batchModule {
+		if err := writeBatchModule(filepath.Dir(outputFile)); err != nil {
+			return err
+		}
+	}
+	return writeFile(outputFile, output.String())
+}
+
+// Write query.ts, the query builder runtime, into dir.
+func writeQueryModule(dir string) error {
+	var b strings.Builder
+	b.WriteString("// Generated OData query builder for SAP Business One Service Layer v2\n")
+	b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n\n")
+	b.WriteString(sapgen.TsQueryModule)
+	if err := writeFile(filepath.Join(dir, "query.ts"), b.String()); err != nil {
+		return fmt.Errorf("writing query.ts: %w", err)
+	}
+	return nil
+}
+
+// Write batch.ts, the $batch builder, and errors.ts, the error payloads
+// it parses, into dir.
+func writeBatchModule(dir string) error {
+	var b strings.Builder
+	b.WriteString("// Generated OData error payloads for SAP Business One Service Layer v2\n")
+	b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n\n")
+	b.WriteString(sapgen.TsErrorsModule)
+	if err := writeFile(filepath.Join(dir, "errors.ts"), b.String()); err != nil {
+		return fmt.Errorf("writing errors.ts: %w", err)
+	}
+	b.Reset()
+	b.WriteString("// Generated OData $batch builder for SAP Business One Service Layer v2\n")
+	b.WriteString("// DO NOT EDIT - Regenerate from metadata.\n\n")
+	b.WriteString(sapgen.TsBatchModule)
+	if err := writeFile(filepath.Join(dir, "batch.ts"), b.String()); err != nil {
+		return fmt.Errorf("writing batch.ts: %w", err)
+	}
+	return nil
+}
+
+// ---------- Entry points ----------
+
+// Options are the settings of one Zod generation run; the flags of main2()
+// and the "zod" targets of a sapgen config file both end up here.
+type Options struct {
+	Split   string // single | perType
+	Output  string // TS file for -split=single
+	OutDir  string // directory for -split=perType
+	Decimal sapgen.DecimalMode
+	IEEE754 bool
+	Types   sapgen.TypeSet // from SelectTypes; nil generates everything
+	Naming  sapgen.Naming  // -keys and -rename (case does not apply to TS)
+	Scalars sapgen.Scalars // user types for Edm types and Type.Property paths
+	UDF     sapgen.UDF     // U_ fields inline or in <Type>UDF extensions
+	UDFEdmx *EDMX          // metadata of the UDF extensions; nil uses edmx
+
+	// StandardModule, set for -objects=user, is the TS module holding the
+	// types of edmx that are not in Types; they are imported from there.
+	StandardModule string
+
+	// PropertyAlias is the "...Property" rule: with keys=camel it picks the
+	// name <Type>ToWire writes; both names are read unless it is off.
+	PropertyAlias sapgen.PropertyAlias
+
+	Quirks sapgen.QuirkSet // SAP B1 rules turned on or off (-quirks)
+
+	Writer     sapgen.Writer // where the files go; nil writes them to disk
+	SourceHash string        // metadata hash for the file headers; "" leaves it out
+
+	Query bool // <Type>Fields query descriptors and query.ts
+	Batch bool // the $batch builder, batch.ts, and errors.ts
+	ETag  bool // '@odata.etag' on entity models
+}
+
+// ParseEDMX decodes EDMX metadata.
+func ParseEDMX(data []byte) (*EDMX, error) {
+	var edmx EDMX
+	if err := xml.Unmarshal(data, &edmx); err != nil {
+		return nil, err
+	}
+	return &edmx, nil
+}
+
+// Generate writes the Zod output for already parsed metadata.
+func Generate(edmx *EDMX, opts Options) error {
+	if opts.Decimal == "" {
+		opts.Decimal = sapgen.DecimalNumber
+	}
+	ieee754 = opts.IEEE754
+	applyDecimalMode(opts.Decimal)
+	external, standardModule = nil, opts.StandardModule
+	if standardModule != "" && opts.Types != nil {
+		external = map[string]bool{}
+		for _, schema := range edmx.DataServices.Schemas {
+			for _, e := range schema.EnumTypes {
+				if !opts.Types.Has(schema.Namespace, e.Name) {
+					external[e.Name] = true
+				}
+			}
 			for _, et := range schema.EntityTypes {
-				output.WriteString(generateZodSchema(et, true, schema.Namespace))
-				generatedCount++
-				log.Printf("  Generated EntityType Schema: %s", et.Name)
+				if !opts.Types.Has(schema.Namespace, et.Name) {
+					external[et.Name] = false
+				}
 			}
 			for _, ct := range schema.ComplexTypes {
-				output.WriteString(generateZodSchema(ct, false, schema.Namespace))
-				generatedCount++
-				log.Printf("  Generated ComplexType Schema: %s", ct.Name)
+				if !opts.Types.Has(schema.Namespace, ct.Name) {
+					external[ct.Name] = false
+				}
 			}
 		}
+	}
+	edmx = FilterEDMX(edmx, opts.Types)
+	udfs = nil
+	if opts.UDF.Extension() {
+		ext := opts.UDFEdmx
+		if ext == nil {
+			ext = edmx
+		}
+		edmx, udfs = splitUDFs(edmx, ext)
+	}
+	navStubs = collectNavStubs(edmx)
+	scalars = opts.Scalars
+	quirks = opts.Quirks
+	propertyAlias = quirks.PropertyAlias(opts.PropertyAlias)
+	output, sourceHash = sapgen.OrDisk(opts.Writer), opts.SourceHash
+	queryFields, batchModule, etagKey = opts.Query, opts.Batch, opts.ETag
+	if err := applyNaming(edmx, opts.Naming); err != nil {
+		return err
+	}
 
-		if generatedCount == 0 {
-			log.Println("Warning: No types generated.")
-			output.WriteString("// No schemas found in metadata.\n")
-		} else {
-			log.Printf("Successfully generated %d schemas (including %d enums)", generatedCount, len(allEnums))
+	switch opts.Split {
+	case "single":
+		if err := writeSingleFile(edmx, opts.Output); err != nil {
+			return fmt.Errorf("writing output file: %w", err)
 		}
+		log.Printf("Generated Zod schemas in %s", opts.Output)
+	case "perType", "":
+		if err := writePerTypeOutputs(edmx, opts.OutDir); err != nil {
+			return fmt.Errorf("generating per-type outputs: %w", err)
+		}
+		log.Printf("Generated per-type TS files in %s", opts.OutDir)
+	default:
+		return fmt.Errorf("unknown split mode: %s (use 'single' or 'perType')", opts.Split)
+	}
+	return nil
+}
+
+// ---------- Main (supports single file or per-type split) ----------
+
+func main2() {
+	inputFile := flag.String("input", "", "Path to the EDMX XML file")
+	outputFile := flag.String("output", "types.ts", "Path to the output TS file for -split=single")
+	outDir := flag.String("outDir", "types", "Directory to write TS files for -split=perType")
+	splitMode := flag.String("split", "perType", "Output mode: single | perType")
+	dumpParsed := flag.Bool("dump", false, "Dump parsed XML structure to debug.xml")
+	decimal := flag.String("decimal", "number", "Edm.Decimal representation: number | string | decimal.js | big.js")
+	ieee := flag.Bool("ieee754", false, "Service uses IEEE754Compatible=true (decimals sent as strings)")
+	include := flag.String("include", "", "Comma-separated patterns of types to generate, e.g. set:Orders,type:Item* (default: all)")
+	exclude := flag.String("exclude", "", "Comma-separated patterns of types to leave out")
+	stubNav := flag.Bool("stub-nav", false, "Do not follow navigation properties when selecting types; emit them untyped")
+	keys := flag.String("keys", "wire", "Property keys: wire (JSON names) | camel (camelCase, mapped to the wire names)")
+	renames := flag.String("rename", "", "Comma-separated overrides: Type=TsName,Type.Property=key")
+	scalarsFile := flag.String("scalars", "", "JSON file mapping Edm types and Type.Property paths to user types")
+	udfMode := flag.String("udf", "inline", "User-defined (U_) fields: inline | extension (<Type>UDF schemas and <Type>WithUDF merges)")
+	udfInput := flag.String("udf-in", "", "-udf=extension: EDMX file the UDF extensions are generated from (default: -input)")
+	objects := flag.String("objects", "all", "Objects to generate: all | standard | user (UDTs and UDOs)")
+	userObjects := flag.String("user-objects", "", "Comma-separated patterns of more types to treat as user objects")
+	stdModule := flag.String("standard-module", "", "-objects=user: module of the standard output, relative to -outDir (-output for single)")
+	aliasMode := flag.String("property-alias", "alias", `"...Property" fields (ActivityProperty): alias (read both, write Activity) | metadata | off`)
+	quirkList := flag.String("quirks", "", "Comma-separated SAP quirk rules to turn on or off, e.g. yesNoBool,enumCasing=false")
+	listQuirks := flag.Bool("list-quirks", false, "Print the SAP quirk rules and exit")
+	hashHeader := flag.Bool("source-hash", false, "Record the SHA-256 of the metadata in the file headers")
+	query := flag.Bool("query", false, "Generate <Type>Fields query descriptors and the query builder (query.ts)")
+	batch := flag.Bool("batch", false, "Generate the $batch builder (batch.ts) and the error payloads (errors.ts)")
+	etag := flag.Bool("etag", false, "Keep @odata.etag on entity models, for If-Match")
+	flag.Parse()
 
-		err = ioutil.WriteFile(*outputFile, []byte(output.String()), 0644)
+	if *listQuirks {
+		sapgen.WriteQuirks(os.Stdout)
+		return
+	}
+
+	mode, err := sapgen.ParseDecimalMode(*decimal, sapgen.TsDecimalModes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	naming := sapgen.Naming{Keys: *keys}
+	if naming.Renames, err = sapgen.ParseRenames(*renames); err == nil {
+		err = naming.Validate()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	userScalars, err := sapgen.LoadScalars(*scalarsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	udf := sapgen.UDF{Mode: *udfMode, Source: *udfInput}
+	if err := udf.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	uo := sapgen.UserObjects{Objects: *objects, UserPatterns: sapgen.SplitList(*userObjects), StandardModule: *stdModule}
+	if err := uo.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if uo.Side() == sapgen.ObjectsUser && uo.StandardModule == "" {
+		log.Fatal("-objects=user requires -standard-module")
+	}
+	alias := sapgen.PropertyAlias(*aliasMode)
+	if err := alias.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	userQuirks, err := sapgen.ParseQuirks(*quirkList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *inputFile == "" {
+		log.Fatal("Please provide -input flag with the XML file path")
+	}
+
+	data, err := ioutil.ReadFile(*inputFile)
+	if err != nil {
+		log.Fatalf("Error reading XML file: %v", err)
+	}
+
+	edmx, err := ParseEDMX(data)
+	if err != nil {
+		log.Fatalf("Error unmarshaling XML: %v", err)
+	}
+
+	log.Printf("Parsed EDMX Version: %s", edmx.Version)
+	log.Printf("Parsed %d schemas", len(edmx.DataServices.Schemas))
+
+	if *dumpParsed {
+		dumpParsedXML(edmx, "debug.xml")
+	}
+
+	sources := [][]byte{data}
+	var udfEdmx *EDMX
+	if udf.Source != "" {
+		udfData, err := ioutil.ReadFile(udf.Source)
 		if err != nil {
-			log.Fatalf("Error writing output file: %v", err)
+			log.Fatalf("Error reading UDF XML file: %v", err)
+		}
+		if udfEdmx, err = ParseEDMX(udfData); err != nil {
+			log.Fatalf("Error unmarshaling UDF XML: %v", err)
 		}
-		log.Printf("Generated Zod schemas in %s", *outputFile)
+		sources = append(sources, udfData)
+	}
+	var hash string
+	if *hashHeader {
+		hash = sapgen.SourceHash(sources...)
+	}
 
-	case "perType":
-		if err := writePerTypeOutputs(&edmx, *outDir); err != nil {
-			log.Fatalf("Error generating per-type outputs: %v", err)
+	var types sapgen.TypeSet
+	sel := sapgen.Selection{Include: sapgen.SplitList(*include), Exclude: sapgen.SplitList(*exclude), StubNavigation: *stubNav}
+	if !sel.IsZero() {
+		if types, err = SelectTypes(edmx, sel); err != nil {
+			log.Fatalf("Error: %v", err)
 		}
-		log.Printf("Generated per-type TS files in %s", *outDir)
+		log.Printf("Selected %d types", len(types))
+	}
+	if types, err = ObjectTypes(edmx, uo, types); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 
-	default:
-		log.Fatalf("Unknown -split mode: %s (use 'single' or 'perType')", *splitMode)
+	err = Generate(edmx, Options{
+		Split:   *splitMode,
+		Output:  *outputFile,
+		OutDir:  *outDir,
+		Decimal: mode,
+		IEEE754: *ieee,
+		Types:   types,
+		Naming:  naming,
+		Scalars: userScalars,
+		UDF:     udf,
+		UDFEdmx: udfEdmx,
+
+		StandardModule: uo.StandardModule,
+		PropertyAlias:  alias,
+		Quirks:         userQuirks,
+		SourceHash:     hash,
+		Query:          *query,
+		Batch:          *batch,
+		ETag:           *etag,
+	})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 }
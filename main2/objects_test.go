@@ -0,0 +1,124 @@
+package main2
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+const objectsMetadata = `<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx"><edmx:DataServices>
+<Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+  <EnumType Name="BoYesNoEnum"><Member Name="tNO" Value="0"/></EnumType>
+  <EnumType Name="UDOStatus"><Member Name="Open" Value="0"/></EnumType>
+  <ComplexType Name="TRUCK_LINES">
+    <Property Name="LineId" Type="Edm.Int32"/>
+    <Property Name="U_Load" Type="Edm.Double"/>
+    <Property Name="U_Status" Type="SAPB1.UDOStatus"/>
+  </ComplexType>
+  <EntityType Name="Item">
+    <Key><PropertyRef Name="ItemCode"/></Key>
+    <Property Name="ItemCode" Type="Edm.String" Nullable="false"/>
+    <Property Name="Valid" Type="SAPB1.BoYesNoEnum"/>
+  </EntityType>
+  <EntityType Name="U_COLOURS">
+    <Key><PropertyRef Name="Code"/></Key>
+    <Property Name="Code" Type="Edm.String" Nullable="false"/>
+    <Property Name="Name" Type="Edm.String"/>
+  </EntityType>
+  <EntityType Name="Size">
+    <Key><PropertyRef Name="Code"/></Key>
+    <Property Name="Code" Type="Edm.String" Nullable="false"/>
+  </EntityType>
+  <EntityType Name="TRUCK">
+    <Key><PropertyRef Name="DocEntry"/></Key>
+    <Property Name="DocEntry" Type="Edm.Int32" Nullable="false"/>
+    <Property Name="Object" Type="Edm.String"/>
+    <Property Name="LogInst" Type="Edm.Int32"/>
+    <Property Name="U_Active" Type="SAPB1.BoYesNoEnum"/>
+    <Property Name="TRUCK_LINESCollection" Type="Collection(SAPB1.TRUCK_LINES)"/>
+  </EntityType>
+  <EntityType Name="Depot">
+    <Key><PropertyRef Name="Code"/></Key>
+    <Property Name="Code" Type="Edm.String" Nullable="false"/>
+    <Property Name="City" Type="Edm.String"/>
+  </EntityType>
+  <EntityContainer Name="ServiceLayer">
+    <EntitySet Name="Items" EntityType="SAPB1.Item"/>
+    <EntitySet Name="U_COLOURS" EntityType="SAPB1.U_COLOURS"/>
+    <EntitySet Name="U_SIZES" EntityType="SAPB1.Size"/>
+    <EntitySet Name="TRUCK" EntityType="SAPB1.TRUCK"/>
+    <EntitySet Name="Depot" EntityType="SAPB1.Depot"/>
+  </EntityContainer>
+</Schema></edmx:DataServices></edmx:Edmx>`
+
+func sortedTypes(s sapgen.TypeSet) []string {
+	var out []string
+	for qn := range s {
+		out = append(out, qn)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestUserTypes(t *testing.T) {
+	edmx, err := ParseEDMX([]byte(objectsMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// U_COLOURS by name, Size by its U_SIZES set, TRUCK as a UDO with its
+	// child table and the enum only that uses; BoYesNoEnum stays standard
+	// since Item uses it too. Depot is a UDO only by pattern.
+	user := []string{"SAPB1.Size", "SAPB1.TRUCK", "SAPB1.TRUCK_LINES", "SAPB1.UDOStatus", "SAPB1.U_COLOURS"}
+	got, err := UserTypes(edmx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := sortedTypes(got); !slices.Equal(names, user) {
+		t.Errorf("UserTypes:\n got %v\nwant %v", names, user)
+	}
+	got, err = UserTypes(edmx, []string{"set:Depot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := sortedTypes(got); !slices.Contains(names, "SAPB1.Depot") || len(names) != len(user)+1 {
+		t.Errorf("UserTypes with set:Depot: %v", names)
+	}
+
+	for _, tt := range []struct {
+		uo   sapgen.UserObjects
+		want []string
+	}{
+		{sapgen.UserObjects{Objects: "user"}, user},
+		{sapgen.UserObjects{Objects: "standard"}, []string{"SAPB1.BoYesNoEnum", "SAPB1.Depot", "SAPB1.Item"}},
+		{sapgen.UserObjects{Objects: "standard", UserPatterns: []string{"Depot"}}, []string{"SAPB1.BoYesNoEnum", "SAPB1.Item"}},
+	} {
+		got, err := ObjectTypes(edmx, tt.uo, nil)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.uo, err)
+			continue
+		}
+		if names := sortedTypes(got); !slices.Equal(names, tt.want) {
+			t.Errorf("ObjectTypes(%+v):\n got %v\nwant %v", tt.uo, names, tt.want)
+		}
+	}
+	if got, err := ObjectTypes(edmx, sapgen.UserObjects{}, nil); got != nil || err != nil {
+		t.Errorf("objects=all: %v, %v", got, err)
+	}
+	if _, err := ObjectTypes(edmx, sapgen.UserObjects{Objects: "user"}, sapgen.TypeSet{"SAPB1.Item": true}); err == nil || !strings.Contains(err.Error(), "no user objects") {
+		t.Errorf("user side of Item only: %v", err)
+	}
+}
+
+func TestUserTypesRequiredByStandard(t *testing.T) {
+	edmx, err := ParseEDMX([]byte(strings.Replace(objectsMetadata,
+		`<Property Name="Valid" Type="SAPB1.BoYesNoEnum"/>`,
+		`<Property Name="Valid" Type="SAPB1.BoYesNoEnum"/><Property Name="Colours" Type="Collection(SAPB1.U_COLOURS)"/>`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UserTypes(edmx, nil); err == nil || !strings.Contains(err.Error(), "user object SAPB1.U_COLOURS is required by a standard type") {
+		t.Errorf("error %v", err)
+	}
+}
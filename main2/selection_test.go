@@ -0,0 +1,133 @@
+package main2
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+const selectionMetadata = `<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx"><edmx:DataServices>
+<Schema Namespace="SAPB1" Alias="B1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+  <EnumType Name="BoStatus"><Member Name="bost_Open" Value="0"/></EnumType>
+  <EnumType Name="BoYesNoEnum"><Member Name="tNO" Value="0"/></EnumType>
+  <ComplexType Name="DocumentLine">
+    <Property Name="ItemCode" Type="Edm.String"/>
+    <Property Name="LineStatus" Type="B1.BoStatus"/>
+  </ComplexType>
+  <EntityType Name="BaseDocument">
+    <Key><PropertyRef Name="DocEntry"/></Key>
+    <Property Name="DocEntry" Type="Edm.Int32" Nullable="false"/>
+  </EntityType>
+  <EntityType Name="Document" BaseType="SAPB1.BaseDocument">
+    <Property Name="CardCode" Type="Edm.String"/>
+    <Property Name="DocumentLines" Type="Collection(SAPB1.DocumentLine)"/>
+    <NavigationProperty Name="BusinessPartner" Type="SAPB1.BusinessPartner"/>
+  </EntityType>
+  <EntityType Name="BusinessPartner">
+    <Key><PropertyRef Name="CardCode"/></Key>
+    <Property Name="CardCode" Type="Edm.String" Nullable="false"/>
+    <NavigationProperty Name="SalesPerson" Type="SAPB1.SalesPerson"/>
+  </EntityType>
+  <EntityType Name="SalesPerson">
+    <Key><PropertyRef Name="SalesEmployeeCode"/></Key>
+    <Property Name="SalesEmployeeCode" Type="Edm.Int32" Nullable="false"/>
+    <Property Name="Active" Type="SAPB1.BoYesNoEnum"/>
+  </EntityType>
+  <EntityType Name="Item">
+    <Key><PropertyRef Name="ItemCode"/></Key>
+    <Property Name="ItemCode" Type="Edm.String" Nullable="false"/>
+  </EntityType>
+  <EntityContainer Name="ServiceLayer">
+    <EntitySet Name="Orders" EntityType="B1.Document"/>
+    <EntitySet Name="BusinessPartners" EntityType="SAPB1.BusinessPartner"/>
+    <EntitySet Name="Items" EntityType="SAPB1.Item"/>
+  </EntityContainer>
+</Schema></edmx:DataServices></edmx:Edmx>`
+
+func TestSelectTypes(t *testing.T) {
+	edmx, err := ParseEDMX([]byte(selectionMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := []string{"SAPB1.BaseDocument", "SAPB1.BoStatus", "SAPB1.Document", "SAPB1.DocumentLine"}
+	tests := []struct {
+		name string
+		sel  sapgen.Selection
+		want []string
+		err  string
+	}{
+		{
+			"navigation is followed",
+			sapgen.Selection{Include: []string{"set:Orders"}},
+			append(document, "SAPB1.BoYesNoEnum", "SAPB1.BusinessPartner", "SAPB1.SalesPerson"),
+			"",
+		},
+		{
+			"stub navigation",
+			sapgen.Selection{Include: []string{"set:Orders"}, StubNavigation: true},
+			document,
+			"",
+		},
+		{
+			"excluded navigation target",
+			sapgen.Selection{Include: []string{"set:Orders"}, Exclude: []string{"type:BusinessPartner"}},
+			document,
+			"",
+		},
+		{
+			"excluded past the navigation",
+			sapgen.Selection{Include: []string{"set:Orders"}, Exclude: []string{"SalesPerson"}},
+			append(document, "SAPB1.BusinessPartner"),
+			"",
+		},
+		{
+			"excluded but required",
+			sapgen.Selection{Include: []string{"set:Orders"}, Exclude: []string{"type:DocumentLine"}},
+			nil,
+			"SAPB1.DocumentLine is excluded but required by SAPB1.Document",
+		},
+		{
+			"excluded base type",
+			sapgen.Selection{Include: []string{"set:Orders"}, Exclude: []string{"BaseDocument"}},
+			nil,
+			"SAPB1.BaseDocument is excluded but required by SAPB1.Document",
+		},
+		{
+			"exclude only",
+			sapgen.Selection{Exclude: []string{"Items", "set:Orders", "BaseDocument", "DocumentLine", "BoStatus"}},
+			[]string{"SAPB1.BoYesNoEnum", "SAPB1.BusinessPartner", "SAPB1.SalesPerson"},
+			"",
+		},
+		{
+			"nothing matches",
+			sapgen.Selection{Include: []string{"set:Invoices"}},
+			nil,
+			"selection matches no types",
+		},
+	}
+	for _, tt := range tests {
+		got, err := SelectTypes(edmx, tt.sel)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var names []string
+		for qn := range got {
+			names = append(names, qn)
+		}
+		slices.Sort(names)
+		want := slices.Clone(tt.want)
+		slices.Sort(want)
+		if !slices.Equal(names, want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, names, want)
+		}
+	}
+}
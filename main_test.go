@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+func TestGenerateListQuirks(t *testing.T) {
+	got, err := captureStdout(t, func() error {
+		return runGenerate([]string{"--list-quirks", "--config", "does-not-exist.json"})
+	})
+	if err != nil {
+		t.Fatalf("generate --list-quirks: %v", err)
+	}
+	var want bytes.Buffer
+	sapgen.WriteQuirks(&want)
+	if got != want.String() {
+		t.Errorf("generate --list-quirks printed\n%s\nwant\n%s", got, want.String())
+	}
+}
+
+// captureStdout runs f with os.Stdout sent to a file and returns what f
+// printed.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	tmp, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tmp.Close()
+	stdout := os.Stdout
+	os.Stdout = tmp
+	err = f()
+	os.Stdout = stdout
+	out, _ := os.ReadFile(tmp.Name())
+	return string(out), err
+}
+
+func TestGenerateCheck(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "metadata.xml"), []byte(`<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx"><edmx:DataServices>
+<Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+  <EntityType Name="Item">
+    <Key><PropertyRef Name="ItemCode"/></Key>
+    <Property Name="ItemCode" Type="Edm.String" Nullable="false"/>
+    <Property Name="ItemName" Type="Edm.String"/>
+  </EntityType>
+  <EntityContainer Name="ServiceLayer"><EntitySet Name="Items" EntityType="SAPB1.Item"/></EntityContainer>
+</Schema></edmx:DataServices></edmx:Edmx>`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "sapgen.json")
+	err = os.WriteFile(config, []byte(`{
+  "sources": {"b1": {"path": "metadata.xml"}},
+  "targets": [{"name": "models", "kind": "go", "source": "b1", "split": "perType", "outDir": "models", "package": "models"}]
+}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func() (string, error) {
+		return captureStdout(t, func() error { return runGenerate([]string{"--config", config, "--check"}) })
+	}
+
+	if out, err := check(); err == nil || !strings.Contains(out, "+++ ") {
+		t.Fatalf("--check before generating: %v\n%s", err, out)
+	}
+	if err := runGenerate([]string{"--config", config}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := check(); err != nil || out != "" {
+		t.Fatalf("--check after generating: %v\n%s", err, out)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "models", "*.go"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no models generated: %v", err)
+	}
+	if err := os.WriteFile(files[0], []byte("// Code generated. DO NOT EDIT.\npackage models\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(dir, "models", "removed_gen.go")
+	if err := os.WriteFile(orphan, []byte("// Code generated. DO NOT EDIT.\npackage models\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := check()
+	if err == nil || !strings.Contains(out, "--- "+files[0]) || !strings.Contains(out, "--- "+orphan+"\n+++ /dev/null") {
+		t.Errorf("--check after an edit and an orphan: %v\n%s", err, out)
+	}
+}
+
+func TestRunFixtures(t *testing.T) {
+	dir := t.TempDir()
+	metadata := filepath.Join(dir, "metadata.xml")
+	err := os.WriteFile(metadata, []byte(`<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx"><edmx:DataServices>
+<Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+  <EntityType Name="Activity">
+    <Key><PropertyRef Name="ActivityCode"/></Key>
+    <Property Name="ActivityCode" Type="Edm.Int32" Nullable="false"/>
+    <Property Name="ActivityProperty" Type="Edm.String" MaxLength="10"/>
+  </EntityType>
+  <EntityContainer Name="ServiceLayer"><EntitySet Name="Activities" EntityType="SAPB1.Activity"/></EntityContainer>
+</Schema></edmx:DataServices></edmx:Edmx>`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs [][]byte
+	for _, out := range []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")} {
+		if err := runFixtures([]string{"--metadata", metadata, "--out", out, "--seed", "3", "--count", "2"}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(out, "Activities.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, b)
+	}
+	if !bytes.Equal(runs[0], runs[1]) {
+		t.Errorf("fixtures --seed 3 gave\n%s\nand\n%s", runs[0], runs[1])
+	}
+	var entities []map[string]any
+	if err := json.Unmarshal(runs[0], &entities); err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 2 || entities[0]["Activity"] == nil || entities[0]["ActivityProperty"] != nil {
+		t.Errorf("Activities.json = %s, want 2 entities with Activity", runs[0])
+	}
+}
@@ -0,0 +1,590 @@
+// Package mock serves the entity sets of a $metadata document from memory,
+// for tests and local development without a Service Layer: create, read,
+// update and delete by key, the system query options $filter, $select,
+// $orderby, $top, $skip, $count and $expand (along the referential
+// constraints and navigation property bindings of the metadata), paging,
+// ETags, and the Login and Logout of a Service Layer session. The data is
+// seeded from JSON fixtures or Go values.
+//
+// Entities are stored as their JSON form; the mock checks property names
+// but not their types or facets. It accepts the "...Property" properties
+// under both names and sends them under their aliases, Activity for
+// ActivityProperty, as Service Layer does.
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dissemblir/sapModelsGenerator/filter"
+	"dissemblir/sapModelsGenerator/gpt5mini"
+)
+
+// Options configure a Server.
+type Options struct {
+	Root     string // path of the service root, e.g. "/b1s/v2"; "" serves at /
+	PageSize int    // entities per page of a collection; 0 pages only on Prefer: odata.maxpagesize
+	Sessions bool   // answer 401 to requests without the B1SESSION cookie of a Login
+}
+
+// Server is an in-memory OData service. It is safe for concurrent use.
+type Server struct {
+	opts     Options
+	metadata []byte
+	model    *model
+
+	mu       sync.Mutex
+	sessions map[string]bool
+	nextID   int
+}
+
+// Error codes of the payloads, those of Service Layer where it has one.
+const (
+	codeInvalid  = "-1000"
+	codeNotFound = "-2028"
+	codeExists   = "-2035"
+	codeSession  = "301"
+	codeLogin    = "100000027"
+)
+
+// New returns a server for the entity sets of metadata, with no entities.
+func New(metadata []byte, opts Options) (*Server, error) {
+	schemas, err := gpt5mini.ParseMetadata(bytes.NewReader(metadata))
+	if err != nil {
+		return nil, fmt.Errorf("mock: %w", err)
+	}
+	m, err := newModel(schemas)
+	if err != nil {
+		return nil, err
+	}
+	opts.Root = strings.TrimSuffix(opts.Root, "/")
+	return &Server{opts: opts, metadata: metadata, model: m, sessions: map[string]bool{}}, nil
+}
+
+// Seed adds entities to set: maps, generated models or any value whose
+// JSON form is an entity. A missing integer key is numbered like a POST.
+func (s *Server) Seed(set string, entities ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	es := s.model.sets[set]
+	if es == nil {
+		return fmt.Errorf("mock: seed: no entity set %s", set)
+	}
+	for _, e := range entities {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("mock: seed %s: %w", set, err)
+		}
+		data, err := decodeObject(bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("mock: seed %s: %w", set, err)
+		}
+		if _, err := s.insert(es, data); err != nil {
+			return fmt.Errorf("mock: seed %s: %s", set, err.message)
+		}
+	}
+	return nil
+}
+
+// Load seeds the server from fixtures at path: a directory of <Set>.json
+// files holding an array of entities each, or one file holding an object
+// of arrays by set name.
+func (s *Server) Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("mock: fixtures: %w", err)
+	}
+	fixtures := map[string][]json.RawMessage{}
+	if info.IsDir() {
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return fmt.Errorf("mock: fixtures: %w", err)
+		}
+		for _, f := range files {
+			var list []json.RawMessage
+			if err := readJSON(f, &list); err != nil {
+				return err
+			}
+			fixtures[strings.TrimSuffix(filepath.Base(f), ".json")] = list
+		}
+	} else if err := readJSON(path, &fixtures); err != nil {
+		return err
+	}
+	for _, set := range s.model.order { // metadata order, whatever the files
+		list, ok := fixtures[set]
+		if !ok {
+			continue
+		}
+		entities := make([]any, len(list))
+		for i, e := range list {
+			entities[i] = e
+		}
+		if err := s.Seed(set, entities...); err != nil {
+			return err
+		}
+		delete(fixtures, set)
+	}
+	for set := range fixtures {
+		return fmt.Errorf("mock: fixtures: no entity set %s", set)
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("mock: fixtures: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("mock: fixtures: %s: %w", path, err)
+	}
+	return nil
+}
+
+// ExpireSessions ends the sessions of every Login, as their timeout would.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// apiError is an error response: the status and the payload's code.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func errorf(status int, code, format string, args ...any) *apiError {
+	return &apiError{status, code, fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.Path, s.opts.Root)
+	if !ok {
+		s.writeError(w, errorf(http.StatusNotFound, codeInvalid, "%s is outside the service root", r.URL.Path))
+		return
+	}
+	path = strings.TrimPrefix(path, "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch path {
+	case "$metadata":
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write(s.metadata)
+		return
+	case "Login":
+		s.login(w, r)
+		return
+	case "Logout":
+		if c, err := r.Cookie("B1SESSION"); err == nil {
+			delete(s.sessions, c.Value)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if s.opts.Sessions {
+		if c, err := r.Cookie("B1SESSION"); err != nil || !s.sessions[c.Value] {
+			s.writeError(w, errorf(http.StatusUnauthorized, codeSession, "Invalid session or session already timeout."))
+			return
+		}
+	}
+	if path == "" {
+		s.serviceDocument(w)
+		return
+	}
+	if err := s.route(w, r, path); err != nil {
+		s.writeError(w, err)
+	}
+}
+
+// route serves Set, Set/$count and Set(key).
+func (s *Server) route(w http.ResponseWriter, r *http.Request, path string) *apiError {
+	name, rest, _ := strings.Cut(path, "/")
+	name, keyText, hasKey := strings.Cut(name, "(")
+	set := s.model.sets[name]
+	if set == nil {
+		return errorf(http.StatusNotFound, codeInvalid, "Resource not found for the segment '%s'.", name)
+	}
+	if !hasKey {
+		switch {
+		case rest == "$count" && r.Method == http.MethodGet:
+			return s.count(w, r, set)
+		case rest != "":
+			return errorf(http.StatusNotFound, codeInvalid, "Resource not found for the segment '%s'.", rest)
+		case r.Method == http.MethodGet:
+			return s.list(w, r, set)
+		case r.Method == http.MethodPost:
+			return s.create(w, r, set)
+		}
+		return errorf(http.StatusMethodNotAllowed, codeInvalid, "%s is not allowed on %s", r.Method, name)
+	}
+	if rest != "" || !strings.HasSuffix(keyText, ")") {
+		return errorf(http.StatusNotFound, codeInvalid, "Resource not found for the segment '%s'.", path)
+	}
+	key, err := set.typ.parseKey(strings.TrimSuffix(keyText, ")"))
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "key of %s: %v", name, err)
+	}
+	i, found := set.find(key)
+	if found == nil {
+		return errorf(http.StatusNotFound, codeNotFound, "No matching records found (ODBC -2028)")
+	}
+	if r.Method != http.MethodGet {
+		if m := r.Header.Get("If-Match"); m != "" && m != "*" && m != found.etag() {
+			return errorf(http.StatusPreconditionFailed, codeInvalid, "The entity was changed: its ETag is %s, not %s", found.etag(), m)
+		}
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return s.get(w, r, set, found)
+	case http.MethodPatch, http.MethodPut:
+		return s.update(w, r, set, found)
+	case http.MethodDelete:
+		set.rows = append(set.rows[:i], set.rows[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return errorf(http.StatusMethodNotAllowed, codeInvalid, "%s is not allowed on %s", r.Method, path)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, set *entitySet) *apiError {
+	opts, err := s.model.parseQuery(r.URL.Query(), set.typ)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	pageSize := s.opts.PageSize
+	if n := maxPageSize(r.Header.Get("Prefer")); n > 0 {
+		pageSize = n
+		w.Header().Set("Preference-Applied", "odata.maxpagesize="+strconv.Itoa(n))
+	}
+	rows, count, err := opts.apply(s.model, set, set.rows)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	next := ""
+	if pageSize > 0 && len(rows) > pageSize {
+		rows = rows[:pageSize]
+		next = nextLink(set.name, r.URL.Query(), opts, pageSize)
+	}
+	value := []any{}
+	for _, row := range rows {
+		v, err := s.render(set, row, opts)
+		if err != nil {
+			return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+		}
+		value = append(value, v)
+	}
+	body := map[string]any{s.contextKey(): "$metadata#" + set.name, "value": value}
+	if opts.count {
+		if s.model.v3 {
+			body["odata.count"] = strconv.Itoa(count) // a string in v3
+		} else {
+			body["@odata.count"] = count
+		}
+	}
+	if next != "" {
+		body[s.annotation("nextLink")] = next
+	}
+	writeJSON(w, http.StatusOK, body)
+	return nil
+}
+
+// nextLink is the link to the page after the first pageSize entities of
+// the query, relative to the service root.
+func nextLink(set string, q url.Values, opts *queryOptions, pageSize int) string {
+	next := url.Values{}
+	for name, v := range q {
+		next[name] = v
+	}
+	next.Set("$skip", strconv.Itoa(opts.skip+pageSize))
+	if opts.top >= 0 {
+		next.Set("$top", strconv.Itoa(opts.top-pageSize))
+	}
+	return set + "?" + next.Encode()
+}
+
+// maxPageSize reads odata.maxpagesize from a Prefer header; 0 without one.
+func maxPageSize(prefer string) int {
+	for _, p := range strings.Split(prefer, ",") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(p), "odata.maxpagesize="); ok {
+			n, _ := strconv.Atoi(v)
+			return n
+		}
+	}
+	return 0
+}
+
+func (s *Server) count(w http.ResponseWriter, r *http.Request, set *entitySet) *apiError {
+	opts, err := s.model.parseQuery(r.URL.Query(), set.typ)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	_, count, err := opts.apply(s.model, set, set.rows)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, strconv.Itoa(count))
+	return nil
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, set *entitySet, found *row) *apiError {
+	opts, err := s.model.parseQuery(r.URL.Query(), set.typ)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	v, err := s.render(set, found, opts)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	v[s.contextKey()] = "$metadata#" + set.name + "/$entity"
+	w.Header().Set("ETag", found.etag())
+	writeJSON(w, http.StatusOK, v)
+	return nil
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request, set *entitySet) *apiError {
+	data, err := decodeObject(r.Body)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	created, apiErr := s.insert(set, data)
+	if apiErr != nil {
+		return apiErr
+	}
+	v, err := s.render(set, created, &queryOptions{top: -1})
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	v[s.contextKey()] = "$metadata#" + set.name + "/$entity"
+	w.Header().Set("ETag", created.etag())
+	writeJSON(w, http.StatusCreated, v)
+	return nil
+}
+
+// insert adds data to set, numbering a missing integer key after the
+// largest one the set has had.
+func (s *Server) insert(set *entitySet, data map[string]any) (*row, *apiError) {
+	if err := checkProperties(set.typ, data); err != nil {
+		return nil, err
+	}
+	t := set.typ
+	numbered := len(t.keys) == 1 && isIntegral(t.props[t.keys[0]])
+	if numbered && data[t.keys[0]] == nil {
+		data[t.keys[0]] = json.Number(strconv.FormatFloat(set.lastKey+1, 'f', -1, 64))
+	}
+	key, ok := t.keyOf(data)
+	if !ok {
+		return nil, errorf(http.StatusBadRequest, codeInvalid, "%s needs its key %s", t.name, strings.Join(t.keys, ", "))
+	}
+	if _, dup := set.find(key); dup != nil {
+		return nil, errorf(http.StatusBadRequest, codeExists, "This entry already exists in the following tables (ODBC -2035)")
+	}
+	if f, err := strconv.ParseFloat(fmt.Sprint(key[t.keys[0]]), 64); numbered && err == nil && f > set.lastKey {
+		set.lastKey = f
+	}
+	r := &row{data: data, version: 1}
+	set.rows = append(set.rows, r)
+	return r, nil
+}
+
+// update applies a PATCH, merging into the entity, or a PUT, replacing it.
+// PATCH merges collections of complex values element by element, matched
+// on their line number, unless B1S-ReplaceCollectionsOnPatch is true.
+func (s *Server) update(w http.ResponseWriter, r *http.Request, set *entitySet, found *row) *apiError {
+	data, err := decodeObject(r.Body)
+	if err != nil {
+		return errorf(http.StatusBadRequest, codeInvalid, "%v", err)
+	}
+	if err := checkProperties(set.typ, data); err != nil {
+		return err
+	}
+	for _, k := range set.typ.keys {
+		if v, ok := data[k]; ok {
+			if c, ok := filter.Compare(v, found.data[k]); !ok || c != 0 {
+				return errorf(http.StatusBadRequest, codeInvalid, "key property %s cannot be changed", k)
+			}
+		}
+	}
+	if r.Method == http.MethodPut {
+		for _, k := range set.typ.keys {
+			data[k] = found.data[k]
+		}
+		found.data = data
+	} else {
+		merge(found.data, data, r.Header.Get("B1S-ReplaceCollectionsOnPatch") == "true")
+	}
+	found.version++
+	w.Header().Set("ETag", found.etag())
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// lineKeys are the properties that identify the elements of a collection
+// of complex values, the document lines, in the order they are tried.
+var lineKeys = []string{"LineNum", "LineNumber", "LineId", "VisualOrder"}
+
+// merge writes the properties of patch into data.
+func merge(data, patch map[string]any, replaceCollections bool) {
+	for name, v := range patch {
+		switch nv := v.(type) {
+		case map[string]any:
+			if old, ok := data[name].(map[string]any); ok {
+				merge(old, nv, replaceCollections)
+				continue
+			}
+		case []any:
+			if old, ok := data[name].([]any); ok && !replaceCollections {
+				data[name] = mergeLines(old, nv)
+				continue
+			}
+		}
+		data[name] = v
+	}
+}
+
+// mergeLines updates the elements of old matched by the line key of the
+// patch elements and appends the others.
+func mergeLines(old, patch []any) []any {
+	out := append([]any(nil), old...)
+	for _, p := range patch {
+		pm, ok := p.(map[string]any)
+		if !ok {
+			return patch // not complex values: replaced
+		}
+		matched := false
+		for _, k := range lineKeys {
+			if pm[k] == nil {
+				continue
+			}
+			for _, o := range out {
+				if om, ok := o.(map[string]any); ok {
+					if c, ok := filter.Compare(om[k], pm[k]); ok && c == 0 {
+						merge(om, pm, false)
+						matched = true
+						break
+					}
+				}
+			}
+			break
+		}
+		if !matched {
+			out = append(out, pm)
+		}
+	}
+	return out
+}
+
+// checkProperties rejects the properties t does not have, drops the
+// annotations such as @odata.etag and renames aliases, e.g. Activity, to
+// their metadata names.
+func checkProperties(t *entityType, data map[string]any) *apiError {
+	for name, v := range data {
+		if strings.HasPrefix(name, "@") || strings.HasPrefix(name, "odata.") || strings.Contains(name, "@odata.") {
+			delete(data, name)
+			continue
+		}
+		if meta := t.property(name); meta != name {
+			delete(data, name)
+			if _, both := data[meta]; !both {
+				data[meta] = v
+			}
+			continue
+		}
+		if _, ok := t.props[name]; !ok && t.navs[name] == nil {
+			return errorf(http.StatusBadRequest, codeInvalid, "Property '%s' of '%s' is invalid", name, t.name)
+		}
+	}
+	return nil
+}
+
+// login accepts any credentials with a user name and starts a session.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, errorf(http.StatusMethodNotAllowed, codeInvalid, "Login is POST only"))
+		return
+	}
+	var creds struct{ CompanyDB, UserName string }
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds.UserName == "" {
+		s.writeError(w, errorf(http.StatusUnauthorized, codeLogin, "Fail to get DB Credentials from SLD server"))
+		return
+	}
+	s.nextID++
+	id := fmt.Sprintf("mock-%d", s.nextID)
+	s.sessions[id] = true
+	http.SetCookie(w, &http.Cookie{Name: "B1SESSION", Value: id, Path: s.opts.Root + "/", HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: "ROUTEID", Value: ".node1", Path: s.opts.Root + "/"})
+	writeJSON(w, http.StatusOK, map[string]any{
+		s.contextKey():   "$metadata#B1Sessions/$entity",
+		"SessionId":      id,
+		"Version":        "1000000",
+		"SessionTimeout": 30,
+	})
+}
+
+func (s *Server) serviceDocument(w http.ResponseWriter) {
+	var value []any
+	for _, name := range s.model.order {
+		value = append(value, map[string]any{"name": name, "kind": "EntitySet", "url": name})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{s.contextKey(): "$metadata", "value": value})
+}
+
+// writeError writes the error payload in the form of the metadata's
+// version: a numeric code and {"lang", "value"} message in v3.
+func (s *Server) writeError(w http.ResponseWriter, e *apiError) {
+	var payload any = map[string]any{"code": e.code, "message": e.message}
+	if s.model.v3 {
+		code, _ := strconv.Atoi(e.code)
+		payload = map[string]any{"code": code, "message": map[string]any{"lang": "en-us", "value": e.message}}
+	}
+	writeJSON(w, e.status, map[string]any{"error": payload})
+}
+
+func (s *Server) contextKey() string { return s.annotation("context") }
+
+func (s *Server) etagKey() string { return s.annotation("etag") }
+
+// annotation returns the name of an annotation: @odata.<name>, or
+// odata.<name> in v3 (whose context is odata.metadata).
+func (s *Server) annotation(name string) string {
+	if !s.model.v3 {
+		return "@odata." + name
+	}
+	if name == "context" {
+		return "odata.metadata"
+	}
+	return "odata." + name
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// decodeObject reads a JSON object, keeping its numbers as written.
+func decodeObject(r io.Reader) (map[string]any, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var data map[string]any
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+	if data == nil {
+		return nil, errors.New("body: not an object")
+	}
+	return data, nil
+}
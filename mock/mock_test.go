@@ -0,0 +1,169 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="SAPB1" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+      <EntityType Name="Activity">
+        <Key><PropertyRef Name="ActivityCode"/></Key>
+        <Property Name="ActivityCode" Type="Edm.Int32" Nullable="false"/>
+        <Property Name="ActivityProperty" Type="Edm.String"/>
+        <Property Name="Notes" Type="Edm.String"/>
+      </EntityType>
+      <EntityType Name="BusinessPartner">
+        <Key><PropertyRef Name="CardCode"/></Key>
+        <Property Name="CardCode" Type="Edm.String" Nullable="false"/>
+        <Property Name="CardName" Type="Edm.String"/>
+      </EntityType>
+      <EntityType Name="Document">
+        <Key><PropertyRef Name="DocEntry"/></Key>
+        <Property Name="DocEntry" Type="Edm.Int32" Nullable="false"/>
+        <Property Name="CardCode" Type="Edm.String"/>
+        <NavigationProperty Name="BusinessPartner" Type="SAPB1.BusinessPartner">
+          <ReferentialConstraint Property="CardCode" ReferencedProperty="CardCode"/>
+        </NavigationProperty>
+        <NavigationProperty Name="DocumentLines" Type="Collection(SAPB1.DocumentLine)"/>
+      </EntityType>
+      <EntityType Name="DocumentLine">
+        <Key><PropertyRef Name="LineNum"/></Key>
+        <Property Name="LineNum" Type="Edm.Int32" Nullable="false"/>
+      </EntityType>
+      <EntityContainer Name="ServiceLayer">
+        <EntitySet Name="Activities" EntityType="SAPB1.Activity"/>
+        <EntitySet Name="BusinessPartners" EntityType="SAPB1.BusinessPartner"/>
+        <EntitySet Name="Orders" EntityType="SAPB1.Document"/>
+      </EntityContainer>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New([]byte(testMetadata), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// getJSON reads path with query and decodes the response body.
+func getJSON(t *testing.T, ts *httptest.Server, path string, query url.Values) (int, map[string]any) {
+	t.Helper()
+	u := ts.URL + "/" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("GET %s: %v", u, err)
+	}
+	return resp.StatusCode, body
+}
+
+// values returns the values of property name in a collection body.
+func values(body map[string]any, name string) []any {
+	var out []any
+	list, _ := body["value"].([]any)
+	for _, e := range list {
+		out = append(out, e.(map[string]any)[name])
+	}
+	return out
+}
+
+func TestAliasProperty(t *testing.T) {
+	_, ts := newTestServer(t)
+	for _, body := range []string{
+		`{"ActivityCode":1,"Activity":"cn_Task"}`,
+		`{"ActivityCode":2,"ActivityProperty":"cn_Call"}`,
+	} {
+		resp, err := http.Post(ts.URL+"/Activities", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("POST %s: %d", body, resp.StatusCode)
+		}
+	}
+	_, got := getJSON(t, ts, "Activities(1)", nil)
+	if got["Activity"] != "cn_Task" || got["ActivityProperty"] != nil {
+		t.Errorf("Activities(1) = %v, want Activity cn_Task", got)
+	}
+	for _, sel := range []string{"Activity", "ActivityProperty"} {
+		status, got := getJSON(t, ts, "Activities", url.Values{"$select": {sel}, "$orderby": {sel}})
+		if status != http.StatusOK {
+			t.Fatalf("$select=%s: %d %v", sel, status, got)
+		}
+		if want := []any{"cn_Call", "cn_Task"}; !reflect.DeepEqual(values(got, "Activity"), want) {
+			t.Errorf("$select=%s&$orderby=%[1]s: %v, want %v", sel, values(got, "Activity"), want)
+		}
+		if list := got["value"].([]any); len(list) > 0 && len(list[0].(map[string]any)) != 2 { // and the ETag
+			t.Errorf("$select=%s: %v", sel, list[0])
+		}
+	}
+	_, got = getJSON(t, ts, "Activities", url.Values{"$filter": {"Activity eq 'cn_Task'"}})
+	if want := []any{1.0}; !reflect.DeepEqual(values(got, "ActivityCode"), want) {
+		t.Errorf("$filter=Activity eq 'cn_Task': %v, want %v", values(got, "ActivityCode"), want)
+	}
+}
+
+func TestFilterNavigation(t *testing.T) {
+	s, ts := newTestServer(t)
+	if err := s.Seed("BusinessPartners",
+		map[string]any{"CardCode": "C1", "CardName": "A"},
+		map[string]any{"CardCode": "C2", "CardName": "B"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Seed("Orders",
+		map[string]any{"DocEntry": 1, "CardCode": "C1"},
+		map[string]any{"DocEntry": 2, "CardCode": "C2"},
+		map[string]any{"DocEntry": 3, "CardCode": "C1"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filter string
+		want   []any
+	}{
+		{"BusinessPartner/CardName eq 'A'", []any{1.0, 3.0}},
+		{"BusinessPartner/CardName eq 'B' or DocEntry eq 1", []any{1.0, 2.0}},
+		{"BusinessPartner/CardName eq 'C'", nil},
+	}
+	for _, tt := range tests {
+		status, got := getJSON(t, ts, "Orders", url.Values{"$filter": {tt.filter}})
+		if status != http.StatusOK {
+			t.Errorf("%s: %d %v", tt.filter, status, got)
+			continue
+		}
+		if docs := values(got, "DocEntry"); !reflect.DeepEqual(docs, tt.want) {
+			t.Errorf("%s: DocEntry %v, want %v", tt.filter, docs, tt.want)
+		}
+	}
+}
+
+func TestExpandWithoutConstraints(t *testing.T) {
+	s, ts := newTestServer(t)
+	if err := s.Seed("Orders", map[string]any{"DocEntry": 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, got := getJSON(t, ts, "Orders(1)", url.Values{"$expand": {"DocumentLines"}})
+	if lines, ok := got["DocumentLines"].([]any); !ok || len(lines) != 0 {
+		t.Errorf("DocumentLines = %#v, want []", got["DocumentLines"])
+	}
+}
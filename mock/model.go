@@ -0,0 +1,291 @@
+package mock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dissemblir/sapModelsGenerator/filter"
+	"dissemblir/sapModelsGenerator/gpt5mini"
+	"dissemblir/sapModelsGenerator/sapgen"
+)
+
+// entityType is an entity type of the metadata with the properties of its
+// base types.
+type entityType struct {
+	name  string // qualified
+	keys  []string
+	props map[string]string // name -> Edm or qualified type
+	navs  map[string]*navigation
+
+	// aliases maps the names Service Layer sends for the "...Property"
+	// properties, e.g. Activity, to the metadata names the rows are
+	// stored under, and wire the reverse; see sapgen.PropertyAlias.
+	aliases map[string]string
+	wire    map[string]string
+}
+
+// navigation is a navigation property and how its entities are found:
+// pairs of a property of the source and the matching one of the target,
+// from the referential constraints. Without pairs, $expand returns the
+// value stored inline with the entity.
+type navigation struct {
+	name   string
+	target string // qualified entity type
+	coll   bool
+	pairs  [][2]string
+}
+
+// entitySet is an entity set and its rows, in insertion order.
+type entitySet struct {
+	name     string
+	typ      *entityType
+	bindings map[string]string // navigation -> target set
+	rows     []*row
+	lastKey  float64 // the largest integer key so far, deleted rows included
+}
+
+// row is a stored entity: its JSON form and the version of its ETag.
+type row struct {
+	data    map[string]any
+	version int
+}
+
+func (r *row) etag() string { return `W/"` + strconv.Itoa(r.version) + `"` }
+
+// model is the entity sets of the metadata.
+type model struct {
+	v3      bool
+	filters *filter.Model
+	types   map[string]*entityType
+	sets    map[string]*entitySet
+	order   []string // set names in metadata order
+}
+
+func newModel(schemas []*gpt5mini.Schema) (*model, error) {
+	m := &model{filters: filter.NewModel(schemas), types: map[string]*entityType{}, sets: map[string]*entitySet{}}
+	raw := map[string]*gpt5mini.EntityType{}
+	assocs := map[string]*gpt5mini.Association{}
+	for _, s := range schemas {
+		m.v3 = m.v3 || s.V3
+		for _, e := range s.EntityTypes {
+			raw[s.Namespace+"."+e.Name] = e
+		}
+		for _, a := range s.Associations {
+			assocs[s.Namespace+"."+a.Name] = a
+		}
+	}
+	for qn, e := range raw {
+		t := &entityType{name: qn, props: map[string]string{}, navs: map[string]*navigation{}}
+		for cur, depth := e, 0; cur != nil && depth < 32; cur, depth = raw[cur.BaseType], depth+1 {
+			if len(t.keys) == 0 {
+				t.keys = cur.Keys
+			}
+			for _, p := range cur.Properties {
+				t.props[p.Name] = p.Type
+			}
+			for _, np := range cur.NavPropsV4 {
+				nav := &navigation{name: np.Name, target: strings.TrimSuffix(strings.TrimPrefix(np.Type, "Collection("), ")")}
+				nav.coll = nav.target != np.Type
+				for _, c := range np.Constraints {
+					nav.pairs = append(nav.pairs, [2]string{c.Property, c.ReferencedProperty})
+				}
+				t.navs[np.Name] = nav
+			}
+			for _, np := range cur.NavPropsV3 {
+				t.navs[np.Name] = v3Navigation(np, assocs[np.Relationship])
+			}
+		}
+		names := make([]string, 0, len(t.props))
+		for name := range t.props {
+			names = append(names, name)
+		}
+		for name, alias := range sapgen.PropertyAlias(sapgen.AliasWire).Aliases(names) {
+			if t.aliases == nil {
+				t.aliases, t.wire = map[string]string{}, map[string]string{}
+			}
+			t.aliases[alias], t.wire[name] = name, alias
+		}
+		m.types[qn] = t
+	}
+	// a collection usually has its constraint on the partner leading back
+	for _, e := range raw {
+		for _, np := range e.NavPropsV4 {
+			nav := m.types[e.Namespace+"."+e.Name].navs[np.Name]
+			target := m.types[nav.target]
+			if len(nav.pairs) > 0 || np.Partner == "" || target == nil || target.navs[np.Partner] == nil {
+				continue
+			}
+			for _, p := range target.navs[np.Partner].pairs {
+				nav.pairs = append(nav.pairs, [2]string{p[1], p[0]})
+			}
+		}
+	}
+	for _, s := range schemas {
+		for _, es := range s.EntitySets {
+			t := m.types[es.EntityType]
+			if t == nil {
+				return nil, fmt.Errorf("mock: entity set %s: unknown type %s", es.Name, es.EntityType)
+			}
+			m.sets[es.Name] = &entitySet{name: es.Name, typ: t, bindings: es.Bindings}
+			m.order = append(m.order, es.Name)
+		}
+	}
+	return m, nil
+}
+
+// v3Navigation resolves a v3 navigation property through its association.
+func v3Navigation(np *gpt5mini.NavPropertyV3, a *gpt5mini.Association) *navigation {
+	nav := &navigation{name: np.Name}
+	if a == nil {
+		return nav
+	}
+	for _, end := range a.Ends {
+		if end.Role == np.ToRole {
+			nav.target, nav.coll = end.Type, end.Multiplicity == "*"
+		}
+	}
+	from, to := a.Principal, a.Dependent
+	if np.FromRole == a.Dependent.Role {
+		from, to = to, from
+	}
+	if from.Role == np.FromRole && len(from.Properties) == len(to.Properties) {
+		for i := range from.Properties {
+			nav.pairs = append(nav.pairs, [2]string{from.Properties[i], to.Properties[i]})
+		}
+	}
+	return nav
+}
+
+// targetSet returns the set the entities of nav are in: its binding, or
+// the first set of the target type.
+func (m *model) targetSet(from *entitySet, nav *navigation) *entitySet {
+	if name, ok := from.bindings[nav.name]; ok {
+		return m.sets[name]
+	}
+	for _, name := range m.order {
+		if s := m.sets[name]; s.typ.name == nav.target {
+			return s
+		}
+	}
+	return nil
+}
+
+// related returns the rows of the target set nav leads to from data; ok
+// is false when the metadata does not say how to find them.
+func (m *model) related(from *entitySet, nav *navigation, data map[string]any) (rows []*row, target *entitySet, ok bool) {
+	target = m.targetSet(from, nav)
+	if len(nav.pairs) == 0 || target == nil {
+		return nil, target, false
+	}
+	for _, r := range target.rows {
+		match := true
+		for _, p := range nav.pairs {
+			if c, ok := filter.Compare(data[p[0]], r.data[p[1]]); !ok || c != 0 {
+				match = false
+				break
+			}
+		}
+		if match {
+			rows = append(rows, r)
+		}
+	}
+	return rows, target, true
+}
+
+// find returns the row of s with key, which maps each key property to
+// its value.
+func (s *entitySet) find(key map[string]any) (int, *row) {
+	for i, r := range s.rows {
+		if keyMatches(r.data, key) {
+			return i, r
+		}
+	}
+	return -1, nil
+}
+
+func keyMatches(data, key map[string]any) bool {
+	for name, v := range key {
+		if c, ok := filter.Compare(data[name], v); !ok || c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// property returns the metadata name of the property name, which may be
+// its alias.
+func (t *entityType) property(name string) string {
+	if _, ok := t.props[name]; ok {
+		return name
+	}
+	if meta, ok := t.aliases[name]; ok {
+		return meta
+	}
+	return name
+}
+
+// wireName returns the name the property name is sent under: its alias,
+// or name when it has none.
+func (t *entityType) wireName(name string) string {
+	if alias, ok := t.wire[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// keyOf returns the key values of data; ok is false when one is missing.
+func (t *entityType) keyOf(data map[string]any) (map[string]any, bool) {
+	key := map[string]any{}
+	for _, k := range t.keys {
+		if data[k] == nil {
+			return nil, false
+		}
+		key[k] = data[k]
+	}
+	return key, true
+}
+
+// parseKey reads the key predicate of a URL, the part between the
+// parentheses: 42, 'C001', 42L (v3) or ItemCode='A',PriceList=1.
+func (t *entityType) parseKey(s string) (map[string]any, error) {
+	key := map[string]any{}
+	for _, part := range splitTop(s, ',') {
+		name, value := "", part
+		if n, v, ok := strings.Cut(part, "="); ok && !strings.ContainsRune(n, '\'') {
+			name, value = strings.TrimSpace(n), v
+		} else if len(t.keys) == 1 {
+			name = t.keys[0]
+		}
+		if !contains(t.keys, name) {
+			return nil, fmt.Errorf("%s is not a key property of %s", part, t.name)
+		}
+		lit, err := filter.ParseLiteral(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		key[name] = lit.Value
+	}
+	if len(key) != len(t.keys) {
+		return nil, fmt.Errorf("key of %s needs %s", t.name, strings.Join(t.keys, ", "))
+	}
+	return key, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isIntegral reports whether an Edm type is an integer, for generated keys.
+func isIntegral(edm string) bool {
+	switch edm {
+	case "Edm.Int16", "Edm.Int32", "Edm.Int64", "Edm.Byte", "Edm.SByte":
+		return true
+	}
+	return false
+}
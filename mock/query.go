@@ -0,0 +1,369 @@
+package mock
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dissemblir/sapModelsGenerator/filter"
+)
+
+// queryOptions are the system query options of a request, or those of an
+// expanded navigation property: $expand=DocumentLines($top=5).
+type queryOptions struct {
+	filter  filter.Expr
+	paths   [][]string // the property paths of filter; see withRelated
+	orderBy []orderItem
+	top     int // -1 for all
+	skip    int
+	count   bool     // $count=true, or $inlinecount=allpages in v3
+	selects []string // nil selects every property
+	expand  []*expandItem
+}
+
+type orderItem struct {
+	path []string
+	desc bool
+}
+
+type expandItem struct {
+	nav  *navigation
+	opts *queryOptions
+}
+
+// parseQuery reads the system query options of q for entities of t.
+func (m *model) parseQuery(q url.Values, t *entityType) (*queryOptions, error) {
+	opts := map[string]string{}
+	for name, v := range q {
+		if !strings.HasPrefix(name, "$") {
+			continue // custom options
+		}
+		switch name {
+		case "$filter", "$select", "$expand", "$orderby", "$top", "$skip", "$count", "$inlinecount":
+			opts[name] = v[0]
+		case "$format", "$skiptoken":
+		default:
+			return nil, fmt.Errorf("query option %s is not supported", name)
+		}
+	}
+	return m.parseOptions(opts, t)
+}
+
+func (m *model) parseOptions(opts map[string]string, t *entityType) (*queryOptions, error) {
+	o := &queryOptions{top: -1}
+	var err error
+	if s, ok := opts["$filter"]; ok {
+		if o.filter, err = m.filters.Parse(t.name, s); err != nil {
+			return nil, fmt.Errorf("$filter: %w", err)
+		}
+		filterPaths(o.filter, nil, &o.paths)
+	}
+	for name, n := range map[string]*int{"$top": &o.top, "$skip": &o.skip} {
+		if s, ok := opts[name]; ok {
+			if *n, err = strconv.Atoi(s); err != nil || *n < 0 {
+				return nil, fmt.Errorf("%s: %q is not a count", name, s)
+			}
+		}
+	}
+	o.count = opts["$count"] == "true" || opts["$inlinecount"] == "allpages"
+	if s := opts["$orderby"]; s != "" {
+		for _, item := range splitTop(s, ',') {
+			path, dir, _ := strings.Cut(strings.TrimSpace(item), " ")
+			segs := strings.Split(path, "/")
+			segs[0] = t.property(segs[0])
+			if _, ok := t.props[segs[0]]; !ok {
+				return nil, fmt.Errorf("$orderby: %s has no property %s", t.name, path)
+			}
+			o.orderBy = append(o.orderBy, orderItem{segs, strings.TrimSpace(dir) == "desc"})
+		}
+	}
+	if s := opts["$expand"]; s != "" {
+		for _, item := range splitTop(s, ',') {
+			if err := m.addExpand(o, t, strings.TrimSpace(item)); err != nil {
+				return nil, fmt.Errorf("$expand: %w", err)
+			}
+		}
+	}
+	if s, ok := opts["$select"]; ok && s != "*" {
+		o.selects = []string{}
+		for _, item := range splitTop(s, ',') {
+			if err := o.addSelect(t, strings.TrimSpace(item)); err != nil {
+				return nil, fmt.Errorf("$select: %w", err)
+			}
+		}
+	}
+	return o, nil
+}
+
+// addExpand adds Nav, Nav(options) or the v3 path Nav/Nav2 to o.
+func (m *model) addExpand(o *queryOptions, t *entityType, item string) error {
+	name, rest, nested := strings.Cut(item, "(")
+	path := strings.Split(name, "/")
+	nav := t.navs[path[0]]
+	if nav == nil || m.types[nav.target] == nil {
+		return fmt.Errorf("%s has no navigation property %s", t.name, path[0])
+	}
+	e := o.expanded(nav.name)
+	if e == nil {
+		e = &expandItem{nav: nav, opts: &queryOptions{top: -1}}
+		o.expand = append(o.expand, e)
+	}
+	target := m.types[nav.target]
+	if len(path) > 1 {
+		return m.addExpand(e.opts, target, strings.Join(path[1:], "/"))
+	}
+	if !nested {
+		return nil
+	}
+	opts := map[string]string{}
+	for _, opt := range splitTop(strings.TrimSuffix(rest, ")"), ';') {
+		k, v, _ := strings.Cut(opt, "=")
+		opts[strings.TrimSpace(k)] = v
+	}
+	sub, err := m.parseOptions(opts, target)
+	if err != nil {
+		return fmt.Errorf("%s: %w", nav.name, err)
+	}
+	e.opts = sub
+	return nil
+}
+
+// addSelect adds a property, or the v3 path Nav/Property of an expanded
+// navigation property, to the selection of o.
+func (o *queryOptions) addSelect(t *entityType, item string) error {
+	first, rest, nested := strings.Cut(item, "/")
+	if nested {
+		e := o.expanded(first)
+		if e == nil {
+			return fmt.Errorf("%s needs $expand=%s", item, first)
+		}
+		if e.opts.selects == nil {
+			e.opts.selects = []string{}
+		}
+		if rest != "*" { // checked when the expanded entities are rendered
+			e.opts.selects = append(e.opts.selects, strings.Split(rest, "/")[0])
+		}
+		return nil
+	}
+	first = t.property(first)
+	if _, ok := t.props[first]; !ok && t.navs[first] == nil {
+		return fmt.Errorf("%s has no property %s", t.name, first)
+	}
+	o.selects = append(o.selects, first)
+	return nil
+}
+
+func (o *queryOptions) expanded(nav string) *expandItem {
+	for _, e := range o.expand {
+		if e.nav.name == nav {
+			return e
+		}
+	}
+	return nil
+}
+
+// splitTop splits s at sep outside parentheses and quoted literals.
+func splitTop(s string, sep byte) []string {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// filterPaths adds the property paths of e to paths, with the lambda
+// variables in vars replaced by the paths of their collections:
+// DocumentLines/any(d: d/ItemCode eq 'A') gives DocumentLines/ItemCode.
+func filterPaths(e filter.Expr, vars map[string][]string, paths *[][]string) {
+	switch e := e.(type) {
+	case *filter.Path:
+		if segs := rootPath(e, vars); len(segs) > 0 {
+			*paths = append(*paths, segs)
+		}
+	case *filter.Unary:
+		filterPaths(e.X, vars, paths)
+	case *filter.Binary:
+		filterPaths(e.Left, vars, paths)
+		filterPaths(e.Right, vars, paths)
+	case *filter.List:
+		for _, item := range e.Items {
+			filterPaths(item, vars, paths)
+		}
+	case *filter.Call:
+		for _, arg := range e.Args {
+			filterPaths(arg, vars, paths)
+		}
+	case *filter.Lambda:
+		filterPaths(e.Collection, vars, paths)
+		if e.Body != nil {
+			inner := maps.Clone(vars)
+			if inner == nil {
+				inner = map[string][]string{}
+			}
+			inner[e.Var] = rootPath(e.Collection, vars)
+			filterPaths(e.Body, inner, paths)
+		}
+	}
+}
+
+// rootPath returns the segments of p from the entity; see filterPaths.
+func rootPath(p *filter.Path, vars map[string][]string) []string {
+	segs := p.Segments
+	if v, ok := vars[segs[0]]; ok {
+		return append(append([]string{}, v...), segs[1:]...)
+	}
+	if segs[0] == "$it" {
+		return segs[1:]
+	}
+	return segs
+}
+
+// withRelated returns data, a row of set, with the navigation properties
+// the paths go through replaced by the entities they lead to, so that a
+// $filter such as BusinessPartner/CardName eq 'A' sees them. Navigation
+// properties the metadata gives no constraints for keep the value stored
+// inline.
+func (m *model) withRelated(set *entitySet, data map[string]any, paths [][]string) map[string]any {
+	rest := map[string][][]string{}
+	for _, p := range paths {
+		if set.typ.navs[p[0]] == nil {
+			continue
+		}
+		if _, ok := rest[p[0]]; !ok {
+			rest[p[0]] = nil
+		}
+		if len(p) > 1 {
+			rest[p[0]] = append(rest[p[0]], p[1:])
+		}
+	}
+	if len(rest) == 0 {
+		return data
+	}
+	out := maps.Clone(data)
+	for name, sub := range rest {
+		nav := set.typ.navs[name]
+		rows, target, ok := m.related(set, nav, data)
+		if !ok {
+			continue
+		}
+		list := []any{}
+		for _, r := range rows {
+			list = append(list, m.withRelated(target, r.data, sub))
+		}
+		switch {
+		case nav.coll:
+			out[name] = list
+		case len(list) > 0:
+			out[name] = list[0]
+		default:
+			out[name] = nil
+		}
+	}
+	return out
+}
+
+// apply filters and orders rows of set and takes the page of $skip and
+// $top; count is the number of rows that matched the filter.
+func (o *queryOptions) apply(m *model, set *entitySet, rows []*row) (page []*row, count int, err error) {
+	for _, r := range rows {
+		if o.filter != nil {
+			ok, err := filter.Match(o.filter, m.withRelated(set, r.data, o.paths))
+			if err != nil {
+				return nil, 0, fmt.Errorf("$filter: %w", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		page = append(page, r)
+	}
+	count = len(page)
+	if len(o.orderBy) > 0 {
+		sort.SliceStable(page, func(i, j int) bool {
+			for _, item := range o.orderBy {
+				a := filter.Value(page[i].data, item.path...)
+				b := filter.Value(page[j].data, item.path...)
+				c, ok := filter.Compare(a, b)
+				if !ok && (a == nil) != (b == nil) { // null sorts first
+					c = 1
+					if a == nil {
+						c = -1
+					}
+				}
+				if c != 0 {
+					return c < 0 != item.desc
+				}
+			}
+			return false
+		})
+	}
+	page = page[min(o.skip, len(page)):]
+	if o.top >= 0 && o.top < len(page) {
+		page = page[:o.top]
+	}
+	return page, count, nil
+}
+
+// render returns the JSON form of r, a row of set: the selected
+// properties under the names Service Layer sends, the expanded navigation
+// properties and the ETag.
+func (s *Server) render(set *entitySet, r *row, o *queryOptions) (map[string]any, error) {
+	out := map[string]any{}
+	for name, v := range r.data {
+		if _, isNav := set.typ.navs[name]; isNav {
+			continue
+		}
+		wire := set.typ.wireName(name)
+		if o.selects == nil || contains(o.selects, name) || contains(o.selects, wire) {
+			out[wire] = v
+		}
+	}
+	out[s.etagKey()] = r.etag()
+	for _, e := range o.expand {
+		rows, target, ok := s.model.related(set, e.nav, r.data)
+		if !ok {
+			v := r.data[e.nav.name] // stored inline, as posted
+			if v == nil && e.nav.coll {
+				v = []any{}
+			}
+			out[e.nav.name] = v
+			continue
+		}
+		rows, _, err := e.opts.apply(s.model, target, rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.nav.name, err)
+		}
+		list := []any{}
+		for _, related := range rows {
+			v, err := s.render(target, related, e.opts)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		switch {
+		case e.nav.coll:
+			out[e.nav.name] = list
+		case len(list) > 0:
+			out[e.nav.name] = list[0]
+		default:
+			out[e.nav.name] = nil
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"dissemblir/sapModelsGenerator/mock"
+)
+
+// ========================= mock-server =========================
+
+// runMockServer implements `mock-server --metadata metadata.xml`: an
+// in-memory OData service for the entity sets of the metadata, seeded
+// from --fixtures.
+func runMockServer(args []string) error {
+	fs := flag.NewFlagSet("mock-server", flag.ExitOnError)
+	metadataPath := fs.String("metadata", "metadata.xml", "Path to the EDMX metadata to serve")
+	addr := fs.String("addr", "localhost:50000", "Address to listen on")
+	root := fs.String("root", "/b1s/v2", "Path of the service root")
+	fixtures := fs.String("fixtures", "", "Directory of <Set>.json files, or one JSON file of arrays by set name")
+	pageSize := fs.Int("page-size", 20, "Entities per page of a collection (0: only on Prefer: odata.maxpagesize)")
+	sessions := fs.Bool("sessions", false, "Require the B1SESSION cookie of a Login")
+	fs.Parse(args)
+
+	metadata, err := os.ReadFile(*metadataPath)
+	if err != nil {
+		return err
+	}
+	srv, err := mock.New(metadata, mock.Options{Root: *root, PageSize: *pageSize, Sessions: *sessions})
+	if err != nil {
+		return err
+	}
+	if *fixtures != "" {
+		if err := srv.Load(*fixtures); err != nil {
+			return err
+		}
+	}
+	log.Printf("Serving %s at http://%s%s", *metadataPath, *addr, *root)
+	if err := http.ListenAndServe(*addr, srv); err != nil {
+		return fmt.Errorf("mock-server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package odata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// YesNo is a BoYesNoEnum property read as a boolean (the yesNoBool quirk).
+// It is sent as "tYES" / "tNO"; booleans are accepted as well.
+type YesNo bool
+
+func (y YesNo) MarshalJSON() ([]byte, error) {
+	if y {
+		return []byte(`"tYES"`), nil
+	}
+	return []byte(`"tNO"`), nil
+}
+
+func (y *YesNo) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "null":
+		return nil
+	case `"tYES"`, "true":
+		*y = true
+	case `"tNO"`, "false":
+		*y = false
+	default:
+		return fmt.Errorf("odata: invalid BoYesNoEnum value %s", b)
+	}
+	return nil
+}
+
+// DateLayout is the form Service Layer uses for calendar dates.
+const DateLayout = "2006-01-02"
+
+// dateLayouts are the forms a B1 date arrives in: date only, a timestamp
+// without zone (Edm.DateTime) and RFC 3339.
+var dateLayouts = []string{DateLayout, "2006-01-02T15:04:05", "2006-01-02T15:04:05.999999999", time.RFC3339Nano}
+
+// Date is an Edm.Date or Edm.DateTime holding a calendar date (the b1Dates
+// quirk). The time of day and zone of a timestamp are dropped, so the date
+// is the one the service shows. It is sent as "2006-01-02".
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the Date of the given day.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses any of the forms Service Layer sends dates in.
+func ParseDate(s string) (Date, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return NewDate(t.Date()), nil
+		}
+	}
+	return Date{}, fmt.Errorf("odata: invalid date %q", s)
+}
+
+// String returns the date as "2006-01-02".
+func (d Date) String() string { return d.Format(DateLayout) }
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("odata: invalid date %s", b)
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
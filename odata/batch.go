@@ -0,0 +1,522 @@
+package odata
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Operation is one request of a batch. Send sets StatusCode, Header and
+// Err, and decodes a successful response into the result the operation
+// was added with.
+type Operation struct {
+	Method    string
+	Path      string      // relative to the service root, or a $<ContentID> reference
+	Header    http.Header // request headers
+	Body      any         // sent as JSON; nil for none
+	ContentID string
+
+	StatusCode int
+	Response   http.Header // response headers
+	Err        error       // the request could not be built, or a *StatusError (*ConflictError for 412)
+
+	out any
+}
+
+// Ref is the reference to the entity the operation creates or addresses,
+// for the paths of later operations of the same change set: "$1",
+// "$1/DocumentLines".
+func (o *Operation) Ref() string { return "$" + o.ContentID }
+
+// Result is an operation whose response body is decoded into Value.
+type Result[T any] struct {
+	*Operation
+	Value *T // nil before Send, on errors and for responses without content
+}
+
+// Requests is where batch operations go: a *Batch, or one of its
+// *ChangeSets.
+type Requests interface {
+	// Add adds a request for path, which may carry a query, and returns
+	// its operation; out, when not nil, receives the response body.
+	Add(method, path string, header http.Header, body, out any) *Operation
+	client() *Client
+}
+
+// Batch collects the operations of a $batch request: single requests, run
+// one after the other, and change sets, each applied as a whole or not at
+// all. Build it with NewBatch, send it with Send.
+type Batch struct {
+	// JSON sends the batch in the JSON format of OData 4.01 instead of
+	// multipart/mixed, which Service Layer and older services take.
+	JSON bool
+
+	c      *Client
+	items  []batchItem
+	nextID int
+}
+
+// batchItem is a single operation or a change set.
+type batchItem struct {
+	op  *Operation
+	set *ChangeSet
+}
+
+// ChangeSet is an atomic group of data modification requests in a batch.
+type ChangeSet struct {
+	b   *Batch
+	ops []*Operation
+}
+
+// NewBatch returns an empty batch sent by c.
+func (c *Client) NewBatch() *Batch { return &Batch{c: c} }
+
+func (b *Batch) client() *Client { return b.c }
+
+func (b *Batch) newOp(method, path string, header http.Header, body, out any) *Operation {
+	b.nextID++
+	return &Operation{Method: method, Path: path, Header: header, Body: body, ContentID: strconv.Itoa(b.nextID), out: out}
+}
+
+// Add adds a request outside any change set.
+func (b *Batch) Add(method, path string, header http.Header, body, out any) *Operation {
+	op := b.newOp(method, path, header, body, out)
+	b.items = append(b.items, batchItem{op: op})
+	return op
+}
+
+// ChangeSet adds an empty change set to the batch.
+func (b *Batch) ChangeSet() *ChangeSet {
+	cs := &ChangeSet{b: b}
+	b.items = append(b.items, batchItem{set: cs})
+	return cs
+}
+
+func (cs *ChangeSet) client() *Client { return cs.b.c }
+
+// Add adds a request to the change set.
+func (cs *ChangeSet) Add(method, path string, header http.Header, body, out any) *Operation {
+	op := cs.b.newOp(method, path, header, body, out)
+	cs.ops = append(cs.ops, op)
+	return op
+}
+
+// operations returns the operations of b in order.
+func (b *Batch) operations() []*Operation {
+	var ops []*Operation
+	for _, it := range b.items {
+		if it.op != nil {
+			ops = append(ops, it.op)
+		} else {
+			ops = append(ops, it.set.ops...)
+		}
+	}
+	return ops
+}
+
+// Send posts the batch to $batch. Its error is for the batch as a whole;
+// each operation reports its own outcome in Err and StatusCode.
+func (b *Batch) Send(ctx context.Context) error {
+	for _, op := range b.operations() {
+		if op.Err != nil {
+			return fmt.Errorf("odata: batch %s %s: %w", op.Method, op.Path, op.Err)
+		}
+	}
+	u := b.c.ServiceRoot + "/$batch"
+	var body []byte
+	var contentType string
+	var err error
+	if b.JSON {
+		body, err = b.encodeJSON()
+		contentType = "application/json"
+	} else {
+		body, contentType, err = b.encodeMultipart()
+	}
+	if err != nil {
+		return fmt.Errorf("odata: batch: %w", err)
+	}
+	header := http.Header{"Content-Type": {contentType}}
+	if !b.JSON {
+		header.Set("Accept", "multipart/mixed")
+	}
+	resp, data, err := b.c.roundTrip(ctx, http.MethodPost, u, header, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(http.MethodPost, u, resp.StatusCode, data, nil)
+	}
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case err != nil:
+		err = fmt.Errorf("response content type: %w", err)
+	case mediaType == "application/json":
+		err = b.decodeJSON(data)
+	case strings.HasPrefix(mediaType, "multipart/"):
+		err = b.decodeMultipart(data, params["boundary"])
+	default:
+		err = fmt.Errorf("unexpected response content type %s", mediaType)
+	}
+	if err != nil {
+		return fmt.Errorf("odata: batch: %w", err)
+	}
+	return nil
+}
+
+// requestPath is the path of op as written in a multipart batch: absolute
+// from the host, as Service Layer wants it, or a Content-ID reference.
+func (b *Batch) requestPath(op *Operation) string {
+	if strings.HasPrefix(op.Path, "$") {
+		return op.Path
+	}
+	if root, err := url.Parse(b.c.ServiceRoot); err == nil {
+		return strings.TrimSuffix(root.Path, "/") + "/" + op.Path
+	}
+	return op.Path
+}
+
+func boundary(prefix string) string {
+	var buf [12]byte
+	rand.Read(buf[:])
+	return prefix + "_" + hex.EncodeToString(buf[:])
+}
+
+// encodeMultipart writes b as multipart/mixed and returns the content type.
+func (b *Batch) encodeMultipart() ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(boundary("batch")); err != nil {
+		return nil, "", err
+	}
+	for _, it := range b.items {
+		if it.op != nil {
+			if err := b.writeRequestPart(w, it.op, false); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		var set bytes.Buffer
+		sw := multipart.NewWriter(&set)
+		if err := sw.SetBoundary(boundary("changeset")); err != nil {
+			return nil, "", err
+		}
+		for _, op := range it.set.ops {
+			if err := b.writeRequestPart(sw, op, true); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := sw.Close(); err != nil {
+			return nil, "", err
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"multipart/mixed; boundary=" + sw.Boundary()}})
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write(set.Bytes())
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/mixed; boundary=" + w.Boundary(), nil
+}
+
+// writeRequestPart writes op as an application/http part; operations in
+// change sets carry their Content-ID.
+func (b *Batch) writeRequestPart(w *multipart.Writer, op *Operation, inSet bool) error {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {"application/http"},
+		"Content-Transfer-Encoding": {"binary"},
+	}
+	if inSet {
+		header["Content-ID"] = []string{op.ContentID}
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	var req bytes.Buffer
+	fmt.Fprintf(&req, "%s %s HTTP/1.1\r\n", op.Method, b.requestPath(op))
+	req.WriteString("Accept: application/json\r\n")
+	var body []byte
+	if op.Body != nil {
+		if body, err = json.Marshal(op.Body); err != nil {
+			return fmt.Errorf("%s %s: %w", op.Method, op.Path, err)
+		}
+		req.WriteString("Content-Type: application/json\r\n")
+	}
+	for _, h := range []http.Header{b.c.Header, op.Header} {
+		for name, values := range h {
+			for _, v := range values {
+				fmt.Fprintf(&req, "%s: %s\r\n", name, v)
+			}
+		}
+	}
+	req.WriteString("\r\n")
+	req.Write(body)
+	_, err = part.Write(req.Bytes())
+	return err
+}
+
+// decodeMultipart matches the parts of a multipart response to the items
+// of b: in order, and by Content-ID within change sets. A change set that
+// failed is answered by one response, which becomes the outcome of each of
+// its operations.
+func (b *Batch) decodeMultipart(data []byte, bound string) error {
+	r := multipart.NewReader(bytes.NewReader(data), bound)
+	for i := 0; ; i++ {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if i >= len(b.items) {
+			return fmt.Errorf("more responses than requests (%d)", len(b.items))
+		}
+		it := b.items[i]
+		mediaType, params, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			resp, body, err := readResponsePart(part)
+			if err != nil {
+				return err
+			}
+			if it.op != nil {
+				it.op.setResponse(resp.StatusCode, resp.Header, body)
+				continue
+			}
+			for _, op := range it.set.ops {
+				op.setResponse(resp.StatusCode, resp.Header, body)
+			}
+			continue
+		}
+		if it.set == nil {
+			return fmt.Errorf("change set response to %s %s", it.op.Method, it.op.Path)
+		}
+		sr := multipart.NewReader(part, params["boundary"])
+		for j := 0; ; j++ {
+			sp, err := sr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			resp, body, err := readResponsePart(sp)
+			if err != nil {
+				return err
+			}
+			op := it.set.byContentID(sp.Header.Get("Content-ID"), j)
+			if op == nil {
+				return fmt.Errorf("response without request in change set (Content-ID %q)", sp.Header.Get("Content-ID"))
+			}
+			op.setResponse(resp.StatusCode, resp.Header, body)
+		}
+	}
+}
+
+// byContentID returns the operation with the Content-ID id, or the i-th
+// when the response has none.
+func (cs *ChangeSet) byContentID(id string, i int) *Operation {
+	for _, op := range cs.ops {
+		if id != "" && op.ContentID == id {
+			return op
+		}
+	}
+	if id == "" && i < len(cs.ops) {
+		return cs.ops[i]
+	}
+	return nil
+}
+
+// readResponsePart reads the HTTP response held by an application/http part.
+func readResponsePart(part io.Reader) (*http.Response, []byte, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("response part: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, fmt.Errorf("response part: %w", err)
+	}
+	return resp, body, nil
+}
+
+// setResponse records the response of op and decodes its body.
+func (op *Operation) setResponse(status int, header http.Header, body []byte) {
+	op.StatusCode, op.Response = status, header
+	if status < 200 || status > 299 {
+		op.Err = statusError(op.Method, op.Path, status, body, op.Header)
+		return
+	}
+	if op.out == nil || len(bytes.TrimSpace(body)) == 0 {
+		return
+	}
+	if err := json.Unmarshal(body, op.out); err != nil {
+		op.Err = fmt.Errorf("odata: %s %s: decode response: %w", op.Method, op.Path, err)
+	}
+}
+
+// jsonRequest is a request of a JSON batch.
+type jsonRequest struct {
+	ID             string            `json:"id"`
+	AtomicityGroup string            `json:"atomicityGroup,omitempty"`
+	DependsOn      []string          `json:"dependsOn,omitempty"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           any               `json:"body,omitempty"`
+}
+
+func (b *Batch) encodeJSON() ([]byte, error) {
+	var reqs []jsonRequest
+	add := func(op *Operation, group string) {
+		headers := map[string]string{}
+		for _, h := range []http.Header{b.c.Header, op.Header} {
+			for name := range h {
+				headers[name] = h.Get(name)
+			}
+		}
+		if op.Body != nil {
+			headers["Content-Type"] = "application/json"
+		}
+		req := jsonRequest{ID: op.ContentID, AtomicityGroup: group, Method: op.Method, URL: op.Path, Headers: headers, Body: op.Body}
+		if id, ok := refID(op.Path); ok {
+			req.DependsOn = []string{id} // required for a $<id> reference
+		}
+		reqs = append(reqs, req)
+	}
+	for i, it := range b.items {
+		if it.op != nil {
+			add(it.op, "")
+			continue
+		}
+		for _, op := range it.set.ops {
+			add(op, "g"+strconv.Itoa(i+1))
+		}
+	}
+	return json.Marshal(map[string][]jsonRequest{"requests": reqs})
+}
+
+// refID returns the request id of a path that starts with a reference,
+// such as $1/DocumentLines.
+func refID(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return "", false
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	id, _, _ = strings.Cut(id, "?")
+	if id == "" || strings.Trim(id, "0123456789") != "" {
+		return "", false
+	}
+	return id, true
+}
+
+func (b *Batch) decodeJSON(data []byte) error {
+	var resp struct {
+		Responses []struct {
+			ID      string            `json:"id"`
+			Status  int               `json:"status"`
+			Headers map[string]string `json:"headers"`
+			Body    json.RawMessage   `json:"body"`
+		} `json:"responses"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	ops := map[string]*Operation{}
+	for _, op := range b.operations() {
+		ops[op.ContentID] = op
+	}
+	for _, r := range resp.Responses {
+		op := ops[r.ID]
+		if op == nil {
+			return fmt.Errorf("response to unknown request %q", r.ID)
+		}
+		header := http.Header{}
+		for name, v := range r.Headers {
+			header.Set(name, v)
+		}
+		op.setResponse(r.Status, header, r.Body)
+	}
+	return nil
+}
+
+// Batch adds the requests of the set to r, a batch or one of its change
+// sets. The generated services wrap it with typed key parameters.
+func (s EntitySet[T]) Batch(r Requests) BatchSet[T] { return BatchSet[T]{Set: s, Requests: r} }
+
+// BatchSet adds requests of an entity set to a batch.
+type BatchSet[T any] struct {
+	Set      EntitySet[T]
+	Requests Requests
+}
+
+// add adds a request for path and query; a path or query that cannot be
+// written fails the operation, and Send with it.
+func (b BatchSet[T]) add(method, path string, pathErr error, query []QueryString, header http.Header, body, out any) *Operation {
+	target, err := b.Requests.client().target(path, query)
+	op := b.Requests.Add(method, target, header, body, out)
+	op.Err = errors.Join(pathErr, err)
+	return op
+}
+
+// Get reads the entity with key.
+func (b BatchSet[T]) Get(key Key, query ...QueryString) *Result[T] {
+	r := &Result[T]{}
+	path, err := b.Set.entityPath(key)
+	r.Operation = b.add(http.MethodGet, path, err, query, nil, nil, &r.Value)
+	return r
+}
+
+// List reads the first page of the entities matching query.
+func (b BatchSet[T]) List(query ...QueryString) *Result[Collection[T]] {
+	r := &Result[Collection[T]]{}
+	r.Operation = b.add(http.MethodGet, b.Set.Name, nil, query, nil, nil, &r.Value)
+	return r
+}
+
+// Create posts entity; Value is the entity the service created.
+func (b BatchSet[T]) Create(entity *T) *Result[T] {
+	r := &Result[T]{}
+	r.Operation = b.add(http.MethodPost, b.Set.Name, nil, nil, nil, untagged(entity), &r.Value)
+	return r
+}
+
+// Update sends a PATCH with body, as EntitySet.Update does.
+func (b BatchSet[T]) Update(key Key, body any) *Operation {
+	path, err := b.Set.entityPath(key)
+	return b.add(http.MethodPatch, path, err, nil, writeHeader(body), untaggedBody(body), nil)
+}
+
+// Replace sends a PUT replacing the whole entity, as EntitySet.Replace does.
+func (b BatchSet[T]) Replace(key Key, entity *T) *Operation {
+	path, err := b.Set.entityPath(key)
+	return b.add(http.MethodPut, path, err, nil, writeHeader(entity), untagged(entity), nil)
+}
+
+// Delete deletes the entity with key.
+func (b BatchSet[T]) Delete(key Key) *Operation {
+	return b.DeleteIfMatch(key, "")
+}
+
+// DeleteIfMatch deletes the entity with key if its ETag is still etag.
+func (b BatchSet[T]) DeleteIfMatch(key Key, etag string) *Operation {
+	path, err := b.Set.entityPath(key)
+	return b.add(http.MethodDelete, path, err, nil, etagHeader(etag), nil, nil)
+}
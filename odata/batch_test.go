@@ -0,0 +1,72 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatchJSONDependsOn(t *testing.T) {
+	var got struct {
+		Requests []struct {
+			ID             string   `json:"id"`
+			AtomicityGroup string   `json:"atomicityGroup"`
+			DependsOn      []string `json:"dependsOn"`
+			URL            string   `json:"url"`
+		} `json:"requests"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("batch body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"responses":[{"id":"1","status":200,"body":{}},{"id":"2","status":201,"body":{}},{"id":"3","status":201,"body":{}}]}`)
+	}))
+	defer ts.Close()
+
+	b := NewClient(ts.URL, ts.Client()).NewBatch()
+	b.JSON = true
+	b.Add(http.MethodGet, "Orders(1)", nil, nil, nil)
+	cs := b.ChangeSet()
+	order := cs.Add(http.MethodPost, "Orders", nil, map[string]any{"CardCode": "C1"}, nil)
+	cs.Add(http.MethodPost, order.Ref()+"/DocumentLines", nil, map[string]any{"ItemCode": "A"}, nil)
+	if err := b.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Requests) != 3 {
+		t.Fatalf("%d requests, want 3", len(got.Requests))
+	}
+	for i, want := range [][]string{nil, nil, {"2"}} {
+		r := got.Requests[i]
+		if len(r.DependsOn) != len(want) || len(want) > 0 && r.DependsOn[0] != want[0] {
+			t.Errorf("request %s (%s): dependsOn %v, want %v", r.ID, r.URL, r.DependsOn, want)
+		}
+	}
+	if got.Requests[2].URL != "$2/DocumentLines" || got.Requests[2].AtomicityGroup != got.Requests[1].AtomicityGroup {
+		t.Errorf("referencing request = %+v", got.Requests[2])
+	}
+}
+
+func TestRefID(t *testing.T) {
+	tests := []struct {
+		path string
+		id   string
+		ok   bool
+	}{
+		{"$1", "1", true},
+		{"$12/DocumentLines", "12", true},
+		{"$3?$select=DocEntry", "3", true},
+		{"$batch", "", false},
+		{"Orders", "", false},
+	}
+	for _, tt := range tests {
+		if id, ok := refID(tt.path); id != tt.id || ok != tt.ok {
+			t.Errorf("refID(%q) = %q, %v", tt.path, id, ok)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package odata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client sends the requests of the generated entity set services. The
+// zero HTTPClient uses http.DefaultClient; tests point ServiceRoot at an
+// httptest.Server and pass its Client.
+type Client struct {
+	ServiceRoot string       // e.g. "https://b1:50000/b1s/v2"
+	HTTPClient  *http.Client // nil uses http.DefaultClient
+	Header      http.Header  // sent with every request
+	V3          bool         // OData v2/v3 service: literals in the v3 forms
+
+	// MaxPageSize asks for pages of that many entities when reading
+	// collections (Prefer: odata.maxpagesize); 0 leaves the service's
+	// default, 20 rows on Service Layer.
+	MaxPageSize int
+
+	// Session, when set, logs in to Service Layer and sends the session
+	// cookies with every request; see NewSession.
+	Session *Session
+}
+
+// NewClient returns a client of the service at serviceRoot.
+func NewClient(serviceRoot string, hc *http.Client) *Client {
+	return &Client{ServiceRoot: strings.TrimSuffix(serviceRoot, "/"), HTTPClient: hc}
+}
+
+// QueryString is the query of a request: a *Query or url.Values.
+type QueryString interface {
+	Encode() string
+}
+
+// KeyPart is one property of an entity key.
+type KeyPart struct {
+	Name  string
+	Type  string // Edm type of the property, for the literal form
+	Value any
+}
+
+// Key addresses an entity within its set. A single part is written as
+// (value), several as (Name=value,...).
+type Key []KeyPart
+
+// predicate returns the key predicate, e.g. ('C001') or (DocEntry=1,LineNum=0).
+func (k Key) predicate(v3 bool) (string, error) {
+	parts := make([]string, len(k))
+	for i, p := range k {
+		lit, err := formatLiteral(p.Value, p.Type, v3)
+		if err != nil {
+			return "", fmt.Errorf("key %s: %w", p.Name, err)
+		}
+		parts[i] = escapePath(lit)
+		if len(k) > 1 {
+			parts[i] = p.Name + "=" + parts[i]
+		}
+	}
+	return "(" + strings.Join(parts, ",") + ")", nil
+}
+
+// escapePath percent-encodes the bytes a path segment cannot hold; quotes,
+// parentheses and the other sub-delimiters of a key predicate stay as they are.
+func escapePath(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			strings.IndexByte("-._~!$&'()*+,;=:@", c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// StatusError is returned for a response outside 2xx. It wraps the error
+// payload of the body, so errors.Is matches the sentinels of B1Errors; a
+// 404 is ErrNotFound too.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+	OData      *Error // the error payload of Body; nil when it has none
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("odata: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.OData != nil {
+		msg += ": " + e.OData.Error()
+	} else if body := strings.TrimSpace(string(e.Body)); body != "" {
+		msg += ": " + body
+	}
+	return msg
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.OData == nil {
+		return nil
+	}
+	return e.OData
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Do sends a request for path (relative to ServiceRoot) with body encoded
+// as JSON, and decodes a response body into out when out is not nil.
+// header is added to the request; it may be nil.
+func (c *Client) Do(ctx context.Context, method, path string, query []QueryString, header http.Header, body, out any) error {
+	target, err := c.target(path, query)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, method, c.ServiceRoot+"/"+target, header, body, out)
+}
+
+// target returns path with the encoded query appended.
+func (c *Client) target(path string, query []QueryString) (string, error) {
+	var qs []string
+	for _, q := range query {
+		if q == nil {
+			continue
+		}
+		s := q.Encode()
+		if bq, ok := q.(*Query); ok {
+			var err error
+			if s, err = bq.encode(c.V3); err != nil {
+				return "", err
+			}
+		}
+		if s != "" {
+			qs = append(qs, s)
+		}
+	}
+	if len(qs) > 0 {
+		path += "?" + strings.Join(qs, "&")
+	}
+	return path, nil
+}
+
+// send sends a request for the URL u; see Do.
+func (c *Client) send(ctx context.Context, method, u string, header http.Header, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("odata: %s %s: %w", method, u, err)
+		}
+		header = withHeader(header, "Content-Type", "application/json")
+	}
+	resp, data, err := c.roundTrip(ctx, method, u, header, data)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(method, u, resp.StatusCode, data, header)
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("odata: %s %s: decode response: %w", method, u, err)
+	}
+	return nil
+}
+
+// roundTrip sends body (nil for none) to u and returns the response with
+// its body read. Accept is application/json unless header says otherwise;
+// c.Header and then header are added to the request. With a Session, it
+// logs in first when needed, and once more on 401 before sending again.
+func (c *Client) roundTrip(ctx context.Context, method, u string, header http.Header, body []byte) (*http.Response, []byte, error) {
+	for retried := false; ; retried = true {
+		var gen int
+		var cookies []*http.Cookie
+		if s := c.Session; s != nil {
+			var ok bool
+			if gen, cookies, ok = s.current(); !ok {
+				if err := s.refresh(ctx, c, gen); err != nil {
+					return nil, nil, err
+				}
+				gen, cookies, _ = s.current()
+			}
+		}
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		for _, h := range []http.Header{c.Header, header} {
+			for name, values := range h {
+				req.Header[name] = values
+			}
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		resp, data, err := c.do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s := c.Session; s != nil {
+			if resp.StatusCode == http.StatusUnauthorized && !retried {
+				if err := s.refresh(ctx, c, gen); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			s.update(resp, gen)
+		}
+		return resp, data, nil
+	}
+}
+
+// do sends req with the HTTP client of c and reads the response body.
+func (c *Client) do(req *http.Request) (*http.Response, []byte, error) {
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("odata: %s %s: %w", req.Method, req.URL, err)
+	}
+	return resp, data, nil
+}
+
+// withHeader returns a copy of h with name set to value.
+func withHeader(h http.Header, name, value string) http.Header {
+	out := h.Clone()
+	if out == nil {
+		out = http.Header{}
+	}
+	out.Set(name, value)
+	return out
+}
+
+// EntitySet is the entity set Name holding entities of type T. The
+// generated services wrap it with typed key parameters.
+type EntitySet[T any] struct {
+	Client *Client
+	Name   string
+}
+
+// NewEntitySet returns the entity set name of c.
+func NewEntitySet[T any](c *Client, name string) EntitySet[T] {
+	return EntitySet[T]{Client: c, Name: name}
+}
+
+func (s EntitySet[T]) entityPath(key Key) (string, error) {
+	pred, err := key.predicate(s.Client.V3)
+	if err != nil {
+		return "", fmt.Errorf("odata: %s: %w", s.Name, err)
+	}
+	return s.Name + pred, nil
+}
+
+// Get reads the entity with key.
+func (s EntitySet[T]) Get(ctx context.Context, key Key, query ...QueryString) (*T, error) {
+	path, err := s.entityPath(key)
+	if err != nil {
+		return nil, err
+	}
+	var out T
+	if err := s.Client.Do(ctx, http.MethodGet, path, query, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// List reads the entities matching query. Only the first page is returned
+// when the service pages the result; see Page and All.
+func (s EntitySet[T]) List(ctx context.Context, query ...QueryString) ([]T, error) {
+	page, err := s.Page(ctx, query...)
+	if err != nil {
+		return nil, err
+	}
+	return page.Value, nil
+}
+
+// Create posts entity and returns the entity the service created, or nil
+// when the service answers without content (Prefer: return=minimal).
+func (s EntitySet[T]) Create(ctx context.Context, entity *T) (*T, error) {
+	var out *T
+	if err := s.Client.Do(ctx, http.MethodPost, s.Name, nil, nil, untagged(entity), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update sends a PATCH with body: an odata.Patch (see the generated
+// PatchDelta), a map, or a model whose unset fields are omitted. A Patch,
+// model or map carrying an ETag is sent with If-Match, not in the body,
+// and a change made since fails with a *ConflictError.
+func (s EntitySet[T]) Update(ctx context.Context, key Key, body any) error {
+	path, err := s.entityPath(key)
+	if err != nil {
+		return err
+	}
+	return s.Client.Do(ctx, http.MethodPatch, path, nil, writeHeader(body), untaggedBody(body), nil)
+}
+
+// Replace sends a PUT replacing the whole entity, with If-Match when the
+// entity carries an ETag.
+func (s EntitySet[T]) Replace(ctx context.Context, key Key, entity *T) error {
+	path, err := s.entityPath(key)
+	if err != nil {
+		return err
+	}
+	return s.Client.Do(ctx, http.MethodPut, path, nil, writeHeader(entity), untagged(entity), nil)
+}
+
+// Delete deletes the entity with key.
+func (s EntitySet[T]) Delete(ctx context.Context, key Key) error {
+	return s.DeleteIfMatch(ctx, key, "")
+}
+
+// DeleteIfMatch deletes the entity with key if its ETag is still etag,
+// and fails with a *ConflictError otherwise; "" deletes it in any case.
+func (s EntitySet[T]) DeleteIfMatch(ctx context.Context, key Key, etag string) error {
+	path, err := s.entityPath(key)
+	if err != nil {
+		return err
+	}
+	return s.Client.Do(ctx, http.MethodDelete, path, nil, etagHeader(etag), nil, nil)
+}
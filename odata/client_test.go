@@ -0,0 +1,194 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type clientItem struct {
+	ItemCode string `json:"ItemCode,omitempty"`
+	ItemName string `json:"ItemName,omitempty"`
+}
+
+// recorded is a request as the test server saw it.
+type recorded struct {
+	method, uri, contentType, accept, custom, body string
+}
+
+// recordingServer answers every request with status and body, and records it.
+func recordingServer(t *testing.T, status int, body string) (*Client, *[]recorded) {
+	var reqs []recorded
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, recorded{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get("Accept"), r.Header.Get("X-Custom"), string(b)})
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(ts.Close)
+	c := NewClient(ts.URL+"/b1s/v2/", ts.Client())
+	c.Header = http.Header{"X-Custom": {"1"}}
+	return c, &reqs
+}
+
+func TestKeyPredicate(t *testing.T) {
+	tests := []struct {
+		key    Key
+		v4, v3 string
+	}{
+		{Key{{"ItemCode", "Edm.String", "A 1/2"}}, "('A%201%2F2')", "('A%201%2F2')"},
+		{Key{{"ItemCode", "Edm.String", "O'Neil"}}, "('O''Neil')", "('O''Neil')"},
+		{Key{{"DocEntry", "Edm.Int32", int32(7)}}, "(7)", "(7)"},
+		{Key{{"AbsEntry", "Edm.Int64", int64(7)}}, "(7)", "(7L)"},
+		{Key{{"DocEntry", "Edm.Int32", int32(7)}, {"LineNum", "Edm.Int32", int32(0)}}, "(DocEntry=7,LineNum=0)", "(DocEntry=7,LineNum=0)"},
+	}
+	for _, tt := range tests {
+		for _, v3 := range []bool{false, true} {
+			want := tt.v4
+			if v3 {
+				want = tt.v3
+			}
+			if got, err := tt.key.predicate(v3); err != nil || got != want {
+				t.Errorf("%v (v3=%v): got %q, %v; want %q", tt.key, v3, got, err, want)
+			}
+		}
+	}
+}
+
+func TestEntitySet(t *testing.T) {
+	key := Key{{"ItemCode", "Edm.String", "A1"}}
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		call   func(s EntitySet[clientItem]) (any, error)
+		want   recorded
+		check  func(got any) bool
+	}{
+		{
+			"get", 200, `{"ItemCode":"A1","ItemName":"Apple"}`,
+			func(s EntitySet[clientItem]) (any, error) {
+				return s.Get(context.Background(), key, NewQuery().Select(NewStringField("ItemName")))
+			},
+			recorded{method: "GET", uri: "/b1s/v2/Items('A1')?$select=ItemName"},
+			func(got any) bool { return got.(*clientItem).ItemName == "Apple" },
+		},
+		{
+			"list", 200, `{"value":[{"ItemCode":"A1"},{"ItemCode":"B2"}],"@odata.nextLink":"Items?$skip=2"}`,
+			func(s EntitySet[clientItem]) (any, error) {
+				return s.List(context.Background(), NewQuery().Filter(NewStringField("ItemName").StartsWith("A")).Top(2))
+			},
+			recorded{method: "GET", uri: "/b1s/v2/Items?$filter=startswith%28ItemName%2C%27A%27%29&$top=2"},
+			func(got any) bool { return len(got.([]clientItem)) == 2 },
+		},
+		{
+			"create", 201, `{"ItemCode":"A1","ItemName":"Apple"}`,
+			func(s EntitySet[clientItem]) (any, error) {
+				return s.Create(context.Background(), &clientItem{ItemCode: "A1", ItemName: "Apple"})
+			},
+			recorded{method: "POST", uri: "/b1s/v2/Items", contentType: "application/json", body: `{"ItemCode":"A1","ItemName":"Apple"}`},
+			func(got any) bool { return got.(*clientItem).ItemCode == "A1" },
+		},
+		{
+			"create without content", 204, ``,
+			func(s EntitySet[clientItem]) (any, error) {
+				return s.Create(context.Background(), &clientItem{ItemCode: "A1"})
+			},
+			recorded{method: "POST", uri: "/b1s/v2/Items", contentType: "application/json", body: `{"ItemCode":"A1"}`},
+			func(got any) bool { return got.(*clientItem) == nil },
+		},
+		{
+			"update", 204, ``,
+			func(s EntitySet[clientItem]) (any, error) {
+				return nil, s.Update(context.Background(), key, map[string]any{"ItemName": "Pear"})
+			},
+			recorded{method: "PATCH", uri: "/b1s/v2/Items('A1')", contentType: "application/json", body: `{"ItemName":"Pear"}`},
+			nil,
+		},
+		{
+			"replace", 204, ``,
+			func(s EntitySet[clientItem]) (any, error) {
+				return nil, s.Replace(context.Background(), key, &clientItem{ItemCode: "A1", ItemName: "Pear"})
+			},
+			recorded{method: "PUT", uri: "/b1s/v2/Items('A1')", contentType: "application/json", body: `{"ItemCode":"A1","ItemName":"Pear"}`},
+			nil,
+		},
+		{
+			"delete", 204, ``,
+			func(s EntitySet[clientItem]) (any, error) {
+				return nil, s.Delete(context.Background(), key)
+			},
+			recorded{method: "DELETE", uri: "/b1s/v2/Items('A1')"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		c, reqs := recordingServer(t, tt.status, tt.body)
+		got, err := tt.call(NewEntitySet[clientItem](c, "Items"))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.check != nil && !tt.check(got) {
+			t.Errorf("%s: got %+v", tt.name, got)
+		}
+		if len(*reqs) != 1 {
+			t.Fatalf("%s: %d requests", tt.name, len(*reqs))
+		}
+		r := (*reqs)[0]
+		if r.accept != "application/json" || r.custom != "1" {
+			t.Errorf("%s: Accept %q, X-Custom %q", tt.name, r.accept, r.custom)
+		}
+		r.accept, r.custom = "", ""
+		if r != tt.want {
+			t.Errorf("%s: request\n got %+v\nwant %+v", tt.name, r, tt.want)
+		}
+	}
+}
+
+func TestEntitySetV3(t *testing.T) {
+	c, reqs := recordingServer(t, 200, `{"d":{}}`)
+	c.V3 = true
+	s := NewEntitySet[clientItem](c, "Items")
+	if _, err := s.Get(context.Background(), Key{{"AbsEntry", "Edm.Int64", int64(3)}}, NewQuery().Count()); err != nil {
+		t.Fatal(err)
+	}
+	if uri := (*reqs)[0].uri; uri != "/b1s/v2/Items(3L)?$inlinecount=allpages" {
+		t.Errorf("uri %s", uri)
+	}
+	nested := NewQuery().Expand(NewCollectionField(Path("Lines"), func(p Path) Path { return p }), NewQuery().Top(1))
+	if _, err := s.List(context.Background(), nested); err == nil {
+		t.Error("v3 nested $top: no error")
+	}
+	if len(*reqs) != 1 {
+		t.Errorf("a query v3 cannot express was sent")
+	}
+}
+
+func TestEntitySetErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		is     error
+	}{
+		{404, `{"error":{"code":"-2028","message":"No matching records found"}}`, ErrNotFound},
+		{404, ``, ErrNotFound},
+		{400, `{"error":{"code":-2035,"message":{"lang":"en-us","value":"This entry already exists"}}}`, ErrAlreadyExists},
+	}
+	for _, tt := range tests {
+		c, _ := recordingServer(t, tt.status, tt.body)
+		_, err := NewEntitySet[clientItem](c, "Items").Get(context.Background(), Key{{"ItemCode", "Edm.String", "X"}})
+		var se *StatusError
+		if !errors.As(err, &se) || se.StatusCode != tt.status || !errors.Is(err, tt.is) {
+			t.Errorf("%d %s: got %v", tt.status, tt.body, err)
+		}
+	}
+
+	c, _ := recordingServer(t, 200, `not JSON`)
+	if _, err := NewEntitySet[clientItem](c, "Items").List(context.Background()); err == nil {
+		t.Error("bad body: no error")
+	}
+}
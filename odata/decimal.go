@@ -0,0 +1,103 @@
+// Package odata is the runtime support imported by the generated Go models.
+package odata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MarshalDecimalAsString makes Rat values marshal as JSON strings, which is
+// what a service expects when the request is sent with IEEE754Compatible=true.
+// It mirrors decimal.MarshalJSONWithoutQuotes in github.com/shopspring/decimal.
+var MarshalDecimalAsString = false
+
+// Rat is an exact Edm.Decimal backed by math/big.Rat. It unmarshals from both
+// JSON numbers and numeric strings (IEEE754Compatible payloads). The big.Rat
+// is never changed once set, so copies of a Rat are independent values; the
+// zero Rat is 0.
+type Rat struct {
+	r *big.Rat
+}
+
+// NewRat parses a decimal literal such as "12.50".
+func NewRat(s string) (Rat, error) {
+	r, ok := parseDecimal(s)
+	if !ok {
+		return Rat{}, fmt.Errorf("odata: invalid decimal %q", s)
+	}
+	return Rat{r}, nil
+}
+
+// RatFromBig returns the value of x; later changes of x do not change it.
+func RatFromBig(x *big.Rat) Rat {
+	return Rat{new(big.Rat).Set(x)}
+}
+
+// parseDecimal reads a decimal literal, with an exponent or not; SetString
+// also takes fractions such as "1/3", which are not decimals.
+func parseDecimal(s string) (*big.Rat, bool) {
+	if strings.Contains(s, "/") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// Big returns a copy of the value.
+func (r Rat) Big() *big.Rat {
+	if r.r == nil {
+		return new(big.Rat)
+	}
+	return new(big.Rat).Set(r.r)
+}
+
+// Cmp compares r and y as -1, 0 or +1.
+func (r Rat) Cmp(y Rat) int { return r.Big().Cmp(y.Big()) }
+
+// Sign returns -1, 0 or +1 for a negative, zero or positive value.
+func (r Rat) Sign() int { return r.Big().Sign() }
+
+func (r *Rat) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	x, ok := parseDecimal(s)
+	if !ok {
+		return fmt.Errorf("odata: invalid decimal %s", b)
+	}
+	r.r = x
+	return nil
+}
+
+func (r Rat) MarshalJSON() ([]byte, error) {
+	s := r.String()
+	if MarshalDecimalAsString {
+		return json.Marshal(s)
+	}
+	return []byte(s), nil
+}
+
+// String formats the value as a plain decimal literal without exponent.
+// Values that have no finite decimal expansion are rounded to 20 places.
+func (r Rat) String() string {
+	x := r.Big()
+	prec, exact := x.FloatPrec()
+	if !exact {
+		prec = 20
+	}
+	return x.FloatString(prec)
+}
+
+// Scale reports the number of fractional digits in the exact value.
+func (r Rat) Scale() int {
+	prec, _ := r.Big().FloatPrec()
+	return prec
+}
@@ -0,0 +1,63 @@
+package odata
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestRatUnmarshal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{`12.50`, "12.5", true},
+		{`"12.50"`, "12.5", true},
+		{`1e3`, "1000", true},
+		{`"-0.001"`, "-0.001", true},
+		{`"1/3"`, "", false},
+		{`1/3`, "", false},
+		{`"abc"`, "", false},
+	}
+	for _, tt := range tests {
+		var r Rat
+		err := json.Unmarshal([]byte(tt.in), &r)
+		if (err == nil) != tt.ok {
+			t.Errorf("Unmarshal(%s): err = %v, want ok = %v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && r.String() != tt.want {
+			t.Errorf("Unmarshal(%s) = %s, want %s", tt.in, r, tt.want)
+		}
+	}
+	if _, err := NewRat("2/3"); err == nil {
+		t.Error(`NewRat("2/3") accepted a fraction`)
+	}
+}
+
+func TestRatCopies(t *testing.T) {
+	x := big.NewRat(5, 2)
+	a := RatFromBig(x)
+	x.SetInt64(7)
+	b := a
+	if err := json.Unmarshal([]byte(`"1.25"`), &b); err != nil {
+		t.Fatal(err)
+	}
+	a.Big().SetInt64(9)
+	if a.String() != "2.5" || b.String() != "1.25" {
+		t.Errorf("a = %s, b = %s; want 2.5 and 1.25", a, b)
+	}
+}
+
+func TestRatMarshal(t *testing.T) {
+	r, _ := NewRat("0.10")
+	b, _ := json.Marshal(struct{ V Rat }{r})
+	if string(b) != `{"V":0.1}` {
+		t.Errorf("Marshal = %s", b)
+	}
+	var zero Rat
+	if zero.String() != "0" || zero.Scale() != 0 {
+		t.Errorf("zero Rat = %s", zero)
+	}
+}
@@ -0,0 +1,133 @@
+package odata
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error is the error payload of a response, {"error": {...}}: the v4 form
+// with a message string, or the v3 form (also Service Layer v1 and v2)
+// with a numeric code and {"lang", "value"} as message.
+type Error struct {
+	Code       string // numeric codes in decimal, e.g. "-2028"
+	Message    string
+	Lang       string // language of Message, v3 only
+	Target     string
+	Details    []ErrorDetail
+	InnerError json.RawMessage // the service's debugging information, unparsed
+}
+
+// ErrorDetail is one of the details of an Error.
+type ErrorDetail struct {
+	Code    string
+	Message string
+	Target  string
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Code != "" {
+		msg = e.Code + " " + msg
+	}
+	if e.Target != "" {
+		msg += " (" + e.Target + ")"
+	}
+	return strings.TrimSpace(msg)
+}
+
+// Is reports whether target is the sentinel B1Errors maps the code to.
+func (e *Error) Is(target error) bool {
+	s, ok := B1Errors[e.Code]
+	return ok && s == target
+}
+
+// Sentinel errors of Service Layer, for errors.Is on the errors of the
+// client and of batch operations.
+var (
+	ErrNotFound       = errors.New("odata: no matching records found")
+	ErrAlreadyExists  = errors.New("odata: entry already exists")
+	ErrSessionExpired = errors.New("odata: invalid session or session already timeout")
+	ErrLoginFailed    = errors.New("odata: login failed")
+)
+
+// B1Errors maps Service Layer error codes to sentinel errors. Add the
+// codes an application handles before making requests.
+var B1Errors = map[string]error{
+	"-2028":     ErrNotFound,
+	"-2035":     ErrAlreadyExists,
+	"301":       ErrSessionExpired,
+	"100000027": ErrLoginFailed,
+}
+
+// statusError returns the error of a response outside 2xx to a request
+// with header: a *ConflictError for 412, a *StatusError otherwise.
+func statusError(method, u string, code int, body []byte, header http.Header) error {
+	err := &StatusError{Method: method, URL: u, StatusCode: code, Body: body, OData: ParseError(body)}
+	if code == http.StatusPreconditionFailed {
+		return &ConflictError{StatusError: err, IfMatch: header.Get("If-Match")}
+	}
+	return err
+}
+
+// ParseError reads the error payload in body; it returns nil when body
+// holds none.
+func ParseError(body []byte) *Error {
+	var payload struct {
+		V4 *rawError `json:"error"`
+		V3 *rawError `json:"odata.error"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return nil
+	}
+	raw := payload.V4
+	if raw == nil {
+		raw = payload.V3
+	}
+	if raw == nil {
+		return nil
+	}
+	e := &Error{Code: codeText(raw.Code), Target: raw.Target, InnerError: raw.InnerError}
+	e.Message, e.Lang = messageText(raw.Message)
+	for _, d := range raw.Details {
+		msg, _ := messageText(d.Message)
+		e.Details = append(e.Details, ErrorDetail{Code: codeText(d.Code), Message: msg, Target: d.Target})
+	}
+	return e
+}
+
+// rawError is an error payload in either form.
+type rawError struct {
+	Code       json.RawMessage `json:"code"`    // a string (v4) or a number (v3, Service Layer)
+	Message    json.RawMessage `json:"message"` // a string (v4) or {"lang", "value"} (v3)
+	Target     string          `json:"target"`
+	Details    []rawError      `json:"details"`
+	InnerError json.RawMessage `json:"innererror"`
+}
+
+func codeText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+func messageText(raw json.RawMessage) (msg, lang string) {
+	if json.Unmarshal(raw, &msg) == nil {
+		return msg, ""
+	}
+	var v3 struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &v3) == nil {
+		return v3.Value, v3.Lang
+	}
+	return "", ""
+}
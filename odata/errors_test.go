@@ -0,0 +1,100 @@
+package odata
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *Error // nil for none
+		is   error
+	}{
+		{
+			"v4",
+			`{"error":{"code":"-2028","message":"No matching records found","target":"CardCode","details":[{"code":"1","message":"detail"}]}}`,
+			&Error{Code: "-2028", Message: "No matching records found", Target: "CardCode", Details: []ErrorDetail{{Code: "1", Message: "detail"}}},
+			ErrNotFound,
+		},
+		{
+			"Service Layer, numeric code",
+			`{"error":{"code":-2035,"message":{"lang":"en-us","value":"This entry already exists in the following tables"}}}`,
+			&Error{Code: "-2035", Message: "This entry already exists in the following tables", Lang: "en-us"},
+			ErrAlreadyExists,
+		},
+		{
+			"v3",
+			`{"odata.error":{"code":"301","message":{"lang":"en-us","value":"Invalid session or session already timeout."}}}`,
+			&Error{Code: "301", Message: "Invalid session or session already timeout.", Lang: "en-us"},
+			ErrSessionExpired,
+		},
+		{
+			"login",
+			`{"error":{"code":100000027,"message":"Login failed"}}`,
+			&Error{Code: "100000027", Message: "Login failed"},
+			ErrLoginFailed,
+		},
+		{
+			"unknown code",
+			`{"error":{"code":"-5002","message":"Quantity falls into negative inventory"}}`,
+			&Error{Code: "-5002", Message: "Quantity falls into negative inventory"},
+			nil,
+		},
+		{"no payload", `{"value":[]}`, nil, nil},
+		{"not JSON", `<html>Bad Gateway</html>`, nil, nil},
+		{"empty", ``, nil, nil},
+	}
+	sentinels := []error{ErrNotFound, ErrAlreadyExists, ErrSessionExpired, ErrLoginFailed}
+	for _, tt := range tests {
+		got := ParseError([]byte(tt.body))
+		if (got == nil) != (tt.want == nil) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		if got == nil {
+			continue
+		}
+		if got.Code != tt.want.Code || got.Message != tt.want.Message || got.Lang != tt.want.Lang || got.Target != tt.want.Target || !slices.Equal(got.Details, tt.want.Details) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		for _, s := range sentinels {
+			if errors.Is(got, s) != (s == tt.is) {
+				t.Errorf("%s: errors.Is(%v) = %v", tt.name, s, !(s == tt.is))
+			}
+		}
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	body := []byte(`{"error":{"code":-2035,"message":"exists"}}`)
+	err := statusError(http.MethodPost, "https://sl/b1s/v2/Items", http.StatusBadRequest, body, nil)
+	var se *StatusError
+	if !errors.As(err, &se) || se.OData == nil || se.OData.Code != "-2035" {
+		t.Fatalf("got %#v", err)
+	}
+	if !errors.Is(err, ErrAlreadyExists) || errors.Is(err, ErrNotFound) {
+		t.Errorf("400 -2035: errors.Is ErrAlreadyExists %v, ErrNotFound %v", errors.Is(err, ErrAlreadyExists), errors.Is(err, ErrNotFound))
+	}
+	if want := "odata: POST https://sl/b1s/v2/Items: 400 Bad Request: -2035 exists"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// a 404 without a payload, as a proxy may send it
+	err = statusError(http.MethodGet, "https://sl/b1s/v2/Items('A')", http.StatusNotFound, []byte("not found\n"), nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("404: not ErrNotFound: %v", err)
+	}
+	if want := "odata: GET https://sl/b1s/v2/Items('A'): 404 Not Found: not found"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	err = statusError(http.MethodPatch, "https://sl/b1s/v2/Items('A')", http.StatusPreconditionFailed, nil, http.Header{"If-Match": {`W/"1"`}})
+	var ce *ConflictError
+	if !errors.As(err, &ce) || ce.IfMatch != `W/"1"` || ce.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("412: got %#v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package odata
+
+import (
+	"maps"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ETagger is a model that keeps the ETag of the entity it was read from:
+// the generated entity types with the etag option, which read
+// @odata.etag (odata.etag in v3) into their ETag field.
+type ETagger interface {
+	EntityTag() string
+}
+
+// ConflictError is returned for 412 Precondition Failed: the entity was
+// changed after the version IfMatch was read. Read it again, apply the
+// change to it and send it once more.
+type ConflictError struct {
+	*StatusError
+	IfMatch string // the ETag the request was conditional on
+}
+
+func (e *ConflictError) Unwrap() error { return e.StatusError }
+
+// ifMatch returns the ETag a write of body is conditional on: that of a
+// Patch, of a model read with one, or the annotation of a map; "" for none.
+func ifMatch(body any) string {
+	switch b := body.(type) {
+	case *Patch:
+		if b != nil {
+			return b.ETag
+		}
+	case map[string]any:
+		return annotationETag(b)
+	case ETagger:
+		if v := reflect.ValueOf(b); v.Kind() != reflect.Pointer || !v.IsNil() {
+			return b.EntityTag()
+		}
+	}
+	return ""
+}
+
+// writeHeader returns the headers of a PATCH or PUT of body: If-Match
+// when it carries an ETag, and ReplaceCollectionsHeader for a Patch that
+// needs it.
+func writeHeader(body any) http.Header {
+	var header http.Header
+	if p, ok := body.(*Patch); ok && p != nil && p.ReplaceCollections {
+		header = withHeader(header, ReplaceCollectionsHeader, "true")
+	}
+	if etag := ifMatch(body); etag != "" {
+		header = withHeader(header, "If-Match", etag)
+	}
+	return header
+}
+
+// untagged returns entity without its ETag, which is sent as If-Match
+// rather than in the body.
+func untagged[T any](entity *T) *T {
+	if ifMatch(entity) == "" {
+		return entity
+	}
+	out := *entity
+	clearETag(reflect.ValueOf(&out).Elem())
+	return &out
+}
+
+// untaggedBody returns the body of a PATCH without the ETag annotation,
+// which goes in If-Match only: a model is copied with its ETag field
+// cleared, a map without the annotation. A Patch never holds one.
+func untaggedBody(body any) any {
+	if m, ok := body.(map[string]any); ok {
+		if annotationETag(m) == "" {
+			return m
+		}
+		out := maps.Clone(m)
+		delete(out, "@odata.etag")
+		delete(out, "odata.etag")
+		return out
+	}
+	if _, ok := body.(*Patch); ok || ifMatch(body) == "" {
+		return body
+	}
+	v := reflect.ValueOf(body)
+	if v.Kind() == reflect.Pointer {
+		out := reflect.New(v.Elem().Type())
+		out.Elem().Set(v.Elem())
+		clearETag(out.Elem())
+		return out.Interface()
+	}
+	out := reflect.New(v.Type()).Elem()
+	out.Set(v)
+	clearETag(out)
+	return out.Interface()
+}
+
+// clearETag zeroes the ETag annotation fields of the struct v and of the
+// structs it embeds.
+func clearETag(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "@odata.etag" || name == "odata.etag":
+			v.Field(i).SetZero()
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			clearETag(v.Field(i))
+		}
+	}
+}
+
+// etagHeader returns the If-Match header for etag; nil for "".
+func etagHeader(etag string) http.Header {
+	if etag == "" {
+		return nil
+	}
+	return http.Header{"If-Match": {etag}}
+}
+
+// annotationETag returns the ETag annotation of an entity in JSON form.
+func annotationETag(m map[string]any) string {
+	for _, name := range []string{"@odata.etag", "odata.etag"} {
+		if s, ok := m[name].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type taggedItem struct {
+	ETag     string `json:"@odata.etag,omitempty"`
+	ItemCode string `json:"ItemCode,omitempty"`
+	ItemName string `json:"ItemName,omitempty"`
+}
+
+func (m *taggedItem) EntityTag() string { return m.ETag }
+
+func TestUpdateSendsETagAsIfMatch(t *testing.T) {
+	type request struct {
+		ifMatch string
+		body    map[string]any
+	}
+	var got []request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if strings.HasSuffix(r.URL.Path, "$batch") {
+			// one PATCH part: its headers and the JSON body after them
+			s := string(data)
+			i := strings.Index(s, "PATCH ")
+			part := s[i:]
+			header, body, _ := strings.Cut(part, "\r\n\r\n")
+			body = body[:strings.Index(body, "\r\n--")]
+			req := request{}
+			for _, line := range strings.Split(header, "\r\n") {
+				if v, ok := strings.CutPrefix(line, "If-Match: "); ok {
+					req.ifMatch = v
+				}
+			}
+			json.Unmarshal([]byte(body), &req.body)
+			got = append(got, req)
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"responses":[]}`)
+			return
+		}
+		req := request{ifMatch: r.Header.Get("If-Match")}
+		json.Unmarshal(data, &req.body)
+		got = append(got, req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	c := NewClient(ts.URL, ts.Client())
+	items := NewEntitySet[taggedItem](c, "Items")
+	ctx := context.Background()
+	key := Key{{Name: "ItemCode", Type: "Edm.String", Value: "A1"}}
+
+	item := &taggedItem{ETag: `W/"7"`, ItemCode: "A1", ItemName: "Pen"}
+	if err := items.Update(ctx, key, item); err != nil {
+		t.Fatal(err)
+	}
+	if err := items.Update(ctx, key, map[string]any{"@odata.etag": `W/"8"`, "ItemName": "Pencil"}); err != nil {
+		t.Fatal(err)
+	}
+	b := c.NewBatch()
+	items.Batch(b).Update(key, item)
+	if err := b.Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{`W/"7"`, `W/"8"`, `W/"7"`}
+	if len(got) != len(want) {
+		t.Fatalf("%d requests, want %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if r.ifMatch != want[i] {
+			t.Errorf("request %d: If-Match %q, want %q", i, r.ifMatch, want[i])
+		}
+		if _, ok := r.body["@odata.etag"]; ok || r.body["ItemName"] == nil {
+			t.Errorf("request %d: body %v", i, r.body)
+		}
+	}
+	if item.ETag != `W/"7"` {
+		t.Errorf("Update changed the ETag of the model to %q", item.ETag)
+	}
+}
@@ -0,0 +1,109 @@
+package odata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatLiteral writes v as an OData URL literal of edmType ("Edm.Int32",
+// "Edm.String", or a qualified enum type; "" goes by the Go type). v3 selects
+// the OData v2/v3 forms: guid'...', datetime'...', 12L and 1.5M.
+func formatLiteral(v any, edmType string, v3 bool) (string, error) {
+	var s string
+	quoted := false
+	switch x := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		s, quoted = x, true
+	case bool:
+		s = strconv.FormatBool(x)
+	case int:
+		s = strconv.Itoa(x)
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		s = fmt.Sprint(x)
+	case float32:
+		s = strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case float64:
+		s = strconv.FormatFloat(x, 'g', -1, 64)
+	case time.Time:
+		if edmType == "Edm.DateTime" {
+			s = x.Format("2006-01-02T15:04:05.999999999") // no zone in Edm.DateTime
+		} else {
+			s = x.Format(time.RFC3339Nano)
+		}
+	case Date:
+		s = x.String()
+		if edmType == "Edm.DateTime" {
+			s += "T00:00:00"
+		}
+	default:
+		// generated enums, decimals and user scalars go by their JSON form
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("odata: literal of %T: %w", v, err)
+		}
+		s = string(b)
+		if len(b) > 0 && b[0] == '"' {
+			if err := json.Unmarshal(b, &s); err != nil {
+				return "", err
+			}
+			quoted = true
+		}
+	}
+
+	switch edmType {
+	case "Edm.String", "":
+		if quoted {
+			return quoteLiteral(s), nil
+		}
+		return s, nil
+	case "Edm.Guid":
+		if v3 {
+			return "guid" + quoteLiteral(s), nil
+		}
+		return s, nil
+	case "Edm.DateTime":
+		if v3 {
+			return "datetime" + quoteLiteral(s), nil
+		}
+		return s, nil
+	case "Edm.DateTimeOffset":
+		if v3 {
+			return "datetimeoffset" + quoteLiteral(s), nil
+		}
+		return s, nil
+	case "Edm.Date", "Edm.TimeOfDay", "Edm.Boolean", "Edm.Byte", "Edm.SByte",
+		"Edm.Int16", "Edm.Int32", "Edm.Single", "Edm.Double":
+		return s, nil
+	case "Edm.Int64":
+		if v3 {
+			return s + "L", nil
+		}
+		return s, nil
+	case "Edm.Decimal":
+		if v3 {
+			return s + "M", nil
+		}
+		return s, nil
+	}
+	if strings.HasPrefix(edmType, "Edm.") {
+		return quoteLiteral(s), nil
+	}
+	// an enum member: NS.Type'Member' in v4, the plain string in v3
+	if v3 || !quoted {
+		if quoted {
+			return quoteLiteral(s), nil
+		}
+		return s, nil
+	}
+	return edmType + quoteLiteral(s), nil
+}
+
+// quoteLiteral quotes s as an OData string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,111 @@
+package odata
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+type optState uint8
+
+const (
+	optAbsent optState = iota
+	optNull
+	optValue
+)
+
+// Opt is a nullable property that distinguishes three states:
+//
+//   - absent: not sent at all (the zero value, dropped by `omitzero`)
+//   - null:   sent as JSON null, which clears the field on the service
+//   - value:  sent as the JSON encoding of T
+//
+// Generated models use it with `json:",omitzero"` when run with -nullable=opt,
+// so a PATCH payload carries exactly the fields that were set.
+type Opt[T any] struct {
+	value T
+	state optState
+}
+
+// Some returns an Opt holding v.
+func Some[T any](v T) Opt[T] {
+	return Opt[T]{value: v, state: optValue}
+}
+
+// Null returns an Opt that marshals as an explicit JSON null.
+func Null[T any]() Opt[T] {
+	return Opt[T]{state: optNull}
+}
+
+// FromPtr maps nil to null and a non-nil pointer to its value.
+func FromPtr[T any](p *T) Opt[T] {
+	if p == nil {
+		return Null[T]()
+	}
+	return Some(*p)
+}
+
+// IsZero reports whether the field is absent; encoding/json's omitzero uses it.
+func (o Opt[T]) IsZero() bool { return o.state == optAbsent }
+
+// IsNull reports whether the field is an explicit null.
+func (o Opt[T]) IsNull() bool { return o.state == optNull }
+
+// IsSet reports whether the field holds a value.
+func (o Opt[T]) IsSet() bool { return o.state == optValue }
+
+// Get returns the value and whether one is set.
+func (o Opt[T]) Get() (T, bool) { return o.value, o.state == optValue }
+
+// OrElse returns the value, or d when the field is absent or null.
+func (o Opt[T]) OrElse(d T) T {
+	if o.state == optValue {
+		return o.value
+	}
+	return d
+}
+
+// Ptr returns a pointer to a copy of the value, or nil when not set.
+func (o Opt[T]) Ptr() *T {
+	if o.state != optValue {
+		return nil
+	}
+	v := o.value
+	return &v
+}
+
+// Set stores v.
+func (o *Opt[T]) Set(v T) { *o = Some(v) }
+
+// SetNull marks the field as an explicit null.
+func (o *Opt[T]) SetNull() { *o = Null[T]() }
+
+// Unset marks the field as absent.
+func (o *Opt[T]) Unset() { *o = Opt[T]{} }
+
+func (o Opt[T]) MarshalJSON() ([]byte, error) {
+	if o.state != optValue {
+		// absent fields only get here without omitzero; null is the closest match
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+func (o *Opt[T]) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		o.SetNull()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		// IEEE754Compatible services quote Int64/Decimal; retry unquoted.
+		var s string
+		if len(b) == 0 || b[0] != '"' || json.Unmarshal(b, &s) != nil {
+			return err
+		}
+		if json.Unmarshal([]byte(s), &v) != nil {
+			return err
+		}
+	}
+	o.Set(v)
+	return nil
+}
@@ -0,0 +1,79 @@
+package odata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type optItem struct {
+	ItemName Opt[string] `json:"ItemName,omitzero"`
+	Quantity Opt[int64]  `json:"Quantity,omitzero"`
+	Price    Opt[Rat]    `json:"Price,omitzero"`
+}
+
+func TestOptMarshal(t *testing.T) {
+	price, _ := NewRat("9.95")
+	tests := []struct {
+		name string
+		v    optItem
+		want string
+	}{
+		{"unset", optItem{}, `{}`},
+		{"null", optItem{ItemName: Null[string](), Quantity: Null[int64]()}, `{"ItemName":null,"Quantity":null}`},
+		{"value", optItem{ItemName: Some("Apple"), Quantity: Some[int64](0), Price: Some(price)}, `{"ItemName":"Apple","Quantity":0,"Price":9.95}`},
+		{"zero value", optItem{ItemName: Some("")}, `{"ItemName":""}`},
+		{"from nil pointer", optItem{ItemName: FromPtr[string](nil)}, `{"ItemName":null}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.v)
+		if err != nil || string(b) != tt.want {
+			t.Errorf("%s: got %s, %v; want %s", tt.name, b, err, tt.want)
+		}
+	}
+	// without omitzero an unset field has no absent form; it is sent as null
+	if b, _ := json.Marshal(struct{ V Opt[int] }{}); string(b) != `{"V":null}` {
+		t.Errorf("unset without omitzero: %s", b)
+	}
+}
+
+func TestOptUnmarshal(t *testing.T) {
+	tests := []struct {
+		body             string
+		unset, null, set bool
+		name             string
+		quantity         int64  // OrElse(-1)
+		back             string // marshalled again
+	}{
+		{`{}`, true, false, false, "", -1, `{}`},
+		{`{"ItemName":null,"Quantity":null}`, false, true, false, "", -1, `{"ItemName":null,"Quantity":null}`},
+		{`{"ItemName":"Apple","Quantity":12}`, false, false, true, "Apple", 12, `{"ItemName":"Apple","Quantity":12}`},
+		{`{"ItemName":"","Quantity":0}`, false, false, true, "", 0, `{"ItemName":"","Quantity":0}`},
+		{`{"ItemName":"A","Quantity":"12"}`, false, false, true, "A", 12, `{"ItemName":"A","Quantity":12}`}, // IEEE754Compatible
+	}
+	for _, tt := range tests {
+		var v optItem
+		if err := json.Unmarshal([]byte(tt.body), &v); err != nil {
+			t.Errorf("%s: %v", tt.body, err)
+			continue
+		}
+		for _, o := range []interface {
+			IsZero() bool
+			IsNull() bool
+			IsSet() bool
+		}{v.ItemName, v.Quantity} {
+			if o.IsZero() != tt.unset || o.IsNull() != tt.null || o.IsSet() != tt.set {
+				t.Errorf("%s: unset %v, null %v, set %v", tt.body, o.IsZero(), o.IsNull(), o.IsSet())
+			}
+		}
+		if name, _ := v.ItemName.Get(); name != tt.name || v.Quantity.OrElse(-1) != tt.quantity {
+			t.Errorf("%s: got %q, %d", tt.body, name, v.Quantity.OrElse(-1))
+		}
+		if b, _ := json.Marshal(v); string(b) != tt.back {
+			t.Errorf("%s: marshals back as %s", tt.body, b)
+		}
+	}
+	var v optItem
+	if err := json.Unmarshal([]byte(`{"Quantity":"many"}`), &v); err == nil {
+		t.Error("Quantity many: no error")
+	}
+}
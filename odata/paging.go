@@ -0,0 +1,147 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Collection is the body of a collection response: one page of entities,
+// with the link to the next page when the service pages the result.
+type Collection[T any] struct {
+	Value     []T    `json:"value"`
+	Count     *int64 `json:"@odata.count,omitempty"`     // with $count=true
+	NextLink  string `json:"@odata.nextLink,omitempty"`  // "" on the last page
+	DeltaLink string `json:"@odata.deltaLink,omitempty"` // on the last page of a delta query
+}
+
+// UnmarshalJSON reads the v4 annotations and the v3 ones (odata.nextLink,
+// and odata.count, which v3 services send as a string).
+func (c *Collection[T]) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Value      []T             `json:"value"`
+		Count      json.RawMessage `json:"@odata.count"`
+		NextLink   string          `json:"@odata.nextLink"`
+		DeltaLink  string          `json:"@odata.deltaLink"`
+		V3Count    json.RawMessage `json:"odata.count"`
+		V3NextLink string          `json:"odata.nextLink"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = Collection[T]{Value: raw.Value, NextLink: raw.NextLink, DeltaLink: raw.DeltaLink}
+	if c.NextLink == "" {
+		c.NextLink = raw.V3NextLink
+	}
+	count := raw.Count
+	if count == nil {
+		count = raw.V3Count
+	}
+	if s := strings.Trim(string(count), `"`); s != "" && s != "null" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("odata: count %s: %w", count, err)
+		}
+		c.Count = &n
+	}
+	return nil
+}
+
+// pageHeader asks for MaxPageSize entities per page.
+func (c *Client) pageHeader() http.Header {
+	if c.MaxPageSize <= 0 {
+		return nil
+	}
+	return http.Header{"Prefer": {"odata.maxpagesize=" + strconv.Itoa(c.MaxPageSize)}}
+}
+
+// resolve returns the URL of link, a next or delta link, which services
+// send absolute, relative to the service root or rooted at the host.
+func (c *Client) resolve(link string) (string, error) {
+	base, err := url.Parse(c.ServiceRoot + "/")
+	if err != nil {
+		return "", fmt.Errorf("odata: service root: %w", err)
+	}
+	ref, err := url.Parse(escapeLink(link))
+	if err != nil {
+		return "", fmt.Errorf("odata: next link %q: %w", link, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// escapeLink percent-encodes the bytes a URL cannot hold; some services
+// send the $filter of a next link back with its spaces as they are.
+func escapeLink(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"<>\^`+"`{|}", c) >= 0 {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Page reads the first page of the entities matching query, with the count
+// ($count=true) and the link to the next page.
+func (s EntitySet[T]) Page(ctx context.Context, query ...QueryString) (*Collection[T], error) {
+	var page Collection[T]
+	if err := s.Client.Do(ctx, http.MethodGet, s.Name, query, s.Client.pageHeader(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// NextPage reads the page at link, the NextLink of the page before.
+func (s EntitySet[T]) NextPage(ctx context.Context, link string) (*Collection[T], error) {
+	u, err := s.Client.resolve(link)
+	if err != nil {
+		return nil, err
+	}
+	var page Collection[T]
+	if err := s.Client.send(ctx, http.MethodGet, u, s.Client.pageHeader(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// All iterates over the entities matching query, reading the pages of a
+// paged result one after the other as the loop goes on. It stops after
+// yielding an error: a failed request, or ctx's error once ctx is done.
+func (s EntitySet[T]) All(ctx context.Context, query ...QueryString) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		page, err := s.Page(ctx, query...)
+		for {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, v := range page.Value {
+				if err := ctx.Err(); err != nil {
+					yield(zero, err)
+					return
+				}
+				if !yield(v, nil) {
+					return
+				}
+			}
+			if page.NextLink == "" {
+				return
+			}
+			if err = ctx.Err(); err == nil {
+				page, err = s.NextPage(ctx, page.NextLink)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+type pagedItem struct {
+	ItemCode string `json:"ItemCode"`
+}
+
+func TestCollectionUnmarshal(t *testing.T) {
+	tests := []struct {
+		body      string
+		count     int64 // -1 for none
+		nextLink  string
+		deltaLink string
+	}{
+		{`{"value":[{"ItemCode":"A"}]}`, -1, "", ""},
+		{`{"@odata.count":12,"value":[{"ItemCode":"A"}],"@odata.nextLink":"Items?$skip=20"}`, 12, "Items?$skip=20", ""},
+		{`{"odata.count":"12","value":[{"ItemCode":"A"}],"odata.nextLink":"Items?$skip=20"}`, 12, "Items?$skip=20", ""},
+		{`{"@odata.count":null,"value":[{"ItemCode":"A"}],"@odata.deltaLink":"Items?$deltatoken=1"}`, -1, "", "Items?$deltatoken=1"},
+	}
+	for _, tt := range tests {
+		var c Collection[pagedItem]
+		if err := json.Unmarshal([]byte(tt.body), &c); err != nil {
+			t.Errorf("%s: %v", tt.body, err)
+			continue
+		}
+		count := int64(-1)
+		if c.Count != nil {
+			count = *c.Count
+		}
+		if len(c.Value) != 1 || c.Value[0].ItemCode != "A" || count != tt.count || c.NextLink != tt.nextLink || c.DeltaLink != tt.deltaLink {
+			t.Errorf("%s: got %+v, count %d", tt.body, c, count)
+		}
+	}
+	var c Collection[pagedItem]
+	if err := json.Unmarshal([]byte(`{"odata.count":"many","value":[]}`), &c); err == nil {
+		t.Error("count many: no error")
+	}
+}
+
+// pagedServer serves Items in pages of two, linking each page to the next
+// the way next is told to.
+func pagedServer(t *testing.T, items []string, next func(ts *httptest.Server, skip int) string) (*httptest.Server, *[]*http.Request) {
+	var reqs []*http.Request
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r)
+		if r.URL.Path != "/b1s/v2/Items" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":{"code":"-2028","message":"No matching records found"}}`)
+			return
+		}
+		skip := 0
+		if s := r.URL.Query().Get("$skip"); s != "" {
+			skip, _ = strconv.Atoi(s)
+		}
+		page := Collection[pagedItem]{}
+		for _, code := range items[skip:min(skip+2, len(items))] {
+			page.Value = append(page.Value, pagedItem{code})
+		}
+		if skip+2 < len(items) {
+			page.NextLink = next(ts, skip+2)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &reqs
+}
+
+func TestAll(t *testing.T) {
+	items := []string{"A", "B", "C", "D", "E"}
+	links := map[string]func(ts *httptest.Server, skip int) string{
+		"relative": func(_ *httptest.Server, skip int) string { return "Items?$skip=" + strconv.Itoa(skip) },
+		"rooted":   func(_ *httptest.Server, skip int) string { return "/b1s/v2/Items?$skip=" + strconv.Itoa(skip) },
+		"absolute": func(ts *httptest.Server, skip int) string {
+			return ts.URL + "/b1s/v2/Items?$skip=" + strconv.Itoa(skip)
+		},
+	}
+	for name, next := range links {
+		ts, reqs := pagedServer(t, items, next)
+		c := NewClient(ts.URL+"/b1s/v2", ts.Client())
+		c.MaxPageSize = 2
+		var got []string
+		for v, err := range NewEntitySet[pagedItem](c, "Items").All(context.Background()) {
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			got = append(got, v.ItemCode)
+		}
+		if !slices.Equal(got, items) {
+			t.Errorf("%s: got %v, want %v", name, got, items)
+		}
+		if len(*reqs) != 3 {
+			t.Errorf("%s: %d requests, want 3", name, len(*reqs))
+		}
+		for _, r := range *reqs {
+			if p := r.Header.Get("Prefer"); p != "odata.maxpagesize=2" {
+				t.Errorf("%s: Prefer %q", name, p)
+			}
+		}
+	}
+}
+
+func TestAllStop(t *testing.T) {
+	ts, reqs := pagedServer(t, []string{"A", "B", "C"}, func(_ *httptest.Server, skip int) string { return "Items?$skip=" + strconv.Itoa(skip) })
+	c := NewClient(ts.URL+"/b1s/v2", ts.Client())
+	for v, err := range NewEntitySet[pagedItem](c, "Items").All(context.Background()) {
+		if err != nil || v.ItemCode != "A" {
+			t.Fatalf("got %v, %v", v, err)
+		}
+		break
+	}
+	if len(*reqs) != 1 {
+		t.Errorf("%d requests after break on the first page, want 1", len(*reqs))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var n int
+	var last error
+	for _, err := range NewEntitySet[pagedItem](c, "Items").All(ctx) {
+		if err != nil {
+			last = err
+			continue
+		}
+		n++
+		cancel()
+	}
+	if n != 1 || !errors.Is(last, context.Canceled) {
+		t.Errorf("after cancel: %d entities, error %v", n, last)
+	}
+
+	var failed error
+	for _, err := range NewEntitySet[pagedItem](c, "Missing").All(context.Background()) {
+		failed = err
+	}
+	if !errors.Is(failed, ErrNotFound) {
+		t.Errorf("missing set: error %v, want ErrNotFound", failed)
+	}
+}
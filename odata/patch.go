@@ -0,0 +1,352 @@
+package odata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ReplaceCollectionsHeader makes SAP B1 Service Layer replace (rather than
+// merge) collection properties such as DocumentLines on PATCH. A Patch sets
+// ReplaceCollections when a line was removed, since a merge cannot express it.
+const ReplaceCollectionsHeader = "B1S-ReplaceCollectionsOnPatch"
+
+// PatchRules describes which properties of a generated type may be sent in a
+// PATCH body and how nested values are compared. The generator emits one
+// value per entity and complex type from the metadata.
+type PatchRules struct {
+	Keys        []string                    // key properties, addressed in the URL and never sent
+	Skip        []string                    // navigation properties and other read-only fields
+	Complex     map[string]*PatchRules      // single-valued complex properties, diffed field by field
+	Collections map[string]*CollectionRules // collections of complex values (document lines)
+}
+
+// CollectionRules describes a collection of complex values. Elements are
+// matched on Keys (e.g. LineNum); without keys a changed collection is sent whole.
+type CollectionRules struct {
+	Keys  []string
+	Rules *PatchRules
+}
+
+// Patch is a minimal PATCH body.
+type Patch struct {
+	Fields             map[string]any
+	ReplaceCollections bool   // send ReplaceCollectionsHeader: true
+	ETag               string // sent as If-Match; Delta takes it from the model before
+}
+
+// Empty reports whether nothing changed.
+func (p *Patch) Empty() bool { return len(p.Fields) == 0 }
+
+func (p *Patch) MarshalJSON() ([]byte, error) {
+	if p.Fields == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(p.Fields)
+}
+
+// Snapshot remembers the JSON form of a model so later changes can be sent
+// as a delta.
+type Snapshot struct {
+	orig  map[string]any
+	rules *PatchRules
+	etag  string
+}
+
+// TakeSnapshot records v (a generated model or pointer to one).
+func TakeSnapshot(v any, rules *PatchRules) (*Snapshot, error) {
+	m, err := toJSONObject(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{orig: m, rules: rules, etag: annotationETag(m)}, nil
+}
+
+// Delta returns the changes between the snapshot and current.
+func (s *Snapshot) Delta(current any) (*Patch, error) {
+	cur, err := toJSONObject(current)
+	if err != nil {
+		return nil, err
+	}
+	p := &Patch{ETag: s.etag}
+	p.Fields = diffObject(s.orig, cur, s.rules, shapeOf(reflect.TypeOf(current)), p, true)
+	if p.ReplaceCollections && s.rules != nil {
+		// the header applies to every collection in the body, so none may be partial
+		for name := range s.rules.Collections {
+			if _, ok := p.Fields[name]; ok {
+				p.Fields[name] = cur[name]
+			}
+		}
+	}
+	return p, nil
+}
+
+// Delta returns the PATCH body that turns before into after. Properties that
+// are present in before but missing from after are sent as null, except the
+// non-nullable fields of a model, which omitempty leaves out when zero: those
+// are sent as their zero value.
+func Delta(before, after any, rules *PatchRules) (*Patch, error) {
+	s, err := TakeSnapshot(before, rules)
+	if err != nil {
+		return nil, err
+	}
+	return s.Delta(after)
+}
+
+func toJSONObject(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("odata: patch: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("odata: patch: %w", err)
+	}
+	return m, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// shape is what the Go type of a model tells beyond its JSON form: the JSON
+// zero values of the fields that cannot be null but are left out when zero
+// (by omitempty, or omitzero for Rat), and the shapes of nested structs and
+// of the elements of slices, by JSON name.
+type shape struct {
+	zeros  map[string]any
+	nested map[string]*shape
+}
+
+var ratType = reflect.TypeOf(Rat{})
+
+// shapeOf returns the shape of struct type t, or nil for maps and other
+// values.
+func shapeOf(t reflect.Type) *shape {
+	return buildShape(t, map[reflect.Type]*shape{})
+}
+
+func buildShape(t reflect.Type, seen map[reflect.Type]*shape) *shape {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t == ratType {
+		return nil
+	}
+	if sh, ok := seen[t]; ok {
+		return sh
+	}
+	sh := &shape{zeros: map[string]any{}, nested: map[string]*shape{}}
+	seen[t] = sh
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if embedded := buildShape(f.Type, seen); embedded != nil {
+				for k, v := range embedded.zeros {
+					sh.zeros[k] = v
+				}
+				for k, v := range embedded.nested {
+					sh.nested[k] = v
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitted := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		switch {
+		case omitted && (f.Type == ratType || isScalar(f.Type.Kind())):
+			sh.zeros[name] = zeroJSON(f.Type, strings.Contains(","+opts+",", ",string,"))
+		case f.Type.Kind() == reflect.Struct, f.Type.Kind() == reflect.Slice, f.Type.Kind() == reflect.Pointer:
+			if nested := buildShape(f.Type, seen); nested != nil {
+				sh.nested[name] = nested
+			}
+		}
+	}
+	return sh
+}
+
+func isScalar(k reflect.Kind) bool {
+	return k == reflect.Bool || k == reflect.String || reflect.Int <= k && k <= reflect.Float64
+}
+
+// zeroJSON returns the zero value of t as toJSONObject decodes it; quoted
+// for a field tagged ,string.
+func zeroJSON(t reflect.Type, quoted bool) any {
+	var v any
+	switch t.Kind() {
+	case reflect.String:
+		return ""
+	case reflect.Bool:
+		v = false
+	default:
+		v = json.Number("0")
+	}
+	if quoted {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// diffObject returns the changed fields of after relative to before.
+// top is false for nested values, where keys identify the element and stay.
+// sh, from the Go type of after, may be nil.
+func diffObject(before, after map[string]any, rules *PatchRules, sh *shape, p *Patch, top bool) map[string]any {
+	if rules == nil {
+		rules = &PatchRules{}
+	}
+	out := map[string]any{}
+	names := map[string]struct{}{}
+	for k := range before {
+		names[k] = struct{}{}
+	}
+	for k := range after {
+		names[k] = struct{}{}
+	}
+	for name := range names {
+		if strings.HasPrefix(name, "@") || strings.HasPrefix(name, "odata.") || strings.Contains(name, "@odata.") {
+			continue // annotations such as @odata.etag (odata.etag in v3)
+		}
+		if contains(rules.Skip, name) || (top && contains(rules.Keys, name)) {
+			continue
+		}
+		ov, inBefore := before[name]
+		nv, inAfter := after[name]
+		if !inAfter {
+			zero, notNull := sh.zero(name)
+			switch {
+			case notNull && ov != nil && !reflect.DeepEqual(ov, zero):
+				out[name] = zero
+			case !notNull && ov != nil:
+				out[name] = nil
+			}
+			continue
+		}
+		if inBefore && reflect.DeepEqual(ov, nv) {
+			continue
+		}
+		if cr, ok := rules.Complex[name]; ok {
+			om, ok1 := ov.(map[string]any)
+			nm, ok2 := nv.(map[string]any)
+			if ok1 && ok2 {
+				if d := diffObject(om, nm, cr, sh.field(name), p, false); len(d) > 0 {
+					out[name] = d
+				}
+				continue
+			}
+		}
+		if cr, ok := rules.Collections[name]; ok {
+			oldList, _ := ov.([]any)
+			newList, ok2 := nv.([]any)
+			if ok2 {
+				if d, changed := diffCollection(oldList, newList, cr, sh.field(name), p); changed {
+					out[name] = d
+				}
+				continue
+			}
+		}
+		out[name] = nv
+	}
+	return out
+}
+
+// diffCollection matches elements on their keys. Changed elements are sent
+// with their keys plus changed fields, new elements in full. A removed
+// element forces the whole collection to be sent with ReplaceCollections.
+func diffCollection(before, after []any, cr *CollectionRules, sh *shape, p *Patch) ([]any, bool) {
+	if len(cr.Keys) == 0 {
+		return after, !reflect.DeepEqual(before, after)
+	}
+	index := map[string]map[string]any{}
+	for _, v := range before {
+		if m, ok := v.(map[string]any); ok {
+			if k := elementKey(m, cr.Keys, sh); k != "" {
+				index[k] = m
+			}
+		}
+	}
+	var out []any
+	seen := map[string]bool{}
+	for _, v := range after {
+		m, ok := v.(map[string]any)
+		if !ok {
+			out = append(out, v)
+			continue
+		}
+		k := elementKey(m, cr.Keys, sh)
+		old, exists := index[k]
+		if !exists || k == "" {
+			out = append(out, m)
+			continue
+		}
+		seen[k] = true
+		d := diffObject(old, m, cr.Rules, sh, p, false)
+		if len(d) == 0 {
+			continue
+		}
+		for _, key := range cr.Keys {
+			d[key], _ = keyValue(m, key, sh)
+		}
+		out = append(out, d)
+	}
+	if len(seen) < len(index) {
+		p.ReplaceCollections = true
+		return after, true
+	}
+	return out, len(out) > 0
+}
+
+// elementKey returns the key values of the element m joined, or "" when
+// one is missing. sh, the shape of the elements, may be nil.
+func elementKey(m map[string]any, keys []string, sh *shape) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v, ok := keyValue(m, k, sh)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, fmt.Sprint(v))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// keyValue returns the key property name of m. A key the model leaves out
+// when zero, such as LineNum 0 of the first document line, is its zero.
+func keyValue(m map[string]any, name string, sh *shape) (any, bool) {
+	if v, ok := m[name]; ok {
+		return v, v != nil
+	}
+	return sh.zero(name)
+}
+
+// zero returns the JSON zero value of the field name when it cannot be null.
+func (sh *shape) zero(name string) (any, bool) {
+	if sh == nil {
+		return nil, false
+	}
+	v, ok := sh.zeros[name]
+	return v, ok
+}
+
+// field returns the shape of the struct or the elements of the field name.
+func (sh *shape) field(name string) *shape {
+	if sh == nil {
+		return nil
+	}
+	return sh.nested[name]
+}
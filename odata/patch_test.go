@@ -0,0 +1,83 @@
+package odata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testLine struct {
+	LineNum  int     `json:"LineNum"`
+	Quantity float64 `json:"Quantity,omitempty"`
+	Text     *string `json:"Text,omitempty"`
+}
+
+type testDoc struct {
+	DocEntry int        `json:"DocEntry,omitempty"`
+	Comments *string    `json:"Comments,omitempty"`
+	Printed  bool       `json:"Printed,omitempty"`
+	Code     string     `json:"Code,omitempty"`
+	Total    Rat        `json:"Total,omitzero"`
+	Count    int64      `json:"Count,omitempty,string"`
+	Lines    []testLine `json:"Lines,omitempty"`
+}
+
+var testDocRules = PatchRules{
+	Keys:        []string{"DocEntry"},
+	Collections: map[string]*CollectionRules{"Lines": {Keys: []string{"LineNum"}}},
+}
+
+func TestDeltaZeroValues(t *testing.T) {
+	comments, text := "hi", "x"
+	total, _ := NewRat("12.5")
+	before := &testDoc{DocEntry: 1, Comments: &comments, Printed: true, Code: "A", Total: total, Count: 3,
+		Lines: []testLine{{LineNum: 0, Quantity: 2, Text: &text}}}
+	after := &testDoc{DocEntry: 1, Lines: []testLine{{LineNum: 0}}}
+
+	p, err := Delta(before, after, &testDocRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(p)
+	want := `{"Code":"","Comments":null,"Count":"0","Lines":[{"LineNum":0,"Quantity":0,"Text":null}],"Printed":false,"Total":0}`
+	if string(got) != want {
+		t.Errorf("Delta =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDeltaMaps(t *testing.T) {
+	before := map[string]any{"DocEntry": 1, "Code": "A", "@odata.etag": `W/"1"`}
+	after := map[string]any{"DocEntry": 1}
+	p, err := Delta(before, after, &testDocRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(p); string(got) != `{"Code":null}` || p.ETag != `W/"1"` {
+		t.Errorf("Delta = %s, ETag %q", got, p.ETag)
+	}
+}
+
+// generatedLine is a document line as gpt5mini generates it: the key is
+// left out when zero.
+type generatedLine struct {
+	LineNum  int32   `json:"LineNum,omitempty"`
+	Quantity float64 `json:"Quantity,omitempty"`
+}
+
+type generatedDoc struct {
+	DocEntry int             `json:"DocEntry,omitempty"`
+	Lines    []generatedLine `json:"Lines,omitempty"`
+}
+
+func TestDeltaZeroKey(t *testing.T) {
+	before := &generatedDoc{DocEntry: 1, Lines: []generatedLine{{LineNum: 0, Quantity: 1}, {LineNum: 1, Quantity: 2}}}
+	after := &generatedDoc{DocEntry: 1, Lines: []generatedLine{{LineNum: 0, Quantity: 5}, {LineNum: 1, Quantity: 2}}}
+
+	p, err := Delta(before, after, &testDocRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(p)
+	if want := `{"Lines":[{"LineNum":0,"Quantity":5}]}`; string(got) != want || p.ReplaceCollections {
+		t.Errorf("Delta = %s, ReplaceCollections %v; want %s, false", got, p.ReplaceCollections, want)
+	}
+}
@@ -0,0 +1,436 @@
+package odata
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Path is the path of a property within a query ("DocumentLines" or, in a
+// lambda, "x0/ItemCode"). The generated <Type>Fields descriptors hold one;
+// their zero value addresses the entity itself.
+type Path string
+
+// Child returns the path of the property name below p.
+func (p Path) Child(name string) Path {
+	if p == "" {
+		return Path(name)
+	}
+	return p + "/" + Path(name)
+}
+
+// Pather is implemented by the generated field descriptors and the fields
+// of this package: anything that can be selected, expanded or ordered by.
+type Pather interface {
+	ODataPath() Path
+}
+
+// ODataPath returns p.
+func (p Path) ODataPath() Path { return p }
+
+// filterKind orders the filter operators by binding: an atom is never
+// parenthesized, "and" binds tighter than "or".
+type filterKind uint8
+
+const (
+	filterAtom filterKind = iota
+	filterAnd
+	filterOr
+)
+
+// Filter is a $filter expression. The literals are written when the query
+// is sent, in the form of the client's OData version.
+type Filter struct {
+	kind   filterKind
+	render func(v3 bool) (string, error)
+}
+
+// IsZero reports whether f is the empty filter, which And and Or ignore.
+func (f Filter) IsZero() bool { return f.render == nil }
+
+func (f Filter) expr(v3 bool, parent filterKind) (string, error) {
+	s, err := f.render(v3)
+	if err != nil {
+		return "", err
+	}
+	if f.kind != filterAtom && f.kind > parent {
+		s = "(" + s + ")"
+	}
+	return s, nil
+}
+
+func join(kind filterKind, op string, filters []Filter) Filter {
+	var fs []Filter
+	for _, f := range filters {
+		if !f.IsZero() {
+			fs = append(fs, f)
+		}
+	}
+	switch len(fs) {
+	case 0:
+		return Filter{}
+	case 1:
+		return fs[0]
+	}
+	return Filter{kind: kind, render: func(v3 bool) (string, error) {
+		parts := make([]string, len(fs))
+		for i, f := range fs {
+			s, err := f.expr(v3, kind)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, " "+op+" "), nil
+	}}
+}
+
+// And is the conjunction of filters; zero filters are left out.
+func And(filters ...Filter) Filter { return join(filterAnd, "and", filters) }
+
+// Or is the disjunction of filters; zero filters are left out.
+func Or(filters ...Filter) Filter { return join(filterOr, "or", filters) }
+
+// Not negates f.
+func Not(f Filter) Filter {
+	return Filter{render: func(v3 bool) (string, error) {
+		s, err := f.expr(v3, filterAtom)
+		if err != nil {
+			return "", err
+		}
+		return "not " + s, nil
+	}}
+}
+
+// RawFilter is a filter written by hand, for what the fields cannot express.
+func RawFilter(expr string) Filter {
+	return Filter{kind: filterOr, render: func(bool) (string, error) { return expr, nil }}
+}
+
+// Field is a primitive property of Go type T (the value type, also for
+// nullable properties) and Edm type Type, e.g. Field[int32] for Edm.Int32.
+type Field[T any] struct {
+	path Path
+	typ  string
+}
+
+// NewField returns the field at path of Edm type edmType.
+func NewField[T any](path Path, edmType string) Field[T] {
+	return Field[T]{path: path, typ: edmType}
+}
+
+func (f Field[T]) ODataPath() Path { return f.path }
+
+func (f Field[T]) compare(op string, v T) Filter {
+	return Filter{render: func(v3 bool) (string, error) {
+		lit, err := formatLiteral(v, f.typ, v3)
+		if err != nil {
+			return "", err
+		}
+		return string(f.path) + " " + op + " " + lit, nil
+	}}
+}
+
+func (f Field[T]) Eq(v T) Filter { return f.compare("eq", v) }
+func (f Field[T]) Ne(v T) Filter { return f.compare("ne", v) }
+func (f Field[T]) Gt(v T) Filter { return f.compare("gt", v) }
+func (f Field[T]) Ge(v T) Filter { return f.compare("ge", v) }
+func (f Field[T]) Lt(v T) Filter { return f.compare("lt", v) }
+func (f Field[T]) Le(v T) Filter { return f.compare("le", v) }
+
+// In matches any of values. It is sent as eq comparisons joined by or,
+// which OData 4.0 and v3 services understand too.
+func (f Field[T]) In(values ...T) Filter {
+	if len(values) == 0 {
+		return RawFilter("false")
+	}
+	fs := make([]Filter, len(values))
+	for i, v := range values {
+		fs[i] = f.Eq(v)
+	}
+	return Or(fs...)
+}
+
+// IsNull matches entities without a value.
+func (f Field[T]) IsNull() Filter {
+	return Filter{render: func(bool) (string, error) { return string(f.path) + " eq null", nil }}
+}
+
+// NotNull matches entities with a value.
+func (f Field[T]) NotNull() Filter {
+	return Filter{render: func(bool) (string, error) { return string(f.path) + " ne null", nil }}
+}
+
+// Asc orders by f, ascending.
+func (f Field[T]) Asc() Order { return Order(f.path) }
+
+// Desc orders by f, descending.
+func (f Field[T]) Desc() Order { return Order(f.path + " desc") }
+
+// StringField is an Edm.String property, with the string functions.
+type StringField struct {
+	Field[string]
+}
+
+// NewStringField returns the string field at path.
+func NewStringField(path Path) StringField {
+	return StringField{NewField[string](path, "Edm.String")}
+}
+
+func (f StringField) call(fn, s string) Filter {
+	return Filter{render: func(v3 bool) (string, error) {
+		if v3 && fn == "contains" {
+			// OData v2/v3 has substringof(needle, haystack) instead
+			return "substringof(" + quoteLiteral(s) + "," + string(f.path) + ")", nil
+		}
+		return fn + "(" + string(f.path) + "," + quoteLiteral(s) + ")", nil
+	}}
+}
+
+func (f StringField) Contains(s string) Filter   { return f.call("contains", s) }
+func (f StringField) StartsWith(s string) Filter { return f.call("startswith", s) }
+func (f StringField) EndsWith(s string) Filter   { return f.call("endswith", s) }
+
+// CollectionField is a collection property whose elements are addressed
+// through E: a generated <Type>Fields for complex types and entities, or a
+// Field for primitives.
+type CollectionField[E any] struct {
+	path Path
+	elem func(Path) E
+}
+
+// NewCollectionField returns the collection at path; elem builds the element
+// descriptor for a lambda variable.
+func NewCollectionField[E any](path Path, elem func(Path) E) CollectionField[E] {
+	return CollectionField[E]{path: path, elem: elem}
+}
+
+func (c CollectionField[E]) ODataPath() Path { return c.path }
+
+// Any matches entities where pred holds for some element.
+func (c CollectionField[E]) Any(pred func(E) Filter) Filter { return c.lambda("any", pred) }
+
+// All matches entities where pred holds for every element.
+func (c CollectionField[E]) All(pred func(E) Filter) Filter { return c.lambda("all", pred) }
+
+// lambda writes path/op(x: pred). The variable is numbered by the depth of
+// the path, so a nested lambda never shadows the one it is in.
+func (c CollectionField[E]) lambda(op string, pred func(E) Filter) Filter {
+	v := "x" + strconv.Itoa(strings.Count(string(c.path), "/"))
+	body := pred(c.elem(Path(v)))
+	return Filter{render: func(v3 bool) (string, error) {
+		if body.IsZero() {
+			return string(c.path) + "/" + op + "()", nil
+		}
+		s, err := body.expr(v3, filterOr)
+		if err != nil {
+			return "", err
+		}
+		return string(c.path) + "/" + op + "(" + v + ":" + s + ")", nil
+	}}
+}
+
+// Order is one $orderby item.
+type Order string
+
+// Query holds the system query options of a request. The methods set an
+// option and return q, so a query is written as one expression:
+//
+//	q := odata.NewQuery().Filter(d.CardCode().Eq("C001")).Top(10)
+type Query struct {
+	filter  Filter
+	selects []Path
+	expands []expandItem
+	orderBy []Order
+	top     *int
+	skip    *int
+	count   bool
+}
+
+type expandItem struct {
+	path Path
+	opts *Query
+}
+
+// NewQuery returns an empty query.
+func NewQuery() *Query { return &Query{} }
+
+// Filter adds f; filters added more than once are joined with and.
+func (q *Query) Filter(f Filter) *Query {
+	q.filter = And(q.filter, f)
+	return q
+}
+
+// Select adds properties to $select.
+func (q *Query) Select(props ...Pather) *Query {
+	for _, p := range props {
+		q.selects = append(q.selects, p.ODataPath())
+	}
+	return q
+}
+
+// Expand adds a navigation property to $expand, with the options of opts
+// applied to the expanded entities when given.
+func (q *Query) Expand(nav Pather, opts ...*Query) *Query {
+	item := expandItem{path: nav.ODataPath()}
+	if len(opts) > 0 {
+		item.opts = opts[0]
+	}
+	q.expands = append(q.expands, item)
+	return q
+}
+
+// OrderBy adds items to $orderby.
+func (q *Query) OrderBy(items ...Order) *Query {
+	q.orderBy = append(q.orderBy, items...)
+	return q
+}
+
+// Top sets $top.
+func (q *Query) Top(n int) *Query {
+	q.top = &n
+	return q
+}
+
+// Skip sets $skip.
+func (q *Query) Skip(n int) *Query {
+	q.skip = &n
+	return q
+}
+
+// Count asks for the total number of matching entities ($count=true, or
+// $inlinecount=allpages in v3).
+func (q *Query) Count() *Query {
+	q.count = true
+	return q
+}
+
+// Encode returns the query string in the OData v4 form. Literals that
+// cannot be written leave the string empty; the client reports the error.
+func (q *Query) Encode() string {
+	s, _ := q.encode(false)
+	return s
+}
+
+// encode writes the query string for an OData v4 or v2/v3 service.
+func (q *Query) encode(v3 bool) (string, error) {
+	opts, err := q.options(v3, "&")
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(opts))
+	for i, o := range opts {
+		parts[i] = o.name + "=" + url.QueryEscape(o.value)
+	}
+	return strings.Join(parts, "&"), nil
+}
+
+type queryOption struct{ name, value string }
+
+// options returns the options in a fixed order. In v3, which has no nested
+// expand options, expanded selects are flattened into $select and $expand.
+func (q *Query) options(v3 bool, sep string) ([]queryOption, error) {
+	var opts []queryOption
+	if !q.filter.IsZero() {
+		s, err := q.filter.render(v3)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, queryOption{"$filter", s})
+	}
+	selects := paths(q.selects)
+	var expands []string
+	for _, e := range q.expands {
+		if !v3 {
+			s := string(e.path)
+			if e.opts != nil {
+				sub, err := e.opts.options(false, ";")
+				if err != nil {
+					return nil, err
+				}
+				if len(sub) > 0 {
+					parts := make([]string, len(sub))
+					for i, o := range sub {
+						parts[i] = o.name + "=" + o.value
+					}
+					s += "(" + strings.Join(parts, ";") + ")"
+				}
+			}
+			expands = append(expands, s)
+			continue
+		}
+		sel, exp, err := e.flatten()
+		if err != nil {
+			return nil, err
+		}
+		if len(q.selects) > 0 {
+			selects = append(selects, sel...)
+		}
+		expands = append(expands, exp...)
+	}
+	if len(selects) > 0 {
+		opts = append(opts, queryOption{"$select", strings.Join(selects, ",")})
+	}
+	if len(expands) > 0 {
+		opts = append(opts, queryOption{"$expand", strings.Join(expands, ",")})
+	}
+	if len(q.orderBy) > 0 {
+		items := make([]string, len(q.orderBy))
+		for i, o := range q.orderBy {
+			items[i] = string(o)
+		}
+		opts = append(opts, queryOption{"$orderby", strings.Join(items, ",")})
+	}
+	if q.top != nil {
+		opts = append(opts, queryOption{"$top", strconv.Itoa(*q.top)})
+	}
+	if q.skip != nil {
+		opts = append(opts, queryOption{"$skip", strconv.Itoa(*q.skip)})
+	}
+	if q.count {
+		if v3 {
+			opts = append(opts, queryOption{"$inlinecount", "allpages"})
+		} else {
+			opts = append(opts, queryOption{"$count", "true"})
+		}
+	}
+	return opts, nil
+}
+
+// flatten writes an expand for v3: A/B paths in $expand and $select.
+func (e expandItem) flatten() (selects, expands []string, err error) {
+	if e.opts == nil {
+		return []string{string(e.path)}, []string{string(e.path)}, nil
+	}
+	o := e.opts
+	if !o.filter.IsZero() || len(o.orderBy) > 0 || o.top != nil || o.skip != nil || o.count {
+		return nil, nil, errors.New("odata: v3 services take only $select and $expand within $expand")
+	}
+	if len(o.selects) == 0 {
+		selects = []string{string(e.path)}
+	}
+	for _, s := range o.selects {
+		selects = append(selects, string(e.path.Child(string(s))))
+	}
+	expands = []string{string(e.path)}
+	for _, sub := range o.expands {
+		sel, exp, err := expandItem{path: e.path.Child(string(sub.path)), opts: sub.opts}.flatten()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(o.selects) > 0 {
+			selects = append(selects, sel...)
+		}
+		expands = append(expands, exp...)
+	}
+	return selects, expands, nil
+}
+
+func paths(ps []Path) []string {
+	out := make([]string, len(ps))
+	for i, p := range ps {
+		out[i] = string(p)
+	}
+	return out
+}
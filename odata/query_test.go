@@ -0,0 +1,158 @@
+package odata
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testStatus string
+
+func TestFormatLiteral(t *testing.T) {
+	dec, _ := NewRat("1.5")
+	at := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		v      any
+		typ    string
+		v4, v3 string
+	}{
+		{"it's", "Edm.String", "'it''s'", "'it''s'"},
+		{nil, "Edm.String", "null", "null"},
+		{true, "Edm.Boolean", "true", "true"},
+		{int32(7), "Edm.Int32", "7", "7"},
+		{int64(12), "Edm.Int64", "12", "12L"},
+		{dec, "Edm.Decimal", "1.5", "1.5M"},
+		{1.5, "Edm.Double", "1.5", "1.5"},
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", "Edm.Guid", "0f8fad5b-d9cb-469f-a165-70867728950e", "guid'0f8fad5b-d9cb-469f-a165-70867728950e'"},
+		{at, "Edm.DateTime", "2024-03-01T09:30:00", "datetime'2024-03-01T09:30:00'"},
+		{at, "Edm.DateTimeOffset", "2024-03-01T09:30:00Z", "datetimeoffset'2024-03-01T09:30:00Z'"},
+		{NewDate(2024, 3, 1), "Edm.Date", "2024-03-01", "2024-03-01"},
+		{NewDate(2024, 3, 1), "Edm.DateTime", "2024-03-01T00:00:00", "datetime'2024-03-01T00:00:00'"},
+		{testStatus("bost_Open"), "SAPB1.BoStatus", "SAPB1.BoStatus'bost_Open'", "'bost_Open'"},
+		{"C001", "", "'C001'", "'C001'"},
+	}
+	for _, tt := range tests {
+		for _, v3 := range []bool{false, true} {
+			want := tt.v4
+			if v3 {
+				want = tt.v3
+			}
+			got, err := formatLiteral(tt.v, tt.typ, v3)
+			if err != nil || got != want {
+				t.Errorf("formatLiteral(%v, %s, v3=%v) = %q, %v; want %q", tt.v, tt.typ, v3, got, err, want)
+			}
+		}
+	}
+}
+
+// line is the field descriptor of a document line, as generated.
+type line struct{ p Path }
+
+func (l line) ItemCode() StringField { return NewStringField(l.p.Child("ItemCode")) }
+func (l line) Quantity() Field[float64] {
+	return NewField[float64](l.p.Child("Quantity"), "Edm.Double")
+}
+func (l line) Serials() CollectionField[line] {
+	return NewCollectionField(l.p.Child("Serials"), func(p Path) line { return line{p} })
+}
+
+var (
+	cardCode  = NewStringField("CardCode")
+	docEntry  = NewField[int64]("DocEntry", "Edm.Int64")
+	docLines  = NewCollectionField(Path("DocumentLines"), func(p Path) line { return line{p} })
+	docStatus = NewField[testStatus]("DocumentStatus", "SAPB1.BoStatus")
+)
+
+func TestQueryEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      *Query
+		v4, v3 string
+	}{
+		{
+			"filter and paging",
+			NewQuery().Filter(cardCode.Eq("C001")).Filter(docEntry.Gt(10)).OrderBy(docEntry.Desc()).Top(5).Skip(10).Count(),
+			"$filter=CardCode eq 'C001' and DocEntry gt 10&$orderby=DocEntry desc&$top=5&$skip=10&$count=true",
+			"$filter=CardCode eq 'C001' and DocEntry gt 10L&$orderby=DocEntry desc&$top=5&$skip=10&$inlinecount=allpages",
+		},
+		{
+			"or inside and",
+			NewQuery().Filter(And(Or(cardCode.Eq("A"), cardCode.Eq("B")), Not(docStatus.Eq("bost_Close")))),
+			"$filter=(CardCode eq 'A' or CardCode eq 'B') and not DocumentStatus eq SAPB1.BoStatus'bost_Close'",
+			"$filter=(CardCode eq 'A' or CardCode eq 'B') and not DocumentStatus eq 'bost_Close'",
+		},
+		{
+			"string functions",
+			NewQuery().Filter(cardCode.Contains("x")).Filter(cardCode.StartsWith("C")),
+			"$filter=contains(CardCode,'x') and startswith(CardCode,'C')",
+			"$filter=substringof('x',CardCode) and startswith(CardCode,'C')",
+		},
+		{
+			"nested lambdas",
+			NewQuery().Filter(docLines.Any(func(l line) Filter {
+				return And(l.Quantity().Gt(1), l.Serials().Any(func(s line) Filter { return s.ItemCode().Eq("S") }))
+			})),
+			"$filter=DocumentLines/any(x0:x0/Quantity gt 1 and x0/Serials/any(x1:x1/ItemCode eq 'S'))",
+			"$filter=DocumentLines/any(x0:x0/Quantity gt 1 and x0/Serials/any(x1:x1/ItemCode eq 'S'))",
+		},
+		{
+			"in",
+			NewQuery().Filter(docEntry.In(1, 2)),
+			"$filter=DocEntry eq 1 or DocEntry eq 2",
+			"$filter=DocEntry eq 1L or DocEntry eq 2L",
+		},
+		{
+			"in without values",
+			NewQuery().Filter(docEntry.In()),
+			"$filter=false",
+			"$filter=false",
+		},
+		{
+			"expand with options",
+			NewQuery().Select(cardCode).Expand(docLines, NewQuery().Select(line{}.ItemCode())),
+			"$select=CardCode&$expand=DocumentLines($select=ItemCode)",
+			"$select=CardCode,DocumentLines/ItemCode&$expand=DocumentLines",
+		},
+		{
+			"expand",
+			NewQuery().Expand(docLines),
+			"$expand=DocumentLines",
+			"$expand=DocumentLines",
+		},
+	}
+	for _, tt := range tests {
+		for _, v3 := range []bool{false, true} {
+			want := tt.v4
+			if v3 {
+				want = tt.v3
+			}
+			s, err := tt.q.encode(v3)
+			if err != nil {
+				t.Errorf("%s (v3=%v): %v", tt.name, v3, err)
+				continue
+			}
+			got, _ := url.QueryUnescape(strings.ReplaceAll(s, "+", "%20"))
+			if got != want {
+				t.Errorf("%s (v3=%v):\n got %s\nwant %s", tt.name, v3, got, want)
+			}
+		}
+	}
+}
+
+func TestQueryV3NestedOptions(t *testing.T) {
+	for _, sub := range []*Query{
+		NewQuery().Filter(line{}.Quantity().Gt(1)),
+		NewQuery().Top(1),
+		NewQuery().OrderBy(line{}.Quantity().Asc()),
+		NewQuery().Count(),
+	} {
+		q := NewQuery().Expand(docLines, sub)
+		if _, err := q.encode(true); err == nil || !strings.Contains(err.Error(), "v3") {
+			t.Errorf("v3 expand with %s: error %v", sub.Encode(), err)
+		}
+		if _, err := q.encode(false); err != nil {
+			t.Errorf("v4 expand with %s: %v", sub.Encode(), err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package odata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Credentials are the body of a Service Layer Login.
+type Credentials struct {
+	CompanyDB string `json:"CompanyDB"`
+	UserName  string `json:"UserName"`
+	Password  string `json:"Password"`
+	Language  int    `json:"Language,omitempty"`
+}
+
+// CredentialProvider returns the credentials of each Login, so that they
+// can come from a vault and change while the client runs.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials provides the same credentials for every Login.
+type StaticCredentials Credentials
+
+func (c StaticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// CredentialFunc adapts a function to a CredentialProvider.
+type CredentialFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialFunc) Credentials(ctx context.Context) (Credentials, error) { return f(ctx) }
+
+// ErrNoCredentials is returned when a session has to log in without a
+// credential provider.
+var ErrNoCredentials = errors.New("odata: session needs to log in but has no credentials")
+
+// Session is a Service Layer session: the cookies of a Login (B1SESSION,
+// and ROUTEID behind a load balancer), sent with every request of the
+// client it is set on. The client logs in before its first request and
+// again when the session has been idle for its timeout or a request gets
+// 401, then sends the request once more; concurrent requests share one
+// Login. A Session is safe for concurrent use by one client.
+type Session struct {
+	Credentials CredentialProvider
+
+	mu       sync.Mutex
+	cookies  []*http.Cookie
+	timeout  time.Duration // SessionTimeout of the Login; 0 when unknown
+	lastUsed time.Time
+	gen      int        // counts the logins, to tell a stale 401 from a current one
+	login    *loginCall // the Login in flight
+}
+
+// loginCall is a Login that concurrent requests wait for.
+type loginCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewSession returns a session that logs in with the credentials of p.
+func NewSession(p CredentialProvider) *Session { return &Session{Credentials: p} }
+
+// Cookies returns the cookies of the session, to persist it, e.g. to be
+// shared by the instances of an application.
+func (s *Session) Cookies() []*http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Cookie(nil), s.cookies...)
+}
+
+// SetCookies resumes a session from cookies saved with Cookies; timeout is
+// its SessionTimeout, 0 if unknown.
+func (s *Session) SetCookies(cookies []*http.Cookie, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cookies = append([]*http.Cookie(nil), cookies...)
+	s.timeout, s.lastUsed = timeout, time.Now()
+	s.gen++
+}
+
+// current returns the login generation and cookies to send; ok is false
+// when there is no session or it has been idle for too long.
+func (s *Session) current() (gen int, cookies []*http.Cookie, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok = len(s.cookies) > 0 && (s.timeout == 0 || time.Since(s.lastUsed) < s.timeout)
+	return s.gen, s.cookies, ok
+}
+
+// update records the cookies a response set (Service Layer may move the
+// session to another ROUTEID) and that the session of gen was used.
+func (s *Session) update(resp *http.Response, gen int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen != s.gen {
+		return
+	}
+	s.lastUsed = time.Now()
+	s.cookies = mergeCookies(s.cookies, resp.Cookies())
+}
+
+// mergeCookies returns to with the cookies of from added, replacing those
+// of the same name.
+func mergeCookies(to, from []*http.Cookie) []*http.Cookie {
+	out := append([]*http.Cookie(nil), to...)
+	for _, c := range from {
+		replaced := false
+		for i, have := range out {
+			if have.Name == c.Name {
+				out[i], replaced = c, true
+			}
+		}
+		if !replaced {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// refresh logs in unless a Login after the session gen has happened; a
+// Login in flight is waited for rather than repeated.
+func (s *Session) refresh(ctx context.Context, c *Client, gen int) error {
+	s.mu.Lock()
+	if s.gen != gen {
+		s.mu.Unlock()
+		return nil
+	}
+	if call := s.login; call != nil {
+		s.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &loginCall{done: make(chan struct{})}
+	s.login = call
+	s.mu.Unlock()
+
+	cookies, timeout, err := s.doLogin(ctx, c)
+	s.mu.Lock()
+	if err == nil {
+		// keep the ROUTEID of the old session unless the Login set another
+		s.cookies = mergeCookies(s.cookies, cookies)
+		s.timeout, s.lastUsed = timeout, time.Now()
+		s.gen++
+	}
+	s.login = nil
+	s.mu.Unlock()
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// doLogin posts the credentials to Login and returns the session cookies
+// and timeout.
+func (s *Session) doLogin(ctx context.Context, c *Client) ([]*http.Cookie, time.Duration, error) {
+	if s.Credentials == nil {
+		return nil, 0, ErrNoCredentials
+	}
+	creds, err := s.Credentials.Credentials(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("odata: login: credentials: %w", err)
+	}
+	body, err := json.Marshal(creds)
+	if err != nil {
+		return nil, 0, fmt.Errorf("odata: login: %w", err)
+	}
+	u := c.ServiceRoot + "/Login"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for _, cookie := range s.Cookies() {
+		if cookie.Name == "ROUTEID" {
+			req.AddCookie(cookie) // stay on the node of the session
+		}
+	}
+	resp, data, err := c.do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, 0, statusError(http.MethodPost, u, resp.StatusCode, data, nil)
+	}
+	var info struct {
+		SessionTimeout int // minutes
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, 0, fmt.Errorf("odata: login: decode response: %w", err)
+	}
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return nil, 0, errors.New("odata: login: no session cookie in the response")
+	}
+	return cookies, time.Duration(info.SessionTimeout) * time.Minute, nil
+}
+
+// Login logs in with the credentials of the client's session, replacing
+// the session it has.
+func (c *Client) Login(ctx context.Context) error {
+	if c.Session == nil {
+		return errors.New("odata: login: client has no Session")
+	}
+	gen, _, _ := c.Session.current()
+	return c.Session.refresh(ctx, c, gen)
+}
+
+// Logout ends the client's session; the next request logs in again.
+func (c *Client) Logout(ctx context.Context) error {
+	s := c.Session
+	if s == nil {
+		return nil
+	}
+	_, cookies, _ := s.current()
+	s.mu.Lock()
+	s.cookies, s.gen = nil, s.gen+1
+	s.mu.Unlock()
+	if len(cookies) == 0 {
+		return nil
+	}
+	u := c.ServiceRoot + "/Logout"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return err
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	resp, data, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(http.MethodPost, u, resp.StatusCode, data, nil)
+	}
+	return nil
+}
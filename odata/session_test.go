@@ -0,0 +1,230 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServiceLayer hands out sessions at Login, as Service Layer does:
+// B1SESSION for every Login, ROUTEID only for the first, and answers 401
+// to requests without a live session.
+type fakeServiceLayer struct {
+	t          *testing.T
+	loginDelay time.Duration
+
+	mu          sync.Mutex
+	logins      int
+	logouts     int
+	sessions    map[string]bool
+	routeIDs    []string // ROUTEID sent with each request
+	loginRoutes []string // ROUTEID sent with the Logins after the first
+	loginBody   Credentials
+}
+
+func newFakeServiceLayer(t *testing.T) (*fakeServiceLayer, *httptest.Server) {
+	f := &fakeServiceLayer{t: t, sessions: map[string]bool{}}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts
+}
+
+func (f *fakeServiceLayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/b1s/v2/Login":
+		time.Sleep(f.loginDelay)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if err := json.NewDecoder(r.Body).Decode(&f.loginBody); err != nil {
+			f.t.Errorf("login body: %v", err)
+		}
+		f.logins++
+		id := fmt.Sprintf("s%d", f.logins)
+		f.sessions[id] = true
+		http.SetCookie(w, &http.Cookie{Name: "B1SESSION", Value: id})
+		if f.logins == 1 {
+			http.SetCookie(w, &http.Cookie{Name: "ROUTEID", Value: ".node1"})
+		} else if c, err := r.Cookie("ROUTEID"); err == nil {
+			f.loginRoutes = append(f.loginRoutes, c.Value)
+		}
+		io.WriteString(w, `{"SessionId":"`+id+`","SessionTimeout":30}`)
+		return
+	case "/b1s/v2/Logout":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if c, err := r.Cookie("B1SESSION"); err == nil {
+			delete(f.sessions, c.Value)
+		}
+		f.logouts++
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := r.Cookie("B1SESSION")
+	if err != nil || !f.sessions[c.Value] {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error":{"code":301,"message":"Invalid session or session already timeout."}}`)
+		return
+	}
+	route := ""
+	if c, err := r.Cookie("ROUTEID"); err == nil {
+		route = c.Value
+	}
+	f.routeIDs = append(f.routeIDs, route)
+	io.WriteString(w, `{"value":"`+c.Value+`"}`)
+}
+
+// expire ends every session, as the SessionTimeout of the server does.
+func (f *fakeServiceLayer) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions = map[string]bool{}
+}
+
+func (f *fakeServiceLayer) counts() (logins, logouts int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins, f.logouts
+}
+
+func newSessionClient(ts *httptest.Server) *Client {
+	c := NewClient(ts.URL+"/b1s/v2", ts.Client())
+	c.Session = NewSession(StaticCredentials{CompanyDB: "SBODEMO", UserName: "manager", Password: "secret"})
+	return c
+}
+
+func getSession(t *testing.T, c *Client) string {
+	t.Helper()
+	var out struct{ Value string }
+	if err := c.Do(context.Background(), http.MethodGet, "Items", nil, nil, nil, &out); err != nil {
+		t.Fatalf("GET Items: %v", err)
+	}
+	return out.Value
+}
+
+func TestSessionLogin(t *testing.T) {
+	f, ts := newFakeServiceLayer(t)
+	c := newSessionClient(ts)
+
+	if got := getSession(t, c); got != "s1" {
+		t.Errorf("first request used session %q, want s1", got)
+	}
+	getSession(t, c)
+	if logins, _ := f.counts(); logins != 1 {
+		t.Errorf("%d logins, want 1", logins)
+	}
+	if f.loginBody.CompanyDB != "SBODEMO" || f.loginBody.UserName != "manager" {
+		t.Errorf("login body = %+v", f.loginBody)
+	}
+	if err := c.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := getSession(t, c); got != "s2" {
+		t.Errorf("after Login the session is %q, want s2", got)
+	}
+}
+
+func TestSessionReloginAfter401(t *testing.T) {
+	f, ts := newFakeServiceLayer(t)
+	c := newSessionClient(ts)
+	getSession(t, c)
+	f.expire()
+	if got := getSession(t, c); got != "s2" {
+		t.Errorf("after a 401 the session is %q, want s2", got)
+	}
+	if logins, _ := f.counts(); logins != 2 {
+		t.Errorf("%d logins, want 2", logins)
+	}
+}
+
+func TestSessionLoginFailure(t *testing.T) {
+	_, ts := newFakeServiceLayer(t)
+	c := NewClient(ts.URL+"/b1s/v2", ts.Client())
+	c.Session = NewSession(nil)
+	err := c.Do(context.Background(), http.MethodGet, "Items", nil, nil, nil, nil)
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("err = %v, want ErrNoCredentials", err)
+	}
+}
+
+func TestSessionSingleFlight(t *testing.T) {
+	f, ts := newFakeServiceLayer(t)
+	f.loginDelay = 50 * time.Millisecond
+	c := newSessionClient(ts)
+
+	run := func() {
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var out struct{ Value string }
+				if err := c.Do(context.Background(), http.MethodGet, "Items", nil, nil, nil, &out); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	run()
+	if logins, _ := f.counts(); logins != 1 {
+		t.Errorf("%d logins for 20 concurrent first requests, want 1", logins)
+	}
+	f.expire()
+	run()
+	if logins, _ := f.counts(); logins != 2 {
+		t.Errorf("%d logins after the session expired, want 2", logins)
+	}
+}
+
+func TestSessionRouteID(t *testing.T) {
+	f, ts := newFakeServiceLayer(t)
+	c := newSessionClient(ts)
+	getSession(t, c)
+	f.expire()
+	getSession(t, c) // the second Login sets no ROUTEID
+	if len(f.loginRoutes) != 1 || f.loginRoutes[0] != ".node1" {
+		t.Errorf("second Login sent ROUTEID %v, want .node1", f.loginRoutes)
+	}
+	for i, route := range f.routeIDs {
+		if route != ".node1" {
+			t.Errorf("request %d sent ROUTEID %q, want .node1", i, route)
+		}
+	}
+	names := map[string]string{}
+	for _, cookie := range c.Session.Cookies() {
+		names[cookie.Name] = cookie.Value
+	}
+	if names["B1SESSION"] != "s2" || names["ROUTEID"] != ".node1" {
+		t.Errorf("session cookies = %v", names)
+	}
+}
+
+func TestSessionLogout(t *testing.T) {
+	f, ts := newFakeServiceLayer(t)
+	c := newSessionClient(ts)
+	getSession(t, c)
+	if err := c.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, logouts := f.counts(); logouts != 1 {
+		t.Errorf("%d logouts, want 1", logouts)
+	}
+	if len(c.Session.Cookies()) != 0 {
+		t.Errorf("cookies after Logout: %v", c.Session.Cookies())
+	}
+	if got := getSession(t, c); got != "s2" {
+		t.Errorf("after Logout the session is %q, want a new one", got)
+	}
+	if err := NewClient(ts.URL, ts.Client()).Logout(context.Background()); err != nil {
+		t.Errorf("Logout without a session: %v", err)
+	}
+}
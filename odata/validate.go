@@ -0,0 +1,163 @@
+package odata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError is one failed check, addressed by the JSON path of the field
+// (e.g. "DocumentLines[2].ItemCode").
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError is returned by the generated Validate methods and holds
+// every failed check. errors.As finds the single FieldErrors as well.
+type ValidationError []*FieldError
+
+func (v ValidationError) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the FieldErrors.
+func (v ValidationError) Unwrap() []error {
+	errs := make([]error, len(v))
+	for i, e := range v {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Validator collects the failed checks of a generated Validate method.
+type Validator struct {
+	errs ValidationError
+}
+
+// Add records a failed check of the field at path.
+func (v *Validator) Add(path, format string, args ...any) {
+	v.errs = append(v.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Required checks that a non-nullable field is set.
+func (v *Validator) Required(path string, ok bool) {
+	if !ok {
+		v.Add(path, "is required")
+	}
+}
+
+// MaxLength checks the length of s in characters.
+func (v *Validator) MaxLength(path, s string, max int) {
+	if n := utf8.RuneCountInString(s); n > max {
+		v.Add(path, "is %d characters long, the maximum is %d", n, max)
+	}
+}
+
+// Decimal checks that value fits Edm.Decimal(precision, scale). Precision 0
+// leaves the number of digits unchecked and scale -1 (unspecified or
+// variable) the fraction; scale 0 allows integers only, as in Edm. value is
+// a string, json.Number, float64 or a fmt.Stringer such as Rat or
+// decimal.Decimal.
+func (v *Validator) Decimal(path string, value any, precision, scale int) {
+	var s string
+	switch x := value.(type) {
+	case string:
+		s = x
+	case json.Number:
+		s = x.String()
+	case float64:
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	case fmt.Stringer:
+		s = x.String()
+	default:
+		v.Add(path, "is not a decimal")
+		return
+	}
+	intDigits, fracDigits, ok := decimalDigits(s)
+	switch {
+	case !ok:
+		v.Add(path, "%q is not a decimal", s)
+	case scale >= 0 && fracDigits > scale,
+		precision > 0 && intDigits > precision-max(scale, 0):
+		v.Add(path, "%s does not fit Edm.Decimal(%d, %d)", s, precision, scale)
+	}
+}
+
+// decimalDigits counts the significant integer and fractional digits of a
+// plain decimal literal.
+func decimalDigits(s string) (intDigits, fracDigits int, ok bool) {
+	s = strings.TrimLeft(strings.TrimSpace(s), "+-")
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, 0, false
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, 0, false
+			}
+		}
+	}
+	return len(strings.TrimLeft(intPart, "0")), len(strings.TrimRight(fracPart, "0")), true
+}
+
+// Enum checks that an enum field holds one of the members.
+func (v *Validator) Enum(path string, ok bool) {
+	if !ok {
+		v.Add(path, "is not a valid value")
+	}
+}
+
+// Nested adds the errors of a nested value's Validate under path; an empty
+// path merges them as they are (embedded structs).
+func (v *Validator) Nested(path string, err error) {
+	if err == nil {
+		return
+	}
+	var ve ValidationError
+	if !errors.As(err, &ve) {
+		v.Add(path, "%v", err)
+		return
+	}
+	for _, e := range ve {
+		sub := e.Path
+		switch {
+		case path == "":
+		case sub == "":
+			sub = path
+		case strings.HasPrefix(sub, "["):
+			sub = path + sub
+		default:
+			sub = path + "." + sub
+		}
+		v.errs = append(v.errs, &FieldError{Path: sub, Message: e.Message})
+	}
+}
+
+// ElemPath is the path of element i of the collection at path.
+func ElemPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// Err returns the collected errors as a ValidationError, or nil.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
@@ -0,0 +1,39 @@
+package odata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidatorDecimal(t *testing.T) {
+	r, _ := NewRat("12.345")
+	tests := []struct {
+		value            any
+		precision, scale int
+		ok               bool
+	}{
+		{"12.5", 0, -1, true},
+		{"123456789.123456789", 0, -1, true},
+		{"12", 3, 0, true},
+		{"12.0", 3, 0, true}, // trailing zeros are not significant
+		{"12.5", 3, 0, false},
+		{"12.5", 0, 0, false},
+		{"-0.5", 0, 0, false},
+		{"123.45", 5, 2, true},
+		{"1234.5", 5, 2, false},
+		{"1.234", 5, 2, false},
+		{json.Number("99.9"), 3, 1, true},
+		{1.5, 2, 1, true},
+		{r, 6, 2, false},
+		{r, 6, 3, true},
+		{"1e3", 6, 2, false},
+		{true, 6, 2, false},
+	}
+	for _, tt := range tests {
+		var v Validator
+		v.Decimal("X", tt.value, tt.precision, tt.scale)
+		if ok := len(v.errs) == 0; ok != tt.ok {
+			t.Errorf("Decimal(%v, %d, %d): ok = %v, want %v (%v)", tt.value, tt.precision, tt.scale, ok, tt.ok, v.errs)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package sapgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of PropertyAlias.
+const (
+	AliasWire     = "alias"    // read both names, write the alias (default)
+	AliasMetadata = "metadata" // read both names, write the metadata name
+	AliasOff      = "off"      // metadata names only
+)
+
+// PropertyAlias controls the SAP B1 "...Property" quirk: the metadata
+// declares e.g. ActivityProperty, while the service sends Activity. A
+// property whose name ends in "Property" gets the name without the suffix
+// as its alias, unless a sibling already has that name. User-defined
+// fields keep their names.
+type PropertyAlias string
+
+// Validate checks the mode.
+func (a PropertyAlias) Validate() error {
+	switch a {
+	case "", AliasWire, AliasMetadata, AliasOff:
+		return nil
+	}
+	return fmt.Errorf("unknown propertyAlias %q (use alias | metadata | off)", string(a))
+}
+
+// Enabled reports whether aliases are recognised at all.
+func (a PropertyAlias) Enabled() bool { return a != AliasOff }
+
+// Aliases returns the alias of every property in names that has one,
+// keyed by metadata name; nil with the quirk off.
+func (a PropertyAlias) Aliases(names []string) map[string]string {
+	if !a.Enabled() {
+		return nil
+	}
+	siblings := make(map[string]bool, len(names))
+	for _, n := range names {
+		siblings[n] = true
+	}
+	var out map[string]string
+	for _, n := range names {
+		alias := strings.TrimSuffix(n, "Property")
+		if alias == n || alias == "" || IsUDF(n) || siblings[alias] {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[n] = alias
+	}
+	return out
+}
+
+// WireName is the name written for property prop with the given aliases.
+func (a PropertyAlias) WireName(prop string, aliases map[string]string) string {
+	if alias, ok := aliases[prop]; ok && a != AliasMetadata {
+		return alias
+	}
+	return prop
+}
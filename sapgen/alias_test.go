@@ -0,0 +1,60 @@
+package sapgen
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	names := []string{"ActivityCode", "ActivityProperty", "HandledByProperty", "HandledBy2", "NotesProperty", "Notes", "U_KindProperty", "Property"}
+	want := map[string]string{"ActivityProperty": "Activity", "HandledByProperty": "HandledBy"}
+	for _, mode := range []PropertyAlias{"", AliasWire, AliasMetadata} {
+		if got := mode.Aliases(names); !maps.Equal(got, want) {
+			t.Errorf("%q: Aliases = %v, want %v", mode, got, want)
+		}
+	}
+	if got := PropertyAlias(AliasOff).Aliases(names); got != nil {
+		t.Errorf("off: Aliases = %v", got)
+	}
+	if got := PropertyAlias(AliasWire).Aliases([]string{"CardCode"}); got != nil {
+		t.Errorf("no aliases: %v", got)
+	}
+}
+
+func TestWireName(t *testing.T) {
+	aliases := map[string]string{"ActivityProperty": "Activity"}
+	tests := []struct {
+		mode       PropertyAlias
+		prop, want string
+	}{
+		{"", "ActivityProperty", "Activity"},
+		{AliasWire, "ActivityProperty", "Activity"},
+		{AliasMetadata, "ActivityProperty", "ActivityProperty"},
+		{AliasOff, "ActivityProperty", "ActivityProperty"},
+		{AliasWire, "ActivityCode", "ActivityCode"},
+	}
+	for _, tt := range tests {
+		a := aliases
+		if tt.mode == AliasOff {
+			a = tt.mode.Aliases([]string{tt.prop})
+		}
+		if got := tt.mode.WireName(tt.prop, a); got != tt.want {
+			t.Errorf("%q: WireName(%s) = %s, want %s", tt.mode, tt.prop, got, tt.want)
+		}
+	}
+}
+
+func TestPropertyAliasValidate(t *testing.T) {
+	for _, mode := range []PropertyAlias{"", AliasWire, AliasMetadata, AliasOff} {
+		if err := mode.Validate(); err != nil {
+			t.Errorf("%q: %v", mode, err)
+		}
+	}
+	if err := PropertyAlias("both").Validate(); err == nil || !strings.Contains(err.Error(), `unknown propertyAlias "both"`) {
+		t.Errorf("both: %v", err)
+	}
+	if PropertyAlias(AliasOff).Enabled() || !PropertyAlias("").Enabled() {
+		t.Error("Enabled is wrong")
+	}
+}
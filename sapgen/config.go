@@ -0,0 +1,259 @@
+package sapgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Target kinds understood by `generate --config`.
+const (
+	KindGo      = "go"      // Go structs (gpt5mini emitter)
+	KindZod     = "zod"     // Zod schemas (main2 emitter)
+	KindArkType = "arktype" // ArkType validators (root emitter)
+)
+
+// Config is a checked-in description of what to generate:
+//
+//	{
+//	  "sources": { "b1": { "path": "metadata.xml" } },
+//	  "targets": [
+//	    { "name": "models", "kind": "go", "source": "b1", "split": "perType",
+//	      "outDir": "internal/models", "package": "models", "decimal": "bigrat" },
+//	    { "name": "web", "kind": "zod", "source": "b1", "outDir": "web/src/types",
+//	      "include": ["set:Orders", "set:BusinessPartners"], "stubNavigation": true }
+//	  ]
+//	}
+//
+// Relative paths are resolved against the directory of the config file, so
+// the result does not depend on where the command is run from.
+type Config struct {
+	Sources map[string]Source `json:"sources"`
+	Scalars Scalars           `json:"scalars,omitempty"` // shared by all targets
+	Targets []Target          `json:"targets"`
+}
+
+// Source is one $metadata document.
+type Source struct {
+	Path string `json:"path"`
+}
+
+// Target is one generator run. Options that a kind does not use are ignored;
+// zero values take the defaults of the matching command-line flag.
+type Target struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"` // go | zod | arktype
+	Source string `json:"source"`
+
+	Split  string `json:"split,omitempty"`  // single | perType
+	Out    string `json:"out,omitempty"`    // output file for split=single
+	OutDir string `json:"outDir,omitempty"` // output directory for split=perType
+
+	Decimal string `json:"decimal,omitempty"` // see GoDecimalModes / TsDecimalModes
+	IEEE754 bool   `json:"ieee754,omitempty"`
+
+	Selection   // include, exclude, stubNavigation
+	Naming      // case, keys, initialisms, renames
+	UserObjects // objects, userObjects, standardModule
+
+	Scalars Scalars `json:"scalars,omitempty"` // merged over Config.Scalars
+	UDF     UDF     `json:"udf,omitempty"`     // U_ fields inline or in extensions (go, zod)
+
+	PropertyAlias PropertyAlias `json:"propertyAlias,omitempty"` // alias | metadata | off
+	Quirks        QuirkSet      `json:"quirks,omitempty"`        // SAP B1 rules on or off, see Quirks
+	SourceHash    bool          `json:"sourceHash,omitempty"`    // metadata SHA-256 in the file headers
+	Query         bool          `json:"query,omitempty"`         // query field descriptors (go, zod)
+	Batch         bool          `json:"batch,omitempty"`         // $batch builder (zod; Go clients always have one)
+	ETag          bool          `json:"etag,omitempty"`          // @odata.etag kept on entity models (go, zod)
+
+	// Go only
+	Package         string `json:"package,omitempty"`
+	ImportPath      string `json:"importPath,omitempty"`
+	PkgPerNamespace bool   `json:"pkgPerNamespace,omitempty"`
+	NsPrefix        string `json:"nsPrefix,omitempty"` // auto | always | none
+	Nullable        string `json:"nullable,omitempty"` // pointer | opt
+	Patch           bool   `json:"patch,omitempty"`
+	Validate        bool   `json:"validate,omitempty"` // Validate() error per struct
+	Client          bool   `json:"client,omitempty"`   // Client with a service per entity set
+	Runtime         string `json:"runtime,omitempty"`
+}
+
+// LoadConfig reads and validates a config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.resolvePaths(filepath.Dir(path))
+	for i := range cfg.Targets {
+		cfg.Targets[i].Scalars = cfg.Scalars.Merge(cfg.Targets[i].Scalars)
+	}
+	return &cfg, nil
+}
+
+// DecimalMode returns the parsed decimal option of t, or "" when unset.
+func (t *Target) DecimalMode() (DecimalMode, error) {
+	if t.Decimal == "" {
+		return "", nil
+	}
+	allowed := TsDecimalModes
+	if t.Kind == KindGo {
+		allowed = GoDecimalModes
+	}
+	return ParseDecimalMode(t.Decimal, allowed)
+}
+
+// SplitMode returns the split option of t, defaulting like the flags of the
+// respective generator (single for Go, perType for TypeScript).
+func (t *Target) SplitMode() string {
+	if t.Split != "" {
+		return t.Split
+	}
+	if t.Kind == KindGo {
+		return "single"
+	}
+	return "perType"
+}
+
+// Select returns the targets with the given names, or all of them when names
+// is empty.
+func (c *Config) Select(names []string) ([]Target, error) {
+	if len(names) == 0 {
+		return c.Targets, nil
+	}
+	var out []Target
+	for _, n := range names {
+		found := false
+		for _, t := range c.Targets {
+			if t.Name == n {
+				out = append(out, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown target %q", n)
+		}
+	}
+	return out, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Targets) == 0 {
+		return fmt.Errorf("no targets")
+	}
+	if err := c.Scalars.Validate(); err != nil {
+		return err
+	}
+	for name, src := range c.Sources {
+		if src.Path == "" {
+			return fmt.Errorf("source %q: path is required", name)
+		}
+	}
+	seen := map[string]bool{}
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		if t.Name == "" {
+			return fmt.Errorf("target %d: name is required", i+1)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("duplicate target %q", t.Name)
+		}
+		seen[t.Name] = true
+		switch t.Kind {
+		case KindGo, KindZod, KindArkType:
+		default:
+			return fmt.Errorf("target %q: unknown kind %q (use go | zod | arktype)", t.Name, t.Kind)
+		}
+		if _, ok := c.Sources[t.Source]; !ok {
+			return fmt.Errorf("target %q: unknown source %q", t.Name, t.Source)
+		}
+		switch t.Split {
+		case "", "single", "perType":
+		default:
+			return fmt.Errorf("target %q: unknown split %q (use single | perType)", t.Name, t.Split)
+		}
+		if t.SplitMode() == "single" && t.Out == "" {
+			return fmt.Errorf("target %q: out is required for split=single", t.Name)
+		}
+		if t.SplitMode() == "perType" && t.OutDir == "" {
+			return fmt.Errorf("target %q: outDir is required for split=perType", t.Name)
+		}
+		if _, err := t.DecimalMode(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		switch strings.ToLower(t.NsPrefix) {
+		case "", "auto", "always", "none":
+		default:
+			return fmt.Errorf("target %q: unknown nsPrefix %q (use auto | always | none)", t.Name, t.NsPrefix)
+		}
+		switch strings.ToLower(t.Nullable) {
+		case "", "pointer", "opt":
+		default:
+			return fmt.Errorf("target %q: unknown nullable %q (use pointer | opt)", t.Name, t.Nullable)
+		}
+		if err := t.Scalars.Validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if err := t.PropertyAlias.Validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if err := t.Quirks.Validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if err := t.Naming.Validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if _, err := t.Selection.Compile(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if err := t.UDF.Validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if _, ok := c.Sources[t.UDF.Source]; t.UDF.Source != "" && !ok {
+			return fmt.Errorf("target %q: unknown udf source %q", t.Name, t.UDF.Source)
+		}
+		if t.UDF.Extension() && t.Kind == KindArkType {
+			return fmt.Errorf("target %q: udf extension is not supported by arktype targets", t.Name)
+		}
+		if err := t.UserObjects.Validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		if t.Side() == ObjectsUser && t.StandardModule == "" && t.Kind != KindArkType {
+			return fmt.Errorf("target %q: objects=user requires standardModule", t.Name)
+		}
+		if t.PkgPerNamespace && t.ImportPath == "" {
+			return fmt.Errorf("target %q: pkgPerNamespace requires importPath", t.Name)
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolvePaths(dir string) {
+	abs := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for name, src := range c.Sources {
+		src.Path = abs(src.Path)
+		c.Sources[name] = src
+	}
+	for i := range c.Targets {
+		c.Targets[i].Out = abs(c.Targets[i].Out)
+		c.Targets[i].OutDir = abs(c.Targets[i].OutDir)
+	}
+}
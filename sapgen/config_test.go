@@ -0,0 +1,104 @@
+package sapgen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sapgen.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	webDir := filepath.Join(t.TempDir(), "web") // absolute, kept as it is
+	webJSON, _ := json.Marshal(webDir)
+	path := writeConfig(t, `{
+  "sources": {"b1": {"path": "metadata.xml"}},
+  "targets": [
+    {"name": "models", "kind": "go", "source": "b1", "out": "models/models.go", "package": "models", "decimal": "bigrat"},
+    {"name": "web", "kind": "zod", "source": "b1", "outDir": `+string(webJSON)+`}
+  ]
+}`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(path)
+	if got := cfg.Sources["b1"].Path; got != filepath.Join(dir, "metadata.xml") {
+		t.Errorf("source path %s", got)
+	}
+	models, web := cfg.Targets[0], cfg.Targets[1]
+	if models.Out != filepath.Join(dir, "models", "models.go") || web.OutDir != webDir {
+		t.Errorf("out %s, outDir %s", models.Out, web.OutDir)
+	}
+	if models.SplitMode() != "single" || web.SplitMode() != "perType" {
+		t.Errorf("split modes %s, %s", models.SplitMode(), web.SplitMode())
+	}
+	if mode, err := models.DecimalMode(); err != nil || mode != DecimalBigRat {
+		t.Errorf("decimal %q, %v", mode, err)
+	}
+
+	for _, tt := range []struct {
+		names []string
+		want  string
+		err   string
+	}{
+		{nil, "models,web", ""},
+		{[]string{"web"}, "web", ""},
+		{[]string{"web", "models"}, "web,models", ""},
+		{[]string{"api"}, "", `unknown target "api"`},
+	} {
+		got, err := cfg.Select(tt.names)
+		var names []string
+		for _, target := range got {
+			names = append(names, target.Name)
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Select(%v): error %v, want %q", tt.names, err, tt.err)
+			}
+		} else if err != nil || strings.Join(names, ",") != tt.want {
+			t.Errorf("Select(%v) = %v, %v; want %s", tt.names, names, err, tt.want)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	const src = `"sources": {"b1": {"path": "m.xml"}}, `
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{`{` + src + `"targets": []}`, "no targets"},
+		{`{"sources": {"b1": {}}, "targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go"}]}`, `source "b1": path is required`},
+		{`{` + src + `"targets": [{"kind": "go", "source": "b1", "out": "a.go"}]}`, "target 1: name is required"},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go"}, {"name": "a", "kind": "zod", "source": "b1", "outDir": "a"}]}`, `duplicate target "a"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "java", "source": "b1", "out": "a.go"}]}`, `unknown kind "java"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b2", "out": "a.go"}]}`, `unknown source "b2"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "split": "perFile", "out": "a.go"}]}`, `unknown split "perFile"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1"}]}`, "out is required for split=single"},
+		{`{` + src + `"targets": [{"name": "a", "kind": "zod", "source": "b1"}]}`, "outDir is required for split=perType"},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go", "decimal": "number"}]}`, `target "a"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go", "nsPrefix": "some"}]}`, `unknown nsPrefix "some"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go", "nullable": "zero"}]}`, `unknown nullable "zero"`},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go", "pkgPerNamespace": true}]}`, "pkgPerNamespace requires importPath"},
+		{`{` + src + `"targets": [{"name": "a", "kind": "go", "source": "b1", "out": "a.go", "pakage": "m"}]}`, `unknown field "pakage"`},
+	}
+	for _, tt := range tests {
+		_, err := LoadConfig(writeConfig(t, tt.config))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s:\nerror %v, want %q", tt.config, err, tt.err)
+		}
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing config: no error")
+	}
+}
@@ -0,0 +1,142 @@
+// Package sapgen holds the generator options that are shared by the Go and
+// TypeScript emitters, so every target understands the same flags.
+package sapgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecimalMode selects how Edm.Decimal is represented in generated code.
+type DecimalMode string
+
+const (
+	// Go representations
+	DecimalShopspring DecimalMode = "shopspring" // github.com/shopspring/decimal
+	DecimalBigRat     DecimalMode = "bigrat"     // odata.Rat (math/big.Rat)
+	DecimalFloat64    DecimalMode = "float64"    // lossy, kept for compatibility
+
+	// TypeScript representations
+	DecimalNumber    DecimalMode = "number"     // lossy JS number
+	DecimalDecimalJS DecimalMode = "decimal.js" // new Decimal(v)
+	DecimalBigJS     DecimalMode = "big.js"     // new Big(v)
+
+	// Shared by both
+	DecimalString DecimalMode = "string"
+)
+
+// GoDecimalModes lists the modes accepted by the Go emitters.
+var GoDecimalModes = []DecimalMode{DecimalShopspring, DecimalBigRat, DecimalString, DecimalFloat64}
+
+// TsDecimalModes lists the modes accepted by the TypeScript emitters.
+var TsDecimalModes = []DecimalMode{DecimalNumber, DecimalString, DecimalDecimalJS, DecimalBigJS}
+
+// ParseDecimalMode validates s (case-insensitive) against the allowed modes.
+func ParseDecimalMode(s string, allowed []DecimalMode) (DecimalMode, error) {
+	m := DecimalMode(strings.ToLower(strings.TrimSpace(s)))
+	for _, a := range allowed {
+		if m == a {
+			return m, nil
+		}
+	}
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		names[i] = string(a)
+	}
+	return "", fmt.Errorf("invalid decimal mode %q (use %s)", s, strings.Join(names, " | "))
+}
+
+// ========================= TypeScript helpers =========================
+
+// TsDecimalType is the TS type a decimal property has after parsing.
+func TsDecimalType(mode DecimalMode) string {
+	switch mode {
+	case DecimalString:
+		return "string"
+	case DecimalDecimalJS:
+		return "Decimal"
+	case DecimalBigJS:
+		return "Big"
+	default:
+		return "number"
+	}
+}
+
+// TsDecimalImport is the import line required by the parse transform, if any.
+func TsDecimalImport(mode DecimalMode) string {
+	switch mode {
+	case DecimalDecimalJS:
+		return "import Decimal from 'decimal.js';\n"
+	case DecimalBigJS:
+		return "import Big from 'big.js';\n"
+	}
+	return ""
+}
+
+// TsDecimalTypeImport is the type-only import needed by files that merely
+// mention the decimal type in their model declarations.
+func TsDecimalTypeImport(mode DecimalMode) string {
+	switch mode {
+	case DecimalDecimalJS:
+		return "import type Decimal from 'decimal.js';\n"
+	case DecimalBigJS:
+		return "import type Big from 'big.js';\n"
+	}
+	return ""
+}
+
+// TsDecimalConvert returns the expression that turns the raw wire value `v`
+// (a number, or a string under IEEE754Compatible) into the target type.
+func TsDecimalConvert(mode DecimalMode) string {
+	switch mode {
+	case DecimalString:
+		return "String(v)"
+	case DecimalDecimalJS:
+		return "new Decimal(v)"
+	case DecimalBigJS:
+		return "new Big(v)"
+	default:
+		return "Number(v)"
+	}
+}
+
+// TsDecimalAcceptsString reports whether the wire value may be a string.
+// With IEEE754Compatible=true the service always sends decimals as strings;
+// the arbitrary-precision modes accept both so they work either way.
+func TsDecimalAcceptsString(mode DecimalMode, ieee754 bool) bool {
+	return ieee754 || mode != DecimalNumber
+}
+
+// TsFitsDecimalFunc is emitted once per output and checks Precision/Scale
+// on the textual form of the value before it is converted.
+const TsFitsDecimalFunc = `export function fitsDecimal(s: string, precision?: number, scale?: number): boolean {
+  const m = /^[-+]?(\d*)(?:\.(\d*))?(?:[eE]([-+]?\d+))?$/.exec(s.trim());
+  if (!m || (m[1] === '' && (m[2] ?? '') === '')) return false;
+  // move the decimal point by the exponent: 1e20 has 21 integer digits
+  const exp = Number(m[3] ?? 0);
+  if (Math.abs(exp) > 1000) return false;
+  let digits = m[1] + (m[2] ?? '');
+  let point = m[1].length + exp;
+  if (point < 0) { digits = '0'.repeat(-point) + digits; point = 0; }
+  if (point > digits.length) digits += '0'.repeat(point - digits.length);
+  const intDigits = digits.slice(0, point).replace(/^0+/, '').length;
+  const fracDigits = digits.slice(point).replace(/0+$/, '').length;
+  if (scale !== undefined && fracDigits > scale) return false;
+  if (precision !== undefined && intDigits > precision - (scale ?? 0)) return false;
+  return true;
+}
+`
+
+// TsDecimalArgs renders the (precision, scale) arguments for the generated
+// decimal() helper; zero values mean "not declared in metadata".
+func TsDecimalArgs(precision, scale int) string {
+	switch {
+	case precision > 0 && scale > 0:
+		return fmt.Sprintf("%d, %d", precision, scale)
+	case precision > 0:
+		return fmt.Sprintf("%d", precision)
+	case scale > 0:
+		return fmt.Sprintf("undefined, %d", scale)
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package sapgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around each hunk.
+const diffContext = 3
+
+// diffMaxEdits bounds the Myers search. Past it the changed middle of the
+// files is shown as one replacement, which is still a valid diff.
+const diffMaxEdits = 2000
+
+// UnifiedDiff returns the unified diff turning a into b, or "" when they
+// are equal.
+func UnifiedDiff(aName, bName string, a, b []byte) string {
+	if string(a) == string(b) {
+		return ""
+	}
+	x, y := splitLines(string(a)), splitLines(string(b))
+	ops := diffOps(x, y)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	// ai, bi are the line indexes in x and y at ops[i]
+	ai, bi := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		ai[i+1], bi[i+1] = ai[i], bi[i]
+		if op != '+' {
+			ai[i+1]++
+		}
+		if op != '-' {
+			bi[i+1]++
+		}
+	}
+	for i := 0; i < len(ops); {
+		if ops[i] == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(ops) && j < end+2*diffContext+1; j++ {
+			if ops[j] != ' ' {
+				end = j + 1
+			}
+		}
+		end = min(end+diffContext, len(ops))
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(ai[start], ai[end]-ai[start]), hunkRange(bi[start], bi[end]-bi[start]))
+		for j := start; j < end; j++ {
+			line := ""
+			if ops[j] == '+' {
+				line = y[bi[j]]
+			} else {
+				line = x[ai[j]]
+			}
+			out.WriteByte(ops[j])
+			out.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the start,count of a hunk header.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s after each newline; the last line may lack one.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOps returns the edit script turning x into y: ' ' keeps a line, '-'
+// deletes one of x, '+' inserts one of y.
+func diffOps(x, y []string) []byte {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix &&
+		x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	mx, my := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	ops := []byte(strings.Repeat(" ", prefix))
+	if mid, ok := myers(mx, my); ok {
+		ops = append(ops, mid...)
+	} else {
+		ops = append(ops, strings.Repeat("-", len(mx))...)
+		ops = append(ops, strings.Repeat("+", len(my))...)
+	}
+	return append(ops, strings.Repeat(" ", suffix)...)
+}
+
+// myers computes a shortest edit script (E. Myers, "An O(ND) Difference
+// Algorithm"), giving up after diffMaxEdits edits.
+func myers(x, y []string) ([]byte, bool) {
+	n, m := len(x), len(y)
+	maxD := min(n+m, diffMaxEdits)
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds v[-d..d] after round d
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				i = v[off+k+1]
+			} else {
+				i = v[off+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[off+k] = i
+			if i >= n && j >= m {
+				trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+				return backtrack(trace, n, m), true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+	return nil, false
+}
+
+// backtrack walks the rounds of myers back from (n, m).
+func backtrack(trace [][]int, n, m int) []byte {
+	var ops []byte
+	i, j := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := i - j
+		var pk int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		pi := at(pk)
+		pj := pi - pk
+		for i > pi && j > pj {
+			ops = append(ops, ' ')
+			i--
+			j--
+		}
+		if i == pi {
+			ops = append(ops, '+')
+			j--
+		} else {
+			ops = append(ops, '-')
+			i--
+		}
+	}
+	for i > 0 && j > 0 {
+		ops = append(ops, ' ')
+		i--
+		j--
+	}
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
@@ -0,0 +1,139 @@
+package sapgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestName is the file in a perType output directory listing the files
+// the generator wrote there.
+const ManifestName = ".sapgen-manifest.json"
+
+// Manifest lists generated files by path relative to the output directory
+// (slash-separated), with the SHA-256 of their content.
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// ReadManifest reads the manifest of dir; a directory without one gives an
+// empty manifest.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Manifest{Files: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, ManifestName), err)
+	}
+	if m.Files == nil {
+		m.Files = map[string]string{}
+	}
+	return &m, nil
+}
+
+// Remover is implemented by writers that can delete stale files.
+type Remover interface {
+	Remove(path string) error
+}
+
+// Remove deletes the file at path.
+func (DiskWriter) Remove(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	log.Printf("Removed %s", path)
+	return nil
+}
+
+// ManifestWriter records the files written into a perType output directory.
+// Finish writes the manifest and deletes the files the previous manifest
+// listed that were not written this time. Files the manifest never listed
+// (hand-written ones) and generated files edited since are left alone.
+type ManifestWriter struct {
+	w     Writer
+	dir   string
+	files map[string]string
+}
+
+func NewManifestWriter(w Writer, dir string) *ManifestWriter {
+	return &ManifestWriter{w: w, dir: dir, files: map[string]string{}}
+}
+
+func (m *ManifestWriter) WriteFile(path string, content []byte) error {
+	if rel, err := filepath.Rel(m.dir, path); err == nil && filepath.IsLocal(rel) {
+		m.files[filepath.ToSlash(rel)] = contentHash(content)
+	}
+	return m.w.WriteFile(path, content)
+}
+
+// Finish removes the stale files, when the underlying writer can, and
+// writes the new manifest.
+func (m *ManifestWriter) Finish() error {
+	old, err := ReadManifest(m.dir)
+	if err != nil {
+		return err
+	}
+	if r, ok := m.w.(Remover); ok {
+		if err := m.removeStale(r, old); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(Manifest{Files: m.files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return m.w.WriteFile(filepath.Join(m.dir, ManifestName), append(data, '\n'))
+}
+
+func (m *ManifestWriter) removeStale(r Remover, old *Manifest) error {
+	stale := make([]string, 0, len(old.Files))
+	for rel := range old.Files {
+		if _, ok := m.files[rel]; !ok && filepath.IsLocal(filepath.FromSlash(rel)) {
+			stale = append(stale, rel)
+		}
+	}
+	sort.Strings(stale)
+	for _, rel := range stale {
+		path := filepath.Join(m.dir, filepath.FromSlash(rel))
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if contentHash(content) != old.Files[rel] {
+			log.Printf("Keeping %s: edited since it was generated", path)
+			continue
+		}
+		if err := r.Remove(path); err != nil {
+			return err
+		}
+		// drop directories the removal left empty, up to the output directory
+		for dir := filepath.Dir(path); dir != filepath.Clean(m.dir) &&
+			strings.HasPrefix(dir, filepath.Clean(m.dir)); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
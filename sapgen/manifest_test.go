@@ -0,0 +1,141 @@
+package sapgen
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// generateInto writes files (relative to dir) through a ManifestWriter and
+// finishes it, as a perType run does.
+func generateInto(t *testing.T, w Writer, dir string, files map[string]string) {
+	t.Helper()
+	m := NewManifestWriter(w, dir)
+	for name, content := range files {
+		if err := m.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Finish(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(t *testing.T, path string) bool {
+	t.Helper()
+	_, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestManifestWriter(t *testing.T) {
+	dir := t.TempDir()
+	generateInto(t, DiskWriter{}, dir, map[string]string{
+		"item.go":           "// item\n",
+		"order.go":          "// order\n",
+		"edited.go":         "// edited\n",
+		"sub/line.go":       "// line\n",
+		"sub/deep/extra.go": "// extra\n",
+	})
+	m, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for rel := range m.Files {
+		listed = append(listed, rel)
+	}
+	slices.Sort(listed)
+	if want := []string{"edited.go", "item.go", "order.go", "sub/deep/extra.go", "sub/line.go"}; !slices.Equal(listed, want) {
+		t.Errorf("manifest lists %v, want %v", listed, want)
+	}
+	if m.Files["item.go"] != contentHash([]byte("// item\n")) {
+		t.Errorf("item.go hash %s", m.Files["item.go"])
+	}
+
+	// a hand-written file, a generated file edited since, and a metadata
+	// change that drops order, edited, line and extra
+	if err := os.WriteFile(filepath.Join(dir, "helpers.go"), []byte("package models\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "edited.go"), []byte("// edited by hand\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "sub", "line.go")) // already gone
+	generateInto(t, DiskWriter{}, dir, map[string]string{"item.go": "// item v2\n"})
+
+	for path, want := range map[string]bool{
+		"item.go":     true,
+		"helpers.go":  true,
+		"edited.go":   true,
+		"order.go":    false,
+		"sub/line.go": false,
+		"sub":         false, // emptied directories go too
+	} {
+		if got := exists(t, filepath.Join(dir, filepath.FromSlash(path))); got != want {
+			t.Errorf("%s exists: %v, want %v", path, got, want)
+		}
+	}
+	if !exists(t, dir) {
+		t.Error("the output directory was removed")
+	}
+	m, err = ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 1 || m.Files["item.go"] != contentHash([]byte("// item v2\n")) {
+		t.Errorf("manifest after the second run: %v", m.Files)
+	}
+}
+
+func TestManifestWriterMemory(t *testing.T) {
+	dir := t.TempDir()
+	generateInto(t, DiskWriter{}, dir, map[string]string{"item.go": "// item\n", "order.go": "// order\n"})
+
+	// a writer that cannot remove (generate --check) leaves the disk alone
+	mem := NewMemoryWriter()
+	generateInto(t, mem, dir, map[string]string{"item.go": "// item\n"})
+	if !exists(t, filepath.Join(dir, "order.go")) {
+		t.Error("a MemoryWriter run removed order.go")
+	}
+	if _, ok := mem.Files[filepath.Join(dir, ManifestName)]; !ok {
+		t.Error("the manifest was not rendered into memory")
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	m, err := ReadManifest(dir)
+	if err != nil || m.Files == nil || len(m.Files) != 0 {
+		t.Errorf("no manifest: %v, %v", m, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(dir); err == nil {
+		t.Error("broken manifest: no error")
+	}
+
+	// paths outside the directory are neither recorded nor removed
+	outside := filepath.Join(t.TempDir(), "keep.go")
+	if err := os.WriteFile(outside, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rel, _ := filepath.Rel(dir, outside)
+	manifest := `{"files": {"` + filepath.ToSlash(rel) + `": "` + contentHash([]byte("x")) + `"}}`
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	generateInto(t, DiskWriter{}, dir, map[string]string{"../stray.go": "x"})
+	if !exists(t, outside) {
+		t.Error("a file outside the output directory was removed")
+	}
+	if m, _ := ReadManifest(dir); len(m.Files) != 0 {
+		t.Errorf("manifest records %v", m.Files)
+	}
+}
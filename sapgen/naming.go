@@ -0,0 +1,217 @@
+package sapgen
+
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Naming controls how metadata names become identifiers.
+type Naming struct {
+	// Case of Go identifiers: "preserve" keeps the metadata spelling
+	// (U_MyField, Id); "go" drops underscores and applies initialisms
+	// (UMyField, ID).
+	Case string `json:"case,omitempty"`
+	// Keys of TypeScript models: "wire" uses the JSON names, "camel" uses
+	// camelCase and maps to and from the wire names.
+	Keys string `json:"keys,omitempty"`
+	// Initialisms are added to the defaults used by case=go.
+	Initialisms []string `json:"initialisms,omitempty"`
+	// Renames maps "Type" or "Type.Property" (Type plain or qualified) to
+	// the identifier to use instead of the derived one.
+	Renames map[string]string `json:"renames,omitempty"`
+}
+
+// Validate checks the options and the rename targets.
+func (n Naming) Validate() error {
+	switch n.Case {
+	case "", "preserve", "go":
+	default:
+		return fmt.Errorf("unknown case %q (use preserve | go)", n.Case)
+	}
+	switch n.Keys {
+	case "", "wire", "camel":
+	default:
+		return fmt.Errorf("unknown keys %q (use wire | camel)", n.Keys)
+	}
+	for from, to := range n.Renames {
+		if from == "" || !token.IsIdentifier(to) {
+			return fmt.Errorf("invalid rename %q=%q", from, to)
+		}
+	}
+	return nil
+}
+
+// commonInitialisms are upper-cased as a whole word by case=go.
+var commonInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+	"HTTPS", "ID", "IP", "JSON", "QPS", "RAM", "RPC", "SLA", "SMTP", "SQL",
+	"SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "UUID", "URI", "URL",
+	"UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// Namer derives identifiers according to a Naming.
+type Namer struct {
+	opts        Naming
+	initialisms map[string]bool
+}
+
+// NewNamer returns a Namer for n.
+func NewNamer(n Naming) *Namer {
+	m := &Namer{opts: n, initialisms: map[string]bool{}}
+	for _, s := range commonInitialisms {
+		m.initialisms[s] = true
+	}
+	for _, s := range n.Initialisms {
+		m.initialisms[strings.ToUpper(s)] = true
+	}
+	return m
+}
+
+// GoCase reports whether Go identifiers use case=go.
+func (m *Namer) GoCase() bool { return m.opts.Case == "go" }
+
+// CamelKeys reports whether TypeScript keys use camelCase.
+func (m *Namer) CamelKeys() bool { return m.opts.Keys == "camel" }
+
+// TypeName returns the rename of the type ns.name, if any.
+func (m *Namer) TypeName(ns, name string) (string, bool) {
+	if to, ok := m.opts.Renames[ns+"."+name]; ok && ns != "" {
+		return to, true
+	}
+	to, ok := m.opts.Renames[name]
+	return to, ok
+}
+
+// FieldName returns the rename of property prop of the type ns.name, if any.
+func (m *Namer) FieldName(ns, name, prop string) (string, bool) {
+	if to, ok := m.opts.Renames[ns+"."+name+"."+prop]; ok && ns != "" {
+		return to, true
+	}
+	to, ok := m.opts.Renames[name+"."+prop]
+	return to, ok
+}
+
+var reWordBreak = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// SplitWords splits an identifier at separators and case changes:
+// "U_MyField" -> U My Field, "HTTPServer" -> HTTP Server, "DocEntry" -> Doc Entry.
+func SplitWords(s string) []string {
+	var words []string
+	for _, part := range reWordBreak.Split(s, -1) {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			next := rune(0)
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+			if (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(cur) ||
+				unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			words = append(words, string(runes[start:]))
+		}
+	}
+	return words
+}
+
+// Pascal joins the words of s in PascalCase with initialisms upper-cased.
+func (m *Namer) Pascal(s string) string {
+	var b strings.Builder
+	for _, w := range SplitWords(s) {
+		b.WriteString(m.pascalWord(w))
+	}
+	return fixLeading(b.String())
+}
+
+// Camel is Pascal with the first word lower-cased ("ID" -> "id").
+func (m *Namer) Camel(s string) string {
+	words := SplitWords(s)
+	var b strings.Builder
+	for i, w := range words {
+		if i == 0 {
+			if m.initialisms[strings.ToUpper(w)] || isUpper(w) {
+				b.WriteString(strings.ToLower(w))
+			} else {
+				r := []rune(w)
+				r[0] = unicode.ToLower(r[0])
+				b.WriteString(string(r))
+			}
+			continue
+		}
+		b.WriteString(m.pascalWord(w))
+	}
+	return fixLeading(b.String())
+}
+
+func (m *Namer) pascalWord(w string) string {
+	if up := strings.ToUpper(w); m.initialisms[up] {
+		return up
+	}
+	r := []rune(w)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func isUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func fixLeading(s string) string {
+	if s == "" {
+		return "X"
+	}
+	if r := []rune(s)[0]; !unicode.IsLetter(r) && r != '_' {
+		return "X" + s
+	}
+	return s
+}
+
+// NameScope detects identifiers that two different metadata names map to.
+type NameScope struct {
+	what string // e.g. "field of Document"
+	seen map[string]string
+}
+
+// NewNameScope returns an empty scope; what describes it in errors.
+func NewNameScope(what string) *NameScope {
+	return &NameScope{what: what, seen: map[string]string{}}
+}
+
+// Claim records that source maps to ident.
+func (s *NameScope) Claim(ident, source string) error {
+	if prev, ok := s.seen[ident]; ok && prev != source {
+		pair := []string{prev, source}
+		sort.Strings(pair)
+		return fmt.Errorf("name collision: %s %q and %q both become %s; add a rename",
+			s.what, pair[0], pair[1], ident)
+	}
+	s.seen[ident] = source
+	return nil
+}
+
+// ParseRenames parses a -rename flag: "Type=Name,Type.Prop=Name".
+func ParseRenames(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, kv := range SplitList(s) {
+		from, to, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rename %q (use From=To)", kv)
+		}
+		out[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	return out, nil
+}
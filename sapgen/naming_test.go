@@ -0,0 +1,134 @@
+package sapgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := map[string][]string{
+		"U_MyField":      {"U", "My", "Field"},
+		"HTTPServer":     {"HTTP", "Server"},
+		"DocEntry":       {"Doc", "Entry"},
+		"ItemID":         {"Item", "ID"},
+		"Line2Total":     {"Line2", "Total"},
+		"vat_group-code": {"vat", "group", "code"},
+		"":               nil,
+	}
+	for in, want := range tests {
+		if got := SplitWords(in); !slices.Equal(got, want) {
+			t.Errorf("SplitWords(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNamerCase(t *testing.T) {
+	m := NewNamer(Naming{Case: "go", Initialisms: []string{"vat"}})
+	tests := []struct {
+		in, pascal, camel string
+	}{
+		{"U_MyField", "UMyField", "uMyField"},
+		{"Id", "ID", "id"},
+		{"ItemId", "ItemID", "itemID"},
+		{"VatGroup", "VATGroup", "vatGroup"},
+		{"URLPath", "URLPath", "urlPath"},
+		{"CardCode", "CardCode", "cardCode"},
+		{"2ndName", "X2ndName", "X2ndName"},
+		{"__", "X", "X"},
+	}
+	for _, tt := range tests {
+		if got := m.Pascal(tt.in); got != tt.pascal {
+			t.Errorf("Pascal(%q) = %q, want %q", tt.in, got, tt.pascal)
+		}
+		if got := m.Camel(tt.in); got != tt.camel {
+			t.Errorf("Camel(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+	}
+	if !m.GoCase() || m.CamelKeys() {
+		t.Error("GoCase or CamelKeys wrong")
+	}
+}
+
+func TestNamerRenames(t *testing.T) {
+	m := NewNamer(Naming{Renames: map[string]string{
+		"Document":                   "Doc",
+		"Other.Document":             "OtherDoc",
+		"Document.U_X":               "Extra",
+		"SAPB1.Document.DocEntry":    "Entry",
+		"BusinessPartner.CardCode":   "Code",
+		"SAPB1.BusinessPartner.Name": "PartnerName",
+	}})
+	tests := []struct {
+		ns, name, prop string
+		want           string
+		ok             bool
+	}{
+		{"SAPB1", "Document", "", "Doc", true},
+		{"Other", "Document", "", "OtherDoc", true},
+		{"SAPB1", "Item", "", "", false},
+		{"SAPB1", "Document", "U_X", "Extra", true},
+		{"SAPB1", "Document", "DocEntry", "Entry", true},
+		{"Other", "Document", "DocEntry", "", false},
+		{"SAPB1", "BusinessPartner", "CardCode", "Code", true},
+		{"SAPB1", "BusinessPartner", "Name", "PartnerName", true},
+		{"", "BusinessPartner", "Name", "", false},
+	}
+	for _, tt := range tests {
+		var got string
+		var ok bool
+		if tt.prop == "" {
+			got, ok = m.TypeName(tt.ns, tt.name)
+		} else {
+			got, ok = m.FieldName(tt.ns, tt.name, tt.prop)
+		}
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("rename of %s.%s %s = %q, %v; want %q, %v", tt.ns, tt.name, tt.prop, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNamingValidate(t *testing.T) {
+	tests := []struct {
+		n   Naming
+		err string
+	}{
+		{Naming{}, ""},
+		{Naming{Case: "go", Keys: "camel", Renames: map[string]string{"A.B": "C"}}, ""},
+		{Naming{Case: "snake"}, `unknown case "snake"`},
+		{Naming{Keys: "pascal"}, `unknown keys "pascal"`},
+		{Naming{Renames: map[string]string{"A": "1B"}}, `invalid rename "A"="1B"`},
+		{Naming{Renames: map[string]string{"": "B"}}, `invalid rename ""="B"`},
+	}
+	for _, tt := range tests {
+		err := tt.n.Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: error %v, want %q", tt.n, err, tt.err)
+		}
+	}
+}
+
+func TestNameScope(t *testing.T) {
+	s := NewNameScope("property of SAPB1.Item")
+	if err := s.Claim("UMyField", "U_MyField"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Claim("UMyField", "U_MyField"); err != nil {
+		t.Errorf("the same name claimed twice: %v", err)
+	}
+	err := s.Claim("UMyField", "UMy_Field")
+	want := `name collision: property of SAPB1.Item "UMy_Field" and "U_MyField" both become UMyField; add a rename`
+	if err == nil || err.Error() != want {
+		t.Errorf("collision: %v, want %s", err, want)
+	}
+}
+
+func TestParseRenames(t *testing.T) {
+	got, err := ParseRenames(" Document = Doc ,Item.U_X=Extra,")
+	if err != nil || len(got) != 2 || got["Document"] != "Doc" || got["Item.U_X"] != "Extra" {
+		t.Errorf("ParseRenames = %v, %v", got, err)
+	}
+	if _, err := ParseRenames("Document"); err == nil {
+		t.Error("rename without =: no error")
+	}
+}
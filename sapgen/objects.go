@@ -0,0 +1,56 @@
+package sapgen
+
+import "fmt"
+
+// Values of UserObjects.Objects.
+const (
+	ObjectsAll      = "all"      // standard and user objects together
+	ObjectsStandard = "standard" // standard B1 objects, shareable across customers
+	ObjectsUser     = "user"     // user-defined tables and objects of one customer
+)
+
+// UserObjects splits company-specific user-defined tables (UDTs, U_ entity
+// sets) and user-defined objects (UDOs) from the standard B1 objects, so
+// that each side can be generated into its own package or module:
+//
+//	{ "name": "std", "kind": "go", "objects": "standard", ... },
+//	{ "name": "acme", "kind": "go", "objects": "user",
+//	  "standardModule": "example.com/app/models", ... }
+//
+// See main2.UserTypes for the detection rules.
+type UserObjects struct {
+	// Objects selects the side to generate: all (default), standard or user.
+	Objects string `json:"objects,omitempty"`
+	// UserPatterns marks more types as user objects, in Selection pattern
+	// syntax, for UDOs the conventions do not recognise.
+	UserPatterns []string `json:"userObjects,omitempty"`
+	// StandardModule is where user objects find the standard types they
+	// use: the import path of the standard Go package (named after its last
+	// element), or the TS module of the standard output, relative to the user
+	// output directory (split=single: its file) unless it is a package name.
+	StandardModule string `json:"standardModule,omitempty"`
+}
+
+// Side returns the Objects option, defaulting to ObjectsAll.
+func (u UserObjects) Side() string {
+	if u.Objects == "" {
+		return ObjectsAll
+	}
+	return u.Objects
+}
+
+// Validate checks the options.
+func (u UserObjects) Validate() error {
+	switch u.Side() {
+	case ObjectsAll, ObjectsStandard, ObjectsUser:
+	default:
+		return fmt.Errorf("unknown objects %q (use all | standard | user)", u.Objects)
+	}
+	if _, err := (Selection{Include: u.UserPatterns}).Compile(); err != nil {
+		return fmt.Errorf("userObjects: %w", err)
+	}
+	if u.StandardModule != "" && u.Side() != ObjectsUser {
+		return fmt.Errorf("standardModule needs objects=user")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package sapgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUserObjectsValidate(t *testing.T) {
+	tests := []struct {
+		u    UserObjects
+		side string
+		err  string
+	}{
+		{UserObjects{}, ObjectsAll, ""},
+		{UserObjects{Objects: "standard"}, ObjectsStandard, ""},
+		{UserObjects{Objects: "user", StandardModule: "example.com/app/models", UserPatterns: []string{"set:ACME_*"}}, ObjectsUser, ""},
+		{UserObjects{Objects: "custom"}, "custom", `unknown objects "custom"`},
+		{UserObjects{UserPatterns: []string{"/[/"}}, ObjectsAll, "userObjects: selection pattern"},
+		{UserObjects{Objects: "standard", StandardModule: "example.com/app/models"}, ObjectsStandard, "standardModule needs objects=user"},
+	}
+	for _, tt := range tests {
+		if got := tt.u.Side(); got != tt.side {
+			t.Errorf("%+v: Side() = %q, want %q", tt.u, got, tt.side)
+		}
+		err := tt.u.Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: error %v, want %q", tt.u, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package sapgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Writer receives the files a generator produces.
+type Writer interface {
+	WriteFile(path string, content []byte) error
+}
+
+// DiskWriter writes the files, creating their directories.
+type DiskWriter struct{}
+
+func (DiskWriter) WriteFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return err
+	}
+	log.Printf("Wrote %s", path)
+	return nil
+}
+
+// MemoryWriter keeps the files in memory, keyed by cleaned path, for
+// generate --check.
+type MemoryWriter struct {
+	Files map[string][]byte
+}
+
+func NewMemoryWriter() *MemoryWriter {
+	return &MemoryWriter{Files: map[string][]byte{}}
+}
+
+func (m *MemoryWriter) WriteFile(path string, content []byte) error {
+	m.Files[filepath.Clean(path)] = bytes.Clone(content)
+	return nil
+}
+
+// OrDisk returns w, or a DiskWriter when w is nil.
+func OrDisk(w Writer) Writer {
+	if w == nil {
+		return DiskWriter{}
+	}
+	return w
+}
+
+// SourceHash is the hex SHA-256 of the metadata sources, recorded in the
+// output headers with sourceHash (-source-hash) so models can be traced to
+// the metadata they were generated from.
+func SourceHash(sources ...[]byte) string {
+	h := sha256.New()
+	for _, s := range sources {
+		h.Write(s)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashHeader is the header comment line recording hash, or "" without one.
+func HashHeader(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	return "// Metadata SHA-256: " + hash + "\n"
+}
+
+// IsGenerated reports whether content starts with a generated-file marker
+// ("DO NOT EDIT" in the header comment). Hand-written files never count
+// as orphans.
+func IsGenerated(content []byte) bool {
+	for range 5 {
+		line, rest, _ := bytes.Cut(content, []byte("\n"))
+		if !bytes.HasPrefix(line, []byte("//")) {
+			return false
+		}
+		if bytes.Contains(line, []byte("DO NOT EDIT")) {
+			return true
+		}
+		content = rest
+	}
+	return false
+}
+
+// Check compares the rendered files with the disk and writes a unified diff
+// of every difference to w: changed files, files missing on disk, and
+// generated files under dirs that were not rendered (orphans). It reports
+// whether anything differs.
+func Check(w io.Writer, files map[string][]byte, dirs []string) (bool, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	differs := false
+	for _, p := range paths {
+		disk, err := os.ReadFile(p)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			differs = true
+			fmt.Fprint(w, UnifiedDiff("/dev/null", p, nil, files[p]))
+		case err != nil:
+			return differs, err
+		case !bytes.Equal(disk, files[p]):
+			differs = true
+			fmt.Fprint(w, UnifiedDiff(p, p+" (generated)", disk, files[p]))
+		}
+	}
+
+	orphans := map[string][]byte{}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			p = filepath.Clean(p)
+			if _, ok := files[p]; ok {
+				return nil
+			}
+			content, err := os.ReadFile(p)
+			if err == nil && IsGenerated(content) {
+				orphans[p] = content
+			}
+			return err
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return differs, err
+		}
+	}
+	paths = paths[:0]
+	for p := range orphans {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		differs = true
+		fmt.Fprint(w, UnifiedDiff(p, "/dev/null", orphans[p], nil))
+	}
+	return differs, nil
+}
@@ -0,0 +1,138 @@
+package sapgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"new file", "", "a\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{"removed file", "a\nb\n", "", "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{
+			"change in the middle",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"missing newline",
+			"a\nb",
+			"a\nb\n",
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := UnifiedDiff("a", "b", []byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+
+	// hunks far apart stay separate
+	var a, b []string
+	for i := range 20 {
+		a = append(a, "x")
+		b = append(b, "x")
+		if i == 1 || i == 18 {
+			b[i] = "y"
+		}
+	}
+	got := UnifiedDiff("a", "b", []byte(strings.Join(a, "\n")+"\n"), []byte(strings.Join(b, "\n")+"\n"))
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("%d hunks, want 2:\n%s", n, got)
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"// Code generated by sapModelsGenerator. DO NOT EDIT.\npackage models\n", true},
+		{"// Metadata SHA-256: 00\n// DO NOT EDIT\n", true},
+		{"package models\n// DO NOT EDIT\n", false},
+		{"// hand-written helpers\npackage models\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsGenerated([]byte(tt.content)); got != tt.want {
+			t.Errorf("IsGenerated(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	const header = "// Code generated. DO NOT EDIT.\n"
+	write := func(name, content string) string {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	same := write("same.go", header+"package m\n")
+	changed := write("changed.go", header+"package m\nvar A = 1\n")
+	write("helpers.go", "package m\n")                     // hand-written, not rendered
+	orphan := write("sub/orphan.go", header+"package m\n") // generated, no longer rendered
+	missing := filepath.Join(dir, "missing.go")
+
+	files := map[string][]byte{
+		same:    []byte(header + "package m\n"),
+		changed: []byte(header + "package m\nvar A = 2\n"),
+		missing: []byte(header + "package m\n"),
+	}
+	var out strings.Builder
+	differs, err := Check(&out, files, []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !differs {
+		t.Error("Check reported no difference")
+	}
+	for _, want := range []string{
+		"--- " + changed + "\n+++ " + changed + " (generated)\n",
+		"-var A = 1\n+var A = 2\n",
+		"--- /dev/null\n+++ " + missing + "\n",
+		"--- " + orphan + "\n+++ /dev/null\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("diff lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "same.go") || strings.Contains(out.String(), "helpers.go") {
+		t.Errorf("diff names an unchanged or hand-written file:\n%s", out.String())
+	}
+
+	// once the disk matches, nothing differs
+	write("changed.go", header+"package m\nvar A = 2\n")
+	write("missing.go", header+"package m\n")
+	os.Remove(orphan)
+	out.Reset()
+	if differs, err := Check(&out, files, []string{dir, filepath.Join(dir, "absent")}); err != nil || differs {
+		t.Errorf("up to date: differs %v, %v:\n%s", differs, err, out.String())
+	}
+}
+
+func TestMemoryWriter(t *testing.T) {
+	m := NewMemoryWriter()
+	content := []byte("a")
+	if err := m.WriteFile("out/./x.go", content); err != nil {
+		t.Fatal(err)
+	}
+	content[0] = 'b'
+	if got := string(m.Files[filepath.Join("out", "x.go")]); got != "a" {
+		t.Errorf("MemoryWriter kept %q, want a copy of %q", got, "a")
+	}
+}
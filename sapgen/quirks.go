@@ -0,0 +1,153 @@
+package sapgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Names of the SAP B1 quirk rules.
+const (
+	QuirkPropertyAlias = "propertyAlias"
+	QuirkEnumCasing    = "enumCasing"
+	QuirkYesNoBool     = "yesNoBool"
+	QuirkB1Dates       = "b1Dates"
+)
+
+// Quirk is a Service Layer behaviour that differs from plain OData, which
+// the generators compensate for when the rule is on.
+type Quirk struct {
+	Name     string
+	Default  bool
+	Emitters string // outputs the rule changes
+	Doc      string
+}
+
+// Quirks lists every rule, for --list-quirks and validation.
+var Quirks = []Quirk{
+	{
+		Name: QuirkPropertyAlias, Default: true, Emitters: "go, zod, arktype",
+		Doc: `Properties declared as "<Name>Property" (ActivityProperty) are sent as
+<Name> unless a sibling has that name. Both names are read; the
+propertyAlias option picks the one written (alias | metadata).`,
+	},
+	{
+		Name: QuirkEnumCasing, Default: true, Emitters: "go, zod, arktype",
+		Doc: `Enum values are sent as the member name with a lower-case first
+letter. Go enums read either casing and write that one.`,
+	},
+	{
+		Name: QuirkYesNoBool, Default: false, Emitters: "go, zod",
+		Doc: `BoYesNoEnum properties ("tYES" / "tNO") become booleans: odata.YesNo
+in Go, boolean in Zod models (<Type>ToWire writes tYES/tNO back).`,
+	},
+	{
+		Name: QuirkB1Dates, Default: true, Emitters: "go, zod",
+		Doc: `Edm.Date and Edm.DateTime hold calendar dates, sent as "2024-01-31"
+or as a timestamp without zone ("2024-01-31T00:00:00"), which
+time.Time and JS Date misread. Go uses odata.Date; Zod reads
+zone-less timestamps as UTC and <Type>ToWire writes the date only.`,
+	},
+}
+
+// QuirkSet turns rules on or off by name; unlisted rules keep their
+// default. In a config file: "quirks": { "yesNoBool": true }.
+type QuirkSet map[string]bool
+
+// Enabled reports whether the rule name is on.
+func (q QuirkSet) Enabled(name string) bool {
+	if on, ok := q[name]; ok {
+		return on
+	}
+	for _, r := range Quirks {
+		if r.Name == name {
+			return r.Default
+		}
+	}
+	return false
+}
+
+// Validate rejects unknown rule names.
+func (q QuirkSet) Validate() error {
+	names := make([]string, 0, len(q))
+	for name := range q {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !knownQuirk(name) {
+			return fmt.Errorf("unknown quirk %q (see --list-quirks)", name)
+		}
+	}
+	return nil
+}
+
+func knownQuirk(name string) bool {
+	for _, r := range Quirks {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseQuirks parses the -quirks flag: "yesNoBool,enumCasing=false".
+func ParseQuirks(s string) (QuirkSet, error) {
+	q := QuirkSet{}
+	for _, item := range SplitList(s) {
+		name, value, hasValue := strings.Cut(item, "=")
+		on := true
+		if hasValue {
+			var err error
+			if on, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("quirk %s: %q is not a boolean", name, value)
+			}
+		}
+		q[name] = on
+	}
+	return q, q.Validate()
+}
+
+// PropertyAlias returns mode, or AliasOff with the propertyAlias rule off.
+func (q QuirkSet) PropertyAlias(mode PropertyAlias) PropertyAlias {
+	if !q.Enabled(QuirkPropertyAlias) {
+		return AliasOff
+	}
+	return mode
+}
+
+// EnumValue is the JSON value of the enum member name.
+func (q QuirkSet) EnumValue(member string) string {
+	if member == "" || !q.Enabled(QuirkEnumCasing) {
+		return member
+	}
+	return strings.ToLower(member[:1]) + member[1:]
+}
+
+// YesNo reports whether a property of the (inner) type edmType is a
+// BoYesNoEnum read as a boolean.
+func (q QuirkSet) YesNo(edmType string) bool {
+	name := edmType[strings.LastIndex(edmType, ".")+1:]
+	return name == "BoYesNoEnum" && q.Enabled(QuirkYesNoBool)
+}
+
+// DateOnly reports whether the Edm type edmType holds a B1 calendar date.
+func (q QuirkSet) DateOnly(edmType string) bool {
+	return (edmType == "Edm.Date" || edmType == "Edm.DateTime") && q.Enabled(QuirkB1Dates)
+}
+
+// WriteQuirks prints the rules with their defaults, for --list-quirks.
+func WriteQuirks(w io.Writer) {
+	for _, r := range Quirks {
+		state := "off"
+		if r.Default {
+			state = "on"
+		}
+		fmt.Fprintf(w, "%s (default %s; %s)\n", r.Name, state, r.Emitters)
+		for _, line := range strings.Split(r.Doc, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package sapgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseQuirks(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    QuirkSet
+		wantErr string
+	}{
+		{"", QuirkSet{}, ""},
+		{"yesNoBool", QuirkSet{QuirkYesNoBool: true}, ""},
+		{"enumCasing=false, b1Dates=0", QuirkSet{QuirkEnumCasing: false, QuirkB1Dates: false}, ""},
+		{"propertyAlias=true,yesNoBool=1", QuirkSet{QuirkPropertyAlias: true, QuirkYesNoBool: true}, ""},
+		{"yesNoBool=maybe", nil, `quirk yesNoBool: "maybe" is not a boolean`},
+		{"bogus", nil, `unknown quirk "bogus"`},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuirks(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseQuirks(%q): err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseQuirks(%q): %v", tt.in, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseQuirks(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		for name, on := range tt.want {
+			if v, ok := got[name]; !ok || v != on {
+				t.Errorf("ParseQuirks(%q)[%s] = %v, want %v", tt.in, name, v, on)
+			}
+		}
+	}
+}
+
+func TestQuirkDefaults(t *testing.T) {
+	want := map[string]bool{QuirkPropertyAlias: true, QuirkEnumCasing: true, QuirkYesNoBool: false, QuirkB1Dates: true}
+	if len(Quirks) != len(want) {
+		t.Fatalf("%d quirks, want %d", len(Quirks), len(want))
+	}
+	for name, on := range want {
+		if got := (QuirkSet{}).Enabled(name); got != on {
+			t.Errorf("default %s = %v, want %v", name, got, on)
+		}
+		if got := (QuirkSet{name: !on}).Enabled(name); got == on {
+			t.Errorf("%s=%v is not applied", name, !on)
+		}
+	}
+	if (QuirkSet{}).Enabled("bogus") {
+		t.Error("an unknown quirk is on")
+	}
+}
+
+func TestQuirkPropertyAlias(t *testing.T) {
+	names := []string{"ActivityProperty", "Code", "CodeProperty", "U_NameProperty", "Property"}
+	tests := []struct {
+		quirks QuirkSet
+		mode   PropertyAlias
+		wire   map[string]string // metadata name -> name written
+	}{
+		{QuirkSet{}, AliasWire, map[string]string{"ActivityProperty": "Activity", "CodeProperty": "CodeProperty", "U_NameProperty": "U_NameProperty", "Property": "Property"}},
+		{QuirkSet{}, AliasMetadata, map[string]string{"ActivityProperty": "ActivityProperty"}},
+		{QuirkSet{QuirkPropertyAlias: false}, AliasWire, map[string]string{"ActivityProperty": "ActivityProperty"}},
+	}
+	for _, tt := range tests {
+		mode := tt.quirks.PropertyAlias(tt.mode)
+		aliases := mode.Aliases(names)
+		for name, want := range tt.wire {
+			if got := mode.WireName(name, aliases); got != want {
+				t.Errorf("%v %s: WireName(%s) = %s, want %s", tt.quirks, tt.mode, name, got, want)
+			}
+		}
+	}
+	if got := (QuirkSet{QuirkPropertyAlias: false}).PropertyAlias(AliasWire); got != AliasOff || got.Aliases(names) != nil {
+		t.Errorf("propertyAlias=false gives mode %q", got)
+	}
+}
+
+func TestQuirkEnumCasing(t *testing.T) {
+	tests := []struct {
+		quirks QuirkSet
+		in     string
+		want   string
+	}{
+		{QuirkSet{}, "TYES", "tYES"},
+		{QuirkSet{}, "BoStatus", "boStatus"},
+		{QuirkSet{}, "", ""},
+		{QuirkSet{QuirkEnumCasing: false}, "TYES", "TYES"},
+	}
+	for _, tt := range tests {
+		if got := tt.quirks.EnumValue(tt.in); got != tt.want {
+			t.Errorf("%v EnumValue(%q) = %q, want %q", tt.quirks, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuirkYesNoBool(t *testing.T) {
+	tests := []struct {
+		quirks QuirkSet
+		typ    string
+		want   bool
+	}{
+		{QuirkSet{}, "SAPB1.BoYesNoEnum", false},
+		{QuirkSet{QuirkYesNoBool: true}, "SAPB1.BoYesNoEnum", true},
+		{QuirkSet{QuirkYesNoBool: true}, "BoYesNoEnum", true},
+		{QuirkSet{QuirkYesNoBool: true}, "SAPB1.BoStatus", false},
+	}
+	for _, tt := range tests {
+		if got := tt.quirks.YesNo(tt.typ); got != tt.want {
+			t.Errorf("%v YesNo(%s) = %v, want %v", tt.quirks, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestQuirkB1Dates(t *testing.T) {
+	tests := []struct {
+		quirks QuirkSet
+		typ    string
+		want   bool
+	}{
+		{QuirkSet{}, "Edm.Date", true},
+		{QuirkSet{}, "Edm.DateTime", true},
+		{QuirkSet{}, "Edm.DateTimeOffset", false},
+		{QuirkSet{QuirkB1Dates: false}, "Edm.Date", false},
+	}
+	for _, tt := range tests {
+		if got := tt.quirks.DateOnly(tt.typ); got != tt.want {
+			t.Errorf("%v DateOnly(%s) = %v, want %v", tt.quirks, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestWriteQuirks(t *testing.T) {
+	var b bytes.Buffer
+	WriteQuirks(&b)
+	out := b.String()
+	for _, want := range []string{
+		"propertyAlias (default on; go, zod, arktype)\n",
+		"enumCasing (default on; go, zod, arktype)\n",
+		"yesNoBool (default off; go, zod)\n",
+		"b1Dates (default on; go, zod)\n",
+		"    Enum values are sent as the member name with a lower-case first\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteQuirks output lacks %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if !strings.HasPrefix(line, "    ") && !strings.Contains(line, "(default ") {
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+}
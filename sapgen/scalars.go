@@ -0,0 +1,206 @@
+package sapgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Scalars maps Edm types ("Edm.Guid") and single properties
+// ("BusinessPartner.CardCode", optionally namespace-qualified) to user
+// types, replacing the built-in mapping of every generator:
+//
+//	"scalars": {
+//	  "BusinessPartner.CardCode": {
+//	    "go": { "import": "example.com/app/partner", "type": "partner.Code" },
+//	    "ts": { "import": "@/partner", "type": "PartnerCode",
+//	            "zod": "partnerCodeSchema", "ark": "partnerCode" }
+//	  },
+//	  "Edm.Guid": { "go": { "import": "github.com/google/uuid", "type": "uuid.UUID" } }
+//	}
+//
+// A property entry wins over an Edm type entry. Nullability and collections
+// are applied by the generator around the mapped type.
+type Scalars map[string]ScalarMapping
+
+// ScalarMapping is the replacement for one key; a missing side keeps the
+// generator's built-in type for that language.
+type ScalarMapping struct {
+	Go *GoScalar `json:"go,omitempty"`
+	TS *TsScalar `json:"ts,omitempty"`
+}
+
+// GoScalar is a Go type; its package qualifier must be the package name of Import.
+type GoScalar struct {
+	Import string `json:"import,omitempty"`
+	Type   string `json:"type"`
+}
+
+// TsScalar is a TypeScript type plus the validators for it. With Import set,
+// Type, Zod and Ark are names exported by that module; without it they are
+// used as written (e.g. "z.string().uuid()"). Without Zod the schema is
+// z.custom<Type>(); without Ark the ArkType output keeps its built-in type.
+type TsScalar struct {
+	Import string `json:"import,omitempty"`
+	Type   string `json:"type"`
+	Zod    string `json:"zod,omitempty"`
+	Ark    string `json:"ark,omitempty"`
+}
+
+// Lookup returns the mapping for property prop of the type ns.typeName
+// whose (inner, for collections) Edm type is edmType.
+func (s Scalars) Lookup(ns, typeName, prop, edmType string) (ScalarMapping, bool) {
+	if len(s) == 0 {
+		return ScalarMapping{}, false
+	}
+	keys := []string{typeName + "." + prop, edmType}
+	if ns != "" {
+		keys = append([]string{ns + "." + typeName + "." + prop}, keys...)
+	}
+	for _, k := range keys {
+		if m, ok := s[k]; ok {
+			return m, true
+		}
+	}
+	return ScalarMapping{}, false
+}
+
+// Merge returns s with the entries of override added or replaced.
+func (s Scalars) Merge(override Scalars) Scalars {
+	if len(override) == 0 {
+		return s
+	}
+	out := Scalars{}
+	for k, v := range s {
+		out[k] = v
+	}
+	for k, v := range override {
+		out[k] = v
+	}
+	return out
+}
+
+// Validate checks that every mapping names a type.
+func (s Scalars) Validate() error {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		m := s[key]
+		if m.Go == nil && m.TS == nil {
+			return fmt.Errorf("scalar %q: needs go or ts", key)
+		}
+		if m.Go != nil {
+			if m.Go.Type == "" {
+				return fmt.Errorf("scalar %q: go.type is required", key)
+			}
+			if m.Go.Import != "" {
+				pkg := m.Go.Import[strings.LastIndex(m.Go.Import, "/")+1:]
+				if !strings.HasPrefix(m.Go.Type, pkg+".") {
+					return fmt.Errorf("scalar %q: go.type %q must be qualified with %s", key, m.Go.Type, pkg)
+				}
+			}
+		}
+		if m.TS != nil {
+			if m.TS.Type == "" {
+				return fmt.Errorf("scalar %q: ts.type is required", key)
+			}
+			if m.TS.Import != "" {
+				for _, name := range []string{m.TS.Type, m.TS.Zod, m.TS.Ark} {
+					if name != "" && !token.IsIdentifier(name) {
+						return fmt.Errorf("scalar %q: %q is not a name exported by %s", key, name, m.TS.Import)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// LoadScalars reads a JSON file holding a Scalars object, for the -scalars flag.
+func LoadScalars(path string) (Scalars, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s Scalars
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// TsImports groups the names a generated TS file needs from user modules.
+type TsImports map[string]*tsModuleImports
+
+type tsModuleImports struct {
+	types  map[string]bool
+	values map[string]bool
+}
+
+// Add records the imports needed to use m; kind is "zod" or "ark".
+func (t TsImports) Add(m *TsScalar, kind string) {
+	if m == nil || m.Import == "" {
+		return
+	}
+	mi := t[m.Import]
+	if mi == nil {
+		mi = &tsModuleImports{types: map[string]bool{}, values: map[string]bool{}}
+		t[m.Import] = mi
+	}
+	switch kind {
+	case "zod":
+		mi.types[m.Type] = true
+		if m.Zod != "" {
+			mi.values[m.Zod] = true
+		}
+	case "ark":
+		// the ArkType output only references the validator
+		if m.Ark != "" {
+			mi.values[m.Ark] = true
+		}
+	}
+}
+
+// Render returns the import lines, sorted by module, using quote for strings.
+func (t TsImports) Render(quote string) string {
+	mods := make([]string, 0, len(t))
+	for mod := range t {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	var b strings.Builder
+	for _, mod := range mods {
+		mi := t[mod]
+		if len(mi.types) > 0 {
+			b.WriteString(fmt.Sprintf("import type { %s } from %s%s%s;\n", joinSorted(mi.types), quote, mod, quote))
+		}
+		if len(mi.values) > 0 {
+			b.WriteString(fmt.Sprintf("import { %s } from %s%s%s;\n", joinSorted(mi.values), quote, mod, quote))
+		}
+	}
+	return b.String()
+}
+
+func joinSorted(set map[string]bool) string {
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
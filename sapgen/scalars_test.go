@@ -0,0 +1,116 @@
+package sapgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScalarsLookup(t *testing.T) {
+	s := Scalars{
+		"Edm.Guid":                       {Go: &GoScalar{Import: "github.com/google/uuid", Type: "uuid.UUID"}},
+		"BusinessPartner.CardCode":       {Go: &GoScalar{Type: "string"}},
+		"SAPB1.BusinessPartner.CardName": {TS: &TsScalar{Type: "Name"}},
+		"Document.Reference":             {Go: &GoScalar{Type: "Ref"}},
+	}
+	tests := []struct {
+		ns, typ, prop, edm string
+		want               string // the Go or TS type, "" for none
+	}{
+		{"SAPB1", "Item", "Guid", "Edm.Guid", "uuid.UUID"},
+		{"SAPB1", "BusinessPartner", "CardCode", "Edm.String", "string"},
+		{"Other", "BusinessPartner", "CardCode", "Edm.String", "string"},
+		{"SAPB1", "BusinessPartner", "CardName", "Edm.String", "Name"},
+		{"Other", "BusinessPartner", "CardName", "Edm.String", ""},
+		{"SAPB1", "Document", "Reference", "Edm.Guid", "Ref"}, // the property wins
+		{"SAPB1", "Document", "Comments", "Edm.String", ""},
+	}
+	for _, tt := range tests {
+		m, ok := s.Lookup(tt.ns, tt.typ, tt.prop, tt.edm)
+		got := ""
+		switch {
+		case m.Go != nil:
+			got = m.Go.Type
+		case m.TS != nil:
+			got = m.TS.Type
+		}
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Lookup(%s.%s.%s %s) = %q, %v; want %q", tt.ns, tt.typ, tt.prop, tt.edm, got, ok, tt.want)
+		}
+	}
+	if _, ok := (Scalars{}).Lookup("SAPB1", "Item", "Guid", "Edm.Guid"); ok {
+		t.Error("empty Scalars found a mapping")
+	}
+}
+
+func TestScalarsMerge(t *testing.T) {
+	base := Scalars{"Edm.Guid": {Go: &GoScalar{Type: "string"}}, "Edm.Date": {Go: &GoScalar{Type: "string"}}}
+	got := base.Merge(Scalars{"Edm.Guid": {Go: &GoScalar{Type: "[16]byte"}}})
+	if len(got) != 2 || got["Edm.Guid"].Go.Type != "[16]byte" || got["Edm.Date"].Go.Type != "string" {
+		t.Errorf("Merge = %v", got)
+	}
+	if base["Edm.Guid"].Go.Type != "string" {
+		t.Error("Merge changed the base")
+	}
+}
+
+func TestScalarsValidate(t *testing.T) {
+	tests := []struct {
+		s   Scalars
+		err string
+	}{
+		{Scalars{"Edm.Guid": {Go: &GoScalar{Import: "github.com/google/uuid", Type: "uuid.UUID"}}}, ""},
+		{Scalars{"Edm.Guid": {TS: &TsScalar{Type: "string", Zod: "z.string().uuid()"}}}, ""},
+		{Scalars{"A.B": {TS: &TsScalar{Import: "@/a", Type: "Code", Zod: "codeSchema", Ark: "code"}}}, ""},
+		{Scalars{"Edm.Guid": {}}, `scalar "Edm.Guid": needs go or ts`},
+		{Scalars{"Edm.Guid": {Go: &GoScalar{}}}, "go.type is required"},
+		{Scalars{"Edm.Guid": {Go: &GoScalar{Import: "github.com/google/uuid", Type: "UUID"}}}, `go.type "UUID" must be qualified with uuid`},
+		{Scalars{"Edm.Guid": {TS: &TsScalar{}}}, "ts.type is required"},
+		{Scalars{"A.B": {TS: &TsScalar{Import: "@/a", Type: "Code", Zod: "z.string()"}}}, `"z.string()" is not a name exported by @/a`},
+	}
+	for _, tt := range tests {
+		err := tt.s.Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%v: error %v, want %q", tt.s, err, tt.err)
+		}
+	}
+}
+
+func TestLoadScalars(t *testing.T) {
+	if s, err := LoadScalars(""); s != nil || err != nil {
+		t.Errorf("no path: %v, %v", s, err)
+	}
+	dir := t.TempDir()
+	for content, wantErr := range map[string]string{
+		`{"Edm.Guid": {"go": {"type": "string"}}}`:             "",
+		`{"Edm.Guid": {"go": {"typ": "string"}}}`:              `unknown field "typ"`,
+		`{"Edm.Guid": {"go": {"import": "x/y", "type": "z"}}}`: "must be qualified with y",
+	} {
+		path := filepath.Join(dir, "scalars.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := LoadScalars(path)
+		if wantErr == "" && (err != nil || s["Edm.Guid"].Go.Type != "string") ||
+			wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)) {
+			t.Errorf("%s: %v, %v; want error %q", content, s, err, wantErr)
+		}
+	}
+}
+
+func TestTsImports(t *testing.T) {
+	imports := TsImports{}
+	imports.Add(&TsScalar{Import: "@/partner", Type: "PartnerCode", Zod: "partnerCodeSchema", Ark: "partnerCode"}, "zod")
+	imports.Add(&TsScalar{Import: "@/partner", Type: "PartnerName"}, "zod")
+	imports.Add(&TsScalar{Import: "@/money", Type: "Money", Ark: "money"}, "ark")
+	imports.Add(&TsScalar{Type: "string", Zod: "z.string()"}, "zod") // no module
+	imports.Add(nil, "zod")
+	want := `import { money } from '@/money';
+import type { PartnerCode, PartnerName } from '@/partner';
+import { partnerCodeSchema } from '@/partner';
+`
+	if got := imports.Render("'"); got != want {
+		t.Errorf("Render:\n%s\nwant\n%s", got, want)
+	}
+}
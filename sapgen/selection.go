@@ -0,0 +1,152 @@
+package sapgen
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Selection limits generation to part of the metadata. Each pattern is
+// "[set:|type:|ns:]expr" where expr is a glob (path.Match syntax) or, when
+// wrapped in slashes, a regular expression:
+//
+//	set:Orders        entity set name
+//	type:Document*    type name, plain or qualified (SAPB1.Document)
+//	ns:SAPB1          schema namespace
+//	/^U_/             no prefix: entity set or type name
+//
+// The selected types are closed over their dependencies by the generators;
+// see main2.SelectTypes.
+type Selection struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// StubNavigation does not follow navigation properties when computing the
+	// closure; navigation properties to types outside it are emitted untyped.
+	StubNavigation bool `json:"stubNavigation,omitempty"`
+}
+
+// IsZero reports whether the selection keeps every type.
+func (s Selection) IsZero() bool { return len(s.Include) == 0 && len(s.Exclude) == 0 }
+
+// Matcher is a compiled Selection.
+type Matcher struct {
+	include []pattern
+	exclude []pattern
+}
+
+type pattern struct {
+	kind string // "set", "type", "ns" or "" for set or type
+	glob string
+	re   *regexp.Regexp
+}
+
+// Compile parses the include and exclude patterns.
+func (s Selection) Compile() (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range s.Include {
+		c, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		m.include = append(m.include, c)
+	}
+	for _, p := range s.Exclude {
+		c, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		m.exclude = append(m.exclude, c)
+	}
+	return m, nil
+}
+
+func compilePattern(s string) (pattern, error) {
+	p := pattern{}
+	expr := strings.TrimSpace(s)
+	if i := strings.Index(expr, ":"); i > 0 {
+		switch kind := expr[:i]; kind {
+		case "set", "type", "ns":
+			p.kind, expr = kind, expr[i+1:]
+		}
+	}
+	if expr == "" {
+		return p, fmt.Errorf("empty selection pattern %q", s)
+	}
+	if len(expr) > 1 && strings.HasPrefix(expr, "/") && strings.HasSuffix(expr, "/") {
+		re, err := regexp.Compile(expr[1 : len(expr)-1])
+		if err != nil {
+			return p, fmt.Errorf("selection pattern %q: %w", s, err)
+		}
+		p.re = re
+		return p, nil
+	}
+	if _, err := path.Match(expr, ""); err != nil {
+		return p, fmt.Errorf("selection pattern %q: %w", s, err)
+	}
+	p.glob = expr
+	return p, nil
+}
+
+func (p pattern) matchString(v string) bool {
+	if p.re != nil {
+		return p.re.MatchString(v)
+	}
+	ok, _ := path.Match(p.glob, v)
+	return ok
+}
+
+func (p pattern) match(ns, name string, sets []string) bool {
+	if p.kind == "ns" {
+		return p.matchString(ns)
+	}
+	if p.kind != "set" && (p.matchString(name) || p.matchString(ns+"."+name)) {
+		return true
+	}
+	if p.kind != "type" {
+		for _, s := range sets {
+			if p.matchString(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(ps []pattern, ns, name string, sets []string) bool {
+	for _, p := range ps {
+		if p.match(ns, name, sets) {
+			return true
+		}
+	}
+	return false
+}
+
+// Included reports whether a type, exposed by the given entity sets, matches
+// an include pattern. Without include patterns every type does.
+func (m *Matcher) Included(ns, name string, sets []string) bool {
+	return len(m.include) == 0 || matchAny(m.include, ns, name, sets)
+}
+
+// Excluded reports whether a type matches an exclude pattern.
+func (m *Matcher) Excluded(ns, name string, sets []string) bool {
+	return matchAny(m.exclude, ns, name, sets)
+}
+
+// TypeSet holds the qualified names ("Namespace.Name") of the entity,
+// complex and enum types to generate. A nil TypeSet keeps everything.
+type TypeSet map[string]bool
+
+// Has reports whether the type ns.name is generated.
+func (s TypeSet) Has(ns, name string) bool { return s == nil || s[ns+"."+name] }
+
+// SplitList splits a comma-separated flag value, dropping empty entries.
+func SplitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package sapgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSelectionMatch(t *testing.T) {
+	// Document is exposed by Orders and Invoices, DocumentLine by no set
+	tests := []struct {
+		pattern  string
+		document bool
+		line     bool
+	}{
+		{"set:Orders", true, false},
+		{"set:Order*", true, false},
+		{"set:Document", false, false},
+		{"type:Document", true, false},
+		{"type:SAPB1.Document*", true, true},
+		{"type:Orders", false, false},
+		{"ns:SAPB1", true, true},
+		{"ns:SAP*", true, true},
+		{"ns:Other", false, false},
+		{"Invoices", true, false},
+		{"DocumentLine", false, true},
+		{"/^Document/", true, true},
+		{"/Line$/", false, true},
+		{"type:/^SAPB1\\.Document$/", true, false},
+	}
+	for _, tt := range tests {
+		m, err := Selection{Include: []string{tt.pattern}}.Compile()
+		if err != nil {
+			t.Errorf("%s: %v", tt.pattern, err)
+			continue
+		}
+		if got := m.Included("SAPB1", "Document", []string{"Orders", "Invoices"}); got != tt.document {
+			t.Errorf("%s matches Document: %v, want %v", tt.pattern, got, tt.document)
+		}
+		if got := m.Included("SAPB1", "DocumentLine", nil); got != tt.line {
+			t.Errorf("%s matches DocumentLine: %v, want %v", tt.pattern, got, tt.line)
+		}
+	}
+
+	m, err := Selection{Exclude: []string{"set:Invoices", "/^U_/"}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Included("SAPB1", "Item", nil) {
+		t.Error("without include patterns a type is not included")
+	}
+	if !m.Excluded("SAPB1", "Document", []string{"Orders", "Invoices"}) || !m.Excluded("SAPB1", "U_Extra", nil) || m.Excluded("SAPB1", "Item", []string{"Items"}) {
+		t.Error("exclude patterns matched wrongly")
+	}
+}
+
+func TestSelectionCompileErrors(t *testing.T) {
+	for _, p := range []string{"", "set:", " ", "/[/", "type:[a"} {
+		if _, err := (Selection{Exclude: []string{p}}).Compile(); err == nil {
+			t.Errorf("pattern %q: no error", p)
+		}
+	}
+	// an unknown prefix is part of the glob
+	m, err := Selection{Include: []string{"odd:name"}}.Compile()
+	if err != nil || !m.Included("", "odd:name", nil) {
+		t.Errorf("odd:name: %v", err)
+	}
+}
+
+func TestTypeSet(t *testing.T) {
+	var all TypeSet
+	if !all.Has("SAPB1", "Document") {
+		t.Error("a nil TypeSet does not keep every type")
+	}
+	some := TypeSet{"SAPB1.Document": true}
+	if !some.Has("SAPB1", "Document") || some.Has("SAPB1", "Item") || some.Has("Other", "Document") {
+		t.Error("TypeSet.Has matched wrongly")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	for in, want := range map[string][]string{
+		"":                  nil,
+		"a":                 {"a"},
+		" a , ,b,":          {"a", "b"},
+		"set:Orders,type:x": {"set:Orders", "type:x"},
+	} {
+		if got := SplitList(in); !slices.Equal(got, want) {
+			t.Errorf("SplitList(%q) = %q, want %q", in, strings.Join(got, "|"), strings.Join(want, "|"))
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package sapgen
+
+// TsBatchModule is the TypeScript counterpart of the Go odata.Batch,
+// written as batch.ts: operations typed by the generated Zod schemas,
+// change sets, multipart/mixed and JSON encoding, and response parsing.
+// It imports errors.ts (TsErrorsModule).
+const TsBatchModule = `import type { ZodType } from 'zod';
+import { ODataError, parseODataError } from './errors';
+
+function errorMessage(method: string, url: string, status: number, body: string): string {
+  const odata = parseODataError(body);
+  const detail = odata ? odata.toString() : body.trim();
+  return 'odata: ' + method + ' ' + url + ': ' + status + (detail ? ': ' + detail : '');
+}
+
+/** The error response of one batch operation (or of the whole batch), with its parsed error payload. */
+export class BatchError extends Error {
+  readonly odata?: ODataError;
+
+  constructor(readonly method: string, readonly url: string, readonly status: number, readonly body: string) {
+    super(errorMessage(method, url, status, body));
+    this.odata = parseODataError(body);
+  }
+}
+
+/**
+ * A 412 Precondition Failed: the entity was changed after the version
+ * ifMatch was read. Read it again, apply the change to it and send it once more.
+ */
+export class ConflictError extends BatchError {
+  constructor(method: string, url: string, status: number, body: string, readonly ifMatch?: string) {
+    super(method, url, status, body);
+  }
+}
+
+/** The If-Match header for etag, e.g. the '@odata.etag' of an entity model; none without one. */
+export function ifMatch(etag?: string): Record<string, string> {
+  return etag ? { 'If-Match': etag } : {};
+}
+
+export interface BatchRequestOptions<T> {
+  body?: unknown; // sent as JSON, e.g. the output of <Type>ToWire
+  headers?: Record<string, string>;
+  schema?: ZodType<T>; // parses the response body into value
+}
+
+/** One request of a batch; send() fills in the outcome. */
+export class BatchOperation<T = unknown> {
+  status?: number;
+  headers: Record<string, string> = {};
+  value?: T;
+  error?: Error; // a BatchError (ConflictError for 412), or the error of the schema
+
+  constructor(
+    readonly method: string,
+    readonly url: string,
+    readonly contentId: string,
+    readonly options: BatchRequestOptions<T> = {},
+  ) {}
+
+  /** The reference to the entity of this operation for later requests of its change set: $1. */
+  get ref(): string {
+    return '$' + this.contentId;
+  }
+
+  setResponse(status: number, headers: Record<string, string>, body: string): void {
+    this.status = status;
+    this.headers = headers;
+    if (status === 412) {
+      this.error = new ConflictError(this.method, this.url, status, body, this.options.headers?.['If-Match']);
+      return;
+    }
+    if (status < 200 || status > 299) {
+      this.error = new BatchError(this.method, this.url, status, body);
+      return;
+    }
+    if (body.trim() === '') return;
+    try {
+      const data = JSON.parse(body);
+      this.value = this.options.schema ? this.options.schema.parse(data) : data;
+    } catch (e) {
+      this.error = e instanceof Error ? e : new Error(String(e));
+    }
+  }
+}
+
+/** Where operations are added: an ODataBatch or one of its change sets. */
+export abstract class BatchRequests {
+  abstract add<T = unknown>(method: string, url: string, options?: BatchRequestOptions<T>): BatchOperation<T>;
+
+  get<T>(url: string, schema?: ZodType<T>): BatchOperation<T> {
+    return this.add('GET', url, { schema });
+  }
+  post<T>(url: string, body: unknown, schema?: ZodType<T>): BatchOperation<T> {
+    return this.add('POST', url, { body, schema });
+  }
+  /** headers may hold If-Match: ifMatch(entity['@odata.etag']). */
+  patch(url: string, body: unknown, headers?: Record<string, string>): BatchOperation {
+    return this.add('PATCH', url, { body, headers });
+  }
+  /** With etag, the entity is replaced only while its ETag is still etag. */
+  put(url: string, body: unknown, etag?: string): BatchOperation {
+    return this.add('PUT', url, { body, headers: ifMatch(etag) });
+  }
+  /** With etag, the entity is deleted only while its ETag is still etag. */
+  delete(url: string, etag?: string): BatchOperation {
+    return this.add('DELETE', url, { headers: ifMatch(etag) });
+  }
+}
+
+/** An atomic group of data modification requests. */
+export class ODataChangeSet extends BatchRequests {
+  readonly operations: BatchOperation<any>[] = [];
+
+  constructor(private readonly batch: ODataBatch) {
+    super();
+  }
+
+  add<T = unknown>(method: string, url: string, options?: BatchRequestOptions<T>): BatchOperation<T> {
+    const op = new BatchOperation<T>(method, url, this.batch.nextId(), options);
+    this.operations.push(op);
+    return op;
+  }
+}
+
+type BatchItem = BatchOperation<any> | ODataChangeSet;
+
+function boundary(prefix: string): string {
+  return prefix + '_' + Math.random().toString(36).slice(2) + Math.random().toString(36).slice(2);
+}
+
+interface Part {
+  headers: Record<string, string>; // lower-case names
+  body: string;
+}
+
+function splitHead(s: string): [string, string] {
+  const m = /\r?\n\r?\n/.exec(s);
+  return m ? [s.slice(0, m.index), s.slice(m.index + m[0].length)] : [s, ''];
+}
+
+function parseHeaders(lines: string[]): Record<string, string> {
+  const headers: Record<string, string> = {};
+  for (const line of lines) {
+    const i = line.indexOf(':');
+    if (i > 0) headers[line.slice(0, i).trim().toLowerCase()] = line.slice(i + 1).trim();
+  }
+  return headers;
+}
+
+function boundaryOf(contentType: string): string {
+  const m = /boundary=(?:"([^"]+)"|([^;\s]+))/i.exec(contentType);
+  if (!m) throw new Error('odata: batch: no boundary in ' + contentType);
+  return m[1] ?? m[2];
+}
+
+function multipartParts(body: string, bound: string): Part[] {
+  const parts: Part[] = [];
+  for (const chunk of body.split('--' + bound).slice(1)) {
+    if (chunk.startsWith('--')) break;
+    const [head, rest] = splitHead(chunk.replace(/^\r?\n/, '').replace(/\r?\n$/, ''));
+    parts.push({ headers: parseHeaders(head.split(/\r?\n/)), body: rest });
+  }
+  return parts;
+}
+
+function parseResponse(part: Part): { status: number; headers: Record<string, string>; body: string } {
+  const [head, body] = splitHead(part.body);
+  const lines = head.split(/\r?\n/);
+  const m = /^HTTP\/\d\.\d (\d{3})/.exec(lines[0]);
+  if (!m) throw new Error('odata: batch: bad response line ' + lines[0]);
+  return { status: Number(m[1]), headers: parseHeaders(lines.slice(1)), body };
+}
+
+/**
+ * The operations of a $batch request: single requests and change sets,
+ * each applied as a whole or not at all. Service Layer takes multipart/mixed;
+ * json sends the OData 4.01 JSON format instead.
+ */
+export class ODataBatch extends BatchRequests {
+  private readonly items: BatchItem[] = [];
+  private lastId = 0;
+
+  constructor(readonly serviceRoot: string, readonly json = false) {
+    super();
+  }
+
+  nextId(): string {
+    return String(++this.lastId);
+  }
+
+  add<T = unknown>(method: string, url: string, options?: BatchRequestOptions<T>): BatchOperation<T> {
+    const op = new BatchOperation<T>(method, url, this.nextId(), options);
+    this.items.push(op);
+    return op;
+  }
+
+  changeSet(): ODataChangeSet {
+    const cs = new ODataChangeSet(this);
+    this.items.push(cs);
+    return cs;
+  }
+
+  /** The operations in order, change sets included. */
+  get operations(): BatchOperation<any>[] {
+    return this.items.flatMap((it) => (it instanceof ODataChangeSet ? it.operations : [it]));
+  }
+
+  // request paths are absolute from the host, as Service Layer wants them,
+  // or Content-ID references
+  private requestPath(url: string): string {
+    if (url.startsWith('$')) return url;
+    return new URL(this.serviceRoot).pathname.replace(/\/$/, '') + '/' + url;
+  }
+
+  private requestPart(op: BatchOperation<any>, inSet: boolean): string {
+    let s = 'Content-Type: application/http\r\nContent-Transfer-Encoding: binary\r\n';
+    if (inSet) s += 'Content-ID: ' + op.contentId + '\r\n';
+    s += '\r\n' + op.method + ' ' + this.requestPath(op.url) + ' HTTP/1.1\r\nAccept: application/json\r\n';
+    const body = op.options.body === undefined ? '' : JSON.stringify(op.options.body);
+    if (op.options.body !== undefined) s += 'Content-Type: application/json\r\n';
+    for (const [name, value] of Object.entries(op.options.headers ?? {})) s += name + ': ' + value + '\r\n';
+    return s + '\r\n' + body + '\r\n';
+  }
+
+  /** The multipart/mixed body and its content type. */
+  toMultipart(): { body: string; contentType: string } {
+    const bound = boundary('batch');
+    let body = '';
+    for (const it of this.items) {
+      body += '--' + bound + '\r\n';
+      if (!(it instanceof ODataChangeSet)) {
+        body += this.requestPart(it, false);
+        continue;
+      }
+      const setBound = boundary('changeset');
+      body += 'Content-Type: multipart/mixed; boundary=' + setBound + '\r\n\r\n';
+      for (const op of it.operations) body += '--' + setBound + '\r\n' + this.requestPart(op, true);
+      body += '--' + setBound + '--\r\n';
+    }
+    body += '--' + bound + '--\r\n';
+    return { body, contentType: 'multipart/mixed; boundary=' + bound };
+  }
+
+  /** The JSON batch body (OData 4.01). */
+  toJSON(): { requests: Record<string, unknown>[] } {
+    const requests: Record<string, unknown>[] = [];
+    this.items.forEach((it, i) => {
+      const group = it instanceof ODataChangeSet ? 'g' + (i + 1) : undefined;
+      for (const op of it instanceof ODataChangeSet ? it.operations : [it]) {
+        const headers: Record<string, string> = { ...op.options.headers };
+        if (op.options.body !== undefined) headers['Content-Type'] = 'application/json';
+        // a $<id> reference needs its request in dependsOn
+        const ref = /^\$(\d+)(?:[/?]|$)/.exec(op.url);
+        const dependsOn = ref ? [ref[1]] : undefined;
+        requests.push({ id: op.contentId, atomicityGroup: group, dependsOn, method: op.method, url: op.url, headers, body: op.options.body });
+      }
+    });
+    return { requests };
+  }
+
+  /**
+   * Sets the outcome of each operation from the batch response. Parts match
+   * the requests in order, and by Content-ID within change sets; a change
+   * set that failed has one response, which all its operations get.
+   */
+  parse(contentType: string, body: string): void {
+    if (/^application\/json/i.test(contentType)) {
+      const ops = new Map(this.operations.map((op) => [op.contentId, op] as [string, BatchOperation<any>]));
+      for (const r of JSON.parse(body).responses ?? []) {
+        const op = ops.get(String(r.id));
+        if (!op) throw new Error('odata: batch: response to unknown request ' + r.id);
+        op.setResponse(r.status, r.headers ?? {}, r.body === undefined ? '' : JSON.stringify(r.body));
+      }
+      return;
+    }
+    const parts = multipartParts(body, boundaryOf(contentType));
+    parts.forEach((part, i) => {
+      const it = this.items[i];
+      if (!it) throw new Error('odata: batch: more responses than requests');
+      const type = part.headers['content-type'] ?? '';
+      if (!/^multipart\//i.test(type)) {
+        const r = parseResponse(part);
+        for (const op of it instanceof ODataChangeSet ? it.operations : [it]) op.setResponse(r.status, r.headers, r.body);
+        return;
+      }
+      if (!(it instanceof ODataChangeSet)) throw new Error('odata: batch: change set response to ' + it.method + ' ' + it.url);
+      multipartParts(part.body, boundaryOf(type)).forEach((sub, j) => {
+        const id = sub.headers['content-id'];
+        const op = id ? it.operations.find((o) => o.contentId === id) : it.operations[j];
+        if (!op) throw new Error('odata: batch: response without request in change set');
+        const r = parseResponse(sub);
+        op.setResponse(r.status, r.headers, r.body);
+      });
+    });
+  }
+
+  /** Posts the batch to $batch; init carries credentials, e.g. the B1SESSION cookie. */
+  async send(fetchFn: typeof fetch = fetch, init: RequestInit = {}): Promise<void> {
+    const { body, contentType } = this.json
+      ? { body: JSON.stringify(this.toJSON()), contentType: 'application/json' }
+      : this.toMultipart();
+    const url = this.serviceRoot.replace(/\/$/, '') + '/$batch';
+    const headers = { ...(init.headers as Record<string, string> | undefined), 'Content-Type': contentType };
+    const res = await fetchFn(url, { ...init, method: 'POST', headers, body });
+    const text = await res.text();
+    if (!res.ok) throw new BatchError('POST', url, res.status, text);
+    this.parse(res.headers.get('Content-Type') ?? '', text);
+  }
+}
+`
@@ -0,0 +1,90 @@
+package sapgen
+
+// TsErrorsModule is the TypeScript counterpart of the Go odata.Error,
+// written as errors.ts next to batch.ts: the error payloads of OData v4
+// and v3 (Service Layer) responses, and the well-known B1 error codes.
+const TsErrorsModule = `export interface ODataErrorDetail {
+  code: string;
+  message: string;
+  target?: string;
+}
+
+/**
+ * The error payload of a response, { error: ... }: the v4 form with a message
+ * string, or the v3 form (also Service Layer v1 and v2) with a numeric code
+ * and { lang, value } as message. Numeric codes are kept in decimal: '-2028'.
+ */
+export class ODataError extends Error {
+  constructor(
+    readonly code: string,
+    message: string,
+    readonly lang?: string,
+    readonly target?: string,
+    readonly details: ODataErrorDetail[] = [],
+    readonly innerError?: unknown,
+  ) {
+    super(message);
+  }
+
+  /** The B1Errors kind of the code, e.g. 'notFound'. */
+  get kind(): string | undefined {
+    return B1Errors[this.code];
+  }
+
+  toString(): string {
+    return (this.code + ' ' + this.message).trim() + (this.target ? ' (' + this.target + ')' : '');
+  }
+}
+
+/** Service Layer error codes by kind; add the codes an application handles. */
+export const B1Errors: Record<string, string> = {
+  '-2028': 'notFound',
+  '-2035': 'alreadyExists',
+  '301': 'sessionExpired',
+  '100000027': 'loginFailed',
+};
+
+function codeText(code: unknown): string {
+  return typeof code === 'string' || typeof code === 'number' ? String(code) : '';
+}
+
+// message is a string (v4) or { lang, value } (v3)
+function messageText(message: any): [string, string | undefined] {
+  if (typeof message === 'string') return [message, undefined];
+  if (message && typeof message === 'object') return [String(message.value ?? ''), message.lang];
+  return ['', undefined];
+}
+
+/** Reads the error payload of a response body (text or parsed JSON); undefined when it holds none. */
+export function parseODataError(body: unknown): ODataError | undefined {
+  let data = body;
+  if (typeof body === 'string') {
+    try {
+      data = JSON.parse(body);
+    } catch {
+      return undefined;
+    }
+  }
+  if (!data || typeof data !== 'object') return undefined;
+  const raw = (data as any).error ?? (data as any)['odata.error'];
+  if (!raw || typeof raw !== 'object') return undefined;
+  const [message, lang] = messageText(raw.message);
+  const details: ODataErrorDetail[] = Array.isArray(raw.details)
+    ? raw.details.map((d: any) => ({ code: codeText(d.code), message: messageText(d.message)[0], target: d.target }))
+    : [];
+  return new ODataError(codeText(raw.code), message, lang, raw.target, details, raw.innererror);
+}
+
+/**
+ * Reports whether err is, or carries as odata or cause, an ODataError of the
+ * B1Errors kind, e.g. isB1Error(op.error, 'notFound'). A 404 status is
+ * notFound too.
+ */
+export function isB1Error(err: unknown, kind: string): boolean {
+  for (let e: any = err; e; e = e.odata ?? e.cause) {
+    if (e instanceof ODataError) return e.kind === kind;
+    if (kind === 'notFound' && e.status === 404) return true;
+  }
+  return false;
+}
+`
@@ -0,0 +1,297 @@
+package sapgen
+
+// TsQueryModule is the TypeScript counterpart of the Go odata.Query: the
+// runtime of the generated <Type>Fields descriptors, written as query.ts
+// and imported as a namespace (import * as odata from './query').
+const TsQueryModule = `export interface Pather {
+  readonly odataPath: string;
+}
+
+export function childPath(parent: string, name: string): string {
+  return parent === '' ? name : parent + '/' + name;
+}
+
+// Filter kinds by binding: an atom is never parenthesized, 'and' binds
+// tighter than 'or'.
+const ATOM = 0;
+const AND = 1;
+const OR = 2;
+
+/** A $filter expression; literals are written for OData v4, or v2/v3. */
+export class Filter {
+  constructor(readonly kind: number, readonly render: (v3: boolean) => string) {}
+
+  toString(v3 = false): string {
+    return this.render(v3);
+  }
+}
+
+function operand(f: Filter, v3: boolean, parent: number): string {
+  const s = f.render(v3);
+  return f.kind > parent ? '(' + s + ')' : s;
+}
+
+function join(kind: number, op: string, filters: Filter[]): Filter {
+  if (filters.length === 0) return new Filter(ATOM, () => (kind === AND ? 'true' : 'false'));
+  if (filters.length === 1) return filters[0];
+  return new Filter(kind, (v3) => filters.map((f) => operand(f, v3, kind)).join(' ' + op + ' '));
+}
+
+export function and(...filters: Filter[]): Filter {
+  return join(AND, 'and', filters);
+}
+
+export function or(...filters: Filter[]): Filter {
+  return join(OR, 'or', filters);
+}
+
+export function not(f: Filter): Filter {
+  return new Filter(ATOM, (v3) => 'not ' + operand(f, v3, ATOM));
+}
+
+/** A filter written by hand, for what the fields cannot express. */
+export function rawFilter(expr: string): Filter {
+  return new Filter(OR, () => expr);
+}
+
+function quote(s: string): string {
+  return "'" + s.replace(/'/g, "''") + "'";
+}
+
+const bareTypes = ['Edm.Boolean', 'Edm.Byte', 'Edm.SByte', 'Edm.Int16', 'Edm.Int32', 'Edm.Single', 'Edm.Double', 'Edm.Date', 'Edm.TimeOfDay'];
+
+/** Writes v as a URL literal of the Edm type (or qualified enum type). */
+export function formatLiteral(v: unknown, edmType: string, v3: boolean): string {
+  if (v === null || v === undefined) return 'null';
+  if (v instanceof Date) {
+    if (edmType === 'Edm.Date') return v.toISOString().slice(0, 10);
+    if (edmType === 'Edm.DateTime') {
+      const s = v.toISOString().replace(/Z$/, '');
+      return v3 ? 'datetime' + quote(s) : s;
+    }
+    return v3 ? 'datetimeoffset' + quote(v.toISOString()) : v.toISOString();
+  }
+  if (typeof v === 'boolean' && !edmType.startsWith('Edm.')) {
+    // a BoYesNoEnum read as boolean (yesNoBool)
+    return formatLiteral(v ? 'tYES' : 'tNO', edmType, v3);
+  }
+  const quoted = typeof v === 'string';
+  const s = String(v); // numbers, and decimal.js or big.js values
+  switch (edmType) {
+    case 'Edm.String':
+      return quote(s);
+    case 'Edm.Guid':
+      return v3 ? 'guid' + quote(s) : s;
+    case 'Edm.Int64':
+      return v3 ? s + 'L' : s;
+    case 'Edm.Decimal':
+      return v3 ? s + 'M' : s;
+  }
+  if (bareTypes.indexOf(edmType) >= 0) return s;
+  if (edmType.startsWith('Edm.') || !quoted) return quoted ? quote(s) : s;
+  // an enum member: NS.Type'Member' in v4, the plain string in v3
+  return v3 ? quote(s) : edmType + quote(s);
+}
+
+/** One $orderby item. */
+export type Order = string;
+
+/** A primitive property; T is the type of the model field. */
+export class Field<T> implements Pather {
+  constructor(readonly odataPath: string, readonly edmType: string) {}
+
+  private compare(op: string, v: T): Filter {
+    return new Filter(ATOM, (v3) => this.odataPath + ' ' + op + ' ' + formatLiteral(v, this.edmType, v3));
+  }
+
+  eq(v: T): Filter {
+    return this.compare('eq', v);
+  }
+  ne(v: T): Filter {
+    return this.compare('ne', v);
+  }
+  gt(v: T): Filter {
+    return this.compare('gt', v);
+  }
+  ge(v: T): Filter {
+    return this.compare('ge', v);
+  }
+  lt(v: T): Filter {
+    return this.compare('lt', v);
+  }
+  le(v: T): Filter {
+    return this.compare('le', v);
+  }
+
+  /** Matches any of values, sent as eq comparisons joined by or. */
+  in(...values: T[]): Filter {
+    return or(...values.map((v) => this.eq(v)));
+  }
+
+  isNull(): Filter {
+    return new Filter(ATOM, () => this.odataPath + ' eq null');
+  }
+  notNull(): Filter {
+    return new Filter(ATOM, () => this.odataPath + ' ne null');
+  }
+
+  asc(): Order {
+    return this.odataPath;
+  }
+  desc(): Order {
+    return this.odataPath + ' desc';
+  }
+}
+
+/** An Edm.String property, with the string functions. */
+export class StringField extends Field<string> {
+  constructor(odataPath: string) {
+    super(odataPath, 'Edm.String');
+  }
+
+  private call(fn: string, s: string): Filter {
+    return new Filter(ATOM, (v3) =>
+      v3 && fn === 'contains'
+        ? 'substringof(' + quote(s) + ',' + this.odataPath + ')'
+        : fn + '(' + this.odataPath + ',' + quote(s) + ')',
+    );
+  }
+
+  contains(s: string): Filter {
+    return this.call('contains', s);
+  }
+  startsWith(s: string): Filter {
+    return this.call('startswith', s);
+  }
+  endsWith(s: string): Filter {
+    return this.call('endswith', s);
+  }
+}
+
+/** A collection property whose elements are addressed through E. */
+export class CollectionField<E> implements Pather {
+  constructor(readonly odataPath: string, private readonly elem: (path: string) => E) {}
+
+  /** Matches when pred holds for some element, or without pred when there is one. */
+  any(pred?: (e: E) => Filter): Filter {
+    return this.lambda('any', pred);
+  }
+
+  /** Matches when pred holds for every element. */
+  all(pred: (e: E) => Filter): Filter {
+    return this.lambda('all', pred);
+  }
+
+  // the variable is numbered by the depth of the path, so a nested lambda
+  // never shadows the one it is in
+  private lambda(op: string, pred?: (e: E) => Filter): Filter {
+    const x = 'x' + (this.odataPath.split('/').length - 1);
+    const body = pred ? pred(this.elem(x)) : undefined;
+    return new Filter(ATOM, (v3) =>
+      body ? this.odataPath + '/' + op + '(' + x + ':' + operand(body, v3, OR) + ')' : this.odataPath + '/' + op + '()',
+    );
+  }
+}
+
+type Option = [string, string];
+
+/** The system query options of a request, set by chained calls. */
+export class Query {
+  private filterExpr?: Filter;
+  private selects: string[] = [];
+  private expands: { path: string; options?: Query }[] = [];
+  private orders: Order[] = [];
+  private topN?: number;
+  private skipN?: number;
+  private withCount = false;
+
+  /** Adds f; filters added more than once are joined with and. */
+  filter(f: Filter): this {
+    this.filterExpr = this.filterExpr ? and(this.filterExpr, f) : f;
+    return this;
+  }
+
+  select(...props: Pather[]): this {
+    for (const p of props) this.selects.push(p.odataPath);
+    return this;
+  }
+
+  /** Adds a navigation property to $expand, with options for the expanded entities. */
+  expand(nav: Pather, options?: Query): this {
+    this.expands.push({ path: nav.odataPath, options });
+    return this;
+  }
+
+  orderBy(...items: Order[]): this {
+    this.orders.push(...items);
+    return this;
+  }
+
+  top(n: number): this {
+    this.topN = n;
+    return this;
+  }
+
+  skip(n: number): this {
+    this.skipN = n;
+    return this;
+  }
+
+  /** Asks for the total count ($count=true, $inlinecount=allpages in v3). */
+  count(): this {
+    this.withCount = true;
+    return this;
+  }
+
+  /** The query string, for OData v4 or with v3 for v2/v3 services. */
+  toString(v3 = false): string {
+    return this.options(v3)
+      .map(([name, value]) => name + '=' + encodeURIComponent(value))
+      .join('&');
+  }
+
+  private options(v3: boolean): Option[] {
+    const out: Option[] = [];
+    if (this.filterExpr) out.push(['$filter', this.filterExpr.render(v3)]);
+    const selects = this.selects.slice();
+    const expands: string[] = [];
+    for (const e of this.expands) {
+      if (!v3) {
+        const sub = e.options ? e.options.options(false) : [];
+        expands.push(sub.length > 0 ? e.path + '(' + sub.map(([n, v]) => n + '=' + v).join(';') + ')' : e.path);
+        continue;
+      }
+      // v3 has no nested options: A/B paths in $select and $expand
+      const [sel, exp] = flatten(e.path, e.options);
+      if (this.selects.length > 0) selects.push(...sel);
+      expands.push(...exp);
+    }
+    if (selects.length > 0) out.push(['$select', selects.join(',')]);
+    if (expands.length > 0) out.push(['$expand', expands.join(',')]);
+    if (this.orders.length > 0) out.push(['$orderby', this.orders.join(',')]);
+    if (this.topN !== undefined) out.push(['$top', String(this.topN)]);
+    if (this.skipN !== undefined) out.push(['$skip', String(this.skipN)]);
+    if (this.withCount) out.push(v3 ? ['$inlinecount', 'allpages'] : ['$count', 'true']);
+    return out;
+  }
+
+  /** The options of an expand for a v3 service, which takes only $select and $expand. */
+  flattenInto(path: string): [string[], string[]] {
+    if (this.filterExpr || this.orders.length > 0 || this.topN !== undefined || this.skipN !== undefined || this.withCount) {
+      throw new Error('odata: v3 services take only $select and $expand within $expand');
+    }
+    const selects = this.selects.length === 0 ? [path] : this.selects.map((s) => childPath(path, s));
+    const expands = [path];
+    for (const e of this.expands) {
+      const [sel, exp] = flatten(childPath(path, e.path), e.options);
+      if (this.selects.length > 0) selects.push(...sel);
+      expands.push(...exp);
+    }
+    return [selects, expands];
+  }
+}
+
+function flatten(path: string, options?: Query): [string[], string[]] {
+  return options ? options.flattenInto(path) : [[path], [path]];
+}
+`
@@ -0,0 +1,40 @@
+package sapgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UDFPrefix starts the names of SAP B1 user-defined fields.
+const UDFPrefix = "U_"
+
+// IsUDF reports whether a property is a user-defined field.
+func IsUDF(prop string) bool { return strings.HasPrefix(prop, UDFPrefix) }
+
+// UDF controls where user-defined fields go. They differ between company
+// databases, so keeping them in the models ties the models to one company.
+type UDF struct {
+	// Mode "inline" (default) keeps U_ properties in their types;
+	// "extension" leaves them out and generates a <Type>UDF extension for
+	// every entity and complex type instead.
+	Mode string `json:"mode,omitempty"`
+	// Source holds the metadata the extensions are generated from (a config
+	// source name, or a file for the -udf-in flags); default the target's own.
+	Source string `json:"source,omitempty"`
+}
+
+// Extension reports whether U_ properties move to extension types.
+func (u UDF) Extension() bool { return u.Mode == "extension" }
+
+// Validate checks the mode.
+func (u UDF) Validate() error {
+	switch u.Mode {
+	case "", "inline", "extension":
+	default:
+		return fmt.Errorf("unknown udf mode %q (use inline | extension)", u.Mode)
+	}
+	if u.Source != "" && !u.Extension() {
+		return fmt.Errorf("udf source %q needs mode extension", u.Source)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package sapgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsUDF(t *testing.T) {
+	for name, want := range map[string]bool{"U_MyField": true, "U_": true, "UMyField": false, "u_field": false, "ItemCode": false} {
+		if got := IsUDF(name); got != want {
+			t.Errorf("IsUDF(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestUDFValidate(t *testing.T) {
+	tests := []struct {
+		u   UDF
+		ext bool
+		err string
+	}{
+		{UDF{}, false, ""},
+		{UDF{Mode: "inline"}, false, ""},
+		{UDF{Mode: "extension"}, true, ""},
+		{UDF{Mode: "extension", Source: "company"}, true, ""},
+		{UDF{Mode: "separate"}, false, `unknown udf mode "separate"`},
+		{UDF{Source: "company"}, false, `udf source "company" needs mode extension`},
+	}
+	for _, tt := range tests {
+		if got := tt.u.Extension(); got != tt.ext {
+			t.Errorf("%+v: Extension() = %v", tt.u, got)
+		}
+		err := tt.u.Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: error %v, want %q", tt.u, err, tt.err)
+		}
+	}
+}